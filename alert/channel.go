@@ -0,0 +1,84 @@
+package alert
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/webhook"
+)
+
+// Notification is sent to channels when an alert fires or resolves
+type Notification struct {
+	Rule        string    `json:"rule"`
+	Description string    `json:"description"` // The rule condition, e.g. temperature < 2 for 10m
+	Severity    string    `json:"severity,omitempty"`
+	State       State     `json:"state"` // StateFiring or StateResolved
+	Metric      string    `json:"metric"`
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	Time        time.Time `json:"time"`
+	FiredAt     time.Time `json:"fired_at"`
+	Message     string    `json:"message"`
+}
+
+// Channel delivers notifications. Notify is called from the engine goroutine, so
+// slow channels should hand the notification off, e.g. to a queue.
+type Channel interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogChannel writes notifications to the log
+type LogChannel struct{}
+
+// Name implements Channel
+func (LogChannel) Name() string {
+	return "log"
+}
+
+// Notify implements Channel
+func (LogChannel) Notify(ctx context.Context, n Notification) error {
+	log.Printf("Alert %s: %s", n.State, n.Message)
+	return nil
+}
+
+// Publisher publishes webhook events, implemented by webhook.Dispatcher
+type Publisher interface {
+	Publish(event webhook.Event)
+}
+
+// WebhookChannel publishes notifications as alert_firing and alert_resolved webhook events
+type WebhookChannel struct {
+	publisher Publisher
+}
+
+// NewWebhookChannel creates a channel publishing to the webhook dispatcher
+func NewWebhookChannel(publisher Publisher) *WebhookChannel {
+	return &WebhookChannel{publisher: publisher}
+}
+
+// Name implements Channel
+func (c *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// Notify implements Channel. Deliveries are stored and retried by the dispatcher.
+func (c *WebhookChannel) Notify(ctx context.Context, n Notification) error {
+	eventType := webhook.EventAlertFiring
+	if n.State == StateResolved {
+		eventType = webhook.EventAlertResolved
+	}
+
+	c.publisher.Publish(webhook.Event{
+		Type:     eventType,
+		Time:     n.Time,
+		Message:  n.Message,
+		Rule:     n.Rule,
+		Severity: n.Severity,
+		Metric:   n.Metric,
+		Value:    n.Value,
+		Limit:    n.Threshold,
+	})
+	return nil
+}
@@ -0,0 +1,342 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// State of a rule
+type State string
+
+const (
+	StateInactive State = "inactive" // Condition not met
+	StatePending  State = "pending"  // Condition met for less than the rule's For
+	StateFiring   State = "firing"   // Condition held for the rule's For
+	StateResolved State = "resolved" // Was firing, the value is back past the hysteresis margin
+)
+
+// Config configures the Engine
+type Config struct {
+	Rules      []Rule
+	QuietHours QuietHours
+}
+
+// Repository is the subset of the repository used by the Engine
+type Repository interface {
+	GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error)
+	SaveAlertState(state repository.AlertStateRecord) error
+	GetAlertStates() ([]repository.AlertStateRecord, error)
+}
+
+// Status is the current state of a rule, as served by the /alerts endpoint
+type Status struct {
+	Rule        string    `json:"rule"`
+	Description string    `json:"description"`
+	Severity    string    `json:"severity,omitempty"`
+	State       State     `json:"state"`
+	Value       *float64  `json:"value,omitempty"` // Latest evaluated value, nil before the first evaluation
+	Since       time.Time `json:"since"`
+	FiredAt     time.Time `json:"fired_at,omitzero"`
+	ResolvedAt  time.Time `json:"resolved_at,omitzero"`
+}
+
+// pendingNotification is a notification of a rule, sent once mu is released
+type pendingNotification struct {
+	notification Notification
+	channels     []Channel
+}
+
+// ruleState is a rule with its persisted state
+type ruleState struct {
+	rule     Rule
+	record   repository.AlertStateRecord
+	value    *float64
+	channels []Channel
+}
+
+// Engine evaluates the rules on each measurement of the stream
+type Engine struct {
+	config   Config
+	repo     Repository
+	location *time.Location
+	window   time.Duration // Longest rule window, history kept in memory
+
+	mu     sync.Mutex
+	rules  []*ruleState
+	recent []bme280.Measurement
+}
+
+// NewEngine validates the rules and creates an engine notifying the given channels
+func NewEngine(repo Repository, config Config, channels ...Channel) (*Engine, error) {
+	byName := make(map[string]Channel)
+	for _, channel := range channels {
+		byName[channel.Name()] = channel
+	}
+
+	e := &Engine{
+		config:   config,
+		repo:     repo,
+		location: timezone.GetMachineLocation(),
+	}
+
+	names := make(map[string]bool)
+	for _, rule := range config.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate alert rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		state := &ruleState{rule: rule, channels: channels}
+		if len(rule.Channels) > 0 {
+			state.channels = nil
+			for _, name := range rule.Channels {
+				channel, ok := byName[name]
+				if !ok {
+					return nil, fmt.Errorf("alert rule %s: unknown channel %q", rule.Name, name)
+				}
+				state.channels = append(state.channels, channel)
+			}
+		}
+		state.record = repository.AlertStateRecord{Rule: rule.Name, State: string(StateInactive)}
+
+		e.rules = append(e.rules, state)
+		e.window = max(e.window, rule.Window)
+	}
+
+	return e, nil
+}
+
+// Start loads the persisted states and recent history, then evaluates the rules on
+// each measurement from stream until the context is cancelled
+func (e *Engine) Start(ctx context.Context, stream <-chan bme280.Measurement) error {
+	log.Printf("Starting alert engine with %d rules", len(e.rules))
+
+	if err := e.Load(time.Now()); err != nil {
+		logging.Errorf("Failed to load alert state: %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Alert engine stopped")
+			return ctx.Err()
+
+		case measurement, ok := <-stream:
+			if !ok {
+				return nil
+			}
+			e.Check(ctx, measurement)
+		}
+	}
+}
+
+// Load restores the persisted rule states and the history covering the longest
+// rule window ending at now
+func (e *Engine) Load(now time.Time) error {
+	states, err := e.repo.GetAlertStates()
+	if err != nil {
+		return err
+	}
+
+	var recent []bme280.Measurement
+	if e.window > 0 {
+		records, err := e.repo.GetMeasurementsByTimeRange(now.Add(-e.window), now)
+		if err != nil {
+			return fmt.Errorf("failed to load alert history: %w", err)
+		}
+		for _, record := range records {
+			recent = append(recent, record.ToMeasurement())
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, record := range states {
+		for _, rs := range e.rules {
+			if rs.rule.Name == record.Rule {
+				rs.record = record
+			}
+		}
+	}
+	e.recent = recent
+
+	return nil
+}
+
+// Check records the measurement and evaluates every rule against it. Notifications
+// are sent after the rules are evaluated, without holding mu, so a slow channel does
+// not block Alerts.
+func (e *Engine) Check(ctx context.Context, measurement bme280.Measurement) {
+	if measurement.Timestamp.IsZero() {
+		measurement.Timestamp = time.Now()
+	}
+	now := measurement.Timestamp
+
+	e.mu.Lock()
+
+	cutoff := now.Add(-e.window)
+	first := 0
+	for first < len(e.recent) && e.recent[first].Timestamp.Before(cutoff) {
+		first++
+	}
+	e.recent = append(e.recent[first:], measurement)
+
+	quiet := e.config.QuietHours.Contains(now.In(e.location))
+	var notifications []pendingNotification
+	for _, rs := range e.rules {
+		value, ok := rs.rule.value(e.recent, now)
+		if !ok {
+			continue
+		}
+		notifications = e.evaluate(rs, value, now, quiet, notifications)
+	}
+	e.mu.Unlock()
+
+	e.send(ctx, notifications)
+}
+
+// evaluate advances the state machine of a rule and appends the notifications it
+// requires to notifications; the caller holds mu
+func (e *Engine) evaluate(rs *ruleState, value float64, now time.Time, quiet bool, notifications []pendingNotification) []pendingNotification {
+	rs.value = &value
+	before := rs.record
+
+	switch State(rs.record.State) {
+	case StateInactive, StateResolved:
+		if rs.rule.breached(value) {
+			rs.record.State = string(StatePending)
+			rs.record.Since = now
+			if rs.rule.For == 0 {
+				e.fire(rs, now)
+			}
+		}
+	case StatePending:
+		switch {
+		case !rs.rule.breached(value):
+			rs.record.State = string(StateInactive)
+			rs.record.Since = now
+		case now.Sub(rs.record.Since) >= rs.rule.For:
+			e.fire(rs, now)
+		}
+	case StateFiring:
+		if rs.rule.cleared(value) {
+			rs.record.State = string(StateResolved)
+			rs.record.Since = now
+			rs.record.ResolvedAt = now
+			// A resolution is only worth sending when the firing was notified
+			if rs.record.Notified && (!quiet || rs.rule.IgnoreQuietHours) {
+				notifications = append(notifications, e.notification(rs, StateResolved, value, now))
+			}
+			rs.record.Notified = false
+		}
+	}
+
+	// Firings held back by quiet hours are notified once the period ends
+	if State(rs.record.State) == StateFiring && !rs.record.Notified && (!quiet || rs.rule.IgnoreQuietHours) {
+		notifications = append(notifications, e.notification(rs, StateFiring, value, now))
+		rs.record.Notified = true
+	}
+
+	rs.record.Value = value
+	if rs.record.State != before.State || rs.record.Notified != before.Notified {
+		rs.record.UpdatedAt = now
+		if err := e.repo.SaveAlertState(rs.record); err != nil {
+			logging.Errorf("Failed to save alert state of %s: %v", rs.rule.Name, err)
+		}
+	}
+
+	return notifications
+}
+
+// fire moves a rule to the firing state; the caller holds mu
+func (e *Engine) fire(rs *ruleState, now time.Time) {
+	rs.record.State = string(StateFiring)
+	rs.record.Since = now
+	rs.record.FiredAt = now
+	rs.record.Notified = false
+}
+
+// notification builds a notification to the channels of a rule; the caller holds mu
+func (e *Engine) notification(rs *ruleState, state State, value float64, now time.Time) pendingNotification {
+	n := Notification{
+		Rule:        rs.rule.Name,
+		Description: rs.rule.String(),
+		Severity:    rs.rule.Severity,
+		State:       state,
+		Metric:      rs.rule.Metric,
+		Value:       value,
+		Threshold:   rs.rule.Threshold,
+		Time:        now,
+		FiredAt:     rs.record.FiredAt,
+	}
+	if state == StateFiring {
+		n.Message = fmt.Sprintf("%s firing: %s (value %s)", rs.rule.Name, n.Description, formatValue(value))
+	} else {
+		n.Message = fmt.Sprintf("%s resolved: %s (value %s)", rs.rule.Name, n.Description, formatValue(value))
+	}
+
+	return pendingNotification{notification: n, channels: rs.channels}
+}
+
+// send delivers the notifications to their channels; the caller does not hold mu
+func (e *Engine) send(ctx context.Context, notifications []pendingNotification) {
+	for _, p := range notifications {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		for _, channel := range p.channels {
+			if err := channel.Notify(ctx, p.notification); err != nil {
+				logging.Errorf("Failed to notify %s of alert %s: %v", channel.Name(), p.notification.Rule, err)
+			}
+		}
+		cancel()
+	}
+}
+
+// Alerts returns the status of every rule, firing rules first
+func (e *Engine) Alerts() []Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	statuses := make([]Status, 0, len(e.rules))
+	for _, rs := range e.rules {
+		status := Status{
+			Rule:        rs.rule.Name,
+			Description: rs.rule.String(),
+			Severity:    rs.rule.Severity,
+			State:       State(rs.record.State),
+			Since:       rs.record.Since,
+			FiredAt:     rs.record.FiredAt,
+			ResolvedAt:  rs.record.ResolvedAt,
+		}
+		if rs.value != nil {
+			value := *rs.value
+			status.Value = &value
+		}
+		statuses = append(statuses, status)
+	}
+
+	rank := map[State]int{StateFiring: 0, StatePending: 1, StateResolved: 2, StateInactive: 3}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return rank[statuses[i].State] < rank[statuses[j].State]
+	})
+	return statuses
+}
+
+// formatValue formats a rule value with up to two decimals
+func formatValue(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
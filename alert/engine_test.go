@@ -0,0 +1,338 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/webhook"
+)
+
+// memoryRepository keeps alert states and history in memory
+type memoryRepository struct {
+	history []repository.MeasurementRecord
+	states  map[string]repository.AlertStateRecord
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{states: make(map[string]repository.AlertStateRecord)}
+}
+
+func (r *memoryRepository) GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error) {
+	var records []repository.MeasurementRecord
+	for _, record := range r.history {
+		if !record.Timestamp.Before(startTime) && !record.Timestamp.After(endTime) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (r *memoryRepository) SaveAlertState(state repository.AlertStateRecord) error {
+	r.states[state.Rule] = state
+	return nil
+}
+
+func (r *memoryRepository) GetAlertStates() ([]repository.AlertStateRecord, error) {
+	var states []repository.AlertStateRecord
+	for _, state := range r.states {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// recordingChannel records the notifications it receives
+type recordingChannel struct {
+	name          string
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (c *recordingChannel) Name() string {
+	return c.name
+}
+
+func (c *recordingChannel) Notify(ctx context.Context, n Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notifications = append(c.notifications, n)
+	return nil
+}
+
+func (c *recordingChannel) states() []State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var states []State
+	for _, n := range c.notifications {
+		states = append(states, n.State)
+	}
+	return states
+}
+
+var frost = Rule{
+	Name:       "frost",
+	Metric:     MetricTemperature,
+	Aggregate:  Last,
+	Operator:   Below,
+	Threshold:  2,
+	For:        10 * time.Minute,
+	Hysteresis: 1,
+	Severity:   "warning",
+}
+
+func newTestEngine(t *testing.T, repo Repository, config Config, channels ...Channel) *Engine {
+	t.Helper()
+	e, err := NewEngine(repo, config, channels...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.location = time.UTC
+	return e
+}
+
+// feed checks one reading per minute starting at start and returns the time after the last
+func feed(e *Engine, start time.Time, temperatures ...float64) time.Time {
+	for _, temperature := range temperatures {
+		e.Check(context.Background(), bme280.Measurement{Timestamp: start, Temperature: temperature})
+		start = start.Add(time.Minute)
+	}
+	return start
+}
+
+func state(e *Engine, rule string) State {
+	for _, status := range e.Alerts() {
+		if status.Rule == rule {
+			return status.State
+		}
+	}
+	return ""
+}
+
+func TestEngine_DebounceAndHysteresis(t *testing.T) {
+	channel := &recordingChannel{name: "log"}
+	e := newTestEngine(t, newMemoryRepository(), Config{Rules: []Rule{frost}}, channel)
+	now := time.Date(2026, 3, 15, 5, 0, 0, 0, time.UTC)
+
+	now = feed(e, now, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	if state(e, "frost") != StatePending || len(channel.states()) != 0 {
+		t.Fatalf("expected pending without notifications after 9 minutes, got %s %v", state(e, "frost"), channel.states())
+	}
+
+	now = feed(e, now, 1, 0.5, 1.5)
+	if state(e, "frost") != StateFiring || len(channel.states()) != 1 {
+		t.Fatalf("expected a single firing notification, got %s %v", state(e, "frost"), channel.states())
+	}
+
+	// Inside the hysteresis margin the alert keeps firing
+	now = feed(e, now, 2.5, 2.9)
+	if state(e, "frost") != StateFiring {
+		t.Fatalf("expected firing inside the hysteresis margin, got %s", state(e, "frost"))
+	}
+
+	feed(e, now, 3.5)
+	if state(e, "frost") != StateResolved {
+		t.Fatalf("expected resolved, got %s", state(e, "frost"))
+	}
+	if got := channel.states(); len(got) != 2 || got[0] != StateFiring || got[1] != StateResolved {
+		t.Errorf("expected firing then resolved, got %v", got)
+	}
+
+	n := channel.notifications[0]
+	if n.Rule != "frost" || n.Severity != "warning" || n.Description != "temperature < 2 for 10m" || n.Value != 1 {
+		t.Errorf("unexpected notification %+v", n)
+	}
+}
+
+func TestEngine_PendingResetsWhenConditionBreaks(t *testing.T) {
+	channel := &recordingChannel{name: "log"}
+	e := newTestEngine(t, newMemoryRepository(), Config{Rules: []Rule{frost}}, channel)
+	now := time.Date(2026, 3, 15, 5, 0, 0, 0, time.UTC)
+
+	now = feed(e, now, 1, 1, 1, 1, 1, 1, 1, 1, 2.1)
+	if state(e, "frost") != StateInactive {
+		t.Fatalf("expected inactive, got %s", state(e, "frost"))
+	}
+	feed(e, now, 1, 1, 1, 1, 1)
+	if len(channel.states()) != 0 {
+		t.Errorf("expected the debounce to restart, got %v", channel.states())
+	}
+}
+
+func TestEngine_QuietHours(t *testing.T) {
+	quiet, err := ParseQuietHours("22:00", "07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	critical := frost
+	critical.Name = "hard-frost"
+	critical.Threshold = 0
+	critical.Hysteresis = 0
+	critical.IgnoreQuietHours = true
+
+	channel := &recordingChannel{name: "log"}
+	e := newTestEngine(t, newMemoryRepository(), Config{Rules: []Rule{frost, critical}, QuietHours: quiet}, channel)
+
+	now := time.Date(2026, 3, 15, 6, 45, 0, 0, time.UTC)
+	readings := make([]float64, 15)
+	for i := range readings {
+		readings[i] = -1
+	}
+	now = feed(e, now, readings...)
+
+	// Only the rule ignoring quiet hours was notified before 07:00
+	if len(channel.notifications) != 1 || channel.notifications[0].Rule != "hard-frost" {
+		t.Fatalf("expected only hard-frost to notify during quiet hours, got %+v", channel.notifications)
+	}
+
+	feed(e, now, -1)
+	if len(channel.notifications) != 2 || channel.notifications[1].Rule != "frost" || channel.notifications[1].State != StateFiring {
+		t.Fatalf("expected the held firing to be notified after quiet hours, got %+v", channel.notifications)
+	}
+}
+
+func TestEngine_StatePersistsAcrossRestart(t *testing.T) {
+	repo := newMemoryRepository()
+	channel := &recordingChannel{name: "log"}
+	e := newTestEngine(t, repo, Config{Rules: []Rule{frost}}, channel)
+	now := time.Date(2026, 3, 15, 5, 0, 0, 0, time.UTC)
+	now = feed(e, now, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	if len(channel.states()) != 1 {
+		t.Fatalf("expected the alert to fire, got %v", channel.states())
+	}
+
+	restarted := &recordingChannel{name: "log"}
+	e = newTestEngine(t, repo, Config{Rules: []Rule{frost}}, restarted)
+	if err := e.Load(now); err != nil {
+		t.Fatal(err)
+	}
+	if state(e, "frost") != StateFiring {
+		t.Fatalf("expected the firing state to be restored, got %s", state(e, "frost"))
+	}
+
+	now = feed(e, now, 1, 1)
+	if len(restarted.states()) != 0 {
+		t.Fatalf("expected no notification after the restart, got %v", restarted.states())
+	}
+
+	feed(e, now, 5)
+	if got := restarted.states(); len(got) != 1 || got[0] != StateResolved {
+		t.Errorf("expected the restored alert to resolve, got %v", got)
+	}
+}
+
+func TestEngine_LoadsHistoryForWindows(t *testing.T) {
+	repo := newMemoryRepository()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	for i := 180; i > 0; i-- {
+		repo.history = append(repo.history, repository.MeasurementRecord{
+			Timestamp: now.Add(-time.Duration(i) * time.Minute),
+			Pressure:  101000 + int64(i)*2,
+		})
+	}
+
+	storm := Rule{Name: "storm", Metric: MetricPressure, Aggregate: Change, Window: 3 * time.Hour, Operator: Below, Threshold: -3}
+	channel := &recordingChannel{name: "log"}
+	e := newTestEngine(t, repo, Config{Rules: []Rule{storm}}, channel)
+	if err := e.Load(now); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Check(context.Background(), bme280.Measurement{Timestamp: now, Pressure: 101000})
+	if len(channel.notifications) != 1 {
+		t.Fatalf("expected the pressure drop to fire from history, got %v", channel.states())
+	}
+	if v := channel.notifications[0].Value; v > -3.5 || v < -3.7 {
+		t.Errorf("expected a drop of about 3.6 hPa, got %.2f", v)
+	}
+}
+
+func TestEngine_ChannelRouting(t *testing.T) {
+	log := &recordingChannel{name: "log"}
+	email := &recordingChannel{name: "email"}
+
+	routed := frost
+	routed.For = 0
+	routed.Channels = []string{"email"}
+	e := newTestEngine(t, newMemoryRepository(), Config{Rules: []Rule{routed}}, log, email)
+	feed(e, time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), 0)
+
+	if len(log.notifications) != 0 || len(email.notifications) != 1 {
+		t.Errorf("expected only the email channel to be notified, got log=%d email=%d", len(log.notifications), len(email.notifications))
+	}
+
+	routed.Channels = []string{"pager"}
+	if _, err := NewEngine(newMemoryRepository(), Config{Rules: []Rule{routed}}, log); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+	if _, err := NewEngine(newMemoryRepository(), Config{Rules: []Rule{frost, frost}}, log); err == nil {
+		t.Error("expected an error for duplicate rules")
+	}
+}
+
+// statusChannel reads the alert statuses while it is notified
+type statusChannel struct {
+	engine *Engine
+	states []State
+}
+
+func (c *statusChannel) Name() string {
+	return "status"
+}
+
+func (c *statusChannel) Notify(ctx context.Context, n Notification) error {
+	c.states = append(c.states, state(c.engine, n.Rule))
+	return nil
+}
+
+func TestEngine_NotifiesWithoutHoldingLock(t *testing.T) {
+	rule := frost
+	rule.For = 0
+	channel := &statusChannel{}
+	e := newTestEngine(t, newMemoryRepository(), Config{Rules: []Rule{rule}}, channel)
+	channel.engine = e
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		feed(e, time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), 0)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Alerts not to block while a channel is notified")
+	}
+
+	if len(channel.states) != 1 || channel.states[0] != StateFiring {
+		t.Errorf("expected the firing state to be visible to the channel, got %v", channel.states)
+	}
+}
+
+type recordingPublisher struct {
+	events []webhook.Event
+}
+
+func (p *recordingPublisher) Publish(event webhook.Event) {
+	p.events = append(p.events, event)
+}
+
+func TestWebhookChannel(t *testing.T) {
+	publisher := &recordingPublisher{}
+	channel := NewWebhookChannel(publisher)
+
+	channel.Notify(t.Context(), Notification{Rule: "frost", State: StateFiring, Metric: MetricTemperature, Value: 1, Threshold: 2, Message: "frost firing"})
+	channel.Notify(t.Context(), Notification{Rule: "frost", State: StateResolved})
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(publisher.events))
+	}
+	first := publisher.events[0]
+	if first.Type != webhook.EventAlertFiring || first.Rule != "frost" || first.Value != 1 || first.Limit != 2 || first.Message != "frost firing" {
+		t.Errorf("unexpected firing event %+v", first)
+	}
+	if publisher.events[1].Type != webhook.EventAlertResolved {
+		t.Errorf("expected a resolved event, got %s", publisher.events[1].Type)
+	}
+}
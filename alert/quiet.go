@@ -0,0 +1,56 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily period during which notifications are held back. The
+// period may wrap around midnight, e.g. 22:00 to 07:00.
+type QuietHours struct {
+	start, end int // Minutes since midnight
+	enabled    bool
+}
+
+// ParseQuietHours parses a period in HH:MM format. Empty start and end disable quiet hours.
+func ParseQuietHours(start, end string) (QuietHours, error) {
+	if start == "" && end == "" {
+		return QuietHours{}, nil
+	}
+
+	s, err := parseClock(start)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours start: %w", err)
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours end: %w", err)
+	}
+	if s == e {
+		return QuietHours{}, fmt.Errorf("quiet hours start and end cannot be equal")
+	}
+
+	return QuietHours{start: s, end: e, enabled: true}, nil
+}
+
+// Contains reports whether t, in its location, falls in the quiet period
+func (q QuietHours) Contains(t time.Time) bool {
+	if !q.enabled {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
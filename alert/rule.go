@@ -0,0 +1,203 @@
+// Package alert evaluates threshold rules against the live measurement stream,
+// with debouncing, hysteresis and quiet hours, and routes firing and resolved
+// notifications to pluggable channels. Rule states are persisted so alerts are
+// not notified again after a restart.
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+)
+
+// Metrics a rule can watch, in °C, % and hPa
+const (
+	MetricTemperature = "temperature"
+	MetricHumidity    = "humidity"
+	MetricPressure    = "pressure"
+)
+
+// Operator compares the rule value with its threshold
+type Operator string
+
+const (
+	Below        Operator = "<"
+	BelowOrEqual Operator = "<="
+	Above        Operator = ">"
+	AboveOrEqual Operator = ">="
+)
+
+// Aggregate reduces the measurements of the rule window to a single value
+type Aggregate string
+
+const (
+	Last   Aggregate = "last"   // Latest reading, no window
+	Avg    Aggregate = "avg"    // Mean over the window
+	Min    Aggregate = "min"    // Minimum over the window
+	Max    Aggregate = "max"    // Maximum over the window
+	Change Aggregate = "change" // Latest reading minus the oldest reading of the window
+)
+
+// Rule is an alert condition, e.g. temperature < 2 for 10m, humidity avg over 1h > 70,
+// or pressure change over 3h < -3
+type Rule struct {
+	Name             string
+	Metric           string
+	Aggregate        Aggregate
+	Window           time.Duration // Required by every aggregate but last
+	Operator         Operator
+	Threshold        float64
+	For              time.Duration // How long the condition must hold before the alert fires
+	Hysteresis       float64       // How far past the threshold the value must return to resolve
+	Severity         string
+	Channels         []string // Channel names; every channel when empty
+	IgnoreQuietHours bool
+}
+
+// Validate checks the rule fields
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("alert rule requires a name")
+	}
+	if _, ok := metricValue(r.Metric, bme280.Measurement{}); !ok {
+		return fmt.Errorf("alert rule %s: unknown metric %q", r.Name, r.Metric)
+	}
+	switch r.Operator {
+	case Below, BelowOrEqual, Above, AboveOrEqual:
+	default:
+		return fmt.Errorf("alert rule %s: unknown operator %q, use <, <=, > or >=", r.Name, r.Operator)
+	}
+	switch r.Aggregate {
+	case Last:
+	case Avg, Min, Max, Change:
+		if r.Window <= 0 {
+			return fmt.Errorf("alert rule %s: %s requires a window", r.Name, r.Aggregate)
+		}
+	default:
+		return fmt.Errorf("alert rule %s: unknown aggregate %q", r.Name, r.Aggregate)
+	}
+	if r.For < 0 || r.Hysteresis < 0 {
+		return fmt.Errorf("alert rule %s: for and hysteresis cannot be negative", r.Name)
+	}
+	return nil
+}
+
+// String describes the rule condition
+func (r Rule) String() string {
+	s := r.Metric
+	switch r.Aggregate {
+	case Last:
+	case Change:
+		s += " change over " + formatDuration(r.Window)
+	default:
+		s += " " + string(r.Aggregate) + " over " + formatDuration(r.Window)
+	}
+	s += " " + string(r.Operator) + " " + strconv.FormatFloat(r.Threshold, 'f', -1, 64)
+	if r.For > 0 {
+		s += " for " + formatDuration(r.For)
+	}
+	return s
+}
+
+// value reduces the samples ending at now. Windowed aggregates are only available
+// once at least half of the window has been observed.
+func (r Rule) value(samples []bme280.Measurement, now time.Time) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	latest, _ := metricValue(r.Metric, samples[len(samples)-1])
+	if r.Aggregate == Last {
+		return latest, true
+	}
+
+	cutoff := now.Add(-r.Window)
+	first := 0
+	for first < len(samples) && samples[first].Timestamp.Before(cutoff) {
+		first++
+	}
+	window := samples[first:]
+	if len(window) == 0 || now.Sub(window[0].Timestamp) < r.Window/2 {
+		return 0, false
+	}
+
+	oldest, _ := metricValue(r.Metric, window[0])
+	if r.Aggregate == Change {
+		return latest - oldest, true
+	}
+
+	result := oldest
+	sum := 0.0
+	for _, m := range window {
+		v, _ := metricValue(r.Metric, m)
+		sum += v
+		switch {
+		case r.Aggregate == Min && v < result:
+			result = v
+		case r.Aggregate == Max && v > result:
+			result = v
+		}
+	}
+	if r.Aggregate == Avg {
+		result = sum / float64(len(window))
+	}
+	return result, true
+}
+
+// breached reports whether value meets the rule condition
+func (r Rule) breached(value float64) bool {
+	return r.compare(value, r.Threshold)
+}
+
+// cleared reports whether value no longer meets the condition with the threshold
+// moved back by the hysteresis margin
+func (r Rule) cleared(value float64) bool {
+	threshold := r.Threshold - r.Hysteresis
+	if r.Operator == Below || r.Operator == BelowOrEqual {
+		threshold = r.Threshold + r.Hysteresis
+	}
+	return !r.compare(value, threshold)
+}
+
+// compare applies the rule operator
+func (r Rule) compare(value, threshold float64) bool {
+	switch r.Operator {
+	case Below:
+		return value < threshold
+	case BelowOrEqual:
+		return value <= threshold
+	case Above:
+		return value > threshold
+	default:
+		return value >= threshold
+	}
+}
+
+// metricValue returns a metric of a measurement in rule units
+func metricValue(metric string, m bme280.Measurement) (float64, bool) {
+	switch metric {
+	case MetricTemperature:
+		return m.Temperature, true
+	case MetricHumidity:
+		return m.Humidity, true
+	case MetricPressure:
+		return float64(m.Pressure) / 100, true
+	default:
+		return 0, false
+	}
+}
+
+// formatDuration formats a duration without zero units, e.g. 1h instead of 1h0m0s
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package alert
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+)
+
+// series returns one measurement per minute ending at end, with values from f
+func series(end time.Time, minutes int, f func(i int) bme280.Measurement) []bme280.Measurement {
+	var samples []bme280.Measurement
+	for i := 0; i < minutes; i++ {
+		m := f(i)
+		m.Timestamp = end.Add(-time.Duration(minutes-1-i) * time.Minute)
+		samples = append(samples, m)
+	}
+	return samples
+}
+
+func TestRule_Value(t *testing.T) {
+	end := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	samples := series(end, 181, func(i int) bme280.Measurement {
+		return bme280.Measurement{Temperature: float64(i % 10), Humidity: 60 + float64(i%2)*20, Pressure: 101500 - int64(i)*2}
+	})
+
+	tests := []struct {
+		rule Rule
+		want float64
+	}{
+		{Rule{Metric: MetricTemperature, Aggregate: Last}, 0},
+		{Rule{Metric: MetricHumidity, Aggregate: Avg, Window: time.Hour}, 69.84}, // 31 readings of 60 and 30 of 80
+		{Rule{Metric: MetricTemperature, Aggregate: Min, Window: time.Hour}, 0},
+		{Rule{Metric: MetricTemperature, Aggregate: Max, Window: time.Hour}, 9},
+		{Rule{Metric: MetricPressure, Aggregate: Change, Window: 3 * time.Hour}, -3.6},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.rule.value(samples, end)
+		if !ok {
+			t.Errorf("%s: expected a value", tt.rule)
+			continue
+		}
+		if math.Abs(got-tt.want) > 0.005 {
+			t.Errorf("%s: expected %.2f, got %.2f", tt.rule, tt.want, got)
+		}
+	}
+}
+
+func TestRule_ValueNeedsHalfTheWindow(t *testing.T) {
+	end := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	samples := series(end, 20, func(i int) bme280.Measurement { return bme280.Measurement{Humidity: 80} })
+
+	rule := Rule{Metric: MetricHumidity, Aggregate: Avg, Window: time.Hour}
+	if _, ok := rule.value(samples, end); ok {
+		t.Error("expected no value with 20 minutes of a 1h window")
+	}
+	if _, ok := rule.value(nil, end); ok {
+		t.Error("expected no value without samples")
+	}
+}
+
+func TestRule_Hysteresis(t *testing.T) {
+	rule := Rule{Operator: Below, Threshold: 2, Hysteresis: 1}
+	for _, tt := range []struct {
+		value             float64
+		breached, cleared bool
+	}{
+		{1.5, true, false},
+		{2, false, false},
+		{2.9, false, false},
+		{3.1, false, true},
+	} {
+		if got := rule.breached(tt.value); got != tt.breached {
+			t.Errorf("breached(%v) = %v", tt.value, got)
+		}
+		if got := rule.cleared(tt.value); got != tt.cleared {
+			t.Errorf("cleared(%v) = %v", tt.value, got)
+		}
+	}
+
+	rule = Rule{Operator: AboveOrEqual, Threshold: 70}
+	if !rule.breached(70) || rule.cleared(70) || !rule.cleared(69.9) {
+		t.Error("expected >= without hysteresis to clear just below the threshold")
+	}
+}
+
+func TestRule_String(t *testing.T) {
+	for _, tt := range []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Metric: MetricTemperature, Aggregate: Last, Operator: Below, Threshold: 2, For: 10 * time.Minute}, "temperature < 2 for 10m"},
+		{Rule{Metric: MetricHumidity, Aggregate: Avg, Window: time.Hour, Operator: Above, Threshold: 70}, "humidity avg over 1h > 70"},
+		{Rule{Metric: MetricPressure, Aggregate: Change, Window: 3 * time.Hour, Operator: Below, Threshold: -3}, "pressure change over 3h < -3"},
+		{Rule{Metric: MetricPressure, Aggregate: Change, Window: 90 * time.Minute, Operator: Below, Threshold: -3}, "pressure change over 1h30m < -3"},
+	} {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{Name: "frost", Metric: MetricTemperature, Aggregate: Last, Operator: Below, Threshold: 2}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, mutate := range map[string]func(r *Rule){
+		"no name":           func(r *Rule) { r.Name = "" },
+		"unknown metric":    func(r *Rule) { r.Metric = "wind" },
+		"unknown operator":  func(r *Rule) { r.Operator = "==" },
+		"unknown aggregate": func(r *Rule) { r.Aggregate = "median" },
+		"missing window":    func(r *Rule) { r.Aggregate = Avg },
+		"negative for":      func(r *Rule) { r.For = -time.Minute },
+	} {
+		rule := valid
+		mutate(&rule)
+		if err := rule.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	overnight, err := ParseQuietHours("22:00", "07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daytime, err := ParseQuietHours("12:30", "14:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(hour, minute int) time.Time { return time.Date(2026, 3, 15, hour, minute, 0, 0, time.UTC) }
+	for _, tt := range []struct {
+		quiet QuietHours
+		time  time.Time
+		want  bool
+	}{
+		{overnight, at(23, 0), true},
+		{overnight, at(3, 0), true},
+		{overnight, at(7, 0), false},
+		{overnight, at(21, 59), false},
+		{daytime, at(12, 30), true},
+		{daytime, at(14, 0), false},
+		{QuietHours{}, at(3, 0), false},
+	} {
+		if got := tt.quiet.Contains(tt.time); got != tt.want {
+			t.Errorf("Contains(%s) = %v, want %v", tt.time.Format("15:04"), got, tt.want)
+		}
+	}
+
+	for _, period := range [][2]string{{"22:00", ""}, {"25:00", "07:00"}, {"07:00", "07:00"}} {
+		if _, err := ParseQuietHours(period[0], period[1]); err == nil {
+			t.Errorf("expected an error for %v", period)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package anomaly flags unusual measurements by scoring them against rolling
+// per-hour-of-day baselines built from the measurement history.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// Metric names stored with each anomaly
+const (
+	MetricTemperature  = "temperature"
+	MetricHumidity     = "humidity"
+	MetricPressure     = "pressure"
+	MetricPressureRate = "pressure_rate" // Pa per hour
+)
+
+// Config holds the anomaly detector configuration
+type Config struct {
+	Method          Method
+	Threshold       float64       // Absolute score above which a reading is anomalous
+	BaselineWindow  time.Duration // History used to build the baselines
+	MinSamples      int           // Minimum samples in a baseline before it is used
+	RefreshInterval time.Duration // How often baselines are rebuilt from the repository
+	Cooldown        time.Duration // Minimum time between two anomalies of the same metric
+	RateWindow      time.Duration // Window of the pressure rate, in whole hours; rounded up to them otherwise
+}
+
+// DefaultConfig returns sensible defaults for the detector
+func DefaultConfig() Config {
+	return Config{
+		Method:          MAD,
+		Threshold:       3.5,
+		BaselineWindow:  14 * 24 * time.Hour,
+		MinSamples:      30,
+		RefreshInterval: time.Hour,
+		Cooldown:        15 * time.Minute,
+		RateWindow:      time.Hour,
+	}
+}
+
+// Repository is the subset of the repository used by the Detector
+type Repository interface {
+	StreamMeasurements(ctx context.Context, query repository.MeasurementQuery) iter.Seq2[repository.MeasurementRecord, error]
+	SaveAnomaly(anomaly repository.AnomalyRecord) error
+}
+
+// hourBaselines holds the baselines of one hour of the day
+type hourBaselines struct {
+	temperature Baseline
+	humidity    Baseline
+	pressure    Baseline
+}
+
+// Detector scores incoming measurements and persists the anomalous ones
+type Detector struct {
+	config   Config
+	repo     Repository
+	location *time.Location
+
+	mu          sync.Mutex
+	hours       [24]hourBaselines
+	rate        Baseline
+	refreshedAt time.Time
+	recent      []bme280.Measurement
+	lastFlagged map[string]time.Time
+}
+
+// NewDetector creates a new anomaly detector
+func NewDetector(repo Repository, config Config) *Detector {
+	// Baseline rates are changes between hourly averages
+	config.RateWindow = max(time.Hour, (config.RateWindow + time.Hour - 1).Truncate(time.Hour))
+
+	return &Detector{
+		config:      config,
+		repo:        repo,
+		location:    timezone.GetMachineLocation(),
+		lastFlagged: make(map[string]time.Time),
+	}
+}
+
+// Start consumes measurements from stream until the context is cancelled
+func (d *Detector) Start(ctx context.Context, stream <-chan bme280.Measurement) error {
+	log.Printf("Starting anomaly detector (method=%s, threshold=%.1f)", d.config.Method, d.config.Threshold)
+
+	if err := d.Refresh(ctx, time.Now()); err != nil {
+		logging.Errorf("Failed to build anomaly baselines: %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Anomaly detector stopped")
+			return ctx.Err()
+
+		case measurement, ok := <-stream:
+			if !ok {
+				return nil
+			}
+
+			if time.Since(d.refreshedAt) >= d.config.RefreshInterval {
+				if err := d.Refresh(ctx, time.Now()); err != nil {
+					logging.Errorf("Failed to refresh anomaly baselines: %v", err)
+				}
+			}
+
+			for _, anomaly := range d.Check(measurement) {
+				log.Printf("Anomaly detected: %s=%.2f (expected %.2f, score %.2f)",
+					anomaly.Metric, anomaly.Value, anomaly.Expected, anomaly.Score)
+				if err := d.repo.SaveAnomaly(anomaly); err != nil {
+					logging.Errorf("Failed to save anomaly: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Refresh rebuilds the baselines from the repository history ending at now. The
+// history is streamed; only the values of each hour of the day and the hourly
+// pressure averages are kept in memory.
+func (d *Detector) Refresh(ctx context.Context, now time.Time) error {
+	var values [24]struct{ temperature, humidity, pressure []float64 }
+	hourly := make(map[int64]*pressureSum) // By Unix start of the hour
+
+	query := repository.MeasurementQuery{From: now.Add(-d.config.BaselineWindow), To: now}
+	for record, err := range d.repo.StreamMeasurements(ctx, query) {
+		if err != nil {
+			return fmt.Errorf("failed to load baseline history: %w", err)
+		}
+
+		local := record.Timestamp.In(d.location)
+		hour := local.Hour()
+		values[hour].temperature = append(values[hour].temperature, record.Temperature)
+		values[hour].humidity = append(values[hour].humidity, record.Humidity)
+		values[hour].pressure = append(values[hour].pressure, float64(record.Pressure))
+
+		start := local.Truncate(time.Hour).Unix()
+		if hourly[start] == nil {
+			hourly[start] = &pressureSum{}
+		}
+		hourly[start].add(record.Pressure)
+	}
+
+	var hours [24]hourBaselines
+	for hour, v := range values {
+		hours[hour] = hourBaselines{
+			temperature: NewBaseline(v.temperature, d.config.Method),
+			humidity:    NewBaseline(v.humidity, d.config.Method),
+			pressure:    NewBaseline(v.pressure, d.config.Method),
+		}
+	}
+
+	rate := NewBaseline(pressureRates(hourly, d.config.RateWindow), d.config.Method)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hours = hours
+	d.rate = rate
+	d.refreshedAt = now
+
+	return nil
+}
+
+// pressureSum accumulates the pressures of an hour
+type pressureSum struct {
+	sum   float64
+	count int
+}
+
+func (p *pressureSum) add(pressure int64) {
+	p.sum += float64(pressure)
+	p.count++
+}
+
+func (p *pressureSum) average() float64 {
+	return p.sum / float64(p.count)
+}
+
+// pressureRates returns the changes between hourly pressure averages window apart,
+// in Pa/h like the live rate over the same window
+func pressureRates(hourly map[int64]*pressureSum, window time.Duration) []float64 {
+	lag := int64(window / time.Second)
+
+	var rates []float64
+	for start, curr := range hourly {
+		prev, ok := hourly[start-lag]
+		if !ok {
+			continue
+		}
+		rates = append(rates, (curr.average()-prev.average())/window.Hours())
+	}
+
+	return rates
+}
+
+// Check scores a measurement and returns the anomalies found, honoring the cooldown
+func (d *Detector) Check(measurement bme280.Measurement) []repository.AnomalyRecord {
+	if measurement.Timestamp.IsZero() {
+		measurement.Timestamp = time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	baselines := d.hours[measurement.Timestamp.In(d.location).Hour()]
+
+	var anomalies []repository.AnomalyRecord
+	d.score(&anomalies, measurement.Timestamp, MetricTemperature, measurement.Temperature, baselines.temperature)
+	d.score(&anomalies, measurement.Timestamp, MetricHumidity, measurement.Humidity, baselines.humidity)
+	d.score(&anomalies, measurement.Timestamp, MetricPressure, float64(measurement.Pressure), baselines.pressure)
+
+	if rate, ok := d.pressureRate(measurement); ok {
+		d.score(&anomalies, measurement.Timestamp, MetricPressureRate, rate, d.rate)
+	}
+
+	return anomalies
+}
+
+// score appends an anomaly when value deviates from baseline beyond the threshold
+func (d *Detector) score(anomalies *[]repository.AnomalyRecord, ts time.Time, metric string, value float64, baseline Baseline) {
+	if baseline.Samples < d.config.MinSamples {
+		return
+	}
+
+	score := baseline.Score(value, d.config.Method)
+	if math.Abs(score) < d.config.Threshold {
+		return
+	}
+
+	if last, ok := d.lastFlagged[metric]; ok && ts.Sub(last) < d.config.Cooldown {
+		return
+	}
+	d.lastFlagged[metric] = ts
+
+	*anomalies = append(*anomalies, repository.AnomalyRecord{
+		Timestamp: ts,
+		Metric:    metric,
+		Value:     value,
+		Expected:  baseline.Center,
+		Deviation: baseline.Spread,
+		Score:     score,
+		Method:    string(d.config.Method),
+	})
+}
+
+// pressureRate records the measurement and returns the pressure change in Pa/h over RateWindow.
+// The rate is only available once at least half of the window has been observed.
+func (d *Detector) pressureRate(measurement bme280.Measurement) (float64, bool) {
+	cutoff := measurement.Timestamp.Add(-d.config.RateWindow)
+	first := 0
+	for first < len(d.recent) && d.recent[first].Timestamp.Before(cutoff) {
+		first++
+	}
+	d.recent = append(d.recent[first:], measurement)
+
+	oldest := d.recent[0]
+	elapsed := measurement.Timestamp.Sub(oldest.Timestamp)
+	if elapsed < d.config.RateWindow/2 {
+		return 0, false
+	}
+
+	return float64(measurement.Pressure-oldest.Pressure) / elapsed.Hours(), true
+}
@@ -0,0 +1,216 @@
+package anomaly
+
+import (
+	"context"
+	"iter"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+type mockRepository struct {
+	data []repository.MeasurementRecord
+
+	mu        sync.Mutex
+	anomalies []repository.AnomalyRecord
+}
+
+func (m *mockRepository) StreamMeasurements(ctx context.Context, query repository.MeasurementQuery) iter.Seq2[repository.MeasurementRecord, error] {
+	return func(yield func(repository.MeasurementRecord, error) bool) {
+		for _, record := range m.data {
+			if record.Timestamp.Before(query.From) || record.Timestamp.After(query.To) {
+				continue
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *mockRepository) SaveAnomaly(anomaly repository.AnomalyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.anomalies = append(m.anomalies, anomaly)
+	return nil
+}
+
+func (m *mockRepository) saved() []repository.AnomalyRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]repository.AnomalyRecord(nil), m.anomalies...)
+}
+
+// history generates days of readings every 10 minutes with a small daily cycle
+func history(end time.Time, days int) []repository.MeasurementRecord {
+	var data []repository.MeasurementRecord
+	start := end.Add(-time.Duration(days) * 24 * time.Hour)
+	for ts := start; ts.Before(end); ts = ts.Add(10 * time.Minute) {
+		i := len(data)
+		data = append(data, repository.MeasurementRecord{
+			Timestamp:   ts,
+			Temperature: 20 + 5*math.Sin(float64(ts.Hour())/24*2*math.Pi) + float64(i%3)*0.1,
+			Humidity:    60 + float64(i%5),
+			Pressure:    101300 + int64(i%7)*5,
+		})
+	}
+	return data
+}
+
+func newTestDetector(repo Repository, method Method) *Detector {
+	cfg := DefaultConfig()
+	cfg.Method = method
+	cfg.MinSamples = 10
+	detector := NewDetector(repo, cfg)
+	detector.location = time.UTC
+	return detector
+}
+
+func TestBaseline_Scores(t *testing.T) {
+	values := []float64{10, 11, 9, 10, 10, 11, 9, 50}
+
+	mad := NewBaseline(values, MAD)
+	if mad.Center != 10 {
+		t.Errorf("expected median 10, got %v", mad.Center)
+	}
+	if score := mad.Score(50, MAD); score < 3.5 {
+		t.Errorf("expected outlier MAD score above 3.5, got %v", score)
+	}
+
+	z := NewBaseline([]float64{2, 4, 4, 4, 5, 5, 7, 9}, ZScore)
+	if z.Center != 5 || z.Spread != 2 {
+		t.Errorf("expected mean 5 and std 2, got %v and %v", z.Center, z.Spread)
+	}
+	if score := z.Score(9, ZScore); score != 2 {
+		t.Errorf("expected z-score 2, got %v", score)
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	if _, err := ParseMethod("mad"); err != nil {
+		t.Errorf("expected mad to be valid: %v", err)
+	}
+	if _, err := ParseMethod("iqr"); err == nil {
+		t.Error("expected unknown method to fail")
+	}
+}
+
+func TestDetector_FlagsTemperatureOutsideHourlyBand(t *testing.T) {
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	repo := &mockRepository{data: history(now, 7)}
+
+	for _, method := range []Method{MAD, ZScore} {
+		detector := newTestDetector(repo, method)
+		if err := detector.Refresh(t.Context(), now); err != nil {
+			t.Fatalf("Refresh returned error: %v", err)
+		}
+
+		normal := detector.Check(bme280.Measurement{Timestamp: now, Temperature: 20, Humidity: 62, Pressure: 101315})
+		if len(normal) != 0 {
+			t.Fatalf("%s: expected no anomalies for a normal reading, got %+v", method, normal)
+		}
+
+		anomalies := detector.Check(bme280.Measurement{Timestamp: now.Add(time.Minute), Temperature: 35, Humidity: 62, Pressure: 101315})
+		if len(anomalies) != 1 || anomalies[0].Metric != MetricTemperature {
+			t.Fatalf("%s: expected a temperature anomaly, got %+v", method, anomalies)
+		}
+		if anomalies[0].Method != string(method) {
+			t.Errorf("expected method %s, got %s", method, anomalies[0].Method)
+		}
+
+		// Cooldown suppresses repeated anomalies of the same metric
+		repeated := detector.Check(bme280.Measurement{Timestamp: now.Add(2 * time.Minute), Temperature: 35, Humidity: 62, Pressure: 101315})
+		if len(repeated) != 0 {
+			t.Fatalf("%s: expected cooldown to suppress anomaly, got %+v", method, repeated)
+		}
+	}
+}
+
+func TestDetector_FlagsFastPressureDrop(t *testing.T) {
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	repo := &mockRepository{data: history(now, 7)}
+	detector := newTestDetector(repo, MAD)
+	if err := detector.Refresh(t.Context(), now); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	// Only the rate should be outside its band
+	detector.hours[12].pressure = Baseline{}
+
+	detector.Check(bme280.Measurement{Timestamp: now, Temperature: 20, Humidity: 62, Pressure: 101300})
+	anomalies := detector.Check(bme280.Measurement{Timestamp: now.Add(45 * time.Minute), Temperature: 20, Humidity: 62, Pressure: 100900})
+
+	if len(anomalies) != 1 || anomalies[0].Metric != MetricPressureRate {
+		t.Fatalf("expected a pressure rate anomaly, got %+v", anomalies)
+	}
+	if anomalies[0].Value >= 0 {
+		t.Errorf("expected negative pressure rate, got %v", anomalies[0].Value)
+	}
+}
+
+func TestDetector_ScalesRateBaselineToRateWindow(t *testing.T) {
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	data := history(now, 7)
+	for i := range data {
+		// Steady fall of 100 Pa/h
+		data[i].Pressure = 200000 - int64(data[i].Timestamp.Sub(data[0].Timestamp).Hours()*100)
+	}
+
+	cfg := DefaultConfig()
+	cfg.RateWindow = 150 * time.Minute
+	detector := NewDetector(&mockRepository{data: data}, cfg)
+	detector.location = time.UTC
+	if detector.config.RateWindow != 3*time.Hour {
+		t.Fatalf("expected rate window rounded up to 3h, got %v", detector.config.RateWindow)
+	}
+	if err := detector.Refresh(t.Context(), now); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	if math.Abs(detector.rate.Center+100) > 0.01 {
+		t.Errorf("expected rate baseline centered at -100 Pa/h, got %v", detector.rate.Center)
+	}
+}
+
+func TestDetector_SkipsBaselinesWithFewSamples(t *testing.T) {
+	detector := newTestDetector(&mockRepository{}, MAD)
+	if err := detector.Refresh(t.Context(), time.Now()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	anomalies := detector.Check(bme280.Measurement{Timestamp: time.Now(), Temperature: 90})
+	if len(anomalies) != 0 {
+		t.Fatalf("expected no anomalies without baseline, got %+v", anomalies)
+	}
+}
+
+func TestDetector_StartPersistsAnomalies(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	repo := &mockRepository{data: history(now, 7)}
+	detector := newTestDetector(repo, MAD)
+	detector.location = time.Local
+
+	ctx, cancel := context.WithCancel(t.Context())
+	stream := make(chan bme280.Measurement, 1)
+	done := make(chan error, 1)
+	go func() { done <- detector.Start(ctx, stream) }()
+
+	stream <- bme280.Measurement{Timestamp: time.Now(), Temperature: 80, Humidity: 62, Pressure: 101315}
+
+	deadline := time.After(2 * time.Second)
+	for len(repo.saved()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for anomaly to be saved")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Method selects the statistic used to score readings against a baseline
+type Method string
+
+const (
+	// ZScore scores readings as (value - mean) / standard deviation
+	ZScore Method = "zscore"
+	// MAD scores readings with the modified z-score 0.6745 * (value - median) / MAD,
+	// which is robust against outliers already present in the baseline
+	MAD Method = "mad"
+)
+
+// madScale makes the median absolute deviation consistent with the standard
+// deviation of a normal distribution
+const madScale = 0.6745
+
+// meanADScale makes the mean absolute deviation consistent with the standard
+// deviation of a normal distribution, used when the MAD is zero
+const meanADScale = 1.253314
+
+// ParseMethod converts a configuration string into a Method
+func ParseMethod(method string) (Method, error) {
+	switch Method(method) {
+	case ZScore, MAD:
+		return Method(method), nil
+	default:
+		return "", fmt.Errorf("unknown anomaly method: %s", method)
+	}
+}
+
+// Baseline summarizes the expected distribution of a metric
+type Baseline struct {
+	Center  float64 // mean or median
+	Spread  float64 // standard deviation or MAD
+	Samples int
+}
+
+// NewBaseline computes the baseline of values for the given method
+func NewBaseline(values []float64, method Method) Baseline {
+	if len(values) == 0 {
+		return Baseline{}
+	}
+
+	if method == MAD {
+		center := median(values)
+		deviations := make([]float64, len(values))
+		var deviationSum float64
+		for i, v := range values {
+			deviations[i] = math.Abs(v - center)
+			deviationSum += deviations[i]
+		}
+
+		spread := median(deviations)
+		if spread == 0 {
+			// More than half of the values are identical: fall back to the mean
+			// absolute deviation, scaled so Score stays comparable
+			spread = meanADScale * madScale * deviationSum / float64(len(values))
+		}
+
+		return Baseline{Center: center, Spread: spread, Samples: len(values)}
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+
+	return Baseline{Center: mean, Spread: math.Sqrt(squares / float64(len(values))), Samples: len(values)}
+}
+
+// Score returns how many spreads value is away from the baseline center.
+// A zero spread yields a zero score since no meaningful deviation can be computed.
+func (b Baseline) Score(value float64, method Method) float64 {
+	if b.Spread == 0 {
+		return 0
+	}
+	if method == MAD {
+		return madScale * (value - b.Center) / b.Spread
+	}
+	return (value - b.Center) / b.Spread
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
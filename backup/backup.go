@@ -0,0 +1,293 @@
+// Package backup takes online snapshots of the SQLite database, keeps a rotation of
+// daily and weekly snapshots and restores them.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+)
+
+// Repository is the database being backed up
+type Repository interface {
+	Backup(ctx context.Context, path string) error
+}
+
+// Config configures the Manager
+type Config struct {
+	Dir        string // Directory of the snapshots
+	Schedule   bool   // Take a snapshot every day at Time
+	Time       string // HH:MM local time of the daily snapshot, 03:00 when empty
+	KeepDaily  int    // Days whose latest snapshot is kept
+	KeepWeekly int    // Weeks whose latest snapshot is kept
+	Gzip       bool   // Compress the snapshots
+}
+
+// Validate checks the directory, time and rotation
+func (c Config) Validate() error {
+	if c.Dir == "" {
+		return errors.New("backup directory must not be empty")
+	}
+	if _, err := c.at(); err != nil {
+		return err
+	}
+	if c.KeepDaily < 1 {
+		return fmt.Errorf("backups must keep at least 1 daily snapshot, got %d", c.KeepDaily)
+	}
+	if c.KeepWeekly < 0 {
+		return fmt.Errorf("weekly snapshots kept cannot be negative, got %d", c.KeepWeekly)
+	}
+	return nil
+}
+
+// at returns the minutes since midnight of the daily snapshot
+func (c Config) at() (int, error) {
+	at := c.Time
+	if at == "" {
+		at = "03:00"
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return 0, fmt.Errorf("invalid backup time %q, use HH:MM", at)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Snapshot is a backup file
+type Snapshot struct {
+	File      string    `json:"file"`
+	Size      int64     `json:"size"` // Bytes
+	CreatedAt time.Time `json:"created_at"`
+	Removed   []string  `json:"removed,omitempty"` // Older snapshots removed by the rotation
+}
+
+// prefix and timeLayout name the snapshots, e.g. atmosbyte-20261018-030000.db.gz
+const (
+	prefix     = "atmosbyte-"
+	timeLayout = "20060102-150405"
+)
+
+// Manager takes snapshots of the repository into a directory and rotates them
+type Manager struct {
+	repo     Repository
+	config   Config
+	at       int // Minutes since midnight
+	mu       sync.Mutex
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewManager validates the configuration and creates a manager
+func NewManager(repo Repository, config Config) (*Manager, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	at, _ := config.at()
+	return &Manager{
+		repo:     repo,
+		config:   config,
+		at:       at,
+		location: timezone.GetMachineLocation(),
+		now:      time.Now,
+	}, nil
+}
+
+// Start takes a snapshot every day at the configured time until ctx is cancelled.
+// It returns immediately when the schedule is disabled.
+func (m *Manager) Start(ctx context.Context) error {
+	if !m.config.Schedule {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Until(m.next(m.now())))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			snapshot, err := m.Backup(ctx)
+			if err != nil {
+				logging.Errorf("Scheduled backup failed: %v", err)
+			} else {
+				log.Printf("Scheduled backup written to %s (%d bytes)", snapshot.File, snapshot.Size)
+			}
+			timer.Reset(time.Until(m.next(m.now())))
+		}
+	}
+}
+
+// next returns the next snapshot time after now
+func (m *Manager) next(now time.Time) time.Time {
+	now = now.In(m.location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), m.at/60, m.at%60, 0, 0, m.location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Backup takes a snapshot, compressed when configured, and removes the snapshots
+// left out of the rotation. Concurrent calls run one at a time.
+func (m *Manager) Backup(ctx context.Context) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.config.Dir, 0755); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := m.now().In(m.location)
+	file := filepath.Join(m.config.Dir, prefix+now.Format(timeLayout)+".db")
+	if m.config.Gzip {
+		file += ".gz"
+	}
+
+	if err := Write(ctx, m.repo, file); err != nil {
+		return Snapshot{}, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to stat backup file: %w", err)
+	}
+
+	removed, err := m.rotate()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{File: file, Size: info.Size(), CreatedAt: now, Removed: removed}, nil
+}
+
+// Write takes a snapshot of repo into file, gzip compressed when file ends in .gz
+func Write(ctx context.Context, repo Repository, file string) error {
+	plain, compress := strings.CutSuffix(file, ".gz")
+	if !compress {
+		return repo.Backup(ctx, file)
+	}
+
+	if err := repo.Backup(ctx, plain); err != nil {
+		return err
+	}
+	defer os.Remove(plain)
+
+	if err := compressFile(plain, file); err != nil {
+		return fmt.Errorf("failed to compress backup: %w", err)
+	}
+	return nil
+}
+
+// compressFile writes src gzip compressed to dst, through a temporary file
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(src)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// snapshotFile is a snapshot found in the backup directory
+type snapshotFile struct {
+	name string
+	time time.Time
+}
+
+// rotate keeps the latest snapshot of each of the last KeepDaily days and of each of
+// the last KeepWeekly weeks, and removes the others
+func (m *Manager) rotate() ([]string, error) {
+	entries, err := os.ReadDir(m.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var snapshots []snapshotFile
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(strings.TrimSuffix(stamp, ".gz"), ".db")
+		if !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(timeLayout, stamp, m.location)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshotFile{name: name, time: t})
+	}
+
+	// Newest first
+	slices.SortFunc(snapshots, func(a, b snapshotFile) int {
+		return b.time.Compare(a.time)
+	})
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	var removed []string
+	for _, s := range snapshots {
+		day := s.time.Format(time.DateOnly)
+		year, week := s.time.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+
+		keep := false
+		if !days[day] && len(days) < m.config.KeepDaily {
+			days[day] = true
+			keep = true
+		}
+		if !weeks[weekKey] && len(weeks) < m.config.KeepWeekly {
+			weeks[weekKey] = true
+			keep = true
+		}
+		if keep {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(m.config.Dir, s.name)); err != nil {
+			return removed, fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		removed = append(removed, s.name)
+	}
+	return removed, nil
+}
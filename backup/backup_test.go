@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+func newRepository(t *testing.T, path string, measurements int) *repository.SQLiteRepository {
+	t.Helper()
+	repo, err := repository.NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range measurements {
+		m := bme280.Measurement{Timestamp: start.Add(time.Duration(i) * time.Minute), Temperature: 20, Humidity: 50, Pressure: 101325}
+		if err := repo.SaveMeasurement(m); err != nil {
+			t.Fatalf("failed to save measurement: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "gzip"}[compress], func(t *testing.T) {
+			dir := t.TempDir()
+			repo := newRepository(t, filepath.Join(dir, "weather.db"), 3)
+
+			manager, err := NewManager(repo, Config{Dir: filepath.Join(dir, "backups"), KeepDaily: 7, Gzip: compress})
+			if err != nil {
+				t.Fatal(err)
+			}
+			snapshot, err := manager.Backup(context.Background())
+			if err != nil {
+				t.Fatalf("backup failed: %v", err)
+			}
+			if strings.HasSuffix(snapshot.File, ".gz") != compress || snapshot.Size == 0 {
+				t.Errorf("unexpected snapshot %+v", snapshot)
+			}
+
+			// The restored database replaces one with other data
+			target := filepath.Join(dir, "restored.db")
+			other := newRepository(t, target, 1)
+			other.Close()
+
+			if err := Restore(snapshot.File, target); err != nil {
+				t.Fatalf("restore failed: %v", err)
+			}
+			restored := newRepository(t, target, 0)
+			if count, err := restored.GetMeasurementCount(); err != nil || count != 3 {
+				t.Errorf("expected 3 restored measurements, got %d (%v)", count, err)
+			}
+			if _, err := os.Stat(target + ".before-restore"); err != nil {
+				t.Errorf("expected the replaced database to be kept: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestore_Corrupted(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "weather.db")
+	newRepository(t, target, 2).Close()
+
+	corrupted := filepath.Join(dir, "corrupted.db")
+	if err := os.WriteFile(corrupted, []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(corrupted, target); err == nil {
+		t.Fatal("expected restoring a corrupted backup to fail")
+	}
+
+	// The database is left untouched
+	repo := newRepository(t, target, 0)
+	if count, err := repo.GetMeasurementCount(); err != nil || count != 2 {
+		t.Errorf("expected the original 2 measurements, got %d (%v)", count, err)
+	}
+	if _, err := os.Stat(target + ".restore"); !os.IsNotExist(err) {
+		t.Errorf("expected the extracted file to be removed, got %v", err)
+	}
+}
+
+type stubRepository struct{}
+
+func (stubRepository) Backup(ctx context.Context, path string) error {
+	return os.WriteFile(path, []byte("snapshot"), 0644)
+}
+
+func TestManager_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := NewManager(stubRepository{}, Config{Dir: dir, KeepDaily: 3, KeepWeekly: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.location = time.UTC
+
+	// Two snapshots a day, at 03:00 and 15:00, for three weeks
+	start := time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC) // Monday
+	for i := range 42 {
+		now := start.Add(time.Duration(i) * 12 * time.Hour)
+		manager.now = func() time.Time { return now }
+		if _, err := manager.Backup(context.Background()); err != nil {
+			t.Fatalf("backup %d failed: %v", i, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	// The latest snapshot of the last 3 days and of the last 2 weeks; the last
+	// week's is also the last day's
+	want := []string{
+		"atmosbyte-20260315-150000.db", // Sunday of the 2nd week
+		"atmosbyte-20260320-150000.db",
+		"atmosbyte-20260321-150000.db",
+		"atmosbyte-20260322-150000.db",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"valid", Config{Dir: "backups", Time: "02:30", KeepDaily: 7, KeepWeekly: 4}, true},
+		{"default time", Config{Dir: "backups", KeepDaily: 1}, true},
+		{"no directory", Config{KeepDaily: 7}, false},
+		{"invalid time", Config{Dir: "backups", Time: "25:00", KeepDaily: 7}, false},
+		{"no daily snapshot", Config{Dir: "backups", KeepDaily: 0}, false},
+		{"negative weekly", Config{Dir: "backups", KeepDaily: 7, KeepWeekly: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
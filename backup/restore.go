@@ -0,0 +1,79 @@
+package backup
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// Restore replaces the database at dbPath with the snapshot in file, gzip compressed
+// when it ends in .gz. The snapshot is extracted next to the database and checked with
+// PRAGMA integrity_check before the files are swapped; the replaced database is kept
+// as dbPath.before-restore. The service must be stopped.
+func Restore(file, dbPath string) error {
+	tmp := dbPath + ".restore"
+	if err := extract(file, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to extract backup: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	if err := repository.IntegrityCheck(tmp); err != nil {
+		return fmt.Errorf("backup %s: %w", file, err)
+	}
+
+	// The journal files belong to the replaced database and move with it
+	previous := dbPath + ".before-restore"
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		// A journal left by an earlier restore must not be paired with this database
+		if err := os.Remove(previous + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove the previous database: %w", err)
+		}
+		if err := os.Rename(dbPath+suffix, previous+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to move the current database: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+	return nil
+}
+
+// extract copies file to dst, decompressing it when it ends in .gz
+func extract(file, dst string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
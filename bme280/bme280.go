@@ -16,10 +16,11 @@ import (
 
 // Measurement representa uma leitura do sensor BME280
 type Measurement struct {
-	Timestamp   time.Time `json:"timestamp"`   // Timestamp da medição
-	Temperature float64   `json:"temperature"` // Temperatura em Celsius
-	Humidity    float64   `json:"humidity"`    // Umidade relativa em %
-	Pressure    int64     `json:"pressure"`    // Pressão em Pascal
+	Timestamp   time.Time `json:"timestamp"`      // Timestamp da medição
+	Temperature float64   `json:"temperature"`    // Temperatura em Celsius
+	Humidity    float64   `json:"humidity"`       // Umidade relativa em %
+	Pressure    int64     `json:"pressure"`       // Pressão em Pascal
+	Node        string    `json:"node,omitempty"` // Nó remoto de origem, vazio para o sensor local
 }
 
 // Sensor representa um sensor BME280 conectado via I2C
@@ -242,12 +243,24 @@ func (s *SimulatedSensor) Read() (Measurement, error) {
 	pressure := s.config.MinPressure + int64(s.rand.Float64()*pressureRange)
 
 	return Measurement{
+		Timestamp:   time.Now(),
 		Temperature: temp,
 		Humidity:    humidity,
 		Pressure:    pressure,
 	}, nil
 }
 
+// SetConfig replaces the ranges of the simulated readings
+func (s *SimulatedSensor) SetConfig(config *SimulatedConfig) {
+	if config == nil {
+		config = DefaultSimulatedConfig()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
 // Name returns the sensor type name
 func (s *SimulatedSensor) Name() string {
 	return "Simulated"
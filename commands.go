@@ -0,0 +1,194 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/backup"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/config"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/importer"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// runCommand executa um subcomando, como backup, restore ou import, com a configuração carregada
+func runCommand(cfg *config.AppConfig, args []string) error {
+	switch args[0] {
+	case "backup":
+		return runBackup(cfg, args[1:])
+	case "restore":
+		return runRestore(cfg, args[1:])
+	case "import":
+		return runImport(cfg, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, use backup, restore or import", args[0])
+	}
+}
+
+// runBackup grava um snapshot do banco no diretório de backups, com rotação, ou no arquivo de -o.
+// O serviço pode estar rodando.
+func runBackup(cfg *config.AppConfig, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "Write the snapshot to this file instead of the backup directory, gzip compressed when it ends in .gz")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo, err := repository.OpenSQLiteRepository(cfg.StorageConfig())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer repo.Close()
+
+	if *output != "" {
+		if err := backup.Write(ctx, repo, *output); err != nil {
+			return err
+		}
+		fmt.Printf("Backup of %s written to %s\n", cfg.Storage.Path, *output)
+		return nil
+	}
+
+	manager, err := backup.NewManager(repo, cfg.BackupConfig())
+	if err != nil {
+		return err
+	}
+	snapshot, err := manager.Backup(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup of %s written to %s (%d bytes)\n", cfg.Storage.Path, snapshot.File, snapshot.Size)
+	for _, name := range snapshot.Removed {
+		fmt.Printf("Removed old backup %s\n", name)
+	}
+	return nil
+}
+
+// runRestore substitui o banco pelo snapshot dado depois de verificar sua integridade.
+// O serviço deve estar parado.
+func runRestore(cfg *config.AppConfig, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: atmosbyte restore <backup file>")
+	}
+	file := flags.Arg(0)
+
+	if err := backup.Restore(file, cfg.Storage.Path); err != nil {
+		return err
+	}
+	fmt.Printf("Database %s restored from %s, the previous one was kept as %s.before-restore\n", cfg.Storage.Path, file, cfg.Storage.Path)
+	return nil
+}
+
+// runImport importa medições históricas dos arquivos dados, ou da entrada padrão com -.
+// O serviço pode estar rodando.
+func runImport(cfg *config.AppConfig, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	// As flags têm os nomes dos parâmetros do endpoint, com hífens
+	values := make(map[string]*string)
+	var dryRun *bool
+	for _, p := range importer.Parameters {
+		name := strings.ReplaceAll(p.Name, "_", "-")
+		if p.Name == "dry_run" {
+			dryRun = flags.Bool(name, false, p.Usage)
+			continue
+		}
+		values[p.Name] = flags.String(name, "", p.Usage)
+	}
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: atmosbyte import [flags] <file>... (- reads stdin, .gz files are decompressed)")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no files to import")
+	}
+
+	opts, err := importer.ParseOptions(func(name string) string {
+		if name == "dry_run" {
+			return strconv.FormatBool(*dryRun)
+		}
+		return *values[name]
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo, err := repository.OpenSQLiteRepository(cfg.StorageConfig())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer repo.Close()
+
+	for _, file := range flags.Args() {
+		report, err := importFile(ctx, repo, file, opts)
+		if err == nil || report.Read > 0 {
+			printImportReport(file, report)
+		}
+		if err != nil {
+			return fmt.Errorf("import of %s failed: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// importFile importa um arquivo, descompactando arquivos .gz
+func importFile(ctx context.Context, repo *repository.SQLiteRepository, file string, opts importer.Options) (importer.Report, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return importer.Report{}, err
+		}
+		defer f.Close()
+		r = f
+
+		if strings.HasSuffix(file, ".gz") {
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				return importer.Report{}, err
+			}
+			defer zr.Close()
+			r = zr
+		}
+	}
+	return importer.Import(ctx, r, repo, opts)
+}
+
+// printImportReport exibe o resumo de uma importação
+func printImportReport(file string, report importer.Report) {
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s: %s %d of %d measurements (%s format), %d duplicates, %d rejected\n",
+		file, verb, report.Imported, report.Read, report.Format, report.Duplicates, report.Rejected)
+	if report.First != nil {
+		fmt.Printf("  from %s to %s\n", report.First.Format(time.RFC3339), report.Last.Format(time.RFC3339))
+	}
+	for _, rowErr := range report.Errors {
+		fmt.Printf("  line %d: %s\n", rowErr.Line, rowErr.Error)
+	}
+	if report.Rejected > len(report.Errors) {
+		fmt.Printf("  ... and %d more rejected rows\n", report.Rejected-len(report.Errors))
+	}
+}
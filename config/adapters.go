@@ -1,9 +1,25 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/alert"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/anomaly"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/backup"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/email"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/mqtt"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/tsdb"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/uploader"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/web"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/webhook"
 	"periph.io/x/devices/v3/bmxx80"
 )
 
@@ -15,6 +31,42 @@ func (c *AppConfig) WebConfig() *web.Config {
 		WriteTimeout:    c.Web.WriteTimeout,
 		IdleTimeout:     c.Web.IdleTimeout,
 		ShutdownTimeout: c.Timeouts.WebShutdownTimeout,
+		DegreeDays:      c.DegreeDaysConfig(),
+		Units:           c.Units.Default,
+		DashboardNode:   c.Web.DashboardNode,
+		TLS: web.TLSConfig{
+			Enabled:        c.Web.TLS.Enabled,
+			CertFile:       c.Web.TLS.CertFile,
+			KeyFile:        c.Web.TLS.KeyFile,
+			Hostname:       c.Web.TLS.Hostname,
+			RedirectPort:   c.Web.TLS.RedirectPort,
+			ReloadInterval: c.Web.TLS.ReloadInterval,
+		},
+		Ingest: web.IngestConfig{
+			MaxBatch:     c.Ingest.MaxBatch,
+			MaxAge:       c.Ingest.MaxAge,
+			MaxClockSkew: c.Ingest.MaxClockSkew,
+			DedupWindow:  c.Ingest.DedupWindow,
+		},
+	}
+}
+
+// LogLevel converts config to logging.Level, info when the level is invalid
+func (c *AppConfig) LogLevel() logging.Level {
+	level, err := logging.ParseLevel(c.Log.Level)
+	if err != nil {
+		return logging.Info
+	}
+	return level
+}
+
+// DegreeDaysConfig converts config to weather.DegreeDayConfig
+func (c *AppConfig) DegreeDaysConfig() weather.DegreeDayConfig {
+	return weather.DegreeDayConfig{
+		HeatingBase: c.DegreeDays.HeatingBase,
+		CoolingBase: c.DegreeDays.CoolingBase,
+		GrowingBase: c.DegreeDays.GrowingBase,
+		GrowingCap:  c.DegreeDays.GrowingCap,
 	}
 }
 
@@ -36,6 +88,44 @@ func (c *AppConfig) QueueConfig() queue.QueueConfig {
 	}
 }
 
+// StorageConfig converts config to repository.Config
+func (c *AppConfig) StorageConfig() repository.Config {
+	return repository.Config{
+		Path:            c.Storage.Path,
+		JournalMode:     c.Storage.JournalMode,
+		Synchronous:     c.Storage.Synchronous,
+		BusyTimeout:     c.Storage.BusyTimeout,
+		CacheSize:       c.Storage.CacheSize,
+		MaxOpenConns:    c.Storage.MaxOpenConns,
+		MaxIdleConns:    c.Storage.MaxIdleConns,
+		ConnMaxLifetime: c.Storage.ConnMaxLifetime,
+	}
+}
+
+// ReadReplicaConfig converts config to the read-only repository.Config of the web handlers
+func (c *AppConfig) ReadReplicaConfig() repository.Config {
+	replica := c.StorageConfig()
+	if c.Storage.ReadReplica.Path != "" {
+		replica.Path = c.Storage.ReadReplica.Path
+	}
+	replica.MaxOpenConns = c.Storage.ReadReplica.MaxOpenConns
+	replica.MaxIdleConns = c.Storage.ReadReplica.MaxIdleConns
+	replica.ReadOnly = true
+	return replica
+}
+
+// BackupConfig converts config to backup.Config
+func (c *AppConfig) BackupConfig() backup.Config {
+	return backup.Config{
+		Dir:        c.Backup.Dir,
+		Schedule:   c.Backup.Enabled,
+		Time:       c.Backup.Time,
+		KeepDaily:  c.Backup.KeepDaily,
+		KeepWeekly: c.Backup.KeepWeekly,
+		Gzip:       c.Backup.Gzip,
+	}
+}
+
 // BME280Config converts config to bme280.Config
 func (c *AppConfig) BME280Config() *bme280.Config {
 	return &bme280.Config{
@@ -56,3 +146,298 @@ func (c *AppConfig) SimulatedConfig() *bme280.SimulatedConfig {
 		MaxPressure: c.Sensor.Simulation.MaxPressure,
 	}
 }
+
+// AnomalyConfig converts config to anomaly.Config
+func (c *AppConfig) AnomalyConfig() anomaly.Config {
+	cfg := anomaly.DefaultConfig()
+	cfg.Method = anomaly.Method(c.Anomaly.Method)
+	cfg.Threshold = c.Anomaly.Threshold
+	cfg.BaselineWindow = time.Duration(c.Anomaly.BaselineDays) * 24 * time.Hour
+	cfg.MinSamples = c.Anomaly.MinSamples
+	cfg.RefreshInterval = c.Anomaly.RefreshInterval
+	cfg.Cooldown = c.Anomaly.Cooldown
+	return cfg
+}
+
+// AuthConfig converts config to web.AuthConfig
+func (c *AppConfig) AuthConfig() web.AuthConfig {
+	cfg := web.AuthConfig{
+		PublicRead:    c.Auth.PublicRead,
+		MaxFailures:   c.Auth.MaxFailures,
+		FailureWindow: c.Auth.FailureWindow,
+	}
+	for _, key := range c.Auth.APIKeys {
+		cfg.APIKeys = append(cfg.APIKeys, web.APIKey{Name: key.Name, Key: key.Key, Scope: web.Scope(key.Scope)})
+	}
+	for _, user := range c.Auth.Users {
+		cfg.Users = append(cfg.Users, web.User{Username: user.Username, PasswordHash: user.PasswordHash, Scope: web.Scope(user.Scope)})
+	}
+	return cfg
+}
+
+// StationConfig converts config to web.StationConfig
+func (c *AppConfig) StationConfig() web.StationConfig {
+	cfg := web.StationConfig{EcowittPath: c.StationUpload.EcowittPath}
+	for _, station := range c.StationUpload.Stations {
+		cfg.Stations = append(cfg.Stations, web.Station{ID: station.ID, Password: station.Password, Node: station.Node})
+	}
+	return cfg
+}
+
+// UploaderQueueConfig returns the queue configuration of each uploader: a single worker,
+// so uploads of a service stay in order, with the retry and circuit breaker settings of the main queue
+func (c *AppConfig) UploaderQueueConfig() queue.QueueConfig {
+	cfg := c.QueueConfig()
+	cfg.Workers = 1
+	cfg.BufferSize = 10
+	return cfg
+}
+
+// UploadWorkers converts config to the workers of the enabled uploaders
+func (c *AppConfig) UploadWorkers() ([]*uploader.Worker, error) {
+	var workers []*uploader.Worker
+	station := uploader.Station{Altitude: c.Uploaders.Altitude}
+
+	for _, wu := range []struct {
+		config WundergroundUploadConfig
+		create func(uploader.WundergroundConfig) *uploader.Wunderground
+		name   string
+	}{
+		{c.Uploaders.Wunderground, uploader.NewWunderground, "wunderground"},
+		{c.Uploaders.PWSWeather, uploader.NewPWSWeather, "pwsweather"},
+	} {
+		if !wu.config.Enabled {
+			continue
+		}
+		if wu.config.StationID == "" || wu.config.Password == "" {
+			return nil, errors.New(wu.name + " requires station_id and password")
+		}
+		service := wu.create(uploader.WundergroundConfig{
+			URL:       wu.config.URL,
+			StationID: wu.config.StationID,
+			Password:  wu.config.Password,
+			Station:   station,
+		})
+		workers = append(workers, uploader.NewWorker(service, wu.config.Interval))
+	}
+
+	if windy := c.Uploaders.Windy; windy.Enabled {
+		if windy.APIKey == "" {
+			return nil, errors.New("windy requires api_key")
+		}
+		service := uploader.NewWindy(uploader.WindyConfig{
+			URL:     windy.URL,
+			APIKey:  windy.APIKey,
+			Index:   windy.Station,
+			Station: station,
+		})
+		workers = append(workers, uploader.NewWorker(service, windy.Interval))
+	}
+
+	if cwop := c.Uploaders.CWOP; cwop.Enabled {
+		if cwop.Callsign == "" {
+			return nil, errors.New("cwop requires callsign")
+		}
+		if cwop.Latitude == 0 && cwop.Longitude == 0 {
+			return nil, errors.New("cwop requires latitude and longitude")
+		}
+		service := uploader.NewCWOP(uploader.CWOPConfig{
+			Server:    cwop.Server,
+			Callsign:  cwop.Callsign,
+			Passcode:  cwop.Passcode,
+			Latitude:  cwop.Latitude,
+			Longitude: cwop.Longitude,
+			Station:   station,
+		})
+		workers = append(workers, uploader.NewWorker(service, cwop.Interval))
+	}
+
+	return workers, nil
+}
+
+// MQTTWorker converts config to the worker of the MQTT publisher
+func (c *AppConfig) MQTTWorker(version string) (*uploader.Worker, error) {
+	if c.MQTT.QoS != 0 && c.MQTT.QoS != 1 {
+		return nil, fmt.Errorf("unsupported mqtt qos %d, use 0 or 1", c.MQTT.QoS)
+	}
+
+	options := mqtt.Options{
+		Address:   c.MQTT.Broker,
+		ClientID:  c.MQTT.ClientID,
+		Username:  c.MQTT.Username,
+		Password:  c.MQTT.Password,
+		KeepAlive: c.MQTT.KeepAlive,
+	}
+	if c.MQTT.TLS {
+		options.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	publisher := mqtt.NewPublisher(mqtt.PublisherConfig{
+		Options:           options,
+		QoS:               byte(c.MQTT.QoS),
+		StateTopic:        c.MQTT.StateTopic,
+		AvailabilityTopic: c.MQTT.AvailabilityTopic,
+		Discovery:         c.MQTT.Discovery,
+		DiscoveryPrefix:   c.MQTT.DiscoveryPrefix,
+		DeviceName:        c.MQTT.DeviceName,
+		Version:           version,
+		Altitude:          c.Uploaders.Altitude,
+	})
+
+	return uploader.NewWorker(publisher, c.MQTT.Interval), nil
+}
+
+// SinkWorkers converts config to the enabled time-series database sinks
+func (c *AppConfig) SinkWorkers() ([]tsdb.Sink, error) {
+	var sinks []tsdb.Sink
+
+	if influx := c.Sinks.InfluxDB; influx.Enabled {
+		sink, err := tsdb.NewInflux(tsdb.InfluxConfig{
+			URL:             influx.URL,
+			Version:         influx.Version,
+			Database:        influx.Database,
+			RetentionPolicy: influx.RetentionPolicy,
+			Username:        influx.Username,
+			Password:        influx.Password,
+			Org:             influx.Org,
+			Bucket:          influx.Bucket,
+			Token:           influx.Token,
+			Measurement:     influx.Measurement,
+			Tags:            influx.Tags,
+			BatchSize:       influx.BatchSize,
+			FlushInterval:   influx.FlushInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if graphite := c.Sinks.Graphite; graphite.Enabled {
+		sink, err := tsdb.NewGraphite(tsdb.GraphiteConfig{Address: graphite.Address, Prefix: graphite.Prefix})
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if statsd := c.Sinks.StatsD; statsd.Enabled {
+		sink, err := tsdb.NewStatsD(tsdb.StatsDConfig{Address: statsd.Address, Prefix: statsd.Prefix})
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+// WebhookConfig converts config to webhook.Config. Deliveries use a single worker
+// and back off up to five minutes between retries.
+func (c *AppConfig) WebhookConfig() (webhook.Config, error) {
+	queueConfig := c.QueueConfig()
+	queueConfig.Workers = 1
+	queueConfig.BufferSize = 100
+	queueConfig.RetryPolicy.MaxDelay = 5 * time.Minute
+
+	config := webhook.Config{
+		Queue:           queueConfig,
+		RequeueInterval: c.Webhooks.RequeueInterval,
+		Retention:       c.Webhooks.Retention,
+	}
+
+	for _, t := range c.Webhooks.Targets {
+		target := webhook.Target{
+			Name:        t.Name,
+			URL:         t.URL,
+			Method:      t.Method,
+			Headers:     t.Headers,
+			Template:    t.Template,
+			ContentType: t.ContentType,
+			Secret:      t.Secret,
+		}
+		for _, name := range t.Events {
+			event, err := webhook.ParseEventType(name)
+			if err != nil {
+				return webhook.Config{}, fmt.Errorf("webhook %s: %w", t.Name, err)
+			}
+			target.Events = append(target.Events, event)
+		}
+		config.Targets = append(config.Targets, target)
+	}
+
+	for _, t := range c.Webhooks.Thresholds {
+		config.Thresholds = append(config.Thresholds, webhook.Threshold{Metric: t.Metric, Above: t.Above, Below: t.Below})
+	}
+
+	return config, nil
+}
+
+// AlertConfig converts config to alert.Config. Rules without an aggregate use the latest reading.
+func (c *AppConfig) AlertConfig() (alert.Config, error) {
+	quietHours, err := alert.ParseQuietHours(c.Alerts.QuietHours.Start, c.Alerts.QuietHours.End)
+	if err != nil {
+		return alert.Config{}, err
+	}
+
+	config := alert.Config{QuietHours: quietHours}
+	for _, r := range c.Alerts.Rules {
+		aggregate := alert.Aggregate(r.Aggregate)
+		if aggregate == "" {
+			aggregate = alert.Last
+		}
+		rule := alert.Rule{
+			Name:             r.Name,
+			Metric:           r.Metric,
+			Aggregate:        aggregate,
+			Window:           r.Window,
+			Operator:         alert.Operator(r.Operator),
+			Threshold:        r.Threshold,
+			For:              r.For,
+			Hysteresis:       r.Hysteresis,
+			Severity:         r.Severity,
+			Channels:         r.Channels,
+			IgnoreQuietHours: r.IgnoreQuietHours,
+		}
+		if err := rule.Validate(); err != nil {
+			return alert.Config{}, err
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+
+	return config, nil
+}
+
+// EmailConfig converts config to email.Config. Messages use a single worker and back
+// off up to five minutes between retries.
+func (c *AppConfig) EmailConfig() email.Config {
+	queueConfig := c.QueueConfig()
+	queueConfig.Workers = 1
+	queueConfig.BufferSize = 100
+	queueConfig.RetryPolicy.MaxDelay = 5 * time.Minute
+
+	templates := func(t EmailTemplatesConfig) email.Templates {
+		return email.Templates{Subject: t.Subject, Text: t.Text, HTML: t.HTML}
+	}
+
+	return email.Config{
+		Server: email.Server{
+			Host:     c.Email.Host,
+			Port:     c.Email.Port,
+			Security: email.Security(c.Email.Security),
+			Auth:     email.AuthMechanism(c.Email.Auth),
+			Username: c.Email.Username,
+			Password: c.Email.Password,
+			Timeout:  c.Email.Timeout,
+		},
+		From:      c.Email.From,
+		To:        c.Email.To,
+		Templates: templates(c.Email.Templates),
+		Digest: email.DigestConfig{
+			Enabled:   c.Email.Digest.Enabled,
+			Time:      c.Email.Digest.Time,
+			Templates: templates(c.Email.Digest.Templates),
+		},
+		Queue: queueConfig,
+	}
+}
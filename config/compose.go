@@ -0,0 +1,313 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnv selects the profile when none is given to the Loader
+const ProfileEnv = EnvPrefix + "PROFILE"
+
+// position is where a value was read
+type position struct {
+	file string
+	line int
+}
+
+// document is a configuration file merged with the files it includes
+type document struct {
+	main     string                // File given to build
+	environ  map[string]string     // For ${VAR} interpolation
+	files    []string              // Files read, the main one first
+	origin   map[*yaml.Node]string // File of every node
+	profiles map[string]*yaml.Node // Profile overlays by name
+	v        *validator
+}
+
+// load parses file and its includes, reporting unknown fields, type errors and
+// interpolation errors of each file with its lines. It returns the merged mapping,
+// nil when the files are empty. stack holds the files including this one.
+func (d *document) load(file string, data []byte, stack []string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+	}
+	d.files = append(d.files, file)
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+
+	fv := &validator{}
+	defer d.report(file, fv)
+
+	if root.Kind != yaml.MappingNode {
+		fv.errors = append(fv.errors, FieldError{Line: root.Line, Message: "configuration must be a mapping"})
+		return nil, nil
+	}
+	d.mark(root, file)
+
+	lines := make(map[string]int)
+	indexLines(root, "", lines)
+	d.interpolate(root, "", filepath.Dir(file), fv)
+
+	includes := take(root, "include")
+	profiles := take(root, "profiles")
+
+	check(root, "", lines, fv)
+
+	if profiles != nil {
+		if profiles.Kind != yaml.MappingNode {
+			fv.errors = append(fv.errors, FieldError{Path: "profiles", Line: profiles.Line, Message: "must map profile names to configurations"})
+		} else {
+			for i := 0; i+1 < len(profiles.Content); i += 2 {
+				name, body := profiles.Content[i].Value, profiles.Content[i+1]
+				if body.Kind != yaml.MappingNode {
+					fv.errors = append(fv.errors, FieldError{Path: "profiles." + name, Line: body.Line, Message: "must be a mapping"})
+					continue
+				}
+				check(body, "profiles."+name, lines, fv)
+				d.profiles[name] = merge(d.profiles[name], body)
+			}
+		}
+	}
+
+	var base *yaml.Node
+	for _, include := range d.includes(includes, fv) {
+		path := include.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		if path == filepath.Clean(file) || slices.Contains(stack, path) {
+			fv.errors = append(fv.errors, FieldError{Path: "include", Line: include.Line, Message: fmt.Sprintf("%s is included in a loop", include.Value)})
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fv.errors = append(fv.errors, FieldError{Path: "include", Line: include.Line, Message: fmt.Sprintf("failed to read %s: %v", include.Value, err)})
+			continue
+		}
+		included, err := d.load(path, data, append(stack, filepath.Clean(file)))
+		if err != nil {
+			return nil, err
+		}
+		base = merge(base, included)
+	}
+
+	return merge(base, root), nil
+}
+
+// report adds the errors of file to the document validator
+func (d *document) report(file string, fv *validator) {
+	for _, fieldErr := range fv.errors {
+		if file != d.main {
+			fieldErr.File = file
+		}
+		d.v.errors = append(d.v.errors, fieldErr)
+	}
+}
+
+// includes returns the file names of an include: value, a name or a list of names
+func (d *document) includes(node *yaml.Node, fv *validator) []*yaml.Node {
+	switch {
+	case node == nil:
+		return nil
+	case node.Kind == yaml.ScalarNode:
+		return []*yaml.Node{node}
+	case node.Kind == yaml.SequenceNode:
+		var names []*yaml.Node
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				fv.errors = append(fv.errors, FieldError{Path: "include", Line: item.Line, Message: "must be a file name"})
+				continue
+			}
+			names = append(names, item)
+		}
+		return names
+	default:
+		fv.errors = append(fv.errors, FieldError{Path: "include", Line: node.Line, Message: "must be a file name or a list of file names"})
+		return nil
+	}
+}
+
+// mark records file as the origin of node and its descendants
+func (d *document) mark(node *yaml.Node, file string) {
+	d.origin[node] = file
+	for _, child := range node.Content {
+		d.mark(child, file)
+	}
+}
+
+// take removes key from the mapping node and returns its value
+func take(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = slices.Delete(node.Content, i, i+2)
+			return value
+		}
+	}
+	return nil
+}
+
+// check reports the unknown fields and the values of the wrong type of node, a
+// configuration or a profile at path
+func check(node *yaml.Node, path string, lines map[string]int, v *validator) {
+	checkFields(node, reflect.TypeFor[AppConfig](), path, v)
+
+	var config AppConfig
+	var typeErr *yaml.TypeError
+	if err := node.Decode(&config); errors.As(err, &typeErr) {
+		for _, msg := range typeErr.Errors {
+			v.errors = append(v.errors, typeError(msg, lines))
+		}
+	}
+}
+
+// merge merges overlay into base: mappings are merged key by key, any other value of
+// overlay replaces the one of base
+func merge(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil {
+		return overlay
+	}
+	if overlay == nil {
+		return base
+	}
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		j := -1
+		for k := 0; k+1 < len(base.Content); k += 2 {
+			if base.Content[k].Value == key.Value {
+				j = k
+				break
+			}
+		}
+
+		switch {
+		case j < 0:
+			base.Content = append(base.Content, key, value)
+		case base.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			merge(base.Content[j+1], value)
+		default:
+			base.Content[j], base.Content[j+1] = key, value
+		}
+	}
+	return base
+}
+
+// indexPositions records the position of every value of node by path
+func (d *document) indexPositions(node *yaml.Node, path string, positions map[string]position) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			p := join(path, key.Value)
+			positions[p] = position{file: d.origin[key], line: key.Line}
+			d.indexPositions(node.Content[i+1], p, positions)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			positions[p] = position{file: d.origin[item], line: item.Line}
+			d.indexPositions(item, p, positions)
+		}
+	}
+}
+
+// positionOf returns the position of path or of its closest parent read from a file
+func positionOf(path string, positions map[string]position) position {
+	for path != "" {
+		if pos, ok := positions[path]; ok {
+			return pos
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return position{}
+}
+
+// reference matches ${VAR}, ${file:path} and the $${ escape
+var reference = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// interpolate replaces the references in the scalar values of node: ${VAR} with the
+// environment variable, ${file:path} with the content of the file, relative to dir,
+// without trailing newlines, and $${ with a literal ${
+func (d *document) interpolate(node *yaml.Node, path, dir string, v *validator) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			d.interpolate(node.Content[i+1], join(path, node.Content[i].Value), dir, v)
+		}
+		return
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			d.interpolate(item, fmt.Sprintf("%s[%d]", path, i), dir, v)
+		}
+		return
+	case yaml.ScalarNode:
+	default:
+		return
+	}
+
+	if !strings.Contains(node.Value, "${") {
+		return
+	}
+
+	expanded := reference.ReplaceAllStringFunc(node.Value, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+
+		name := ref[2 : len(ref)-1]
+		if file, ok := strings.CutPrefix(name, "file:"); ok {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				v.errors = append(v.errors, FieldError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: %v", ref, err)})
+				return ""
+			}
+			return strings.TrimRight(string(data), "\r\n")
+		}
+
+		value, ok := d.environ[name]
+		if !ok {
+			v.errors = append(v.errors, FieldError{Path: path, Line: node.Line, Message: fmt.Sprintf("%s: environment variable %s is not set", ref, name)})
+		}
+		return value
+	})
+
+	node.Value = expanded
+	if node.Style == 0 {
+		// Resolve the tag again, so that ${PORT} can set an integer
+		node.Tag = ""
+	}
+}
+
+// lookupEnv returns the value of name in environ
+func lookupEnv(environ []string, name string) string {
+	for _, kv := range environ {
+		if k, v, _ := strings.Cut(kv, "="); k == name {
+			return v
+		}
+	}
+	return ""
+}
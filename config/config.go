@@ -3,13 +3,13 @@
 package config
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 
+	"github.com/anibaldeboni/zero-paper/atmosbyte/uploader"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,16 +24,77 @@ type AppConfig struct {
 	// Sensor configuration
 	Sensor SensorConfig `yaml:"sensor"`
 
+	// SQLite database configuration
+	Storage StorageConfig `yaml:"storage"`
+
+	// Database snapshots
+	Backup BackupConfig `yaml:"backup"`
+
 	// Timeouts and shutdown configuration
 	Timeouts TimeoutConfig `yaml:"timeouts"`
+
+	// Degree-day base temperatures
+	DegreeDays DegreeDaysConfig `yaml:"degree_days"`
+
+	// Anomaly detection configuration
+	Anomaly AnomalyConfig `yaml:"anomaly"`
+
+	// Output units configuration
+	Units UnitsConfig `yaml:"units"`
+
+	// Web server authentication
+	Auth AuthConfig `yaml:"auth"`
+
+	// Remote node ingestion
+	Ingest IngestConfig `yaml:"ingest"`
+
+	// Weather Underground and Ecowitt upload receivers
+	StationUpload StationUploadConfig `yaml:"station_upload"`
+
+	// Uploads to public weather networks
+	Uploaders UploadersConfig `yaml:"uploaders"`
+
+	// MQTT publisher with Home Assistant discovery
+	MQTT MQTTConfig `yaml:"mqtt"`
+
+	// Exports to time-series databases
+	Sinks SinksConfig `yaml:"sinks"`
+
+	// Webhook notifications
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+
+	// Alert rules configuration
+	Alerts AlertsConfig `yaml:"alerts"`
+
+	// SMTP email notifications configuration
+	Email EmailConfig `yaml:"email"`
+
+	// Configuration hot reload
+	Reload ReloadConfig `yaml:"reload"`
+
+	// Logging configuration
+	Log LogConfig `yaml:"log"`
 }
 
 // WebConfig contains HTTP server configuration
 type WebConfig struct {
-	Port         int           `yaml:"port"`
-	ReadTimeout  time.Duration `yaml:"read_timeout"`
-	WriteTimeout time.Duration `yaml:"write_timeout"`
-	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	Port          int           `yaml:"port"`
+	ReadTimeout   time.Duration `yaml:"read_timeout"`
+	WriteTimeout  time.Duration `yaml:"write_timeout"`
+	IdleTimeout   time.Duration `yaml:"idle_timeout"`
+	TLS           TLSConfig     `yaml:"tls"`
+	DashboardNode string        `yaml:"dashboard_node"` // Node shown by the dashboards and data endpoints when a request names none, empty for the local sensor
+}
+
+// TLSConfig contains HTTPS configuration. When enabled and the certificate and key
+// files do not exist, a self-signed certificate is generated and persisted.
+type TLSConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	Hostname       string        `yaml:"hostname"`        // Self-signed certificate name, defaults to the machine hostname
+	RedirectPort   int           `yaml:"redirect_port"`   // Plain HTTP port redirecting to HTTPS, 0 disables it
+	ReloadInterval time.Duration `yaml:"reload_interval"` // How often the certificate files are checked for changes
 }
 
 // QueueConfig contains queue processing configuration
@@ -80,6 +141,41 @@ type SimConfig struct {
 	MaxPressure    int64   `yaml:"max_pressure"`
 }
 
+// StorageConfig configures the SQLite database. The defaults suit SD cards: the
+// write-ahead log with synchronous normal syncs on checkpoints rather than on every
+// transaction.
+type StorageConfig struct {
+	Path            string            `yaml:"path"`
+	JournalMode     string            `yaml:"journal_mode"` // "wal", or a rollback journal: "delete", "truncate" or "persist"
+	Synchronous     string            `yaml:"synchronous"`  // "off", "normal", "full" or "extra"
+	BusyTimeout     time.Duration     `yaml:"busy_timeout"` // Wait for a lock before failing
+	CacheSize       int               `yaml:"cache_size"`   // Page cache per connection, in KiB
+	MaxOpenConns    int               `yaml:"max_open_conns"`
+	MaxIdleConns    int               `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration     `yaml:"conn_max_lifetime"` // 0 to reuse connections forever
+	ReadReplica     ReadReplicaConfig `yaml:"read_replica"`
+}
+
+// ReadReplicaConfig configures a read-only connection pool serving the web handlers,
+// so that slow queries do not hold connections the writers need
+type ReadReplicaConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Path         string `yaml:"path"` // Database to read, storage.path when empty
+	MaxOpenConns int    `yaml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns"`
+}
+
+// BackupConfig configures the database snapshots. Snapshots taken on demand, by the
+// API or the backup command, go to the same directory and rotation.
+type BackupConfig struct {
+	Enabled    bool   `yaml:"enabled"` // Take a snapshot every day at Time
+	Dir        string `yaml:"dir"`
+	Time       string `yaml:"time"`        // HH:MM local time
+	KeepDaily  int    `yaml:"keep_daily"`  // Days whose latest snapshot is kept
+	KeepWeekly int    `yaml:"keep_weekly"` // Weeks whose latest snapshot is kept
+	Gzip       bool   `yaml:"gzip"`
+}
+
 // TimeoutConfig contains various timeout configurations
 type TimeoutConfig struct {
 	ShutdownTimeout      time.Duration `yaml:"shutdown_timeout"`
@@ -88,58 +184,297 @@ type TimeoutConfig struct {
 	ProcessingTimeout    time.Duration `yaml:"processing_timeout"`
 }
 
-// ConfigLoader handles loading and caching of configuration
-type ConfigLoader struct {
-	config *AppConfig
-	mu     sync.RWMutex
-	loaded bool
+// DegreeDaysConfig contains base temperatures (°C) for degree-day calculations.
+// Bases missing from the file use the defaults; 0 is a valid base.
+type DegreeDaysConfig struct {
+	HeatingBase float64 `yaml:"heating_base"`
+	CoolingBase float64 `yaml:"cooling_base"`
+	GrowingBase float64 `yaml:"growing_base"`
+	GrowingCap  float64 `yaml:"growing_cap"`
 }
 
-// Global instance - thread-safe singleton
-var globalLoader = &ConfigLoader{}
+// AnomalyConfig contains anomaly detection configuration
+type AnomalyConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Method          string        `yaml:"method"`           // "mad" or "zscore"
+	Threshold       float64       `yaml:"threshold"`        // Absolute score that flags a reading
+	BaselineDays    int           `yaml:"baseline_days"`    // History used for hourly baselines
+	MinSamples      int           `yaml:"min_samples"`      // Samples required before a baseline is used
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // How often baselines are rebuilt
+	Cooldown        time.Duration `yaml:"cooldown"`         // Minimum time between anomalies of the same metric
+}
 
-// Load loads configuration from file or uses defaults
-// Thread-safe: uses sync.RWMutex for concurrent access protection
-func Load(configPath string) (*AppConfig, error) {
-	globalLoader.mu.Lock()
-	defer globalLoader.mu.Unlock()
+// UnitsConfig contains the default output units
+type UnitsConfig struct {
+	// Default is a preset (metric, imperial, si) or a comma separated list of
+	// unit symbols (C, F, Pa, hPa, kPa, inHg, mmHg). Empty keeps °C and Pa in
+	// the API and °C and hPa in CSV exports.
+	Default string `yaml:"default"`
+}
 
-	if globalLoader.loaded {
-		// Return cached config
-		return globalLoader.config, nil
-	}
+// AuthConfig contains web server authentication configuration
+type AuthConfig struct {
+	Enabled       bool           `yaml:"enabled"`
+	PublicRead    bool           `yaml:"public_read"`    // Serve read-only routes without credentials
+	APIKeys       []APIKeyConfig `yaml:"api_keys"`       // Static keys (X-API-Key header, Bearer token or api_key query parameter)
+	Users         []UserConfig   `yaml:"users"`          // HTTP Basic users
+	MaxFailures   int            `yaml:"max_failures"`   // Failed attempts per client before it is blocked
+	FailureWindow time.Duration  `yaml:"failure_window"` // Window in which failures are counted and blocks last
+}
 
-	config, err := loadConfigFromFile(configPath)
-	if err != nil {
-		// File not found or invalid, use defaults
-		config = defaultConfig()
-	}
+// APIKeyConfig contains a static API key
+type APIKeyConfig struct {
+	Name  string `yaml:"name"`
+	Key   string `yaml:"key"`
+	Scope string `yaml:"scope"` // "read", "ingest" or "admin"
+}
 
-	// Cache the loaded config
-	globalLoader.config = config
-	globalLoader.loaded = true
+// UserConfig contains an HTTP Basic user
+type UserConfig struct {
+	Username     string `yaml:"username"`
+	PasswordHash string `yaml:"password_hash"` // bcrypt hash, see --hash-password
+	Scope        string `yaml:"scope"`         // "read", "ingest" or "admin"
+}
 
-	return config, nil
+// IngestConfig contains the configuration of POST /api/v1/ingest. It requires auth
+// to be enabled; nodes authenticate with credentials of the "ingest" scope.
+type IngestConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	MaxBatch     int           `yaml:"max_batch"`      // Maximum readings per request
+	MaxAge       time.Duration `yaml:"max_age"`        // Readings older than this are rejected
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"` // Readings further in the future than this are rejected
+	DedupWindow  time.Duration `yaml:"dedup_window"`   // How long node and timestamp pairs are remembered
 }
 
-// Get returns the cached configuration (must call Load first)
-// Thread-safe: uses sync.RWMutex for read access
-func Get() *AppConfig {
-	globalLoader.mu.RLock()
-	defer globalLoader.mu.RUnlock()
+// StationUploadConfig contains the Weather Underground and Ecowitt upload receivers.
+// Readings are validated with the ingest age limits and an outdoor temperature range
+// and stored under the station node.
+type StationUploadConfig struct {
+	Enabled     bool            `yaml:"enabled"`
+	EcowittPath string          `yaml:"ecowitt_path"` // Path configured as the Ecowitt custom server
+	Stations    []StationConfig `yaml:"stations"`
+}
 
-	if !globalLoader.loaded {
-		panic("Configuration not loaded. Call config.Load() first.")
-	}
+// StationConfig maps an uploading station to a node
+type StationConfig struct {
+	ID       string `yaml:"id"`       // Weather Underground station ID or Ecowitt PASSKEY
+	Password string `yaml:"password"` // Weather Underground station key
+	Node     string `yaml:"node"`
+}
+
+// UploadersConfig contains the uploads of local measurements to public weather networks.
+// Each enabled service runs on its own queue with the queue retry and circuit breaker settings.
+type UploadersConfig struct {
+	Altitude     float64                  `yaml:"altitude"` // Station altitude in meters, used to report sea level pressure
+	Wunderground WundergroundUploadConfig `yaml:"wunderground"`
+	PWSWeather   WundergroundUploadConfig `yaml:"pwsweather"`
+	Windy        WindyUploadConfig        `yaml:"windy"`
+	CWOP         CWOPUploadConfig         `yaml:"cwop"`
+}
+
+// WundergroundUploadConfig contains a station of a service using the Weather Underground protocol
+type WundergroundUploadConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	URL       string        `yaml:"url"`
+	StationID string        `yaml:"station_id"`
+	Password  string        `yaml:"password"` // Station key (Weather Underground) or API key (PWSWeather)
+	Interval  time.Duration `yaml:"interval"` // Minimum time between uploads
+}
+
+// WindyUploadConfig contains a Windy station
+type WindyUploadConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	URL      string        `yaml:"url"`
+	APIKey   string        `yaml:"api_key"`
+	Station  int           `yaml:"station"` // Station index of the API key
+	Interval time.Duration `yaml:"interval"`
+}
+
+// CWOPUploadConfig contains a CWOP station
+type CWOPUploadConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Server    string        `yaml:"server"`
+	Callsign  string        `yaml:"callsign"`
+	Passcode  string        `yaml:"passcode"` // "-1" for CWOP IDs
+	Latitude  float64       `yaml:"latitude"`
+	Longitude float64       `yaml:"longitude"`
+	Interval  time.Duration `yaml:"interval"`
+}
 
-	return globalLoader.config
+// MQTTConfig contains the MQTT publisher. Readings are published as JSON on the state
+// topic; the availability topic is retained and set to "offline" by the broker's last will.
+type MQTTConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Broker            string        `yaml:"broker"` // host:port
+	TLS               bool          `yaml:"tls"`
+	ClientID          string        `yaml:"client_id"`
+	Username          string        `yaml:"username"`
+	Password          string        `yaml:"password"`
+	QoS               int           `yaml:"qos"` // 0 or 1
+	KeepAlive         time.Duration `yaml:"keep_alive"`
+	StateTopic        string        `yaml:"state_topic"`
+	AvailabilityTopic string        `yaml:"availability_topic"`
+	Discovery         bool          `yaml:"discovery"` // Publish Home Assistant discovery payloads
+	DiscoveryPrefix   string        `yaml:"discovery_prefix"`
+	DeviceName        string        `yaml:"device_name"`
+	Interval          time.Duration `yaml:"interval"` // Minimum time between published readings
 }
 
-// loadConfigFromFile attempts to load configuration from a YAML file
-func loadConfigFromFile(configPath string) (*AppConfig, error) {
+// SinksConfig contains the exports of local measurements to time-series databases.
+// Each enabled sink runs on its own queue with the queue retry and circuit breaker settings.
+type SinksConfig struct {
+	InfluxDB InfluxDBSinkConfig `yaml:"influxdb"`
+	Graphite GraphiteSinkConfig `yaml:"graphite"`
+	StatsD   StatsDSinkConfig   `yaml:"statsd"`
+}
+
+// InfluxDBSinkConfig contains the InfluxDB sink. Version 1 uses database, retention_policy,
+// username and password; version 2 uses org, bucket and token.
+type InfluxDBSinkConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	URL             string            `yaml:"url"`
+	Version         int               `yaml:"version"` // 1 or 2
+	Database        string            `yaml:"database"`
+	RetentionPolicy string            `yaml:"retention_policy"`
+	Username        string            `yaml:"username"`
+	Password        string            `yaml:"password"`
+	Org             string            `yaml:"org"`
+	Bucket          string            `yaml:"bucket"`
+	Token           string            `yaml:"token"`
+	Measurement     string            `yaml:"measurement"`
+	Tags            map[string]string `yaml:"tags"`
+	BatchSize       int               `yaml:"batch_size"`     // Points buffered before a write
+	FlushInterval   time.Duration     `yaml:"flush_interval"` // Maximum age of a buffered point
+}
+
+// GraphiteSinkConfig contains the Graphite sink
+type GraphiteSinkConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"` // Carbon plaintext listener, host:port
+	Prefix  string `yaml:"prefix"`
+}
+
+// StatsDSinkConfig contains the StatsD sink
+type StatsDSinkConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"` // StatsD UDP listener, host:port
+	Prefix  string `yaml:"prefix"`
+}
+
+// WebhooksConfig contains the webhook dispatcher. Deliveries are stored in the database
+// and retried on their own queue with the queue retry and circuit breaker settings.
+type WebhooksConfig struct {
+	Enabled         bool                     `yaml:"enabled"`
+	RequeueInterval time.Duration            `yaml:"requeue_interval"` // Pending deliveries not attempted for this long are sent again
+	Retention       time.Duration            `yaml:"retention"`        // Delivered and failed deliveries older than this are deleted
+	Targets         []WebhookTargetConfig    `yaml:"targets"`
+	Thresholds      []WebhookThresholdConfig `yaml:"thresholds"`
+}
+
+// WebhookTargetConfig contains an endpoint called for the listed events:
+// reading, circuit_open, circuit_closed, sensor_down, sensor_up and threshold
+type WebhookTargetConfig struct {
+	Name        string            `yaml:"name"`
+	URL         string            `yaml:"url"`
+	Method      string            `yaml:"method"`
+	Events      []string          `yaml:"events"`
+	Headers     map[string]string `yaml:"headers"`
+	Template    string            `yaml:"template"` // Go text/template; the event as JSON when empty
+	ContentType string            `yaml:"content_type"`
+	Secret      string            `yaml:"secret"` // HMAC-SHA256 signing key
+}
+
+// WebhookThresholdConfig emits a threshold event when a reading goes above or below a limit
+type WebhookThresholdConfig struct {
+	Metric string   `yaml:"metric"` // temperature, humidity or pressure
+	Above  *float64 `yaml:"above"`
+	Below  *float64 `yaml:"below"`
+}
+
+// AlertsConfig contains the alert rules evaluated against each reading. Notifications
+// go to the log and, when webhooks are enabled, to alert_firing and alert_resolved events.
+type AlertsConfig struct {
+	Enabled    bool              `yaml:"enabled"`
+	QuietHours QuietHoursConfig  `yaml:"quiet_hours"`
+	Rules      []AlertRuleConfig `yaml:"rules"`
+}
+
+// QuietHoursConfig is a daily period, in HH:MM local time, during which notifications are held back
+type QuietHoursConfig struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// AlertRuleConfig contains an alert condition, e.g. humidity avg over 1h > 70
+type AlertRuleConfig struct {
+	Name             string        `yaml:"name"`
+	Metric           string        `yaml:"metric"`    // temperature (°C), humidity (%) or pressure (hPa)
+	Aggregate        string        `yaml:"aggregate"` // last, avg, min, max or change
+	Window           time.Duration `yaml:"window"`    // Required by every aggregate but last
+	Operator         string        `yaml:"operator"`  // <, <=, > or >=
+	Threshold        float64       `yaml:"threshold"`
+	For              time.Duration `yaml:"for"`        // How long the condition must hold before firing
+	Hysteresis       float64       `yaml:"hysteresis"` // Margin past the threshold required to resolve
+	Severity         string        `yaml:"severity"`
+	Channels         []string      `yaml:"channels"` // log, webhook, email; every channel when empty
+	IgnoreQuietHours bool          `yaml:"ignore_quiet_hours"`
+}
+
+// EmailConfig contains the SMTP relay used for the email alert channel and the
+// daily digest
+type EmailConfig struct {
+	Enabled   bool                 `yaml:"enabled"`
+	Host      string               `yaml:"host"`
+	Port      int                  `yaml:"port"`
+	Security  string               `yaml:"security"` // none, starttls or tls
+	Auth      string               `yaml:"auth"`     // plain or login, used when username is set
+	Username  string               `yaml:"username"`
+	Password  string               `yaml:"password"`
+	From      string               `yaml:"from"` // e.g. Atmosbyte <station@example.com>
+	To        []string             `yaml:"to"`
+	Timeout   time.Duration        `yaml:"timeout"`
+	Templates EmailTemplatesConfig `yaml:"templates"`
+	Digest    EmailDigestConfig    `yaml:"digest"`
+}
+
+// EmailTemplatesConfig overrides the subject, plain-text and HTML templates; empty
+// templates use the built-in ones
+type EmailTemplatesConfig struct {
+	Subject string `yaml:"subject"`
+	Text    string `yaml:"text"`
+	HTML    string `yaml:"html"`
+}
+
+// EmailDigestConfig sends a daily summary of the previous day
+type EmailDigestConfig struct {
+	Enabled   bool                 `yaml:"enabled"`
+	Time      string               `yaml:"time"` // HH:MM local time
+	Templates EmailTemplatesConfig `yaml:"templates"`
+}
+
+// ReloadConfig configures the configuration watcher. The file is reloaded on SIGHUP
+// and when it changes.
+type ReloadConfig struct {
+	Interval time.Duration `yaml:"interval"` // How often the file is checked for changes
+}
+
+// LogConfig contains logging configuration
+type LogConfig struct {
+	Level string `yaml:"level"` // debug, info, warn or error; debug adds every request and reading
+}
+
+// findConfigFile returns configPath, which must exist, or the first configuration
+// file found in the default locations when configPath is empty
+func findConfigFile(configPath string) (string, error) {
+	if configPath != "" {
+		if _, err := os.Stat(configPath); err != nil {
+			return "", fmt.Errorf("failed to find config file: %w", err)
+		}
+		return configPath, nil
+	}
+
 	// Try to find config file in various locations
 	paths := []string{
-		configPath,
 		"atmosbyte.yaml",
 		"atmosbyte.yml",
 		"config/atmosbyte.yaml",
@@ -148,48 +483,28 @@ func loadConfigFromFile(configPath string) (*AppConfig, error) {
 		"/opt/atmosbyte.yaml",
 	}
 
-	var configFile string
-	var found bool
-
 	for _, path := range paths {
-		if path == "" {
-			continue
-		}
-
 		if _, err := os.Stat(path); err == nil {
-			configFile = path
-			found = true
-			break
+			return path, nil
 		}
 	}
 
-	if !found {
-		return nil, errors.New("configuration file not found in any of the expected locations")
-	}
-
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
-	}
-
-	var config AppConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
-	}
-
-	// Validate and apply defaults for missing values
-	applyDefaults(&config)
-
-	return &config, nil
+	return "", errNotFound
 }
 
 // defaultConfig returns a configuration with sensible defaults
 func defaultConfig() *AppConfig {
-	config := &AppConfig{}
+	config := &AppConfig{DegreeDays: defaultDegreeDays()}
 	applyDefaults(config)
 	return config
 }
 
+// defaultDegreeDays returns the default degree-day bases. Unlike the other defaults
+// they are set before the file is decoded, since a zero base is a valid value.
+func defaultDegreeDays() DegreeDaysConfig {
+	return DegreeDaysConfig(weather.DefaultDegreeDayConfig())
+}
+
 // applyDefaults fills in missing configuration values with sensible defaults
 func applyDefaults(config *AppConfig) {
 	// Web defaults
@@ -205,6 +520,15 @@ func applyDefaults(config *AppConfig) {
 	if config.Web.IdleTimeout == 0 {
 		config.Web.IdleTimeout = 120 * time.Second
 	}
+	if config.Web.TLS.CertFile == "" {
+		config.Web.TLS.CertFile = "atmosbyte.crt"
+	}
+	if config.Web.TLS.KeyFile == "" {
+		config.Web.TLS.KeyFile = "atmosbyte.key"
+	}
+	if config.Web.TLS.ReloadInterval == 0 {
+		config.Web.TLS.ReloadInterval = time.Minute
+	}
 
 	// Queue defaults
 	if config.Queue.Workers == 0 {
@@ -247,12 +571,222 @@ func applyDefaults(config *AppConfig) {
 	if config.Sensor.Simulation.MinTemperature == 0 && config.Sensor.Simulation.MaxTemperature == 0 {
 		config.Sensor.Simulation.MinTemperature = 15.0
 		config.Sensor.Simulation.MaxTemperature = 35.0
+	}
+	if config.Sensor.Simulation.MinHumidity == 0 && config.Sensor.Simulation.MaxHumidity == 0 {
 		config.Sensor.Simulation.MinHumidity = 30.0
 		config.Sensor.Simulation.MaxHumidity = 80.0
+	}
+	if config.Sensor.Simulation.MinPressure == 0 && config.Sensor.Simulation.MaxPressure == 0 {
 		config.Sensor.Simulation.MinPressure = 98000
 		config.Sensor.Simulation.MaxPressure = 102000
 	}
 
+	// Anomaly detection defaults
+	if config.Anomaly.Method == "" {
+		config.Anomaly.Method = "mad"
+	}
+	if config.Anomaly.Threshold == 0 {
+		config.Anomaly.Threshold = 3.5
+	}
+	if config.Anomaly.BaselineDays == 0 {
+		config.Anomaly.BaselineDays = 14
+	}
+	if config.Anomaly.MinSamples == 0 {
+		config.Anomaly.MinSamples = 30
+	}
+	if config.Anomaly.RefreshInterval == 0 {
+		config.Anomaly.RefreshInterval = time.Hour
+	}
+	if config.Anomaly.Cooldown == 0 {
+		config.Anomaly.Cooldown = 15 * time.Minute
+	}
+
+	// Authentication defaults
+	if config.Auth.MaxFailures == 0 {
+		config.Auth.MaxFailures = 5
+	}
+	if config.Auth.FailureWindow == 0 {
+		config.Auth.FailureWindow = 15 * time.Minute
+	}
+
+	// Ingestion defaults
+	if config.Ingest.MaxBatch == 0 {
+		config.Ingest.MaxBatch = 500
+	}
+	if config.Ingest.MaxAge == 0 {
+		config.Ingest.MaxAge = 7 * 24 * time.Hour
+	}
+	if config.Ingest.MaxClockSkew == 0 {
+		config.Ingest.MaxClockSkew = 5 * time.Minute
+	}
+	if config.Ingest.DedupWindow == 0 {
+		config.Ingest.DedupWindow = 24 * time.Hour
+	}
+
+	// Station upload defaults
+	if config.StationUpload.EcowittPath == "" {
+		config.StationUpload.EcowittPath = "/data/report/"
+	}
+
+	// Uploader defaults, following the rate limits of each service
+	if config.Uploaders.Wunderground.URL == "" {
+		config.Uploaders.Wunderground.URL = uploader.DefaultWundergroundURL
+	}
+	if config.Uploaders.Wunderground.Interval == 0 {
+		config.Uploaders.Wunderground.Interval = time.Minute
+	}
+	if config.Uploaders.PWSWeather.URL == "" {
+		config.Uploaders.PWSWeather.URL = uploader.DefaultPWSWeatherURL
+	}
+	if config.Uploaders.PWSWeather.Interval == 0 {
+		config.Uploaders.PWSWeather.Interval = time.Minute
+	}
+	if config.Uploaders.Windy.URL == "" {
+		config.Uploaders.Windy.URL = uploader.DefaultWindyURL
+	}
+	if config.Uploaders.Windy.Interval == 0 {
+		config.Uploaders.Windy.Interval = 5 * time.Minute
+	}
+	if config.Uploaders.CWOP.Server == "" {
+		config.Uploaders.CWOP.Server = uploader.DefaultCWOPServer
+	}
+	if config.Uploaders.CWOP.Passcode == "" {
+		config.Uploaders.CWOP.Passcode = "-1"
+	}
+	if config.Uploaders.CWOP.Interval == 0 {
+		config.Uploaders.CWOP.Interval = 5 * time.Minute
+	}
+
+	// MQTT defaults
+	if config.MQTT.Broker == "" {
+		config.MQTT.Broker = "localhost:1883"
+	}
+	if config.MQTT.ClientID == "" {
+		config.MQTT.ClientID = "atmosbyte"
+	}
+	if config.MQTT.KeepAlive == 0 {
+		config.MQTT.KeepAlive = 60 * time.Second
+	}
+	if config.MQTT.StateTopic == "" {
+		config.MQTT.StateTopic = "atmosbyte/state"
+	}
+	if config.MQTT.AvailabilityTopic == "" {
+		config.MQTT.AvailabilityTopic = "atmosbyte/status"
+	}
+	if config.MQTT.DiscoveryPrefix == "" {
+		config.MQTT.DiscoveryPrefix = "homeassistant"
+	}
+	if config.MQTT.DeviceName == "" {
+		config.MQTT.DeviceName = "Atmosbyte"
+	}
+
+	// Sink defaults
+	if config.Sinks.InfluxDB.URL == "" {
+		config.Sinks.InfluxDB.URL = "http://localhost:8086"
+	}
+	if config.Sinks.InfluxDB.Version == 0 {
+		config.Sinks.InfluxDB.Version = 2
+	}
+	if config.Sinks.InfluxDB.Measurement == "" {
+		config.Sinks.InfluxDB.Measurement = "atmosbyte"
+	}
+	if config.Sinks.InfluxDB.BatchSize == 0 {
+		config.Sinks.InfluxDB.BatchSize = 10
+	}
+	if config.Sinks.InfluxDB.FlushInterval == 0 {
+		config.Sinks.InfluxDB.FlushInterval = 5 * time.Minute
+	}
+	if config.Sinks.Graphite.Address == "" {
+		config.Sinks.Graphite.Address = "localhost:2003"
+	}
+	if config.Sinks.Graphite.Prefix == "" {
+		config.Sinks.Graphite.Prefix = "atmosbyte"
+	}
+	if config.Sinks.StatsD.Address == "" {
+		config.Sinks.StatsD.Address = "localhost:8125"
+	}
+	if config.Sinks.StatsD.Prefix == "" {
+		config.Sinks.StatsD.Prefix = "atmosbyte"
+	}
+
+	// Webhook defaults
+	if config.Webhooks.RequeueInterval == 0 {
+		config.Webhooks.RequeueInterval = 10 * time.Minute
+	}
+	if config.Webhooks.Retention == 0 {
+		config.Webhooks.Retention = 30 * 24 * time.Hour
+	}
+
+	// Email defaults
+	if config.Email.Port == 0 {
+		config.Email.Port = 587
+	}
+	if config.Email.Security == "" {
+		config.Email.Security = "starttls"
+	}
+	if config.Email.Auth == "" {
+		config.Email.Auth = "plain"
+	}
+	if config.Email.Timeout == 0 {
+		config.Email.Timeout = 30 * time.Second
+	}
+	if config.Email.Digest.Time == "" {
+		config.Email.Digest.Time = "07:00"
+	}
+
+	// Reload defaults
+	if config.Reload.Interval == 0 {
+		config.Reload.Interval = 10 * time.Second
+	}
+
+	// Logging defaults
+	if config.Log.Level == "" {
+		config.Log.Level = "info"
+	}
+
+	// Storage defaults
+	if config.Storage.Path == "" {
+		config.Storage.Path = "weather.db"
+	}
+	if config.Storage.JournalMode == "" {
+		config.Storage.JournalMode = "wal"
+	}
+	if config.Storage.Synchronous == "" {
+		config.Storage.Synchronous = "normal"
+	}
+	if config.Storage.BusyTimeout == 0 {
+		config.Storage.BusyTimeout = 5 * time.Second
+	}
+	if config.Storage.CacheSize == 0 {
+		config.Storage.CacheSize = 2048
+	}
+	if config.Storage.MaxOpenConns == 0 {
+		config.Storage.MaxOpenConns = 4
+	}
+	if config.Storage.MaxIdleConns == 0 {
+		config.Storage.MaxIdleConns = 2
+	}
+	if config.Storage.ReadReplica.MaxOpenConns == 0 {
+		config.Storage.ReadReplica.MaxOpenConns = 4
+	}
+	if config.Storage.ReadReplica.MaxIdleConns == 0 {
+		config.Storage.ReadReplica.MaxIdleConns = 2
+	}
+
+	// Backup defaults
+	if config.Backup.Dir == "" {
+		config.Backup.Dir = "backups"
+	}
+	if config.Backup.Time == "" {
+		config.Backup.Time = "03:00"
+	}
+	if config.Backup.KeepDaily == 0 {
+		config.Backup.KeepDaily = 7
+	}
+	if config.Backup.KeepWeekly == 0 {
+		config.Backup.KeepWeekly = 4
+	}
+
 	// Timeout defaults
 	if config.Timeouts.ShutdownTimeout == 0 {
 		config.Timeouts.ShutdownTimeout = 10 * time.Second
@@ -270,9 +804,12 @@ func applyDefaults(config *AppConfig) {
 
 // GenerateExampleConfig creates an example configuration file
 func GenerateExampleConfig(outputPath string) error {
-	config := defaultConfig()
+	root, err := encodeConfig(defaultConfig())
+	if err != nil {
+		return err
+	}
 
-	data, err := yaml.Marshal(config)
+	data, err := yaml.Marshal(root)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
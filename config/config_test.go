@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -9,9 +12,6 @@ import (
 
 // TestConfigConcurrentAccess verifica se não há condições de corrida
 func TestConfigConcurrentAccess(t *testing.T) {
-	// Reset global state
-	globalLoader = &ConfigLoader{}
-
 	// Load config in the main goroutine
 	cfg, err := Load("../test-config.yaml")
 	if err != nil {
@@ -62,11 +62,9 @@ func TestConfigConcurrentAccess(t *testing.T) {
 
 // TestConfigDefaults verifica valores padrão
 func TestConfigDefaults(t *testing.T) {
-	// Reset global state
-	globalLoader = &ConfigLoader{}
-
-	// Load with non-existent file to trigger defaults
-	cfg, err := Load("non-existent-file.yaml")
+	// Load from a directory without configuration files to trigger defaults
+	t.Chdir(t.TempDir())
+	cfg, err := Load("")
 	if err != nil {
 		t.Fatalf("Load should not fail when using defaults: %v", err)
 	}
@@ -89,11 +87,33 @@ func TestConfigDefaults(t *testing.T) {
 	}
 }
 
+// TestGenerateExampleConfig verifica que o exemplo gerado escreve o endereço I2C em
+// hexadecimal e pode ser carregado de volta
+func TestGenerateExampleConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atmosbyte.yaml.example")
+	if err := GenerateExampleConfig(path); err != nil {
+		t.Fatalf("Failed to generate example: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "i2c_address: 0x76\n") {
+		t.Errorf("Expected the I2C address in hexadecimal:\n%s", data)
+	}
+
+	result, err := build(path, data, "", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to load the example: %v", err)
+	}
+	if result.config.Sensor.BME280.I2CAddress != 0x76 {
+		t.Errorf("Expected i2c_address 0x76, got %#x", result.config.Sensor.BME280.I2CAddress)
+	}
+}
+
 // TestConfigAdapters verifica se os adaptadores funcionam corretamente
 func TestConfigAdapters(t *testing.T) {
-	// Reset global state
-	globalLoader = &ConfigLoader{}
-
 	cfg, err := Load("../test-config.yaml")
 	if err != nil {
 		t.Fatalf("Failed to load test config: %v", err)
@@ -123,3 +143,211 @@ func TestConfigAdapters(t *testing.T) {
 		t.Errorf("Simulated adapter failed: expected min temp 15.0, got %f", simConfig.MinTemp)
 	}
 }
+
+// TestStorageConfig verifica os padrões, a validação e a réplica somente leitura do banco
+func TestStorageConfig(t *testing.T) {
+	cfg := defaultConfig()
+	storage := cfg.StorageConfig()
+	if storage.Path != "weather.db" || storage.JournalMode != "wal" || storage.Synchronous != "normal" || storage.ReadOnly {
+		t.Errorf("Unexpected default storage config %+v", storage)
+	}
+
+	cfg.Storage.ReadReplica.Path = "replica.db"
+	cfg.Storage.ReadReplica.MaxOpenConns = 8
+	replica := cfg.ReadReplicaConfig()
+	if replica.Path != "replica.db" || replica.MaxOpenConns != 8 || !replica.ReadOnly || replica.BusyTimeout != storage.BusyTimeout {
+		t.Errorf("Unexpected replica config %+v", replica)
+	}
+
+	cfg.Storage.JournalMode = "memory"
+	cfg.Storage.Synchronous = "fast"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "storage.journal_mode: unknown journal mode \"memory\"") ||
+		!strings.Contains(err.Error(), "storage.synchronous: unknown synchronous level \"fast\"") {
+		t.Errorf("Expected journal mode and synchronous errors, got %v", err)
+	}
+}
+
+// TestUploadWorkers verifica a validação e criação dos uploaders
+func TestUploadWorkers(t *testing.T) {
+	cfg := &AppConfig{}
+	applyDefaults(cfg)
+
+	workers, err := cfg.UploadWorkers()
+	if err != nil || len(workers) != 0 {
+		t.Fatalf("Expected no uploaders by default, got %d (%v)", len(workers), err)
+	}
+
+	cfg.Uploaders.Windy.Enabled = true
+	if _, err := cfg.UploadWorkers(); err == nil {
+		t.Error("Expected error for windy without api_key")
+	}
+
+	cfg.Uploaders.Windy.APIKey = "key"
+	cfg.Uploaders.Wunderground = WundergroundUploadConfig{Enabled: true, StationID: "KTEST1", Password: "secret", Interval: time.Minute}
+	workers, err = cfg.UploadWorkers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(workers) != 2 || workers[0].Name() != "Weather Underground" || workers[1].Name() != "Windy" {
+		t.Errorf("Unexpected uploaders %v", workers)
+	}
+
+	if queueConfig := cfg.UploaderQueueConfig(); queueConfig.Workers != 1 {
+		t.Errorf("Expected a single worker per uploader, got %d", queueConfig.Workers)
+	}
+}
+
+// TestMQTTWorker verifica a validação do publicador MQTT
+func TestMQTTWorker(t *testing.T) {
+	cfg := &AppConfig{}
+	applyDefaults(cfg)
+
+	if cfg.MQTT.StateTopic != "atmosbyte/state" || cfg.MQTT.AvailabilityTopic != "atmosbyte/status" {
+		t.Errorf("Unexpected default topics %s and %s", cfg.MQTT.StateTopic, cfg.MQTT.AvailabilityTopic)
+	}
+
+	cfg.MQTT.QoS = 2
+	if _, err := cfg.MQTTWorker("test"); err == nil {
+		t.Error("Expected error for QoS 2")
+	}
+
+	cfg.MQTT.QoS = 1
+	worker, err := cfg.MQTTWorker("test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if worker.Name() != "MQTT" {
+		t.Errorf("Expected MQTT worker, got %s", worker.Name())
+	}
+}
+
+// TestSinkWorkers verifica a criação e a validação dos destinos de séries temporais
+func TestSinkWorkers(t *testing.T) {
+	cfg := &AppConfig{}
+	applyDefaults(cfg)
+
+	sinks, err := cfg.SinkWorkers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sinks) != 0 {
+		t.Errorf("Expected no sinks by default, got %d", len(sinks))
+	}
+
+	cfg.Sinks.InfluxDB.Enabled = true
+	if _, err := cfg.SinkWorkers(); err == nil {
+		t.Error("Expected error for InfluxDB v2 without org and bucket")
+	}
+
+	cfg.Sinks.InfluxDB.Org = "home"
+	cfg.Sinks.InfluxDB.Bucket = "weather"
+	cfg.Sinks.Graphite.Enabled = true
+	cfg.Sinks.StatsD.Enabled = true
+	sinks, err = cfg.SinkWorkers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() {
+		for _, sink := range sinks {
+			sink.Close()
+		}
+	}()
+
+	var names []string
+	for _, sink := range sinks {
+		names = append(names, sink.Name())
+	}
+	if strings.Join(names, ",") != "InfluxDB v2,Graphite,StatsD" {
+		t.Errorf("Unexpected sinks %v", names)
+	}
+}
+
+// TestWebhookConfig verifica a conversão dos webhooks e a validação dos eventos
+func TestWebhookConfig(t *testing.T) {
+	cfg := &AppConfig{}
+	applyDefaults(cfg)
+
+	above := 35.0
+	cfg.Webhooks.Targets = []WebhookTargetConfig{{Name: "slack", URL: "https://hooks.example.com", Events: []string{"sensor_down", "threshold"}}}
+	cfg.Webhooks.Thresholds = []WebhookThresholdConfig{{Metric: "temperature", Above: &above}}
+
+	webhookConfig, err := cfg.WebhookConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(webhookConfig.Targets) != 1 || len(webhookConfig.Targets[0].Events) != 2 || len(webhookConfig.Thresholds) != 1 {
+		t.Errorf("Unexpected webhook config %+v", webhookConfig)
+	}
+	if webhookConfig.RequeueInterval != 10*time.Minute {
+		t.Errorf("Expected default requeue interval of 10m, got %v", webhookConfig.RequeueInterval)
+	}
+	if webhookConfig.Retention != 30*24*time.Hour {
+		t.Errorf("Expected default retention of 30 days, got %v", webhookConfig.Retention)
+	}
+	if webhookConfig.Queue.Workers != 1 || webhookConfig.Queue.RetryPolicy.MaxDelay == 0 {
+		t.Errorf("Unexpected webhook queue config %+v", webhookConfig.Queue)
+	}
+
+	cfg.Webhooks.Targets[0].Events = []string{"sensor_lost"}
+	if _, err := cfg.WebhookConfig(); err == nil {
+		t.Error("Expected error for unknown event")
+	}
+}
+
+// TestAlertConfig verifica a conversão das regras de alerta e do horário de silêncio
+func TestAlertConfig(t *testing.T) {
+	cfg := &AppConfig{}
+	applyDefaults(cfg)
+
+	cfg.Alerts.QuietHours = QuietHoursConfig{Start: "22:00", End: "07:00"}
+	cfg.Alerts.Rules = []AlertRuleConfig{
+		{Name: "frost", Metric: "temperature", Operator: "<", Threshold: 2, For: 10 * time.Minute, Hysteresis: 1},
+		{Name: "storm", Metric: "pressure", Aggregate: "change", Window: 3 * time.Hour, Operator: "<", Threshold: -3},
+	}
+
+	alertConfig, err := cfg.AlertConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(alertConfig.Rules) != 2 || alertConfig.Rules[0].Aggregate != "last" {
+		t.Errorf("Unexpected alert rules %+v", alertConfig.Rules)
+	}
+	if alertConfig.Rules[1].String() != "pressure change over 3h < -3" {
+		t.Errorf("Unexpected rule %s", alertConfig.Rules[1])
+	}
+
+	cfg.Alerts.Rules[1].Window = 0
+	if _, err := cfg.AlertConfig(); err == nil {
+		t.Error("Expected error for change without window")
+	}
+
+	cfg.Alerts.Rules = nil
+	cfg.Alerts.QuietHours.End = "7h"
+	if _, err := cfg.AlertConfig(); err == nil {
+		t.Error("Expected error for invalid quiet hours")
+	}
+}
+
+// TestEmailConfig verifica os valores padrão e a conversão da configuração de email
+func TestEmailConfig(t *testing.T) {
+	cfg := &AppConfig{}
+	applyDefaults(cfg)
+
+	cfg.Email.Host = "smtp.example.com"
+	cfg.Email.From = "station@example.com"
+	cfg.Email.To = []string{"ops@example.com"}
+	cfg.Email.Digest.Enabled = true
+	cfg.Email.Digest.Templates.Subject = "Yesterday"
+
+	emailConfig := cfg.EmailConfig()
+	if emailConfig.Server.Port != 587 || emailConfig.Server.Security != "starttls" || emailConfig.Server.Auth != "plain" {
+		t.Errorf("Unexpected email server defaults %+v", emailConfig.Server)
+	}
+	if emailConfig.Digest.Time != "07:00" || emailConfig.Digest.Templates.Subject != "Yesterday" {
+		t.Errorf("Unexpected digest config %+v", emailConfig.Digest)
+	}
+	if emailConfig.Queue.Workers != 1 || emailConfig.Queue.RetryPolicy.MaxDelay != 5*time.Minute {
+		t.Errorf("Unexpected email queue config %+v", emailConfig.Queue)
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// errNotFound is returned by findConfigFile when no configuration file exists
+var errNotFound = errors.New("configuration file not found in any of the expected locations")
+
+// Loader loads a configuration file, merged with its includes and the selected
+// profile, then applies the ATMOSBYTE_ environment variables and the -set overrides.
+// Precedence is defaults < file < profile < env < overrides. Loaders are independent
+// of each other and safe for concurrent use.
+type Loader struct {
+	path      string   // Requested file, the default locations are searched when empty
+	profile   string   // Requested profile, ATMOSBYTE_PROFILE when empty
+	overrides []string // -set path=value pairs
+	environ   []string // Environment, os.Environ at every load when nil
+
+	mu      sync.RWMutex
+	config  *AppConfig
+	file    string            // File the configuration was read from, empty for defaults
+	files   []string          // file and its includes
+	active  string            // Profile in use
+	sources map[string]string // Source of each field, see PrintEffective
+}
+
+// LoaderOption configures a Loader
+type LoaderOption func(*Loader)
+
+// WithProfile selects the profile merged over the file, overriding ATMOSBYTE_PROFILE
+func WithProfile(profile string) LoaderOption {
+	return func(l *Loader) {
+		l.profile = profile
+	}
+}
+
+// WithOverrides applies path=value pairs, such as web.port=9090, over the environment
+func WithOverrides(overrides []string) LoaderOption {
+	return func(l *Loader) {
+		l.overrides = overrides
+	}
+}
+
+// WithEnviron replaces the process environment, in os.Environ format, used for the
+// ATMOSBYTE_ overrides, the profile and ${VAR} interpolation
+func WithEnviron(environ []string) LoaderOption {
+	return func(l *Loader) {
+		l.environ = environ
+	}
+}
+
+// NewLoader creates a loader for the file at path; when path is empty the default
+// locations are searched, and the defaults used if none exists. Load fails when a
+// path is given but does not exist.
+func NewLoader(path string, opts ...LoaderOption) *Loader {
+	l := &Loader{path: path}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load reads and validates the configuration. An invalid configuration returns an
+// error, a *ValidationError listing each problem with its file and line when the YAML
+// is well formed, and the previously loaded configuration, if any, is kept.
+func (l *Loader) Load() (*AppConfig, error) {
+	path, err := findConfigFile(l.path)
+	if errors.Is(err, errNotFound) {
+		path = ""
+	} else if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result, err := l.read(path)
+	if err != nil {
+		return nil, err
+	}
+	l.store(path, result)
+	return result.config, nil
+}
+
+// Reload reads the file loaded by Load again. The new configuration replaces the
+// current one only when it is valid; otherwise the current configuration is kept and
+// the error returned.
+func (l *Loader) Reload() (*AppConfig, []Change, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config == nil {
+		return nil, nil, fmt.Errorf("configuration not loaded")
+	}
+
+	result, err := l.read(l.file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := diff(l.config, result.config)
+	l.store(l.file, result)
+	return result.config, changes, nil
+}
+
+// read builds the configuration from file; the caller holds the lock
+func (l *Loader) read(file string) (*loaded, error) {
+	var data []byte
+	if file != "" {
+		var err error
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+	}
+
+	environ := l.environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+
+	profile := l.profile
+	if profile == "" {
+		profile = lookupEnv(environ, ProfileEnv)
+	}
+
+	return build(file, data, profile, environ, l.overrides)
+}
+
+// store replaces the current configuration; the caller holds the lock
+func (l *Loader) store(file string, result *loaded) {
+	l.config = result.config
+	l.file = file
+	l.files = result.files
+	l.active = result.profile
+	l.sources = result.sources
+}
+
+// Config returns the loaded configuration, nil before Load
+func (l *Loader) Config() *AppConfig {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.config
+}
+
+// Path returns the file the configuration was loaded from, empty when the defaults are used
+func (l *Loader) Path() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.file
+}
+
+// Files returns the loaded file followed by the files it includes
+func (l *Loader) Files() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]string(nil), l.files...)
+}
+
+// Profile returns the profile in use, empty when none is selected
+func (l *Loader) Profile() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.active
+}
+
+// defaultLoader is the loader of the last successful Load, read by Get
+var (
+	defaultMu     sync.RWMutex
+	defaultLoader *Loader
+)
+
+// Load loads the configuration at configPath with the given -set overrides and makes
+// it the one returned by Get. It is kept for compatibility, use NewLoader instead.
+func Load(configPath string, overrides ...string) (*AppConfig, error) {
+	loader := NewLoader(configPath, WithOverrides(overrides))
+	config, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultMu.Lock()
+	defaultLoader = loader
+	defaultMu.Unlock()
+
+	return config, nil
+}
+
+// Get returns the configuration of the last successful Load. It is kept for
+// compatibility, use Loader.Config instead.
+func Get() *AppConfig {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	if defaultLoader == nil {
+		panic("Configuration not loaded. Call config.Load() first.")
+	}
+	return defaultLoader.Config()
+}
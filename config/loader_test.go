@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles grava os arquivos em um diretório temporário e retorna o caminho do primeiro
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestLoader_Independent verifica que loaders diferentes coexistem
+func TestLoader_Independent(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "web:\n  port: 9001\n",
+		"b.yaml": "web:\n  port: 9002\n",
+	})
+
+	a := NewLoader(filepath.Join(dir, "a.yaml"), WithEnviron([]string{}))
+	b := NewLoader(filepath.Join(dir, "b.yaml"), WithEnviron([]string{}))
+	if a.Config() != nil {
+		t.Error("Expected no configuration before Load")
+	}
+
+	cfgA, err := a.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfgB, err := b.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfgA.Web.Port != 9001 || cfgB.Web.Port != 9002 || a.Config().Web.Port != 9001 {
+		t.Errorf("Expected ports 9001 and 9002, got %d and %d", cfgA.Web.Port, cfgB.Web.Port)
+	}
+}
+
+// TestLoader_MissingExplicitFile verifica que um arquivo informado e inexistente é um erro
+func TestLoader_MissingExplicitFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atmosbyte.yaml")
+
+	_, err := NewLoader(path, WithEnviron([]string{})).Load()
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected a not found error for %s, got %v", path, err)
+	}
+}
+
+// TestLoader_Include verifica a composição de arquivos e a origem dos valores
+func TestLoader_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"atmosbyte.yaml": `include:
+  - base.yaml
+  - secrets.yaml
+web:
+  port: 9090
+`,
+		"base.yaml": `web:
+  port: 8081
+  read_timeout: 20s
+queue:
+  workers: 3
+`,
+		"secrets.yaml": `email:
+  password: hunter2
+`,
+	})
+	path := filepath.Join(dir, "atmosbyte.yaml")
+
+	loader := NewLoader(path, WithEnviron([]string{}))
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfg.Web.Port != 9090 || cfg.Web.ReadTimeout.Seconds() != 20 || cfg.Queue.Workers != 3 || cfg.Email.Password != "hunter2" {
+		t.Errorf("Unexpected merged configuration: web %+v, queue %+v", cfg.Web, cfg.Queue)
+	}
+
+	files := loader.Files()
+	if len(files) != 3 || files[0] != path {
+		t.Errorf("Expected the file and its two includes, got %v", files)
+	}
+
+	var buf bytes.Buffer
+	if err := loader.PrintEffective(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{
+		"port: 9090 # file " + path + ":5",
+		"read_timeout: 20s # file " + filepath.Join(dir, "base.yaml") + ":3",
+	} {
+		if !strings.Contains(buf.String(), e) {
+			t.Errorf("Expected %q in:\n%s", e, buf.String())
+		}
+	}
+}
+
+// TestLoader_IncludeErrors verifica os erros de arquivos incluídos, ausentes e em ciclo
+func TestLoader_IncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"atmosbyte.yaml": "include: [base.yaml, missing.yaml]\n",
+		"base.yaml":      "include: atmosbyte.yaml\nweb:\n  prot: 9090\n",
+	})
+
+	_, err := NewLoader(filepath.Join(dir, "atmosbyte.yaml"), WithEnviron([]string{})).Load()
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	base := filepath.Join(dir, "base.yaml")
+	for _, e := range []string{
+		"line 1: include: failed to read missing.yaml",
+		base + " line 1: include: atmosbyte.yaml is included in a loop",
+		base + " line 3: web.prot: unknown field, did you mean port?",
+	} {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("Expected %q in:\n%s", e, err)
+		}
+	}
+}
+
+// TestLoader_Profiles verifica a seleção de perfis por opção e por variável de ambiente
+func TestLoader_Profiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"atmosbyte.yaml": `sensor:
+  type: hardware
+  read_interval: 1m
+profiles:
+  dev:
+    sensor:
+      type: simulated
+      read_interval: 5s
+  prod:
+    web:
+      port: 80
+`,
+	})
+	path := filepath.Join(dir, "atmosbyte.yaml")
+
+	tests := []struct {
+		name     string
+		opts     []LoaderOption
+		profile  string
+		sensor   string
+		port     int
+		errorMsg string
+	}{
+		{"no profile", nil, "", "hardware", 8080, ""},
+		{"option", []LoaderOption{WithProfile("dev")}, "dev", "simulated", 8080, ""},
+		{"environment", []LoaderOption{WithEnviron([]string{ProfileEnv + "=prod"})}, "prod", "hardware", 80, ""},
+		{"option over environment", []LoaderOption{WithEnviron([]string{ProfileEnv + "=prod"}), WithProfile("dev")}, "dev", "simulated", 8080, ""},
+		{"unknown", []LoaderOption{WithProfile("staging")}, "", "", 0, `profiles: profile "staging" is not defined`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewLoader(path, append([]LoaderOption{WithEnviron([]string{})}, tt.opts...)...)
+			cfg, err := loader.Load()
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("Expected error %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if loader.Profile() != tt.profile || cfg.Sensor.Type != tt.sensor || cfg.Web.Port != tt.port {
+				t.Errorf("Expected profile %q, sensor %s and port %d, got %q, %s and %d",
+					tt.profile, tt.sensor, tt.port, loader.Profile(), cfg.Sensor.Type, cfg.Web.Port)
+			}
+		})
+	}
+}
+
+// TestLoader_ProfileErrors verifica que perfis são validados mesmo sem serem selecionados
+func TestLoader_ProfileErrors(t *testing.T) {
+	data := "profiles:\n  dev:\n    sensor:\n      read_interval: 10\n"
+	_, err := build("atmosbyte.yaml", []byte(data), "", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "line 4: profiles.dev.sensor.read_interval: cannot unmarshal") {
+		t.Errorf("Expected a type error in the profile, got %v", err)
+	}
+}
+
+// TestLoader_Interpolation verifica a interpolação de variáveis e arquivos
+func TestLoader_Interpolation(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"atmosbyte.yaml": `web:
+  port: ${PORT}
+email:
+  username: ${SMTP_USER}@example.com
+  password: ${file:smtp.secret}
+  from: "Station $${HOME}"
+`,
+		"smtp.secret": "s3cret\n",
+	})
+
+	loader := NewLoader(filepath.Join(dir, "atmosbyte.yaml"), WithEnviron([]string{"PORT=9443", "SMTP_USER=station"}))
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfg.Web.Port != 9443 {
+		t.Errorf("Expected port 9443, got %d", cfg.Web.Port)
+	}
+	if cfg.Email.Username != "station@example.com" || cfg.Email.Password != "s3cret" {
+		t.Errorf("Unexpected credentials %q %q", cfg.Email.Username, cfg.Email.Password)
+	}
+	if cfg.Email.From != "Station ${HOME}" {
+		t.Errorf("Expected the escaped reference to be kept, got %q", cfg.Email.From)
+	}
+}
+
+// TestLoader_InterpolationErrors verifica os erros de variáveis e arquivos ausentes
+func TestLoader_InterpolationErrors(t *testing.T) {
+	data := "web:\n  port: ${PORT}\nemail:\n  password: ${file:/nonexistent/secret}\n"
+	_, err := build("atmosbyte.yaml", []byte(data), "", []string{}, nil)
+
+	for _, e := range []string{
+		"line 2: web.port: ${PORT}: environment variable PORT is not set",
+		"line 4: email.password: ${file:/nonexistent/secret}:",
+	} {
+		if err == nil || !strings.Contains(err.Error(), e) {
+			t.Errorf("Expected %q in %v", e, err)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables overriding configuration fields.
+// The rest of the name is the YAML path in upper case with dots replaced by
+// underscores, e.g. ATMOSBYTE_QUEUE_RETRY_MAX_RETRIES for queue.retry.max_retries.
+const EnvPrefix = "ATMOSBYTE_"
+
+// Sources of configuration values, from the lowest to the highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Overrides collects -set path=value flags; it implements flag.Value
+type Overrides []string
+
+func (o *Overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+// Set validates and records a path=value pair
+func (o *Overrides) Set(value string) error {
+	if path, _, ok := strings.Cut(value, "="); !ok || path == "" {
+		return fmt.Errorf("expected path=value, e.g. web.port=9090")
+	}
+	*o = append(*o, value)
+	return nil
+}
+
+// field is a configuration field addressed by its YAML path
+type field struct {
+	path  string // e.g. queue.retry.max_retries
+	env   string // e.g. ATMOSBYTE_QUEUE_RETRY_MAX_RETRIES
+	index []int  // Index in AppConfig for reflect.Value.FieldByIndex
+	typ   reflect.Type
+}
+
+// fields lists every non-struct field of AppConfig, including lists and maps
+var fields = sync.OnceValue(func() []field {
+	var list []field
+	var walk func(t reflect.Type, path string, index []int)
+	walk = func(t reflect.Type, path string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			p := join(path, name)
+			idx := append(append([]int(nil), index...), i)
+			if f.Type.Kind() == reflect.Struct {
+				walk(f.Type, p, idx)
+				continue
+			}
+			list = append(list, field{
+				path:  p,
+				env:   EnvPrefix + strings.ToUpper(strings.ReplaceAll(p, ".", "_")),
+				index: idx,
+				typ:   f.Type,
+			})
+		}
+	}
+	walk(reflect.TypeFor[AppConfig](), "", nil)
+	return list
+})
+
+// override is a value set from the environment or a flag
+type override struct {
+	field  field
+	value  string
+	source string // SourceEnv or SourceFlag
+	name   string // Variable name or flag
+}
+
+// collectOverrides resolves the ATMOSBYTE_ variables of environ and the path=value
+// pairs of sets, in order of precedence
+func collectOverrides(environ []string, sets []string) ([]override, []FieldError) {
+	byEnv := make(map[string]field)
+	byPath := make(map[string]field)
+	for _, f := range fields() {
+		byEnv[f.env] = f
+		byPath[f.path] = f
+	}
+
+	var overrides []override
+	var errs []FieldError
+
+	sort.Strings(environ)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == ProfileEnv {
+			continue
+		}
+		f, ok := byEnv[name]
+		if !ok {
+			errs = append(errs, FieldError{Message: fmt.Sprintf("unknown environment variable %s", name)})
+			continue
+		}
+		overrides = append(overrides, override{field: f, value: value, source: SourceEnv, name: name})
+	}
+
+	for _, set := range sets {
+		path, value, _ := strings.Cut(set, "=")
+		f, ok := byPath[path]
+		if !ok {
+			errs = append(errs, FieldError{Path: path, Message: "-set: unknown field"})
+			continue
+		}
+		overrides = append(overrides, override{field: f, value: value, source: SourceFlag, name: "-set " + path})
+	}
+
+	return overrides, errs
+}
+
+// applyOverrides sets the overrides on config and returns the fields they set by path
+func applyOverrides(config *AppConfig, overrides []override) (map[string]override, []FieldError) {
+	applied := make(map[string]override)
+	var errs []FieldError
+
+	root := reflect.ValueOf(config).Elem()
+	for _, o := range overrides {
+		if err := setValue(root.FieldByIndex(o.field.index), o.value); err != nil {
+			errs = append(errs, FieldError{Path: o.field.path, Message: fmt.Sprintf("%s: %v", o.name, err)})
+			continue
+		}
+		applied[o.field.path] = o
+	}
+	return applied, errs
+}
+
+// setValue parses s into v. Signed integers are decimal, unsigned integers also
+// accept hex such as 0x76, and lists of strings are comma separated.
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, e.g. 10s or 5m", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("lists of %s cannot be overridden, use the configuration file", v.Type().Elem())
+		}
+		var items []string
+		for item := range strings.SplitSeq(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("%s fields cannot be overridden, use the configuration file", v.Type())
+	}
+	return nil
+}
+
+// sourcesOf returns the source of every field: the override that set it, the file
+// and line it was read from, or the defaults
+func sourcesOf(positions map[string]position, applied map[string]override) map[string]string {
+	sources := make(map[string]string)
+	for _, f := range fields() {
+		switch o, ok := applied[f.path]; {
+		case ok && o.source == SourceEnv:
+			sources[f.path] = SourceEnv + " " + o.name
+		case ok:
+			sources[f.path] = SourceFlag + " " + o.name
+		case positions[f.path].line > 0:
+			pos := positions[f.path]
+			sources[f.path] = fmt.Sprintf("%s %s:%d", SourceFile, pos.file, pos.line)
+		default:
+			sources[f.path] = SourceDefault
+		}
+	}
+	return sources
+}
+
+// secretFields are masked by PrintEffective
+var secretFields = map[string]bool{
+	"password":      true,
+	"password_hash": true,
+	"secret":        true,
+	"key":           true,
+	"api_key":       true,
+	"passcode":      true,
+	"token":         true,
+}
+
+// PrintEffective writes the loaded configuration as YAML, each value commented with
+// its source. Secrets are masked.
+func (l *Loader) PrintEffective(w io.Writer) error {
+	l.mu.RLock()
+	config, sources, profile := l.config, l.sources, l.active
+	l.mu.RUnlock()
+
+	if config == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+	return printEffective(w, config, sources, profile)
+}
+
+// printEffective writes config annotated with sources
+func printEffective(w io.Writer, config *AppConfig, sources map[string]string, profile string) error {
+	root, err := encodeConfig(config)
+	if err != nil {
+		return err
+	}
+	annotate(root, "", sources)
+	if profile != "" {
+		root.HeadComment = "profile " + profile
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(4)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return encoder.Close()
+}
+
+// encodeConfig encodes config as a YAML node. Unsigned 16-bit fields, the I2C
+// address, are written in hexadecimal as in the configuration file.
+func encodeConfig(config *AppConfig) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := root.Encode(config); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	for _, f := range fields() {
+		if f.typ.Kind() != reflect.Uint16 {
+			continue
+		}
+		node := &root
+		for key := range strings.SplitSeq(f.path, ".") {
+			if node = mappingValue(node, key); node == nil {
+				break
+			}
+		}
+		if node != nil && node.Kind == yaml.ScalarNode {
+			if n, err := strconv.ParseUint(node.Value, 10, 16); err == nil {
+				node.Value = fmt.Sprintf("%#x", n)
+			}
+		}
+	}
+	return &root, nil
+}
+
+// mappingValue returns the value of key in the mapping node, nil if there is none
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// annotate comments the fields of node with their source and masks secrets
+func annotate(node *yaml.Node, path string, sources map[string]string) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			annotate(item, path, nil)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := join(path, key.Value)
+
+			if secretFields[key.Value] {
+				mask(value)
+			}
+			if key.Value == "headers" && value.Kind == yaml.MappingNode {
+				for j := 1; j < len(value.Content); j += 2 {
+					mask(value.Content[j])
+				}
+			}
+
+			source, ok := sources[p]
+			if !ok {
+				annotate(value, p, sources)
+				continue
+			}
+			if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+				value.LineComment = source
+			} else {
+				key.LineComment = source
+				annotate(value, p, nil)
+			}
+		}
+	}
+}
+
+// mask hides a non-empty scalar value
+func mask(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Value != "" {
+		node.Value = "********"
+		node.Style = 0
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuild_Overrides verifica a precedência padrão < arquivo < ambiente < flags
+func TestBuild_Overrides(t *testing.T) {
+	data := `web:
+  port: 8081
+  read_timeout: 20s
+sensor:
+  bme280:
+    i2c_address: 0x76
+`
+	environ := []string{
+		"PATH=/usr/bin",
+		"ATMOSBYTE_WEB_PORT=9000",
+		"ATMOSBYTE_QUEUE_RETRY_MAX_RETRIES=7",
+		"ATMOSBYTE_SENSOR_SIMULATION_MAX_TEMPERATURE=40.5",
+		"ATMOSBYTE_WEB_TLS_HOSTNAME=station.local",
+	}
+	sets := []string{
+		"web.port=9090",
+		"sensor.bme280.i2c_address=0x77",
+		"queue.circuit_breaker.timeout=2m",
+	}
+
+	result, err := build("atmosbyte.yaml", []byte(data), "", environ, sets)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg, sources := result.config, result.sources
+
+	if cfg.Web.Port != 9090 {
+		t.Errorf("Expected -set to win over env, got port %d", cfg.Web.Port)
+	}
+	if cfg.Web.ReadTimeout != 20*time.Second {
+		t.Errorf("Expected read_timeout from file, got %v", cfg.Web.ReadTimeout)
+	}
+	if cfg.Queue.Retry.MaxRetries != 7 {
+		t.Errorf("Expected max_retries 7, got %d", cfg.Queue.Retry.MaxRetries)
+	}
+	if cfg.Sensor.Simulation.MaxTemperature != 40.5 {
+		t.Errorf("Expected max_temperature 40.5, got %v", cfg.Sensor.Simulation.MaxTemperature)
+	}
+	if cfg.Sensor.BME280.I2CAddress != 0x77 {
+		t.Errorf("Expected i2c_address 0x77, got %#x", cfg.Sensor.BME280.I2CAddress)
+	}
+	if cfg.Queue.Circuit.Timeout != 2*time.Minute {
+		t.Errorf("Expected circuit breaker timeout 2m, got %v", cfg.Queue.Circuit.Timeout)
+	}
+	if cfg.Web.TLS.Hostname != "station.local" {
+		t.Errorf("Expected hostname from env, got %q", cfg.Web.TLS.Hostname)
+	}
+
+	expected := map[string]string{
+		"web.port":                       "flag -set web.port",
+		"web.read_timeout":               "file atmosbyte.yaml:3",
+		"queue.retry.max_retries":        "env ATMOSBYTE_QUEUE_RETRY_MAX_RETRIES",
+		"sensor.bme280.i2c_address":      "flag -set sensor.bme280.i2c_address",
+		"queue.retry.base_delay":         "default",
+		"sensor.simulation.min_pressure": "default",
+	}
+	for path, source := range expected {
+		if sources[path] != source {
+			t.Errorf("Expected source of %s to be %q, got %q", path, source, sources[path])
+		}
+	}
+}
+
+// TestBuild_OverridesWithoutFile verifica que as sobrescritas se aplicam aos padrões
+func TestBuild_OverridesWithoutFile(t *testing.T) {
+	result, err := build("", nil, "", []string{"ATMOSBYTE_AUTH_PUBLIC_READ=true"}, []string{"email.to=alerts@example.com, ops@example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cfg, sources := result.config, result.sources
+	if !cfg.Auth.PublicRead {
+		t.Error("Expected public_read from env")
+	}
+	if len(cfg.Email.To) != 2 || cfg.Email.To[1] != "ops@example.com" {
+		t.Errorf("Unexpected email.to %v", cfg.Email.To)
+	}
+	if cfg.Web.Port != 8080 || sources["web.port"] != "default" {
+		t.Errorf("Expected default port, got %d from %q", cfg.Web.Port, sources["web.port"])
+	}
+}
+
+// TestBuild_OverrideErrors verifica que valores inválidos e nomes desconhecidos são reportados
+func TestBuild_OverrideErrors(t *testing.T) {
+	environ := []string{
+		"ATMOSBYTE_WEB_PROT=9090",
+		"ATMOSBYTE_SENSOR_READ_INTERVAL=10",
+		"ATMOSBYTE_SENSOR_BME280_I2C_ADDRESS=0x1ffff",
+	}
+	sets := []string{"web.port=70000", "web.tls.enabled=yes please", "nope=1"}
+
+	_, err := build("", nil, "", environ, sets)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	expected := []string{
+		"unknown environment variable ATMOSBYTE_WEB_PROT",
+		`sensor.read_interval: ATMOSBYTE_SENSOR_READ_INTERVAL: invalid duration "10"`,
+		`sensor.bme280.i2c_address: ATMOSBYTE_SENSOR_BME280_I2C_ADDRESS: invalid unsigned integer "0x1ffff"`,
+		"nope: -set: unknown field",
+		`web.tls.enabled: -set web.tls.enabled: invalid boolean "yes please"`,
+		"web.port: must be between 1 and 65535, got 70000 (set by -set web.port)",
+	}
+	msg := err.Error()
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("Expected error %q in:\n%s", e, msg)
+		}
+	}
+}
+
+// TestOverrides_Set verifica o formato path=value da flag -set
+func TestOverrides_Set(t *testing.T) {
+	var o Overrides
+	if err := o.Set("web.port=9090"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := o.Set("web.tls.hostname="); err != nil {
+		t.Errorf("Empty values should be accepted: %v", err)
+	}
+	for _, invalid := range []string{"web.port", "=9090"} {
+		if err := o.Set(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+	if len(o) != 2 {
+		t.Errorf("Expected 2 overrides, got %v", o)
+	}
+}
+
+// TestPrintEffective verifica os comentários de origem e o mascaramento de segredos
+func TestPrintEffective(t *testing.T) {
+	data := `auth:
+  enabled: true
+  api_keys:
+    - name: station
+      key: super-secret-key
+      scope: ingest
+`
+	result, err := build("atmosbyte.yaml", []byte(data), "", []string{"ATMOSBYTE_WEB_PORT=9000"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printEffective(&buf, result.config, result.sources, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, e := range []string{
+		"port: 9000 # env ATMOSBYTE_WEB_PORT",
+		"read_timeout: 10s # default",
+		"enabled: true # file atmosbyte.yaml:2",
+		"api_keys: # file atmosbyte.yaml:3",
+		"key: '********'",
+		"i2c_address: 0x76 # default",
+	} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q in output:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "super-secret-key") {
+		t.Error("Secret key should be masked")
+	}
+}
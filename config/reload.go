@@ -0,0 +1,262 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/web"
+)
+
+// liveFields are the fields, or sections, that running components apply without a
+// restart: see the apply function passed to NewWatcher
+var liveFields = []string{
+	"log.level",
+	"sensor.read_interval",
+	"sensor.simulation",
+	"queue.workers",
+	"queue.retry",
+	"queue.circuit_breaker",
+}
+
+// Change is a field changed by Loader.Reload
+type Change struct {
+	Path string
+	Old  string
+	New  string
+	Live bool // Applied without a restart
+}
+
+// diff lists the fields that differ between old and new
+func diff(old, new *AppConfig) []Change {
+	oldRoot := reflect.ValueOf(old).Elem()
+	newRoot := reflect.ValueOf(new).Elem()
+
+	var changes []Change
+	for _, f := range fields() {
+		before, after := oldRoot.FieldByIndex(f.index), newRoot.FieldByIndex(f.index)
+		if reflect.DeepEqual(before.Interface(), after.Interface()) {
+			continue
+		}
+		changes = append(changes, Change{
+			Path: f.path,
+			Old:  formatValue(f.path, before),
+			New:  formatValue(f.path, after),
+			Live: isLive(f.path),
+		})
+	}
+	return changes
+}
+
+// LiveChanged reports whether changes include a live change of path or of a field
+// inside it
+func LiveChanged(changes []Change, path string) bool {
+	for _, change := range changes {
+		if change.Live && (change.Path == path || strings.HasPrefix(change.Path, path+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLive reports whether path is one of liveFields or inside one of their sections
+func isLive(path string) bool {
+	for _, live := range liveFields {
+		if path == live || strings.HasPrefix(path, live+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue describes a value for a Change, masking secrets and summarizing lists
+// of structures
+func formatValue(path string, v reflect.Value) string {
+	if key := path[strings.LastIndex(path, ".")+1:]; secretFields[key] {
+		if v.IsZero() {
+			return ""
+		}
+		return "********"
+	}
+
+	switch {
+	case v.Type() == reflect.TypeFor[time.Duration]():
+		return v.Interface().(time.Duration).String()
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		return strings.Join(v.Interface().([]string), ",")
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Map:
+		return fmt.Sprintf("%d entries", v.Len())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// Watcher reloads the configuration on SIGHUP and whenever the file changes, and
+// keeps the outcome of the last reload
+type Watcher struct {
+	loader *Loader
+	apply  func(config *AppConfig, changes []Change)
+
+	mu      sync.RWMutex
+	status  web.ReloadStatus
+	pending map[string]web.ConfigChange // Changes waiting for a restart, by path
+	modTime time.Time
+}
+
+// NewWatcher creates a watcher calling apply with the new configuration and the
+// changed fields after each successful reload with live changes. apply must put in
+// effect the changes marked Live; the others are reported as requiring a restart.
+func NewWatcher(loader *Loader, apply func(config *AppConfig, changes []Change)) *Watcher {
+	w := &Watcher{
+		loader:  loader,
+		apply:   apply,
+		pending: make(map[string]web.ConfigChange),
+		status: web.ReloadStatus{
+			File:            loader.Path(),
+			LoadedAt:        time.Now(),
+			Success:         true,
+			Applied:         []web.ConfigChange{},
+			RestartRequired: []web.ConfigChange{},
+		},
+	}
+	w.modTime, _ = w.fileModTime()
+	return w
+}
+
+// Start reloads the configuration on SIGHUP and every time the file changes, checked
+// every interval, until ctx is cancelled
+func (w *Watcher) Start(ctx context.Context, interval time.Duration) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hup:
+			w.Reload("SIGHUP")
+		case <-ticker.C:
+			if w.changed() {
+				w.Reload("file change")
+			}
+		}
+	}
+}
+
+// Reload reloads the configuration, applies it and records the outcome. Changes
+// requiring a restart stay reported until the process restarts or they are reverted.
+func (w *Watcher) Reload(trigger string) error {
+	// The modification time is taken first so that a write during the reload
+	// triggers another one
+	modTime, _ := w.fileModTime()
+
+	config, changes, err := w.loader.Reload()
+	now := time.Now()
+
+	w.mu.Lock()
+	w.modTime = modTime
+	w.status.LastAttempt = &now
+	w.status.Trigger = trigger
+	w.status.Success = err == nil
+	w.status.Error = ""
+	w.status.Applied = []web.ConfigChange{}
+	if err != nil {
+		w.status.Error = err.Error()
+		w.mu.Unlock()
+		logging.Errorf("Failed to reload configuration after %s, keeping the current one: %v", trigger, err)
+		return err
+	}
+
+	w.status.LoadedAt = now
+	var restart []string
+	for _, change := range changes {
+		c := web.ConfigChange{Path: change.Path, Old: change.Old, New: change.New}
+		if change.Live {
+			w.status.Applied = append(w.status.Applied, c)
+			continue
+		}
+
+		restart = append(restart, change.Path)
+		if p, ok := w.pending[c.Path]; ok {
+			c.Old = p.Old // Value still in effect
+		}
+		if c.Old == c.New {
+			delete(w.pending, c.Path)
+		} else {
+			w.pending[c.Path] = c
+		}
+	}
+	w.status.RestartRequired = []web.ConfigChange{}
+	for _, f := range fields() {
+		if c, ok := w.pending[f.path]; ok {
+			w.status.RestartRequired = append(w.status.RestartRequired, c)
+		}
+	}
+	applied := len(w.status.Applied)
+	w.mu.Unlock()
+
+	if len(changes) == 0 {
+		log.Printf("Configuration reloaded after %s, nothing changed", trigger)
+		return nil
+	}
+
+	if applied > 0 {
+		w.apply(config, changes)
+	}
+	log.Printf("Configuration reloaded after %s: %d changes applied", trigger, applied)
+	for _, path := range restart {
+		log.Printf("Configuration change of %s requires a restart", path)
+	}
+	return nil
+}
+
+// ReloadStatus implements web.ReloadStatusProvider
+func (w *Watcher) ReloadStatus() web.ReloadStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status := w.status
+	status.Applied = append([]web.ConfigChange{}, w.status.Applied...)
+	status.RestartRequired = append([]web.ConfigChange{}, w.status.RestartRequired...)
+	return status
+}
+
+// changed reports whether the files were modified since the last reload
+func (w *Watcher) changed() bool {
+	modTime, err := w.fileModTime()
+	if err != nil {
+		return false
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return !modTime.Equal(w.modTime)
+}
+
+// fileModTime returns the latest modification time of the configuration file and
+// its includes, zero when running on defaults
+func (w *Watcher) fileModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range w.loader.Files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDiff verifica as mudanças detectadas, as aplicadas ao vivo e o mascaramento de segredos
+func TestDiff(t *testing.T) {
+	old := defaultConfig()
+	updated := defaultConfig()
+	updated.Sensor.ReadInterval *= 2
+	updated.Sensor.Simulation.MaxTemperature = 40
+	updated.Queue.Retry.MaxRetries = 3
+	updated.Web.Port = 9090
+	updated.Log.Level = "debug"
+	updated.Email.Password = "hunter2"
+	updated.Email.To = []string{"a@example.com", "b@example.com"}
+	updated.Auth.APIKeys = []APIKeyConfig{{Name: "station", Key: "secret", Scope: "ingest"}}
+
+	changes := make(map[string]Change)
+	for _, c := range diff(old, updated) {
+		changes[c.Path] = c
+	}
+
+	expected := map[string]Change{
+		"sensor.read_interval":              {Old: "10s", New: "20s", Live: true},
+		"sensor.simulation.max_temperature": {Old: "35", New: "40", Live: true},
+		"queue.retry.max_retries":           {Old: "10", New: "3", Live: true},
+		"web.port":                          {Old: "8080", New: "9090"},
+		"log.level":                         {Old: "info", New: "debug", Live: true},
+		"email.password":                    {Old: "", New: "********"},
+		"email.to":                          {Old: "", New: "a@example.com,b@example.com"},
+		"auth.api_keys":                     {Old: "0 entries", New: "1 entries"},
+	}
+	if len(changes) != len(expected) {
+		t.Errorf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for path, e := range expected {
+		c, ok := changes[path]
+		if !ok {
+			t.Errorf("Expected change of %s", path)
+			continue
+		}
+		if c.Old != e.Old || c.New != e.New || c.Live != e.Live {
+			t.Errorf("%s: expected %+v, got %+v", path, e, c)
+		}
+	}
+}
+
+// TestLiveChanged verifica a detecção de mudanças ao vivo de um campo ou de uma seção
+func TestLiveChanged(t *testing.T) {
+	changes := []Change{
+		{Path: "queue.retry.max_retries", Live: true},
+		{Path: "queue.buffer_size"},
+		{Path: "web.port"},
+	}
+
+	tests := map[string]bool{
+		"queue":                   true,
+		"queue.retry.max_retries": true,
+		"queue.buffer_size":       false,
+		"web":                     false,
+		"log.level":               false,
+		"queue.retry.max":         false,
+	}
+	for path, want := range tests {
+		if got := LiveChanged(changes, path); got != want {
+			t.Errorf("LiveChanged(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// TestWatcher_Reload verifica o recarregamento válido, o inválido e o estado reportado
+func TestWatcher_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atmosbyte.yaml")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("sensor:\n  read_interval: 5s\n")
+	loader := NewLoader(path, WithOverrides([]string{"web.port=9000"}), WithEnviron([]string{}))
+	if _, err := loader.Load(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var applied *AppConfig
+	watcher := NewWatcher(loader, func(config *AppConfig, changes []Change) {
+		applied = config
+	})
+
+	write("sensor:\n  read_interval: 30s\nqueue:\n  workers: 4\nweb:\n  idle_timeout: 1m\n")
+	if err := watcher.Reload("SIGHUP"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if applied == nil || applied.Sensor.ReadInterval.Seconds() != 30 || applied.Queue.Workers != 4 {
+		t.Fatalf("Expected the new configuration to be applied, got %+v", applied)
+	}
+	if applied.Web.Port != 9000 {
+		t.Errorf("Expected -set overrides to be kept, got port %d", applied.Web.Port)
+	}
+	if loader.Config() != applied {
+		t.Error("Expected the loader to return the reloaded configuration")
+	}
+
+	status := watcher.ReloadStatus()
+	if !status.Success || status.Trigger != "SIGHUP" || status.LastAttempt == nil {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if len(status.Applied) != 2 || len(status.RestartRequired) != 1 || status.RestartRequired[0].Path != "web.idle_timeout" {
+		t.Errorf("Unexpected changes: applied %+v, restart %+v", status.Applied, status.RestartRequired)
+	}
+
+	applied = nil
+	write("sensor:\n  read_interval: 10\n")
+	if err := watcher.Reload("file change"); err == nil {
+		t.Fatal("Expected error for invalid configuration")
+	}
+	if applied != nil {
+		t.Error("Invalid configuration should not be applied")
+	}
+	if loader.Config().Sensor.ReadInterval.Seconds() != 30 {
+		t.Errorf("Expected the previous configuration to be kept, got %v", loader.Config().Sensor.ReadInterval)
+	}
+
+	status = watcher.ReloadStatus()
+	if status.Success || status.Error == "" || status.Trigger != "file change" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if len(status.RestartRequired) != 1 {
+		t.Errorf("Expected pending restart changes to be kept, got %+v", status.RestartRequired)
+	}
+
+	// Voltar ao valor em uso remove a pendência de reinício
+	write("sensor:\n  read_interval: 30s\nqueue:\n  workers: 4\n")
+	if err := watcher.Reload("SIGHUP"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status = watcher.ReloadStatus()
+	if !status.Success || len(status.Applied) != 0 || len(status.RestartRequired) != 0 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// Mudanças que exigem reinício não chamam apply
+	applied = nil
+	write("sensor:\n  read_interval: 30s\nqueue:\n  workers: 4\nweb:\n  idle_timeout: 1m\n")
+	if err := watcher.Reload("SIGHUP"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if applied != nil {
+		t.Error("Expected apply not to be called without live changes")
+	}
+}
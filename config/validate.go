@@ -0,0 +1,433 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/anomaly"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/web"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem with a configuration value
+type FieldError struct {
+	Path    string // YAML path, e.g. sensor.read_interval or alerts.rules[0]
+	File    string // Included file the value comes from, empty for the main file
+	Line    int    // Line in the file, 0 when the value is not in a file
+	Message string
+}
+
+func (e FieldError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + " ")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationError aggregates every problem found in a configuration
+type ValidationError struct {
+	File   string // Empty when validating a configuration not read from a file
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration")
+	if e.File != "" {
+		b.WriteString(" " + e.File)
+	}
+	b.WriteString(":")
+	for _, err := range e.Errors {
+		b.WriteString("\n  " + err.Error())
+	}
+	return b.String()
+}
+
+// Validate checks the values of a configuration with defaults applied. It returns a
+// *ValidationError listing every problem found.
+func (c *AppConfig) Validate() error {
+	v := &validator{}
+
+	v.port("web.port", c.Web.Port)
+	if c.Web.TLS.RedirectPort != 0 {
+		v.port("web.tls.redirect_port", c.Web.TLS.RedirectPort)
+	}
+	v.positive("web.read_timeout", c.Web.ReadTimeout)
+	v.positive("web.write_timeout", c.Web.WriteTimeout)
+	v.positive("web.idle_timeout", c.Web.IdleTimeout)
+	v.positive("web.tls.reload_interval", c.Web.TLS.ReloadInterval)
+	v.positive("reload.interval", c.Reload.Interval)
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		v.add("log.level", "%v", err)
+	}
+	if c.Web.DashboardNode != "" && !web.ValidNodeID(c.Web.DashboardNode) {
+		v.add("web.dashboard_node", "node must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+
+	v.check(c.Queue.Workers >= 1, "queue.workers", "must be at least 1")
+	v.check(c.Queue.BufferSize >= 1, "queue.buffer_size", "must be at least 1")
+	v.check(c.Queue.Retry.MaxRetries >= 0, "queue.retry.max_retries", "cannot be negative")
+	v.positive("queue.retry.base_delay", c.Queue.Retry.BaseDelay)
+	v.check(c.Queue.Circuit.FailureThreshold >= 1, "queue.circuit_breaker.failure_threshold", "must be at least 1")
+	v.positive("queue.circuit_breaker.timeout", c.Queue.Circuit.Timeout)
+
+	switch c.Sensor.Type {
+	case "hardware", "simulated":
+	default:
+		v.add("sensor.type", "unknown sensor type %q, use hardware or simulated", c.Sensor.Type)
+	}
+	v.positive("sensor.read_interval", c.Sensor.ReadInterval)
+	if addr := c.Sensor.BME280.I2CAddress; addr != 0x76 && addr != 0x77 {
+		v.add("sensor.bme280.i2c_address", "BME280 address must be 0x76 or 0x77, got %#x", addr)
+	}
+
+	sim := c.Sensor.Simulation
+	v.check(sim.MinTemperature <= sim.MaxTemperature, "sensor.simulation.min_temperature", "must not be greater than max_temperature")
+	v.check(sim.MinHumidity <= sim.MaxHumidity, "sensor.simulation.min_humidity", "must not be greater than max_humidity")
+	v.check(sim.MinHumidity >= 0 && sim.MaxHumidity <= 100, "sensor.simulation", "humidity must be between 0 and 100")
+	v.check(sim.MinPressure <= sim.MaxPressure, "sensor.simulation.min_pressure", "must not be greater than max_pressure")
+	v.check(sim.MinPressure > 0, "sensor.simulation.min_pressure", "must be positive, in Pa")
+
+	v.check(c.Storage.Path != "", "storage.path", "must not be empty")
+	switch c.Storage.JournalMode {
+	case "wal", "delete", "truncate", "persist":
+	default:
+		v.add("storage.journal_mode", "unknown journal mode %q, use wal, delete, truncate or persist", c.Storage.JournalMode)
+	}
+	switch c.Storage.Synchronous {
+	case "off", "normal", "full", "extra":
+	default:
+		v.add("storage.synchronous", "unknown synchronous level %q, use off, normal, full or extra", c.Storage.Synchronous)
+	}
+	v.positive("storage.busy_timeout", c.Storage.BusyTimeout)
+	v.check(c.Storage.CacheSize > 0, "storage.cache_size", "must be positive, in KiB")
+	v.check(c.Storage.MaxOpenConns >= 1, "storage.max_open_conns", "must be at least 1")
+	v.check(c.Storage.MaxIdleConns >= 1, "storage.max_idle_conns", "must be at least 1")
+	v.check(c.Storage.ConnMaxLifetime >= 0, "storage.conn_max_lifetime", "cannot be negative")
+	v.check(c.Storage.ReadReplica.MaxOpenConns >= 1, "storage.read_replica.max_open_conns", "must be at least 1")
+	v.check(c.Storage.ReadReplica.MaxIdleConns >= 1, "storage.read_replica.max_idle_conns", "must be at least 1")
+
+	if err := c.BackupConfig().Validate(); err != nil {
+		v.add("backup", "%v", err)
+	}
+
+	v.positive("timeouts.shutdown_timeout", c.Timeouts.ShutdownTimeout)
+	v.positive("timeouts.queue_shutdown_timeout", c.Timeouts.QueueShutdownTimeout)
+	v.positive("timeouts.web_shutdown_timeout", c.Timeouts.WebShutdownTimeout)
+	v.positive("timeouts.processing_timeout", c.Timeouts.ProcessingTimeout)
+
+	if _, err := anomaly.ParseMethod(c.Anomaly.Method); err != nil {
+		v.add("anomaly.method", "%v", err)
+	}
+	v.check(c.Anomaly.Threshold > 0, "anomaly.threshold", "must be positive")
+	v.check(c.Anomaly.BaselineDays >= 1, "anomaly.baseline_days", "must be at least 1")
+	v.check(c.Anomaly.MinSamples >= 1, "anomaly.min_samples", "must be at least 1")
+	v.positive("anomaly.refresh_interval", c.Anomaly.RefreshInterval)
+	v.check(c.Anomaly.Cooldown >= 0, "anomaly.cooldown", "cannot be negative")
+
+	v.check(c.DegreeDays.GrowingCap > c.DegreeDays.GrowingBase, "degree_days.growing_cap", "must be greater than growing_base")
+
+	if _, err := weather.ParseUnits(c.Units.Default, weather.DefaultUnits()); err != nil {
+		v.add("units.default", "%v", err)
+	}
+
+	v.check(c.Auth.MaxFailures >= 1, "auth.max_failures", "must be at least 1")
+	v.positive("auth.failure_window", c.Auth.FailureWindow)
+	v.check(!c.Ingest.Enabled || c.Auth.Enabled, "ingest.enabled", "requires auth to be enabled")
+	v.check(c.Ingest.MaxBatch >= 1, "ingest.max_batch", "must be at least 1")
+
+	if c.Webhooks.Enabled {
+		v.positive("webhooks.retention", c.Webhooks.Retention)
+		if _, err := c.WebhookConfig(); err != nil {
+			v.add("webhooks.targets", "%v", err)
+		}
+	}
+	if c.Alerts.Enabled {
+		if _, err := c.AlertConfig(); err != nil {
+			v.add("alerts", "%v", err)
+		}
+	}
+	if c.Email.Enabled {
+		if err := c.EmailConfig().Validate(); err != nil {
+			v.add("email", "%v", err)
+		}
+	}
+
+	return v.err("")
+}
+
+// validator collects FieldErrors
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.errors = append(v.errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(ok bool, path, message string) {
+	if !ok {
+		v.add(path, "%s", message)
+	}
+}
+
+func (v *validator) port(path string, port int) {
+	v.check(port >= 1 && port <= 65535, path, fmt.Sprintf("must be between 1 and 65535, got %d", port))
+}
+
+func (v *validator) positive(path string, d time.Duration) {
+	v.check(d > 0, path, fmt.Sprintf("must be a positive duration, got %v", d))
+}
+
+// err returns the collected errors as a *ValidationError, nil when there are none
+func (v *validator) err(file string) error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(v.errors, func(a, b FieldError) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return &ValidationError{File: file, Errors: v.errors}
+}
+
+// loaded is a configuration built from a file
+type loaded struct {
+	config  *AppConfig
+	sources map[string]string // Source of every field
+	files   []string          // The file and its includes
+	profile string            // Profile merged over the file
+}
+
+// build decodes the configuration file, when there is one, merged with its includes
+// and profile, reporting unknown fields and type errors with their lines, applies the
+// environment and -set overrides and the defaults, then validates the result.
+func build(file string, data []byte, profile string, environ, sets []string) (*loaded, error) {
+	config := AppConfig{DegreeDays: defaultDegreeDays()}
+	v := &validator{}
+	d := &document{
+		main:     file,
+		environ:  make(map[string]string),
+		origin:   make(map[*yaml.Node]string),
+		profiles: make(map[string]*yaml.Node),
+		v:        v,
+	}
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		d.environ[name] = value
+	}
+
+	var root *yaml.Node
+	if file != "" {
+		var err error
+		root, err = d.load(file, data, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if profile != "" {
+		overlay, ok := d.profiles[profile]
+		if !ok {
+			v.add("profiles", "profile %q is not defined", profile)
+		}
+		root = merge(root, overlay)
+	}
+
+	positions := make(map[string]position)
+	if root != nil {
+		d.indexPositions(root, "", positions)
+		// Type errors were reported by file, before merging
+		_ = root.Decode(&config)
+	}
+
+	overrides, errs := collectOverrides(environ, sets)
+	v.errors = append(v.errors, errs...)
+	applied, errs := applyOverrides(&config, overrides)
+	v.errors = append(v.errors, errs...)
+
+	applyDefaults(&config)
+
+	var invalid *ValidationError
+	if err := config.Validate(); errors.As(err, &invalid) {
+		for _, fieldErr := range invalid.Errors {
+			if o, ok := applied[fieldErr.Path]; ok {
+				fieldErr.Message += fmt.Sprintf(" (set by %s)", o.name)
+			} else if pos := positionOf(fieldErr.Path, positions); pos.line > 0 {
+				fieldErr.Line = pos.line
+				if pos.file != file {
+					fieldErr.File = pos.file
+				}
+			}
+			v.errors = append(v.errors, fieldErr)
+		}
+	}
+
+	if err := v.err(file); err != nil {
+		return nil, err
+	}
+	return &loaded{
+		config:  &config,
+		sources: sourcesOf(positions, applied),
+		files:   d.files,
+		profile: profile,
+	}, nil
+}
+
+// checkFields reports the mapping keys of node that do not match a yaml field of t
+func checkFields(node *yaml.Node, t reflect.Type, path string, v *validator) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			checkFields(child, t, path, v)
+		}
+		return
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for i, item := range node.Content {
+				checkFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), v)
+			}
+		}
+		return
+	case yaml.MappingNode:
+	default:
+		return
+	}
+
+	if t.Kind() == reflect.Map {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkFields(node.Content[i+1], t.Elem(), join(path, node.Content[i].Value), v)
+		}
+		return
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			msg := "unknown field"
+			if suggestion := closest(key.Value, fields); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %s?", suggestion)
+			}
+			v.errors = append(v.errors, FieldError{Path: join(path, key.Value), Line: key.Line, Message: msg})
+			continue
+		}
+		checkFields(node.Content[i+1], fieldType, join(path, key.Value), v)
+	}
+}
+
+// indexLines records the line of every value of node by path
+func indexLines(node *yaml.Node, path string, lines map[string]int) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			indexLines(child, path, lines)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := join(path, node.Content[i].Value)
+			lines[key] = node.Content[i].Line
+			indexLines(node.Content[i+1], key, lines)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			key := fmt.Sprintf("%s[%d]", path, i)
+			lines[key] = item.Line
+			indexLines(item, key, lines)
+		}
+	}
+}
+
+var typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// typeError converts a yaml.TypeError message to a FieldError, finding its path by line
+func typeError(msg string, lines map[string]int) FieldError {
+	m := typeErrorLine.FindStringSubmatch(msg)
+	if m == nil {
+		return FieldError{Message: msg}
+	}
+
+	line, _ := strconv.Atoi(m[1])
+	fieldErr := FieldError{Line: line, Message: m[2]}
+	for path, l := range lines {
+		if l == line && len(path) > len(fieldErr.Path) {
+			fieldErr.Path = path
+		}
+	}
+	if strings.HasSuffix(fieldErr.Message, "into time.Duration") {
+		fieldErr.Message += ", durations need a unit, e.g. 10s or 5m"
+	}
+	return fieldErr
+}
+
+// closest returns the known field nearest to name, if it is a likely typo
+func closest(name string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", 3
+	for field := range fields {
+		if d := distance(name, field); d < bestDistance || (d == bestDistance && best != "" && field < best) {
+			best, bestDistance = field, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
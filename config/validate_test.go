@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidate_Defaults verifica que a configuração padrão é válida
+func TestValidate_Defaults(t *testing.T) {
+	if err := defaultConfig().Validate(); err != nil {
+		t.Fatalf("Default configuration should be valid: %v", err)
+	}
+}
+
+// TestBuild_Example verifica que o arquivo de exemplo é válido
+func TestBuild_Example(t *testing.T) {
+	data, err := os.ReadFile("../atmosbyte.yaml.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := build("atmosbyte.yaml.example", data, "", nil, nil)
+	if err != nil {
+		t.Fatalf("Example configuration should be valid: %v", err)
+	}
+	if cfg := result.config; cfg.Sensor.Type != "hardware" || cfg.Sensor.BME280.I2CAddress != 0x76 {
+		t.Errorf("Unexpected sensor config %+v", cfg.Sensor)
+	}
+}
+
+// TestBuild_Errors verifica que todos os erros são reportados com a linha e o caminho
+func TestBuild_Errors(t *testing.T) {
+	data := `web:
+  prot: 9090
+sensor:
+  type: hardwre
+  read_interval: 10
+  bme280:
+    i2c_address: 0x40
+  simulation:
+    min_temperature: 30
+    max_temperature: 10
+alerts:
+  enabled: true
+  rules:
+    - name: frost
+      metric: temperature
+      operator: "=<"
+`
+
+	_, err := build("atmosbyte.yaml", []byte(data), "", nil, nil)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+
+	want := []string{
+		"line 2: web.prot: unknown field, did you mean port?",
+		"line 4: sensor.type: unknown sensor type \"hardwre\", use hardware or simulated",
+		"line 5: sensor.read_interval: cannot unmarshal !!int `10` into time.Duration, durations need a unit, e.g. 10s or 5m",
+		"line 7: sensor.bme280.i2c_address: BME280 address must be 0x76 or 0x77, got 0x40",
+		"line 9: sensor.simulation.min_temperature: must not be greater than max_temperature",
+		"line 11: alerts: alert rule frost: unknown operator \"=<\", use <, <=, > or >=",
+	}
+	if len(invalid.Errors) != len(want) {
+		t.Fatalf("Expected %d errors, got:\n%v", len(want), err)
+	}
+	for i, w := range want {
+		if got := invalid.Errors[i].Error(); got != w {
+			t.Errorf("Error %d:\n got %s\nwant %s", i, got, w)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "invalid configuration atmosbyte.yaml:\n  line 2:") {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+}
+
+// TestBuild_DegreeDays verifica que cada base de graus-dia tem seu próprio padrão, que
+// uma base zero explícita é mantida e que o limite de crescimento fica acima da base
+func TestBuild_DegreeDays(t *testing.T) {
+	result, err := build("atmosbyte.yaml", []byte("degree_days:\n  heating_base: 15\n"), "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DegreeDaysConfig{HeatingBase: 15, CoolingBase: 18, GrowingBase: 10, GrowingCap: 30}
+	if got := result.config.DegreeDays; got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	result, err = build("atmosbyte.yaml", []byte("degree_days:\n  growing_base: 0\n"), "", []string{"ATMOSBYTE_DEGREE_DAYS_HEATING_BASE=0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = DegreeDaysConfig{HeatingBase: 0, CoolingBase: 18, GrowingBase: 0, GrowingCap: 30}
+	if got := result.config.DegreeDays; got != want {
+		t.Errorf("Expected explicit zero bases to be kept, got %+v", got)
+	}
+
+	_, err = build("atmosbyte.yaml", []byte("degree_days:\n  growing_base: 30\n  growing_cap: 25\n"), "", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "degree_days.growing_cap: must be greater than growing_base") {
+		t.Errorf("Expected a growing_cap error, got %v", err)
+	}
+}
+
+// TestBuild_SyntaxError verifica que erros de sintaxe YAML são retornados
+func TestBuild_SyntaxError(t *testing.T) {
+	_, err := build("atmosbyte.yaml", []byte("web:\n  port: [8080\n"), "", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "line") {
+		t.Fatalf("Expected a syntax error with its line, got %v", err)
+	}
+}
+
+// TestLoad_InvalidFile verifica que um arquivo inválido não é substituído pelos valores padrão
+func TestLoad_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atmosbyte.yaml")
+	if err := os.WriteFile(path, []byte("web:\n  port: 70000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(path, WithEnviron([]string{}))
+	if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "line 2: web.port: must be between 1 and 65535, got 70000") {
+		t.Fatalf("Expected a port error, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("web:\n  port: 9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error after fixing the file: %v", err)
+	}
+	if cfg.Web.Port != 9090 || loader.Path() != path {
+		t.Errorf("Expected port 9090 from %s, got %d from %s", path, cfg.Web.Port, loader.Path())
+	}
+}
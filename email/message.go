@@ -0,0 +1,203 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"math"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Templates render the subject and the plain-text and HTML parts of a message.
+// Empty templates use the defaults.
+type Templates struct {
+	Subject string // text/template, a single line
+	Text    string // text/template
+	HTML    string // html/template
+}
+
+// Message is a rendered email waiting to be sent
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+var templateFuncs = map[string]any{
+	"round": func(v float64, places int) float64 {
+		p := math.Pow(10, float64(places))
+		return math.Round(v*p) / p
+	},
+	"upper": strings.ToUpper,
+}
+
+const (
+	defaultAlertSubject = `{{if .Severity}}[{{upper .Severity}}] {{end}}{{.Rule}} {{.State}}`
+	defaultAlertText    = `{{.Message}}
+
+Rule:      {{.Description}}
+State:     {{.State}}
+Value:     {{round .Value 2}}
+Threshold: {{.Threshold}}
+Time:      {{.Time.Format "2006-01-02 15:04 MST"}}
+{{- if not .FiredAt.IsZero}}
+Fired at:  {{.FiredAt.Format "2006-01-02 15:04 MST"}}
+{{- end}}
+`
+	defaultAlertHTML = `<html><body>
+<p>{{.Message}}</p>
+<table>
+<tr><th align="left">Rule</th><td>{{.Description}}</td></tr>
+<tr><th align="left">State</th><td>{{.State}}</td></tr>
+<tr><th align="left">Value</th><td>{{round .Value 2}}</td></tr>
+<tr><th align="left">Threshold</th><td>{{.Threshold}}</td></tr>
+<tr><th align="left">Time</th><td>{{.Time.Format "2006-01-02 15:04 MST"}}</td></tr>
+{{- if not .FiredAt.IsZero}}
+<tr><th align="left">Fired at</th><td>{{.FiredAt.Format "2006-01-02 15:04 MST"}}</td></tr>
+{{- end}}
+</table>
+</body></html>
+`
+
+	defaultDigestSubject = `Weather summary for {{.Date.Format "Mon, 02 Jan 2006"}}`
+	defaultDigestText    = `Weather summary for {{.Date.Format "Monday, 02 January 2006"}}
+
+             Min      Max      Avg
+Temperature  {{printf "%-8.1f" .Temperature.Min}} {{printf "%-8.1f" .Temperature.Max}} {{printf "%.1f" .Temperature.Avg}} °C
+Humidity     {{printf "%-8.1f" .Humidity.Min}} {{printf "%-8.1f" .Humidity.Max}} {{printf "%.1f" .Humidity.Avg}} %
+Pressure     {{printf "%-8.1f" .Pressure.Min}} {{printf "%-8.1f" .Pressure.Max}} {{printf "%.1f" .Pressure.Avg}} hPa
+`
+	defaultDigestHTML = `<html><body>
+<p>Weather summary for {{.Date.Format "Monday, 02 January 2006"}}</p>
+<table>
+<tr><th></th><th>Min</th><th>Max</th><th>Avg</th></tr>
+<tr><th align="left">Temperature (°C)</th><td>{{printf "%.1f" .Temperature.Min}}</td><td>{{printf "%.1f" .Temperature.Max}}</td><td>{{printf "%.1f" .Temperature.Avg}}</td></tr>
+<tr><th align="left">Humidity (%)</th><td>{{printf "%.1f" .Humidity.Min}}</td><td>{{printf "%.1f" .Humidity.Max}}</td><td>{{printf "%.1f" .Humidity.Avg}}</td></tr>
+<tr><th align="left">Pressure (hPa)</th><td>{{printf "%.1f" .Pressure.Min}}</td><td>{{printf "%.1f" .Pressure.Max}}</td><td>{{printf "%.1f" .Pressure.Avg}}</td></tr>
+</table>
+</body></html>
+`
+)
+
+// templates are parsed Templates
+type templates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// parseTemplates parses t, using the given defaults for empty templates
+func parseTemplates(name string, t, defaults Templates) (*templates, error) {
+	if t.Subject == "" {
+		t.Subject = defaults.Subject
+	}
+	if t.Text == "" {
+		t.Text = defaults.Text
+	}
+	if t.HTML == "" {
+		t.HTML = defaults.HTML
+	}
+
+	var parsed templates
+	var err error
+	if parsed.subject, err = texttemplate.New(name + " subject").Funcs(templateFuncs).Option("missingkey=error").Parse(t.Subject); err != nil {
+		return nil, fmt.Errorf("invalid %s subject template: %w", name, err)
+	}
+	if parsed.text, err = texttemplate.New(name + " text").Funcs(templateFuncs).Option("missingkey=error").Parse(t.Text); err != nil {
+		return nil, fmt.Errorf("invalid %s text template: %w", name, err)
+	}
+	if parsed.html, err = htmltemplate.New(name + " html").Funcs(templateFuncs).Option("missingkey=error").Parse(t.HTML); err != nil {
+		return nil, fmt.Errorf("invalid %s html template: %w", name, err)
+	}
+	return &parsed, nil
+}
+
+// render executes the templates with data
+func (t *templates) render(data any) (Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render text: %w", err)
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render html: %w", err)
+	}
+
+	return Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// encode builds the multipart/alternative RFC 5322 message
+func encode(from *mail.Address, to []*mail.Address, msg Message, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary()))
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// messageID returns a random Message-ID in the domain of the sender
+func messageID(from string) string {
+	domain := "atmosbyte"
+	if at := strings.LastIndexByte(from, '@'); at >= 0 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
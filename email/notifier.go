@@ -0,0 +1,262 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/alert"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// Repository is the subset of the repository read by the digest
+type Repository interface {
+	GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error)
+}
+
+// Config configures the Notifier
+type Config struct {
+	Server    Server
+	From      string   // RFC 5322 address, e.g. Atmosbyte <station@example.com>
+	To        []string // Recipients of alerts and digests
+	Templates Templates
+	Digest    DigestConfig
+	Queue     queue.QueueConfig
+}
+
+// DigestConfig configures the daily summary of the previous day
+type DigestConfig struct {
+	Enabled   bool
+	Time      string // HH:MM local time the digest is sent, 07:00 when empty
+	Templates Templates
+}
+
+// Digest is the data passed to the digest templates
+type Digest struct {
+	Date        time.Time // Midnight of the summarized day
+	Temperature Summary   // °C
+	Humidity    Summary   // %
+	Pressure    Summary   // hPa
+}
+
+// Summary holds the daily statistics of a metric
+type Summary struct {
+	Min float64
+	Max float64
+	Avg float64
+}
+
+// ErrNoMeasurements is returned by SendDigest when the day has no measurements
+var ErrNoMeasurements = errors.New("no measurements")
+
+// Notifier is an alert.Channel sending notifications by email; it also sends the
+// daily digest when enabled
+type Notifier struct {
+	config   Config
+	from     *mail.Address
+	to       []*mail.Address
+	alerts   *templates
+	digest   *templates
+	digestAt int // Minutes since midnight
+	repo     Repository
+	queue    *queue.Queue[Message]
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewNotifier validates the configuration and templates and creates a notifier
+func NewNotifier(ctx context.Context, repo Repository, config Config) (*Notifier, error) {
+	n, err := newNotifier(config)
+	if err != nil {
+		return nil, err
+	}
+	n.repo = repo
+	n.queue = queue.NewQueue[Message](ctx, n, config.Queue)
+	return n, nil
+}
+
+// Validate checks the server, addresses, digest time and templates
+func (c Config) Validate() error {
+	_, err := newNotifier(c)
+	return err
+}
+
+// newNotifier parses the configuration into a notifier without a queue
+func newNotifier(config Config) (*Notifier, error) {
+	if err := config.Server.Validate(); err != nil {
+		return nil, err
+	}
+
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email from address %q: %w", config.From, err)
+	}
+	if len(config.To) == 0 {
+		return nil, errors.New("email requires at least one recipient")
+	}
+
+	n := &Notifier{
+		config:   config,
+		from:     from,
+		location: timezone.GetMachineLocation(),
+		now:      time.Now,
+	}
+	for _, to := range config.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email recipient %q: %w", to, err)
+		}
+		n.to = append(n.to, addr)
+	}
+
+	n.alerts, err = parseTemplates("alert", config.Templates, Templates{Subject: defaultAlertSubject, Text: defaultAlertText, HTML: defaultAlertHTML})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Digest.Enabled {
+		at := config.Digest.Time
+		if at == "" {
+			at = "07:00"
+		}
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest time %q, use HH:MM", at)
+		}
+		n.digestAt = t.Hour()*60 + t.Minute()
+
+		n.digest, err = parseTemplates("digest", config.Digest.Templates, Templates{Subject: defaultDigestSubject, Text: defaultDigestText, HTML: defaultDigestHTML})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+// Name implements alert.Channel
+func (n *Notifier) Name() string {
+	return "email"
+}
+
+// Notify implements alert.Channel. The message is rendered and enqueued; it is
+// sent and retried by the queue.
+func (n *Notifier) Notify(ctx context.Context, notification alert.Notification) error {
+	msg, err := n.alerts.render(notification)
+	if err != nil {
+		return fmt.Errorf("alert %s: %w", notification.Rule, err)
+	}
+	return n.queue.Enqueue(msg)
+}
+
+// Start runs the delivery queue and, when enabled, sends the digest every day at
+// the configured time until the context passed to NewNotifier is cancelled
+func (n *Notifier) Start() error {
+	done := make(chan error, 1)
+	go func() {
+		done <- n.queue.Start()
+	}()
+
+	if !n.config.Digest.Enabled {
+		return <-done
+	}
+
+	timer := time.NewTimer(time.Until(n.nextDigest(n.now())))
+	defer timer.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-timer.C:
+			if err := n.SendDigest(n.now()); err != nil {
+				logging.Errorf("Failed to send email digest: %v", err)
+			}
+			timer.Reset(time.Until(n.nextDigest(n.now())))
+		}
+	}
+}
+
+// nextDigest returns the next digest time after now
+func (n *Notifier) nextDigest(now time.Time) time.Time {
+	now = now.In(n.location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), n.digestAt/60, n.digestAt%60, 0, 0, n.location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SendDigest enqueues the digest of the day before now
+func (n *Notifier) SendDigest(now time.Time) error {
+	if n.digest == nil {
+		return errors.New("email digest is not enabled")
+	}
+
+	digest, err := n.Summarize(now)
+	if err != nil {
+		return err
+	}
+
+	msg, err := n.digest.render(digest)
+	if err != nil {
+		return fmt.Errorf("digest: %w", err)
+	}
+	return n.queue.Enqueue(msg)
+}
+
+// Summarize computes the digest of the day before now, in the machine location, from
+// the daily aggregate
+func (n *Notifier) Summarize(now time.Time) (Digest, error) {
+	now = now.In(n.location)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, n.location)
+	start := end.AddDate(0, 0, -1)
+
+	records, err := n.repo.GetMeasurementsByTimeRange(start, end.Add(-time.Nanosecond))
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to load digest measurements: %w", err)
+	}
+
+	for _, day := range weather.AggregateMeasurementsIn(records, weather.Day, n.location) {
+		if day.Date != start.Unix() {
+			continue
+		}
+		return Digest{
+			Date:        start,
+			Temperature: Summary{Min: *day.Temp.Min, Max: *day.Temp.Max, Avg: *day.Temp.Average},
+			Humidity:    Summary{Min: *day.Humidity.Min, Max: *day.Humidity.Max, Avg: *day.Humidity.Average},
+			Pressure: Summary{
+				Min: float64(*day.Pressure.Min) / 100,
+				Max: float64(*day.Pressure.Max) / 100,
+				Avg: *day.Pressure.Average / 100,
+			},
+		}, nil
+	}
+
+	return Digest{}, fmt.Errorf("%w on %s", ErrNoMeasurements, start.Format(time.DateOnly))
+}
+
+// Process implements queue.Worker[Message]
+func (n *Notifier) Process(ctx context.Context, msg queue.Message[Message]) error {
+	return n.Send(ctx, msg.Data)
+}
+
+// Send encodes and sends a message to the recipients right away
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	data, err := encode(n.from, n.to, msg, n.now())
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to encode message: %w", err), false)
+	}
+
+	to := make([]string, len(n.to))
+	for i, addr := range n.to {
+		to[i] = addr.Address
+	}
+	return n.config.Server.send(ctx, n.from.Address, to, data)
+}
@@ -0,0 +1,498 @@
+package email
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/alert"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// received is a message accepted by the fake server
+type received struct {
+	from string
+	to   []string
+	data string
+	auth string
+	tls  bool
+}
+
+// fakeSMTP is a minimal SMTP server supporting STARTTLS, implicit TLS and AUTH PLAIN/LOGIN
+type fakeSMTP struct {
+	host, port  string
+	certificate tls.Certificate
+	implicitTLS bool
+	username    string
+	password    string
+
+	mu       sync.Mutex
+	failures int // DATA commands answered with 451 before messages are accepted
+	messages []received
+}
+
+// startFakeSMTP starts a server and returns it with a client TLS config trusting its certificate
+func startFakeSMTP(t *testing.T, implicitTLS bool) (*fakeSMTP, *tls.Config) {
+	t.Helper()
+
+	certs := httptest.NewTLSServer(http.NotFoundHandler())
+	certs.Close()
+	clientTLS := &tls.Config{RootCAs: certs.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+
+	s := &fakeSMTP{certificate: certs.TLS.Certificates[0], implicitTLS: implicitTLS, username: "station", password: "secret"}
+
+	var listener net.Listener
+	var err error
+	if implicitTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{s.certificate}})
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	s.host, s.port, _ = net.SplitHostPort(listener.Addr().String())
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, clientTLS
+}
+
+func (s *fakeSMTP) server(security Security, auth AuthMechanism, clientTLS *tls.Config) Server {
+	port, _ := strconv.Atoi(s.port)
+	return Server{Host: s.host, Port: port, Security: security, Auth: auth, Username: s.username, Password: s.password, TLS: clientTLS, Timeout: 2 * time.Second}
+}
+
+func (s *fakeSMTP) received() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	msg := received{tls: s.implicitTLS}
+	tp.PrintfLine("220 fake ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-fake")
+			if !msg.tls {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{s.certificate}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg.tls = true
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			var username, password string
+			switch mechanism {
+			case "PLAIN":
+				decoded, _ := base64.StdEncoding.DecodeString(initial)
+				if fields := strings.Split(string(decoded), "\x00"); len(fields) == 3 {
+					username, password = fields[1], fields[2]
+				}
+			case "LOGIN":
+				tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+				line, _ := tp.ReadLine()
+				decoded, _ := base64.StdEncoding.DecodeString(line)
+				username = string(decoded)
+				tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+				line, _ = tp.ReadLine()
+				decoded, _ = base64.StdEncoding.DecodeString(line)
+				password = string(decoded)
+			}
+			if username != s.username || password != s.password {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			msg.auth = mechanism
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			if s.failures > 0 {
+				s.failures--
+				s.mu.Unlock()
+				tp.PrintfLine("451 try again later")
+				continue
+			}
+			msg.data = string(data)
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// parsed is a received message split into its headers and parts
+type parsed struct {
+	header mail.Header
+	text   string
+	html   string
+}
+
+func parse(t *testing.T, data string) parsed {
+	t.Helper()
+
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q", m.Header.Get("Content-Type"))
+	}
+
+	p := parsed{header: m.Header}
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			p.text = string(body)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/html"):
+			p.html = string(body)
+		}
+	}
+	return p
+}
+
+func testQueueConfig() queue.QueueConfig {
+	return queue.QueueConfig{
+		Workers:     1,
+		BufferSize:  10,
+		RetryPolicy: queue.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+		CircuitBreakerConfig: queue.CircuitBreakerConfig{
+			FailureThreshold: 10,
+			Timeout:          time.Second,
+		},
+	}
+}
+
+// startNotifier starts a notifier with the given server
+func startNotifier(t *testing.T, repo Repository, config Config) *Notifier {
+	t.Helper()
+
+	if config.From == "" {
+		config.From = "Atmosbyte <station@example.com>"
+		config.To = []string{"ops@example.com", "Weather Team <team@example.com>"}
+	}
+	config.Queue = testQueueConfig()
+
+	n, err := NewNotifier(t.Context(), repo, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Start()
+	}()
+	t.Cleanup(func() { <-done })
+	return n
+}
+
+// waitFor waits until cond holds or fails the test after a timeout
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var firing = alert.Notification{
+	Rule:        "frost",
+	Description: "temperature < 2 for 10m",
+	Severity:    "critical",
+	State:       alert.StateFiring,
+	Metric:      alert.MetricTemperature,
+	Value:       1.234,
+	Threshold:   2,
+	Time:        time.Date(2026, 3, 15, 5, 10, 0, 0, time.UTC),
+	FiredAt:     time.Date(2026, 3, 15, 5, 10, 0, 0, time.UTC),
+	Message:     "frost firing: temperature < 2 for 10m (value 1.23)",
+}
+
+func TestNotifier_StartTLSWithPlainAuth(t *testing.T) {
+	server, clientTLS := startFakeSMTP(t, false)
+	n := startNotifier(t, nil, Config{Server: server.server(SecurityStartTLS, AuthPlain, clientTLS)})
+
+	if n.Name() != "email" {
+		t.Errorf("expected channel name email, got %s", n.Name())
+	}
+	if err := n.Notify(t.Context(), firing); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(server.received()) == 1 })
+
+	got := server.received()[0]
+	if !got.tls || got.auth != "PLAIN" {
+		t.Errorf("expected a TLS connection with PLAIN auth, got tls=%v auth=%q", got.tls, got.auth)
+	}
+	if got.from != "station@example.com" || strings.Join(got.to, ",") != "ops@example.com,team@example.com" {
+		t.Errorf("unexpected envelope from %s to %v", got.from, got.to)
+	}
+
+	msg := parse(t, got.data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.header.Get("Subject"))
+	if subject != "[CRITICAL] frost firing" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if msg.header.Get("To") != `<ops@example.com>, "Weather Team" <team@example.com>` {
+		t.Errorf("unexpected To header %q", msg.header.Get("To"))
+	}
+	if !strings.Contains(msg.text, "Value:     1.23") || !strings.Contains(msg.text, "Rule:      temperature < 2 for 10m") {
+		t.Errorf("unexpected text part:\n%s", msg.text)
+	}
+	if !strings.Contains(msg.html, "<td>temperature &lt; 2 for 10m</td>") {
+		t.Errorf("expected the html part to be escaped:\n%s", msg.html)
+	}
+}
+
+func TestNotifier_ImplicitTLSWithLoginAuth(t *testing.T) {
+	server, clientTLS := startFakeSMTP(t, true)
+	n := startNotifier(t, nil, Config{
+		Server:    server.server(SecurityTLS, AuthLogin, clientTLS),
+		Templates: Templates{Subject: "{{.Rule}}", Text: "{{.Message}}", HTML: "<p>{{.Message}}</p>"},
+	})
+
+	if err := n.Send(t.Context(), Message{Subject: "Olá", Text: "température", HTML: "<p>ok</p>"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.received()
+	if len(got) != 1 || !got[0].tls || got[0].auth != "LOGIN" {
+		t.Fatalf("expected one message over TLS with LOGIN auth, got %+v", got)
+	}
+	msg := parse(t, got[0].data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.header.Get("Subject"))
+	if subject != "Olá" || msg.text != "température" {
+		t.Errorf("unexpected encoding of subject %q and text %q", subject, msg.text)
+	}
+}
+
+func TestNotifier_RetriesTransientFailures(t *testing.T) {
+	server, clientTLS := startFakeSMTP(t, false)
+	server.failures = 2
+	n := startNotifier(t, nil, Config{Server: server.server(SecurityStartTLS, AuthPlain, clientTLS)})
+
+	if err := n.Notify(t.Context(), firing); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(server.received()) == 1 })
+}
+
+func TestNotifier_Errors(t *testing.T) {
+	server, clientTLS := startFakeSMTP(t, false)
+	config := server.server(SecurityStartTLS, AuthPlain, clientTLS)
+
+	config.Password = "wrong"
+	n := startNotifier(t, nil, Config{Server: config})
+	err := n.Send(t.Context(), Message{Subject: "test"})
+	var retryable queue.RetryableError
+	if !errors.As(err, &retryable) || retryable.IsRetryable() {
+		t.Errorf("expected a non-retryable auth error, got %v", err)
+	}
+
+	// Credentials are never sent over an unencrypted connection to another host
+	auth := &loginAuth{username: "station", password: "secret", host: "smtp.example.com"}
+	if _, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com"}); err == nil {
+		t.Error("expected LOGIN to refuse an unencrypted connection")
+	}
+}
+
+type memoryRepository struct {
+	records []repository.MeasurementRecord
+}
+
+func (r *memoryRepository) GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error) {
+	var records []repository.MeasurementRecord
+	for _, record := range r.records {
+		if !record.Timestamp.Before(startTime) && !record.Timestamp.After(endTime) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func TestNotifier_Digest(t *testing.T) {
+	yesterday := time.Date(2026, 3, 14, 0, 0, 0, 0, time.Local)
+	repo := &memoryRepository{records: []repository.MeasurementRecord{
+		{Timestamp: yesterday.Add(-time.Minute), Temperature: -10, Humidity: 10, Pressure: 90000},
+		{Timestamp: yesterday.Add(6 * time.Hour), Temperature: 12, Humidity: 80, Pressure: 101200},
+		{Timestamp: yesterday.Add(15 * time.Hour), Temperature: 24, Humidity: 50, Pressure: 101000},
+		{Timestamp: yesterday.Add(24 * time.Hour), Temperature: 40, Humidity: 99, Pressure: 110000},
+	}}
+
+	server, clientTLS := startFakeSMTP(t, false)
+	n := startNotifier(t, repo, Config{
+		Server: server.server(SecurityStartTLS, AuthPlain, clientTLS),
+		Digest: DigestConfig{Enabled: true, Time: "07:30"},
+	})
+
+	now := yesterday.Add(31 * time.Hour)
+	if next := n.nextDigest(now); !next.Equal(yesterday.Add(31*time.Hour + 30*time.Minute)) {
+		t.Errorf("unexpected next digest %v", next)
+	}
+
+	digest, err := n.Summarize(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Digest{
+		Date:        yesterday,
+		Temperature: Summary{Min: 12, Max: 24, Avg: 18},
+		Humidity:    Summary{Min: 50, Max: 80, Avg: 65},
+		Pressure:    Summary{Min: 1010, Max: 1012, Avg: 1011},
+	}
+	if digest != want {
+		t.Errorf("expected %+v, got %+v", want, digest)
+	}
+
+	if err := n.SendDigest(now); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(server.received()) == 1 })
+	msg := parse(t, server.received()[0].data)
+	if msg.header.Get("Subject") != "Weather summary for Sat, 14 Mar 2026" {
+		t.Errorf("unexpected subject %q", msg.header.Get("Subject"))
+	}
+	if !strings.Contains(msg.text, "Temperature  12.0     24.0     18.0 °C") || !strings.Contains(msg.html, "<td>1011.0</td>") {
+		t.Errorf("unexpected digest:\n%s\n%s", msg.text, msg.html)
+	}
+
+	if _, err := n.Summarize(now.AddDate(0, 0, 5)); !errors.Is(err, ErrNoMeasurements) {
+		t.Errorf("expected ErrNoMeasurements, got %v", err)
+	}
+}
+
+func TestNotifier_DigestInMachineLocation(t *testing.T) {
+	// A day of the machine location far from time.Local
+	location := time.FixedZone("UTC+13", 13*60*60)
+	yesterday := time.Date(2026, 3, 14, 0, 0, 0, 0, location)
+	repo := &memoryRepository{records: []repository.MeasurementRecord{
+		{Timestamp: yesterday.Add(time.Hour), Temperature: 10, Humidity: 40, Pressure: 100000},
+		{Timestamp: yesterday.Add(23 * time.Hour), Temperature: 20, Humidity: 60, Pressure: 102000},
+	}}
+
+	n := startNotifier(t, repo, Config{
+		Server: Server{Host: "localhost", Port: 25, Security: SecurityNone},
+		Digest: DigestConfig{Enabled: true},
+	})
+	n.location = location
+
+	digest, err := n.Summarize(yesterday.Add(30 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Digest{
+		Date:        yesterday,
+		Temperature: Summary{Min: 10, Max: 20, Avg: 15},
+		Humidity:    Summary{Min: 40, Max: 60, Avg: 50},
+		Pressure:    Summary{Min: 1000, Max: 1020, Avg: 1010},
+	}
+	if digest != want {
+		t.Errorf("expected %+v, got %+v", want, digest)
+	}
+}
+
+func TestNewNotifier_Validation(t *testing.T) {
+	valid := Config{
+		Server: Server{Host: "smtp.example.com", Port: 587, Security: SecurityStartTLS},
+		From:   "station@example.com",
+		To:     []string{"ops@example.com"},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+	}{
+		{"missing host", func(c *Config) { c.Server.Host = "" }},
+		{"invalid port", func(c *Config) { c.Server.Port = 0 }},
+		{"unknown security", func(c *Config) { c.Server.Security = "ssl" }},
+		{"unknown auth", func(c *Config) { c.Server.Auth = "cram-md5" }},
+		{"invalid from", func(c *Config) { c.From = "station" }},
+		{"no recipients", func(c *Config) { c.To = nil }},
+		{"invalid recipient", func(c *Config) { c.To = []string{"ops@"} }},
+		{"invalid template", func(c *Config) { c.Templates.Subject = "{{.Rule" }},
+		{"invalid digest time", func(c *Config) { c.Digest = DigestConfig{Enabled: true, Time: "7am"} }},
+	}
+
+	if _, err := NewNotifier(t.Context(), nil, valid); err != nil {
+		t.Fatalf("unexpected error for a valid config: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			if _, err := NewNotifier(t.Context(), nil, config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package email sends alert notifications and a daily weather digest over SMTP.
+// Messages are multipart/alternative with a plain-text and an HTML part rendered
+// from templates, and are delivered through their own queue so a slow or
+// unavailable relay does not block the alert engine.
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// Security selects how the connection to the relay is encrypted
+type Security string
+
+const (
+	SecurityNone     Security = "none"     // Plain connection, usually port 25
+	SecurityStartTLS Security = "starttls" // Upgraded with STARTTLS, usually port 587
+	SecurityTLS      Security = "tls"      // Implicit TLS, usually port 465
+)
+
+// AuthMechanism selects the SMTP AUTH mechanism
+type AuthMechanism string
+
+const (
+	AuthPlain AuthMechanism = "plain"
+	AuthLogin AuthMechanism = "login"
+)
+
+// Server is the SMTP relay and the credentials used to send
+type Server struct {
+	Host     string
+	Port     int
+	Security Security
+	Auth     AuthMechanism // Used when Username is set, plain when empty
+	Username string
+	Password string
+	TLS      *tls.Config // Verifies Host with the system roots when nil
+	Timeout  time.Duration
+}
+
+// Validate checks the server fields
+func (s Server) Validate() error {
+	if s.Host == "" {
+		return errors.New("smtp host is required")
+	}
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf("invalid smtp port %d", s.Port)
+	}
+	switch s.Security {
+	case SecurityNone, SecurityStartTLS, SecurityTLS:
+	default:
+		return fmt.Errorf("unknown smtp security %q, use none, starttls or tls", s.Security)
+	}
+	switch s.Auth {
+	case "", AuthPlain, AuthLogin:
+	default:
+		return fmt.Errorf("unknown smtp auth %q, use plain or login", s.Auth)
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration used for the relay
+func (s Server) tlsConfig() *tls.Config {
+	if s.TLS != nil {
+		config := s.TLS.Clone()
+		if config.ServerName == "" {
+			config.ServerName = s.Host
+		}
+		return config
+	}
+	return &tls.Config{ServerName: s.Host, MinVersion: tls.VersionTLS12}
+}
+
+// send delivers a message to the recipients. 4xx replies and connection errors
+// are retryable; 5xx replies and configuration errors are not.
+func (s Server) send(ctx context.Context, from string, to []string, message []byte) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if s.Security == SecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig()}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to connect to %s: %w", addr, err), true)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return classify("failed to greet smtp server", err)
+	}
+	defer client.Close()
+
+	if s.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return queue.NewRetryableError(errors.New("smtp server does not support STARTTLS"), false)
+		}
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			return classify("failed to start TLS", err)
+		}
+	}
+
+	if s.Username != "" {
+		auth := smtp.PlainAuth("", s.Username, s.Password, s.Host)
+		if s.Auth == AuthLogin {
+			auth = &loginAuth{username: s.Username, password: s.Password, host: s.Host}
+		}
+		if err := client.Auth(auth); err != nil {
+			return classify("failed to authenticate", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return classify("sender rejected", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return classify(fmt.Sprintf("recipient %s rejected", rcpt), err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return classify("failed to start message", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return classify("failed to write message", err)
+	}
+	if err := w.Close(); err != nil {
+		return classify("message rejected", err)
+	}
+
+	return client.Quit()
+}
+
+// classify wraps an SMTP error, retrying on transient 4xx replies and network errors
+func classify(msg string, err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return queue.NewRetryableError(fmt.Errorf("%s: %w", msg, err), protoErr.Code >= 400 && protoErr.Code < 500)
+	}
+	return queue.NewRetryableError(fmt.Errorf("%s: %w", msg, err), true)
+}
+
+// loginAuth implements the AUTH LOGIN mechanism, which net/smtp does not provide.
+// Like smtp.PlainAuth it only sends credentials over TLS or to localhost.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
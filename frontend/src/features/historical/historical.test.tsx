@@ -59,6 +59,7 @@ jest.mock("react-datepicker", () => {
 })
 
 jest.mock("../../shared/api/client", () => ({
+  API_BASE: "/api/v1",
   client: {
     getHistorical: jest.fn(),
     getHealth: jest.fn(),
@@ -145,7 +146,7 @@ test("exports CSV with current filters", async () => {
 
   expect(window.location.assign).toHaveBeenCalled()
   const firstCall = (window.location.assign as jest.Mock).mock.calls[0][0] as string
-  expect(firstCall).toContain("/data/export?")
+  expect(firstCall).toContain("/api/v1/data/export?")
   expect(firstCall).toContain("from=")
   expect(firstCall).toContain("to=")
   expect(firstCall).toContain("type=")
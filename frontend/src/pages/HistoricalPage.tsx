@@ -1,5 +1,6 @@
 import { HistoricalFiltersForm } from "@/features/historical/HistoricalFiltersForm"
 import { useHistoricalData } from "@/features/historical/useHistoricalData"
+import { API_BASE } from "@/shared/api/client"
 import type { AggregationKind } from "@/shared/types/status"
 import { Skeleton } from "@/shared/ui/Skeleton"
 import { TZDate } from "@date-fns/tz"
@@ -101,7 +102,7 @@ export function HistoricalPage({ }: HistoricalPageProps): React.JSX.Element {
           to: new Date(toIso.getTime()).toISOString(),
           type: values.type,
         })
-        window.location.assign(`/data/export?${params.toString()}`)
+        window.location.assign(`${API_BASE}/data/export?${params.toString()}`)
       },
     }),
     [load],
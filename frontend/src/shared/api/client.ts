@@ -7,6 +7,8 @@ import type {
   QueueStatsDto,
 } from "@/shared/types/api"
 
+export const API_BASE = "/api/v1"
+
 export type ApiErrorKind = "timeout" | "http" | "parse" | "network"
 
 export class ApiError extends Error {
@@ -207,17 +209,17 @@ async function request<T>(path: string, timeoutMs: number): Promise<T> {
 
 export const client = {
   async getMeasurements(timeoutMs = 8000): Promise<MeasurementDto> {
-    const payload = await request<MeasurementDto>("/measurements", timeoutMs)
+    const payload = await request<MeasurementDto>(`${API_BASE}/measurements`, timeoutMs)
     return parseMeasurement(payload)
   },
 
   async getHealth(timeoutMs = 8000): Promise<HealthDto> {
-    const payload = await request<HealthDto>("/health", timeoutMs)
+    const payload = await request<HealthDto>(`${API_BASE}/health`, timeoutMs)
     return parseHealth(payload)
   },
 
   async getQueue(timeoutMs = 8000): Promise<QueueStatsDto> {
-    const payload = await request<QueueStatsDto>("/queue", timeoutMs)
+    const payload = await request<QueueStatsDto>(`${API_BASE}/queue`, timeoutMs)
     return parseQueue(payload)
   },
 
@@ -227,7 +229,7 @@ export const client = {
       to: query.to,
       type: query.type,
     })
-    const payload = await request(`/data?${params.toString()}`, timeoutMs)
+    const payload = await request(`${API_BASE}/data?${params.toString()}`, timeoutMs)
     return parseHistorical(payload)
   },
 }
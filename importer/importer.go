@@ -0,0 +1,568 @@
+// Package importer loads historical measurements from CSV files, JSONL files of
+// bme280.Measurement and the CSV written by /data/export.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// Repository stores imported measurements. The import writes batches through
+// repository.MeasurementImport, which skips measurements already stored for the same
+// node and timestamp and, in a dry run, stores nothing.
+type Repository interface {
+	BeginImport(ctx context.Context, dryRun bool) (*repository.MeasurementImport, error)
+}
+
+// Format is the format of the imported data
+type Format string
+
+const (
+	FormatAuto   Format = ""       // Detected from the content
+	FormatCSV    Format = "csv"    // Columns selected by a Mapping
+	FormatJSONL  Format = "jsonl"  // One bme280.Measurement per line
+	FormatExport Format = "export" // CSV written by /data/export, one measurement per row from its averages
+)
+
+// ParseFormat parses a format name; an empty name detects the format
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatAuto, FormatCSV, FormatJSONL, FormatExport:
+		return f, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("unknown import format %q, use csv, jsonl or export", name)
+	}
+}
+
+// Mapping selects the CSV columns, by header name, and how their values are read
+type Mapping struct {
+	Timestamp   string         // "timestamp" when empty
+	Temperature string         // "temperature" when empty
+	Humidity    string         // "humidity" when empty
+	Pressure    string         // "pressure" when empty
+	Node        string         // Optional column with the node of each row
+	TimeLayout  string         // Go layout, "unix" or "unix_ms"; RFC 3339 and "2006-01-02 15:04:05" when empty
+	Location    *time.Location // Zone of timestamps without an offset, local time when nil
+	Units       weather.Units  // Units of the values, °C, % and Pa when empty
+	Delimiter   rune           // ',' when zero
+}
+
+// Options configures an import
+type Options struct {
+	Format    Format
+	Mapping   Mapping // For FormatCSV
+	Node      string  // Node of measurements without one; empty for the local sensor
+	BatchSize int     // Measurements per transaction, 1000 when zero
+	DryRun    bool    // Report without storing
+}
+
+// ErrInvalidData is returned, wrapped, when the data cannot be imported at all, e.g.
+// when a mapped column is missing; rows that cannot be read are only rejected
+var ErrInvalidData = errors.New("invalid import data")
+
+// bom is the UTF-8 byte order mark some spreadsheets write
+var bom = []byte("\ufeff")
+
+// maxErrors is the number of rejected rows detailed in a Report
+const maxErrors = 100
+
+// Report summarizes an import
+type Report struct {
+	Format     Format     `json:"format"`
+	DryRun     bool       `json:"dry_run"`
+	Read       int        `json:"read"`       // Rows or lines read
+	Imported   int        `json:"imported"`   // Stored, or that would be stored by a dry run
+	Duplicates int        `json:"duplicates"` // Already stored for the same node and timestamp
+	Rejected   int        `json:"rejected"`   // Rows that could not be read
+	Errors     []RowError `json:"errors,omitempty"`
+	First      *time.Time `json:"first,omitempty"` // Oldest measurement read
+	Last       *time.Time `json:"last,omitempty"`  // Newest measurement read
+}
+
+// RowError is a rejected row
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// reject records a rejected row, keeping the details of the first maxErrors
+func (r *Report) reject(line int, err error) {
+	r.Rejected++
+	if len(r.Errors) < maxErrors {
+		r.Errors = append(r.Errors, RowError{Line: line, Error: err.Error()})
+	}
+}
+
+// observe extends the time span of the report
+func (r *Report) observe(t time.Time) {
+	if r.First == nil || t.Before(*r.First) {
+		r.First = &t
+	}
+	if r.Last == nil || t.After(*r.Last) {
+		r.Last = &t
+	}
+}
+
+// rowReader returns the next measurement and its line, io.EOF at the end. A
+// *rowError rejects the row without stopping the import.
+type rowReader func() (bme280.Measurement, int, error)
+
+// rowError is a problem with one row
+type rowError struct {
+	line int
+	err  error
+}
+
+func (e *rowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+// Import reads the measurements in r and stores them in batches. Rows that cannot be
+// read are rejected and reported; the import stops on the first storage error or
+// when ctx is cancelled, and the report counts what was stored until then.
+func Import(ctx context.Context, r io.Reader, repo Repository, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	br := bufio.NewReader(r)
+	format, err := detect(br, opts.Format)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Format: format, DryRun: opts.DryRun}
+
+	var next rowReader
+	switch format {
+	case FormatJSONL:
+		next = jsonlReader(br)
+	default:
+		next, err = csvReader(br, opts.Mapping, format == FormatExport)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	session, err := repo.BeginImport(ctx, opts.DryRun)
+	if err != nil {
+		return report, err
+	}
+	defer session.Close()
+
+	batch := make([]bme280.Measurement, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		imported, err := session.Write(ctx, batch)
+		if err != nil {
+			return err
+		}
+		report.Imported += imported
+		report.Duplicates += len(batch) - imported
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		m, line, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *rowError
+		if errors.As(err, &rowErr) {
+			report.Read++
+			report.reject(rowErr.line, rowErr.err)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		report.Read++
+		if m.Node == "" {
+			m.Node = opts.Node
+		}
+		if err := validate(m); err != nil {
+			report.reject(line, err)
+			continue
+		}
+		// Measurements of the local sensor are stored in local time; the same
+		// zone makes duplicates compare equal
+		m.Timestamp = m.Timestamp.Local()
+		report.observe(m.Timestamp)
+
+		batch = append(batch, m)
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, flush()
+}
+
+// detect returns format or, when it is FormatAuto, the format of the content: JSONL
+// when it starts with {, the export format when the header has a temp_avg column,
+// CSV otherwise
+func detect(br *bufio.Reader, format Format) (Format, error) {
+	if format != FormatAuto {
+		return format, nil
+	}
+
+	head, err := br.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("failed to read import data: %w", err)
+	}
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, bom), " \t\r\n")
+	if bytes.HasPrefix(head, []byte("{")) {
+		return FormatJSONL, nil
+	}
+
+	header, _, _ := bytes.Cut(head, []byte("\n"))
+	if bytes.Contains(header, []byte("temp_avg")) {
+		return FormatExport, nil
+	}
+	return FormatCSV, nil
+}
+
+// validate rejects measurements outside the range of the sensor
+func validate(m bme280.Measurement) error {
+	switch {
+	case m.Timestamp.IsZero():
+		return errors.New("missing timestamp")
+	case m.Temperature < -40 || m.Temperature > 85:
+		return fmt.Errorf("temperature %.2f °C out of range", m.Temperature)
+	case m.Humidity < 0 || m.Humidity > 100:
+		return fmt.Errorf("humidity %.2f %% out of range", m.Humidity)
+	case m.Pressure < 30000 || m.Pressure > 110000:
+		return fmt.Errorf("pressure %d Pa out of range", m.Pressure)
+	}
+	return nil
+}
+
+// jsonlReader reads one bme280.Measurement per line, skipping blank lines
+func jsonlReader(r io.Reader) rowReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+
+	return func() (bme280.Measurement, int, error) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(bytes.TrimPrefix(scanner.Bytes(), bom))
+			if len(text) == 0 {
+				continue
+			}
+			var m bme280.Measurement
+			if err := json.Unmarshal(text, &m); err != nil {
+				return m, line, &rowError{line: line, err: err}
+			}
+			return m, line, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return bme280.Measurement{}, line, fmt.Errorf("failed to read import data: %w", err)
+		}
+		return bme280.Measurement{}, line, io.EOF
+	}
+}
+
+// columns maps the header names of a CSV file to their indexes
+type columns map[string]int
+
+// newColumns reads the header of a CSV file
+func newColumns(reader *csv.Reader) (columns, error) {
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the data is empty", ErrInvalidData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read CSV header: %w", ErrInvalidData, err)
+	}
+
+	cols := make(columns, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, string(bom)))
+		cols[strings.ToLower(name)] = i
+	}
+	return cols, nil
+}
+
+// index returns the index of the column name
+func (c columns) index(name string) (int, error) {
+	i, ok := c[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(c))
+		for n := range c {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return 0, fmt.Errorf("%w: CSV has no %s column, columns are %s", ErrInvalidData, name, strings.Join(names, ", "))
+	}
+	return i, nil
+}
+
+// csvReader reads the columns selected by mapping, or those of the /data/export
+// format when export is set
+func csvReader(r io.Reader, mapping Mapping, export bool) (rowReader, error) {
+	reader := csv.NewReader(r)
+	if mapping.Delimiter != 0 {
+		reader.Comma = mapping.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	cols, err := newColumns(reader)
+	if err != nil {
+		return nil, err
+	}
+	if export {
+		if mapping, err = exportMapping(cols); err != nil {
+			return nil, err
+		}
+	}
+
+	names := []string{
+		cmp.Or(mapping.Timestamp, "timestamp"),
+		cmp.Or(mapping.Temperature, "temperature"),
+		cmp.Or(mapping.Humidity, "humidity"),
+		cmp.Or(mapping.Pressure, "pressure"),
+	}
+	var idx [4]int
+	for i, name := range names {
+		if idx[i], err = cols.index(name); err != nil {
+			return nil, err
+		}
+	}
+	nodeIdx := -1
+	if mapping.Node != "" {
+		if nodeIdx, err = cols.index(mapping.Node); err != nil {
+			return nil, err
+		}
+	}
+
+	units := mapping.Units
+	if units.Temperature == "" {
+		units.Temperature = weather.Celsius
+	}
+	if units.Pressure == "" {
+		units.Pressure = weather.Pascal
+	}
+	location := mapping.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	return func() (bme280.Measurement, int, error) {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return bme280.Measurement{}, 0, io.EOF
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return bme280.Measurement{}, parseErr.Line, &rowError{line: parseErr.Line, err: parseErr.Err}
+			}
+			return bme280.Measurement{}, 0, fmt.Errorf("failed to read import data: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var m bme280.Measurement
+		var values [3]float64
+		m.Timestamp, err = parseTime(field(idx[0]), mapping.TimeLayout, location)
+		if err != nil {
+			return m, line, &rowError{line: line, err: fmt.Errorf("%s: %w", names[0], err)}
+		}
+		for i := range values {
+			values[i], err = parseFloat(field(idx[i+1]))
+			if err != nil {
+				return m, line, &rowError{line: line, err: fmt.Errorf("%s: %w", names[i+1], err)}
+			}
+		}
+		m.Temperature = weather.ToCelsius(values[0], units.Temperature)
+		m.Humidity = values[1]
+		m.Pressure = int64(math.Round(weather.ToPascal(values[2], units.Pressure)))
+		if nodeIdx >= 0 {
+			m.Node = field(nodeIdx)
+		}
+		return m, line, nil
+	}, nil
+}
+
+// exportMapping maps the average columns of the CSV written by /data/export. Their
+// suffixes give the units: temp_avg is in °C, temp_avg_f in °F and pressure_avg_hpa
+// in hPa.
+func exportMapping(cols columns) (Mapping, error) {
+	mapping := Mapping{
+		Timestamp:  "timestamp",
+		Humidity:   "humidity_avg",
+		TimeLayout: time.RFC3339,
+		Units:      weather.DefaultUnits(),
+	}
+
+	for name := range cols {
+		switch {
+		case name == "temp_avg":
+			mapping.Temperature = name
+		case name == "temp_avg_f":
+			mapping.Temperature = name
+			mapping.Units.Temperature = weather.Fahrenheit
+		case strings.HasPrefix(name, "pressure_avg_"):
+			units, err := weather.ParseUnits(strings.TrimPrefix(name, "pressure_avg_"), weather.DefaultUnits())
+			if err != nil {
+				return mapping, fmt.Errorf("%w: export column %s: %w", ErrInvalidData, name, err)
+			}
+			mapping.Pressure = name
+			mapping.Units.Pressure = units.Pressure
+		}
+	}
+
+	if mapping.Temperature == "" || mapping.Pressure == "" {
+		return mapping, fmt.Errorf("%w: not a /data/export CSV, temp_avg and pressure_avg columns are required", ErrInvalidData)
+	}
+	return mapping, nil
+}
+
+// parseTime parses a timestamp with layout; without a layout RFC 3339 and
+// "2006-01-02 15:04:05" are tried
+func parseTime(value, layout string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing timestamp")
+	}
+
+	switch layout {
+	case "unix", "unix_ms":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid Unix time %q", value)
+		}
+		if layout == "unix_ms" {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	case "":
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		layout = time.DateTime
+	}
+
+	t, err := time.ParseInLocation(layout, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+	}
+	return t, nil
+}
+
+// parseFloat parses a number, accepting a decimal comma
+func parseFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, errors.New("missing value")
+	}
+	f, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return f, nil
+}
+
+// Parameters lists the names accepted by ParseOptions with their descriptions, the
+// query parameters of the import endpoint and the flags of the import command
+var Parameters = []struct{ Name, Usage string }{
+	{"format", "Data format: csv, jsonl or export; detected from the content when empty"},
+	{"node", "Node of measurements without one, empty for the local sensor"},
+	{"timestamp_column", "CSV column with the timestamp (default timestamp)"},
+	{"temperature_column", "CSV column with the temperature (default temperature)"},
+	{"humidity_column", "CSV column with the relative humidity (default humidity)"},
+	{"pressure_column", "CSV column with the pressure (default pressure)"},
+	{"node_column", "CSV column with the node of each row"},
+	{"time_layout", "Go time layout of the CSV timestamps, unix or unix_ms (default RFC 3339 or 2006-01-02 15:04:05)"},
+	{"timezone", "Time zone of CSV timestamps without an offset, e.g. America/Sao_Paulo (default local time)"},
+	{"units", "Units of the CSV values, e.g. imperial or F,inHg (default C and Pa)"},
+	{"delimiter", "CSV field delimiter (default ,)"},
+	{"batch_size", "Measurements per transaction (default 1000)"},
+	{"dry_run", "Report what would be imported without storing anything"},
+}
+
+// ParseOptions reads the options named in Parameters through get, which returns an
+// empty string for the options not given
+func ParseOptions(get func(name string) string) (Options, error) {
+	var opts Options
+	var err error
+
+	if opts.Format, err = ParseFormat(get("format")); err != nil {
+		return opts, err
+	}
+	opts.Node = get("node")
+	opts.Mapping = Mapping{
+		Timestamp:   get("timestamp_column"),
+		Temperature: get("temperature_column"),
+		Humidity:    get("humidity_column"),
+		Pressure:    get("pressure_column"),
+		Node:        get("node_column"),
+		TimeLayout:  get("time_layout"),
+	}
+
+	if tz := get("timezone"); tz != "" {
+		if opts.Mapping.Location, err = time.LoadLocation(tz); err != nil {
+			return opts, fmt.Errorf("invalid timezone %q: %w", tz, err)
+		}
+	}
+	if units := get("units"); units != "" {
+		if opts.Mapping.Units, err = weather.ParseUnits(units, weather.DefaultUnits()); err != nil {
+			return opts, err
+		}
+	}
+	if delimiter := get("delimiter"); delimiter != "" {
+		if delimiter == `\t` || delimiter == "tab" {
+			delimiter = "\t"
+		}
+		runes := []rune(delimiter)
+		if len(runes) != 1 {
+			return opts, fmt.Errorf("delimiter must be a single character, got %q", delimiter)
+		}
+		opts.Mapping.Delimiter = runes[0]
+	}
+	if size := get("batch_size"); size != "" {
+		if opts.BatchSize, err = strconv.Atoi(size); err != nil || opts.BatchSize < 1 {
+			return opts, fmt.Errorf("batch_size must be a positive integer, got %q", size)
+		}
+	}
+	if dryRun := get("dry_run"); dryRun != "" {
+		if opts.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return opts, fmt.Errorf("dry_run must be true or false, got %q", dryRun)
+		}
+	}
+
+	return opts, nil
+}
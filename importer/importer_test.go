@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+func newRepository(t *testing.T) *repository.SQLiteRepository {
+	t.Helper()
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "weather.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestImport_CSVMapping(t *testing.T) {
+	data := `Date;Temp (F);RH;Baro (inHg);Station
+2024-01-01 00:00:00;68;55,5;29.92;garden
+2024-01-01 00:10:00;70,2;54;29.90;garden
+2024-01-01 00:20:00;bad;54;29.90;garden
+2024-01-01 00:30:00;71;54;29.91;
+`
+	repo := newRepository(t)
+	report, err := Import(context.Background(), strings.NewReader(data), repo, Options{
+		Format: FormatCSV,
+		Mapping: Mapping{
+			Timestamp:   "date",
+			Temperature: "Temp (F)",
+			Humidity:    "RH",
+			Pressure:    "Baro (inHg)",
+			Node:        "station",
+			Location:    time.UTC,
+			Units:       weather.Units{Temperature: weather.Fahrenheit, Pressure: weather.InchMercury},
+			Delimiter:   ';',
+		},
+		Node: "old-logger",
+	})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	if report.Read != 4 || report.Imported != 3 || report.Rejected != 1 || report.Duplicates != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Line != 4 || !strings.Contains(report.Errors[0].Error, "Temp (F)") {
+		t.Errorf("unexpected errors %+v", report.Errors)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records, err := repo.GetNodeMeasurementsByTimeRange("garden", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 garden measurements, got %d", len(records))
+	}
+	if got := records[0]; got.Temperature != 20 || got.Humidity != 55.5 || got.Pressure != 101321 || !got.Timestamp.Equal(start) {
+		t.Errorf("unexpected converted measurement %+v", got)
+	}
+
+	// Rows without a node take the default one
+	records, err = repo.GetNodeMeasurementsByTimeRange("old-logger", start, start.Add(time.Hour))
+	if err != nil || len(records) != 1 {
+		t.Errorf("expected 1 old-logger measurement, got %d (%v)", len(records), err)
+	}
+}
+
+func TestImport_JSONLDeduplication(t *testing.T) {
+	data := `{"timestamp":"2024-01-01T00:00:00Z","temperature":21.5,"humidity":50,"pressure":101325}
+{"timestamp":"2024-01-01T00:01:00Z","temperature":21.6,"humidity":50,"pressure":101320}
+
+{"timestamp":"2024-01-01T00:01:00Z","temperature":21.6,"humidity":50,"pressure":101320}
+{"timestamp":"2024-01-01T00:02:00Z","temperature":21.7,"humidity":50,"pressure":101320,"node":"attic"}
+not json
+`
+	repo := newRepository(t)
+
+	// A dry run stores nothing and reports what would be stored
+	report, err := Import(context.Background(), strings.NewReader(data), repo, Options{DryRun: true, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if report.Format != FormatJSONL || !report.DryRun || report.Read != 5 || report.Imported != 3 || report.Duplicates != 1 || report.Rejected != 1 {
+		t.Errorf("unexpected dry run report %+v", report)
+	}
+	if count, _ := repo.GetMeasurementCount(); count != 0 {
+		t.Fatalf("dry run stored %d measurements", count)
+	}
+
+	report, err = Import(context.Background(), strings.NewReader(data), repo, Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if report.Imported != 3 || report.Duplicates != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.First == nil || !report.First.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || report.Errors[0].Line != 6 {
+		t.Errorf("unexpected span or errors %+v", report)
+	}
+
+	// Importing the same file again only finds duplicates
+	report, err = Import(context.Background(), strings.NewReader(data), repo, Options{})
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	if report.Imported != 0 || report.Duplicates != 4 {
+		t.Errorf("expected only duplicates, got %+v", report)
+	}
+	if count, _ := repo.GetMeasurementCount(); count != 3 {
+		t.Errorf("expected 3 stored measurements, got %d", count)
+	}
+}
+
+func TestImport_Export(t *testing.T) {
+	data := `timestamp,temp_min_f,temp_avg_f,temp_max_f,humidity_min,humidity_avg,humidity_max,pressure_min_hpa,pressure_avg_hpa,pressure_max_hpa
+2024-01-01T00:00:00Z,50.00,59.00,68.00,40.00,45.00,50.00,1010.00,1013.25,1015.00
+2024-01-01T01:00:00Z,,,,,,,,,
+`
+	repo := newRepository(t)
+	report, err := Import(context.Background(), strings.NewReader(data), repo, Options{})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if report.Format != FormatExport || report.Imported != 1 || report.Rejected != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	records, err := repo.GetLatestMeasurements(1)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected 1 measurement, got %d (%v)", len(records), err)
+	}
+	if got := records[0]; got.Temperature != 15 || got.Humidity != 45 || got.Pressure != 101325 {
+		t.Errorf("unexpected measurement %+v", got)
+	}
+}
+
+func TestImport_Errors(t *testing.T) {
+	repo := newRepository(t)
+
+	tests := []struct {
+		name string
+		data string
+		opts Options
+		want string
+	}{
+		{"missing column", "time,temperature,humidity,pressure\n", Options{Format: FormatCSV}, "CSV has no timestamp column"},
+		{"empty", "", Options{Format: FormatCSV}, "the data is empty"},
+		{"not an export", "timestamp,temp_avg\n", Options{Format: FormatExport}, "not a /data/export CSV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Import(context.Background(), strings.NewReader(tt.data), repo, tt.opts)
+			if !errors.Is(err, ErrInvalidData) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an unknown format error")
+	}
+}
@@ -0,0 +1,115 @@
+// Package logging adds levels to the standard logger.
+//
+// Messages of log.Printf are at the info level. Debugf, Warnf and Errorf log at their
+// own level with the flags of the standard logger. Once SetLevel is called, messages
+// below the level are discarded, including those of log.Printf.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is the severity of a message
+type Level int32
+
+const (
+	Debug Level = iota - 1
+	Info
+	Warn
+	Error
+)
+
+var levelNames = map[Level]string{Debug: "debug", Info: "info", Warn: "warn", Error: "error"}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q, use debug, info, warn or error", s)
+}
+
+var (
+	level   atomic.Int32 // The zero value is Info
+	direct  atomic.Pointer[log.Logger]
+	install sync.Once
+)
+
+// infoWriter is the output of the standard logger, dropping its info messages when
+// the level is higher
+type infoWriter struct {
+	w io.Writer
+}
+
+func (f infoWriter) Write(p []byte) (int, error) {
+	if Level(level.Load()) > Info {
+		return len(p), nil
+	}
+	return f.w.Write(p)
+}
+
+// SetLevel discards the messages below level. It may be called again to change it.
+func SetLevel(l Level) {
+	level.Store(int32(l))
+	install.Do(func() {
+		w := log.Writer()
+		direct.Store(log.New(w, log.Prefix(), log.Flags()))
+		log.SetOutput(infoWriter{w})
+	})
+}
+
+// GetLevel returns the current level
+func GetLevel() Level {
+	return Level(level.Load())
+}
+
+func output(l Level, format string, args ...any) {
+	if l < GetLevel() {
+		return
+	}
+	logger := direct.Load()
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Output(3, fmt.Sprintf(format, args...))
+}
+
+// Debugf logs a message only shown at the debug level, e.g. for every request or reading
+func Debugf(format string, args ...any) {
+	output(Debug, format, args...)
+}
+
+// Warnf logs a problem that does not stop the operation
+func Warnf(format string, args ...any) {
+	output(Warn, format, args...)
+}
+
+// Errorf logs a failed operation
+func Errorf(format string, args ...any) {
+	output(Error, format, args...)
+}
+
+// Fatalf logs at any level and exits with status 1
+func Fatalf(format string, args ...any) {
+	logger := direct.Load()
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Output(2, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
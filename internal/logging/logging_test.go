@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() { SetLevel(Info) })
+
+	logAll := func() {
+		Debugf("debug")
+		log.Printf("info")
+		Warnf("warn")
+		Errorf("error")
+	}
+
+	tests := []struct {
+		level Level
+		want  string
+	}{
+		{Debug, "debug info warn error"},
+		{Info, "info warn error"},
+		{Warn, "warn error"},
+		{Error, "error"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		SetLevel(tt.level)
+		logAll()
+		if got := strings.Join(strings.Fields(buf.String()), " "); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.level, tt.want, got)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"debug", "INFO", "Warn", "error"} {
+		level, err := ParseLevel(s)
+		if err != nil || !strings.EqualFold(level.String(), s) {
+			t.Errorf("ParseLevel(%q) = %v, %v", s, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,97 @@
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact protocol type identifiers
+const (
+	compactTrue   = 1
+	compactFalse  = 2
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// encoder writes the Thrift compact protocol, the serialization of the Parquet page
+// headers and footer. Fields must be written in increasing id order within a struct.
+type encoder struct {
+	buf  []byte
+	last []int16 // Id of the last field written in each open struct
+}
+
+func (e *encoder) structBegin() {
+	e.last = append(e.last, 0)
+}
+
+func (e *encoder) structEnd() {
+	e.buf = append(e.buf, 0)
+	e.last = e.last[:len(e.last)-1]
+}
+
+func (e *encoder) field(id int16, typ byte) {
+	last := &e.last[len(e.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		e.buf = append(e.buf, byte(delta)<<4|typ)
+	} else {
+		e.buf = append(e.buf, typ)
+		e.varint(int64(id))
+	}
+	*last = id
+}
+
+func (e *encoder) varint(v int64) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(v<<1^v>>63))
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) i32(id int16, v int32) {
+	e.field(id, compactI32)
+	e.varint(int64(v))
+}
+
+func (e *encoder) i64(id int16, v int64) {
+	e.field(id, compactI64)
+	e.varint(v)
+}
+
+func (e *encoder) string(id int16, s string) {
+	e.field(id, compactBinary)
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) bool(id int16, v bool) {
+	if v {
+		e.field(id, compactTrue)
+	} else {
+		e.field(id, compactFalse)
+	}
+}
+
+// structField starts a struct valued field, closed with structEnd
+func (e *encoder) structField(id int16) {
+	e.field(id, compactStruct)
+	e.structBegin()
+}
+
+// emptyStruct writes a struct valued field without fields, the members of a union
+// that carry no parameters
+func (e *encoder) emptyStruct(id int16) {
+	e.structField(id)
+	e.structEnd()
+}
+
+// list starts a list field of n elements of the given type
+func (e *encoder) list(id int16, elem byte, n int) {
+	e.field(id, compactList)
+	if n < 15 {
+		e.buf = append(e.buf, byte(n)<<4|elem)
+	} else {
+		e.buf = append(e.buf, 0xf0|elem)
+		e.buf = binary.AppendUvarint(e.buf, uint64(n))
+	}
+}
@@ -0,0 +1,384 @@
+// Package parquet writes Apache Parquet files with flat schemas of typed columns.
+//
+// Rows are buffered in memory and written as a row group, one gzip compressed data
+// page per column, every RowGroupSize rows, so the memory used does not grow with the
+// file. Values are PLAIN encoded and optional columns carry RLE definition levels,
+// which every Parquet reader supports.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// Type is the type of the values of a column
+type Type int
+
+const (
+	Int64     Type = iota // INT64
+	Double                // DOUBLE
+	String                // BYTE_ARRAY annotated as UTF-8 STRING
+	Timestamp             // INT64 annotated as TIMESTAMP(MICROS), adjusted to UTC
+)
+
+// Parquet physical types, repetitions, encodings and codecs used in the file metadata
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	pageData = 0
+)
+
+// magic starts and ends every Parquet file
+var magic = []byte("PAR1")
+
+// DefaultRowGroupSize is the number of rows per row group unless set otherwise
+const DefaultRowGroupSize = 32768
+
+// Column describes a column of the file. Metadata, such as the unit of the values,
+// is stored in the metadata of every chunk of the column.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+	Metadata map[string]string
+}
+
+func (c Column) physicalType() int32 {
+	switch c.Type {
+	case Double:
+		return typeDouble
+	case String:
+		return typeByteArray
+	default:
+		return typeInt64
+	}
+}
+
+// columnBuffer holds the values of a column in the current row group
+type columnBuffer struct {
+	values bytes.Buffer // PLAIN encoded values, without nulls
+	levels []byte       // Definition level of every row of an optional column
+	chunks []columnChunk
+}
+
+// columnChunk locates a written column chunk for the footer
+type columnChunk struct {
+	offset       int64
+	compressed   int64
+	uncompressed int64
+	values       int64
+}
+
+// Writer writes rows to a Parquet file
+type Writer struct {
+	// RowGroupSize is the number of rows buffered before a row group is written
+	RowGroupSize int
+
+	w        io.Writer
+	offset   int64
+	columns  []Column
+	buffers  []*columnBuffer
+	metadata map[string]string
+	rows     int   // Rows in the current row group
+	groups   []int // Rows of every written row group
+	err      error
+}
+
+// NewWriter starts a Parquet file on w with the given columns and file metadata
+func NewWriter(w io.Writer, columns []Column, metadata map[string]string) *Writer {
+	pw := &Writer{
+		RowGroupSize: DefaultRowGroupSize,
+		w:            w,
+		columns:      columns,
+		metadata:     metadata,
+	}
+	for range columns {
+		pw.buffers = append(pw.buffers, &columnBuffer{})
+	}
+	pw.write(magic)
+	return pw
+}
+
+// write appends b to the file, keeping the first error
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+}
+
+// Write adds a row with one value per column: int64 for Int64, float64 or *float64
+// for Double, string for String and time.Time for Timestamp. Optional columns also
+// accept nil.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(w.columns))
+	}
+
+	for i, value := range row {
+		if p, ok := value.(*float64); ok {
+			value = nil
+			if p != nil {
+				value = *p
+			}
+		}
+
+		column, buf := w.columns[i], w.buffers[i]
+		if value == nil {
+			if !column.Optional {
+				return fmt.Errorf("column %s is required", column.Name)
+			}
+			buf.levels = append(buf.levels, 0)
+			continue
+		}
+		if column.Optional {
+			buf.levels = append(buf.levels, 1)
+		}
+
+		var ok bool
+		switch column.Type {
+		case Int64:
+			var v int64
+			if v, ok = value.(int64); ok {
+				buf.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+			}
+		case Double:
+			var v float64
+			if v, ok = value.(float64); ok {
+				buf.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+			}
+		case String:
+			var v string
+			if v, ok = value.(string); ok {
+				buf.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+				buf.values.WriteString(v)
+			}
+		case Timestamp:
+			var v time.Time
+			if v, ok = value.(time.Time); ok {
+				buf.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMicro())))
+			}
+		}
+		if !ok {
+			return fmt.Errorf("column %s: unexpected value %T", column.Name, value)
+		}
+	}
+
+	w.rows++
+	if w.rows >= w.RowGroupSize {
+		w.flush()
+	}
+	return w.err
+}
+
+// flush writes the buffered rows as a row group
+func (w *Writer) flush() {
+	if w.rows == 0 || w.err != nil {
+		return
+	}
+
+	for i, buf := range w.buffers {
+		var page bytes.Buffer
+		if w.columns[i].Optional {
+			levels := encodeLevels(buf.levels)
+			page.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(levels))))
+			page.Write(levels)
+		}
+		page.Write(buf.values.Bytes())
+
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		zw.Write(page.Bytes())
+		if err := zw.Close(); err != nil {
+			w.err = fmt.Errorf("failed to compress page: %w", err)
+			return
+		}
+
+		e := &encoder{}
+		e.structBegin()
+		e.i32(1, pageData)
+		e.i32(2, int32(page.Len()))
+		e.i32(3, int32(compressed.Len()))
+		e.structField(5)
+		e.i32(1, int32(w.rows))
+		e.i32(2, encodingPlain)
+		e.i32(3, encodingRLE)
+		e.i32(4, encodingRLE)
+		e.structEnd()
+		e.structEnd()
+
+		buf.chunks = append(buf.chunks, columnChunk{
+			offset:       w.offset,
+			compressed:   int64(len(e.buf) + compressed.Len()),
+			uncompressed: int64(len(e.buf) + page.Len()),
+			values:       int64(w.rows),
+		})
+		w.write(e.buf)
+		w.write(compressed.Bytes())
+
+		buf.values.Reset()
+		buf.levels = buf.levels[:0]
+	}
+
+	w.groups = append(w.groups, w.rows)
+	w.rows = 0
+}
+
+// encodeLevels encodes definition levels of bit width 1 as RLE runs
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for start := 0; start < len(levels); {
+		end := start
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+		out = binary.AppendUvarint(out, uint64(end-start)<<1)
+		out = append(out, levels[start])
+		start = end
+	}
+	return out
+}
+
+// Close writes the remaining rows and the file footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	w.flush()
+	if w.err != nil {
+		return w.err
+	}
+
+	footer := w.footer()
+	w.write(footer)
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	w.write(magic)
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("parquet writer is closed")
+	return nil
+}
+
+// footer serializes the FileMetaData of the file
+func (w *Writer) footer() []byte {
+	e := &encoder{}
+	e.structBegin()
+	e.i32(1, 1)
+
+	e.list(2, compactStruct, len(w.columns)+1)
+	e.structBegin()
+	e.string(4, "schema")
+	e.i32(5, int32(len(w.columns)))
+	e.structEnd()
+	for _, column := range w.columns {
+		e.structBegin()
+		e.i32(1, column.physicalType())
+		if column.Optional {
+			e.i32(3, repetitionOptional)
+		} else {
+			e.i32(3, repetitionRequired)
+		}
+		e.string(4, column.Name)
+		switch column.Type {
+		case String:
+			e.i32(6, convertedUTF8)
+			e.structField(10)
+			e.emptyStruct(1) // STRING
+			e.structEnd()
+		case Timestamp:
+			e.i32(6, convertedTimestampMicros)
+			e.structField(10)
+			e.structField(8) // TIMESTAMP
+			e.bool(1, true)  // isAdjustedToUTC
+			e.structField(2)
+			e.emptyStruct(2) // MICROS
+			e.structEnd()
+			e.structEnd()
+			e.structEnd()
+		}
+		e.structEnd()
+	}
+
+	var rows int64
+	for _, n := range w.groups {
+		rows += int64(n)
+	}
+	e.i64(3, rows)
+
+	e.list(4, compactStruct, len(w.groups))
+	for g, n := range w.groups {
+		var size int64
+		e.structBegin()
+		e.list(1, compactStruct, len(w.columns))
+		for i, column := range w.columns {
+			chunk := w.buffers[i].chunks[g]
+			size += chunk.uncompressed
+
+			e.structBegin()
+			e.i64(2, chunk.offset)
+			e.structField(3)
+			e.i32(1, column.physicalType())
+			e.list(2, compactI32, 2)
+			e.varint(encodingPlain)
+			e.varint(encodingRLE)
+			e.list(3, compactBinary, 1)
+			e.bytes([]byte(column.Name))
+			e.i32(4, codecGzip)
+			e.i64(5, chunk.values)
+			e.i64(6, chunk.uncompressed)
+			e.i64(7, chunk.compressed)
+			if len(column.Metadata) > 0 {
+				writeKeyValues(e, 8, column.Metadata)
+			}
+			e.i64(9, chunk.offset)
+			e.structEnd()
+			e.structEnd()
+		}
+		e.i64(2, size)
+		e.i64(3, int64(n))
+		e.structEnd()
+	}
+
+	if len(w.metadata) > 0 {
+		writeKeyValues(e, 5, w.metadata)
+	}
+	e.string(6, "atmosbyte")
+	e.structEnd()
+	return e.buf
+}
+
+// writeKeyValues writes a list<KeyValue> field sorted by key
+func writeKeyValues(e *encoder, id int16, kv map[string]string) {
+	e.list(id, compactStruct, len(kv))
+	for _, key := range slices.Sorted(maps.Keys(kv)) {
+		e.structBegin()
+		e.string(1, key)
+		e.string(2, kv[key])
+		e.structEnd()
+	}
+}
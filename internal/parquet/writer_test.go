@@ -0,0 +1,202 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// decoder reads the Thrift compact protocol into maps of field id to value, enough
+// to check what encoder wrote
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) value(typ byte) any {
+	switch typ {
+	case compactTrue:
+		return true
+	case compactFalse:
+		return false
+	case compactI32, compactI64:
+		v := d.uvarint()
+		return int64(v>>1) ^ -int64(v&1)
+	case compactBinary:
+		n := d.uvarint()
+		s := string(d.buf[:n])
+		d.buf = d.buf[n:]
+		return s
+	case compactList:
+		header := d.buf[0]
+		d.buf = d.buf[1:]
+		n, elem := int(header>>4), header&0x0f
+		if n == 15 {
+			n = int(d.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = d.value(elem)
+		}
+		return list
+	case compactStruct:
+		fields := make(map[int16]any)
+		var id int16
+		for {
+			header := d.buf[0]
+			d.buf = d.buf[1:]
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				v := d.uvarint()
+				id = int16(int64(v>>1) ^ -int64(v&1))
+			}
+			fields[id] = d.value(header & 0x0f)
+		}
+	}
+	panic("unexpected type")
+}
+
+func writeFile(t *testing.T, rowGroupSize int, columns []Column, rows [][]any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf, columns, map[string]string{"source": "test"})
+	w.RowGroupSize = rowGroupSize
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readFooter(t *testing.T, file []byte) map[int16]any {
+	t.Helper()
+	if !bytes.HasPrefix(file, magic) || !bytes.HasSuffix(file, magic) {
+		t.Fatal("missing magic")
+	}
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	d := &decoder{buf: file[len(file)-8-size : len(file)-8]}
+	footer := d.value(compactStruct).(map[int16]any)
+	if len(d.buf) != 0 {
+		t.Fatalf("%d bytes left after the footer", len(d.buf))
+	}
+	return footer
+}
+
+func TestWriter_Footer(t *testing.T) {
+	columns := []Column{
+		{Name: "timestamp", Type: Timestamp},
+		{Name: "node", Type: String},
+		{Name: "temperature", Type: Double, Optional: true, Metadata: map[string]string{"unit": "C"}},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var rows [][]any
+	for i := range 5 {
+		rows = append(rows, []any{start.Add(time.Duration(i) * time.Minute), "base", float64(i)})
+	}
+
+	footer := readFooter(t, writeFile(t, 2, columns, rows))
+
+	if footer[3] != int64(5) {
+		t.Errorf("expected 5 rows, got %v", footer[3])
+	}
+	if footer[6] != "atmosbyte" {
+		t.Errorf("unexpected created_by %v", footer[6])
+	}
+
+	schema := footer[2].([]any)
+	if len(schema) != 4 || schema[0].(map[int16]any)[5] != int64(3) {
+		t.Fatalf("unexpected schema %v", schema)
+	}
+	timestamp := schema[1].(map[int16]any)
+	micros := timestamp[10].(map[int16]any)[8].(map[int16]any)
+	if timestamp[1] != int64(typeInt64) || timestamp[6] != int64(convertedTimestampMicros) || micros[1] != true {
+		t.Errorf("unexpected timestamp column %v", timestamp)
+	}
+	if temperature := schema[3].(map[int16]any); temperature[3] != int64(repetitionOptional) || temperature[1] != int64(typeDouble) {
+		t.Errorf("unexpected temperature column %v", temperature)
+	}
+
+	groups := footer[4].([]any)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 row groups, got %d", len(groups))
+	}
+	last := groups[2].(map[int16]any)
+	chunk := last[1].([]any)[2].(map[int16]any)[3].(map[int16]any)
+	if last[3] != int64(1) || chunk[5] != int64(1) {
+		t.Errorf("expected one row in the last group, got %v", last)
+	}
+	if unit := chunk[8].([]any)[0].(map[int16]any); unit[1] != "unit" || unit[2] != "C" {
+		t.Errorf("unexpected column metadata %v", unit)
+	}
+	if kv := footer[5].([]any)[0].(map[int16]any); kv[1] != "source" || kv[2] != "test" {
+		t.Errorf("unexpected file metadata %v", kv)
+	}
+}
+
+func TestWriter_Page(t *testing.T) {
+	pressure := 1013.25
+	file := writeFile(t, DefaultRowGroupSize, []Column{{Name: "pressure", Type: Double, Optional: true}}, [][]any{{&pressure}, {nil}, {nil}})
+
+	d := &decoder{buf: file[len(magic):]}
+	header := d.value(compactStruct).(map[int16]any)
+	if header[1] != int64(pageData) || header[5].(map[int16]any)[1] != int64(3) {
+		t.Fatalf("unexpected page header %v", header)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(d.buf[:header[3].(int64)]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := binary.LittleEndian.AppendUint32(nil, 4)
+	want = append(want, 2, 1, 4, 0)
+	want = binary.LittleEndian.AppendUint64(want, math.Float64bits(pressure))
+	if !bytes.Equal(page, want) {
+		t.Errorf("expected page %x, got %x", want, page)
+	}
+}
+
+func TestWriter_RejectsInvalidRows(t *testing.T) {
+	w := NewWriter(io.Discard, []Column{{Name: "id", Type: Int64}}, nil)
+	for _, row := range [][]any{{}, {nil}, {1.5}} {
+		if err := w.Write(row); err == nil {
+			t.Errorf("expected an error for %v", row)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	e := &encoder{}
+	e.structBegin()
+	e.i32(1, 1)
+	e.i64(20, -2)
+	e.list(21, compactI32, 1)
+	e.varint(3)
+	e.structEnd()
+
+	want := []byte{0x15, 0x02, 0x06, 0x28, 0x03, 0x19, 0x15, 0x06, 0x00}
+	if !bytes.Equal(e.buf, want) {
+		t.Errorf("expected %x, got %x", want, e.buf)
+	}
+}
@@ -0,0 +1,259 @@
+// Package xlsx writes Office Open XML spreadsheets (.xlsx) with a header row and
+// typed cells.
+//
+// Rows are written to the zip archive as they arrive. A sheet holds at most MaxRows
+// rows, the limit of Excel; further rows continue on a new sheet named after the
+// first with a number, e.g. "data (2)".
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxRows is the number of rows of an Excel worksheet, including the header
+const MaxRows = 1048576
+
+// Cell styles defined in styles.xml
+const (
+	styleHeader   = 1
+	styleDateTime = 2
+)
+
+// Column describes a column of the sheet. Width is in characters; zero fits the name.
+type Column struct {
+	Name  string
+	Width float64
+}
+
+// Writer writes rows to a workbook
+type Writer struct {
+	zw      *zip.Writer
+	sheet   *bufio.Writer
+	name    string
+	columns []Column
+	sheets  int
+	rows    int // Rows in the current sheet, including the header
+	maxRows int
+	err     error
+}
+
+// NewWriter starts a workbook on w whose sheets are named after sheet
+func NewWriter(w io.Writer, sheet string, columns []Column) (*Writer, error) {
+	xw := &Writer{zw: zip.NewWriter(w), name: sheet, columns: columns, maxRows: MaxRows}
+	if err := xw.nextSheet(); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+// nextSheet ends the current sheet and starts another with the header row
+func (w *Writer) nextSheet() error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+
+	w.sheets++
+	f, err := w.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", w.sheets))
+	if err != nil {
+		return fmt.Errorf("failed to create sheet: %w", err)
+	}
+	w.sheet = bufio.NewWriter(f)
+	w.rows = 0
+
+	w.sheet.WriteString(xml.Header)
+	w.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	w.sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	w.sheet.WriteString(`<cols>`)
+	for i, column := range w.columns {
+		width := column.Width
+		if width == 0 {
+			width = max(float64(utf8.RuneCountInString(column.Name))+2, 10)
+		}
+		fmt.Fprintf(w.sheet, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	w.sheet.WriteString(`</cols><sheetData>`)
+
+	header := make([]any, len(w.columns))
+	for i, column := range w.columns {
+		header[i] = column.Name
+	}
+	return w.writeRow(header, styleHeader)
+}
+
+// endSheet closes the XML of the current sheet
+func (w *Writer) endSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+	w.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := w.sheet.Flush(); err != nil {
+		return fmt.Errorf("failed to write sheet: %w", err)
+	}
+	w.sheet = nil
+	return nil
+}
+
+// Write adds a row. Values may be numbers, strings, time.Time, written as a UTC
+// date and time, *float64 or nil, written as an empty cell.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(w.columns))
+	}
+
+	if w.rows == w.maxRows {
+		if w.err = w.nextSheet(); w.err != nil {
+			return w.err
+		}
+	}
+	w.err = w.writeRow(row, 0)
+	return w.err
+}
+
+// writeRow writes a row of cells, using style for text cells
+func (w *Writer) writeRow(row []any, style int) error {
+	w.rows++
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows)
+	for i, value := range row {
+		if p, ok := value.(*float64); ok {
+			value = nil
+			if p != nil {
+				value = *p
+			}
+		}
+
+		ref := columnName(i) + strconv.Itoa(w.rows)
+		switch v := value.(type) {
+		case nil:
+		case string:
+			fmt.Fprintf(w.sheet, `<c r="%s" t="inlineStr"`, ref)
+			if style != 0 {
+				fmt.Fprintf(w.sheet, ` s="%d"`, style)
+			}
+			w.sheet.WriteString(`><is><t>`)
+			xml.EscapeText(w.sheet, []byte(v))
+			w.sheet.WriteString(`</t></is></c>`)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			fmt.Fprintf(w.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case int64:
+			fmt.Fprintf(w.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case int:
+			fmt.Fprintf(w.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case time.Time:
+			fmt.Fprintf(w.sheet, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDateTime, strconv.FormatFloat(serial(v), 'f', -1, 64))
+		default:
+			return fmt.Errorf("column %s: unexpected value %T", w.columns[i].Name, value)
+		}
+	}
+	w.sheet.WriteString(`</row>`)
+	return nil
+}
+
+// serial converts t to an Excel date serial number in UTC, rounded to the millisecond
+func serial(t time.Time) float64 {
+	const epoch = 25569 // 1970-01-01 in days since 1899-12-30
+	return epoch + float64(t.UnixMilli())/float64(24*time.Hour/time.Millisecond)
+}
+
+// columnName returns the letters of the zero based column i, e.g. A, Z, AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// Close writes the workbook parts and finishes the archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("xlsx writer is closed")
+
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+
+	var sheets, sheetRels, sheetTypes strings.Builder
+	for i := 1; i <= w.sheets; i++ {
+		name := w.name
+		if i > 1 {
+			name = fmt.Sprintf("%s (%d)", w.name, i)
+		}
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i, i)
+		fmt.Fprintf(&sheetRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+		fmt.Fprintf(&sheetTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			sheetTypes.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			sheetRels.String() +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, w.sheets+1) +
+			`</Relationships>`},
+		{"xl/styles.xml", styles},
+	}
+	for _, part := range parts {
+		f, err := w.zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", part.name, err)
+		}
+		if _, err := io.WriteString(f, xml.Header+part.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	if err := w.zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish workbook: %w", err)
+	}
+	return nil
+}
+
+// escape escapes s for an XML attribute
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// styles defines the default cell style, a bold header and a date and time format
+const styles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
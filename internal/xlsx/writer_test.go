@@ -0,0 +1,124 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readParts(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid archive: %v", err)
+	}
+
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "data", []Column{{Name: "timestamp", Width: 20}, {Name: "node"}, {Name: "temp (°C)"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pressure := 21.5
+	rows := [][]any{
+		{time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), "a<b", &pressure},
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "", (*float64)(nil)},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	parts := readParts(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr" s="1"><is><t>timestamp</t></is></c>`,
+		`<c r="C1" t="inlineStr" s="1"><is><t>temp (°C)</t></is></c>`,
+		`<c r="A2" s="2"><v>45292.5</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t>a&lt;b</t></is></c>`,
+		`<c r="C2"><v>21.5</v></c>`,
+		`<row r="3"><c r="A3" s="2"><v>45293</v></c><c r="B3" t="inlineStr"><is><t></t></is></c></row>`,
+		`state="frozen"`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet lacks %s:\n%s", want, sheet)
+		}
+	}
+}
+
+func TestWriter_ContinuesOnNewSheet(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "data", []Column{{Name: "value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxRows = 3
+
+	for i := range 5 {
+		if err := w.Write([]any{int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	parts := readParts(t, buf.Bytes())
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="data (3)" sheetId="3" r:id="rId3"/>`) {
+		t.Errorf("expected three sheets, got %s", parts["xl/workbook.xml"])
+	}
+	if sheet := parts["xl/worksheets/sheet3.xml"]; !strings.Contains(sheet, `<t>value</t>`) || !strings.Contains(sheet, `<c r="A2"><v>4</v></c>`) {
+		t.Errorf("expected the header and the last row on the third sheet, got %s", sheet)
+	}
+}
+
+func TestWriter_RejectsInvalidRows(t *testing.T) {
+	w, err := NewWriter(io.Discard, "data", []Column{{Name: "value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]any{1.0, 2.0}); err == nil {
+		t.Error("expected an error for a row with too many values")
+	}
+	if err := w.Write([]any{struct{}{}}); err == nil {
+		t.Error("expected an error for an unexpected value")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
@@ -1,21 +1,30 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/anibaldeboni/zero-paper/atmosbyte/alert"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/anomaly"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/backup"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/config"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/email"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/records"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/web"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/webhook"
 )
 
 type SensorSetup struct {
@@ -72,6 +81,12 @@ func main() {
 	var showVersionShort = flag.Bool("v", false, "Show version information (short)")
 	var configPath = flag.String("config", "", "Path to configuration file")
 	var generateConfig = flag.Bool("generate-config", false, "Generate example configuration file")
+	var hashPassword = flag.Bool("hash-password", false, "Read a password from stdin and print its bcrypt hash for auth.users")
+	var checkConfig = flag.Bool("check-config", false, "Validate the configuration file and exit, non-zero on errors")
+	var printConfig = flag.Bool("print-config", false, "Print the effective configuration with the source of each value and exit")
+	var profile = flag.String("profile", "", "Configuration profile to apply, e.g. dev or prod (default $"+config.ProfileEnv+")")
+	var overrides config.Overrides
+	flag.Var(&overrides, "set", "Override a configuration field, e.g. -set web.port=9090 (repeatable)")
 	flag.Parse()
 
 	if *showVersion || *showVersionShort {
@@ -81,19 +96,71 @@ func main() {
 
 	if *generateConfig {
 		if err := config.GenerateExampleConfig("atmosbyte.yaml.example"); err != nil {
-			log.Fatalf("Failed to generate config file: %v", err)
+			logging.Fatalf("Failed to generate config file: %v", err)
 		}
 		fmt.Println("Example configuration file generated: atmosbyte.yaml.example")
 		return
 	}
 
+	if *hashPassword {
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			logging.Fatalf("Failed to read password: %v", err)
+		}
+		hash, err := web.HashPassword(strings.TrimRight(password, "\r\n"))
+		if err != nil {
+			logging.Fatalf("Failed to hash password: %v", err)
+		}
+		fmt.Println(hash)
+		return
+	}
+
 	// Carrega configuração
-	cfg, err := config.Load(*configPath)
+	loader := config.NewLoader(*configPath, config.WithProfile(*profile), config.WithOverrides(overrides))
+	cfg, err := loader.Load()
+	if *printConfig {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := loader.PrintEffective(os.Stdout); err != nil {
+			logging.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+	if *checkConfig {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if loader.Path() == "" {
+			fmt.Println("No configuration file found, the defaults are valid")
+		} else {
+			fmt.Printf("Configuration file %s is valid\n", loader.Path())
+		}
+		return
+	}
 	if err != nil {
-		log.Printf("Warning: Failed to load config file, using defaults: %v", err)
-		cfg, _ = config.Load("") // Force defaults
+		logging.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loader.Path() == "" {
+		log.Printf("No configuration file found, using defaults")
+	}
+	if loader.Profile() != "" {
+		log.Printf("Using configuration profile %s", loader.Profile())
+	}
+
+	// Subcomandos, como backup e restore, rodam sem iniciar o serviço
+	if flag.NArg() > 0 {
+		if err := runCommand(cfg, flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
+	logging.SetLevel(cfg.LogLevel())
+
 	buildInfo := GetBuildInfo()
 	log.Printf("Starting Atmosbyte %s %s %s", buildInfo.Version, buildInfo.Date, buildInfo.GoVersion)
 	log.Printf("Configuration loaded successfully")
@@ -101,38 +168,247 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	repo, err := repository.NewSQLiteRepository("weather.db")
+	repo, err := repository.OpenSQLiteRepository(cfg.StorageConfig())
 	if err != nil {
-		log.Fatalf("Failed to create repository: %v", err)
+		logging.Fatalf("Failed to create repository: %v", err)
 	}
 	defer repo.Close()
+	log.Printf("Using database %s (journal mode %s, synchronous %s)", cfg.Storage.Path, cfg.Storage.JournalMode, cfg.Storage.Synchronous)
+
+	// Os handlers web leem por um pool somente leitura quando a réplica está habilitada,
+	// aberto depois do principal, que cria o banco
+	reader := repo
+	if cfg.Storage.ReadReplica.Enabled {
+		reader, err = repository.OpenSQLiteRepository(cfg.ReadReplicaConfig())
+		if err != nil {
+			logging.Fatalf("Failed to open read replica: %v", err)
+		}
+		defer reader.Close()
+		log.Printf("Web handlers reading from %s", cfg.ReadReplicaConfig().Path)
+	}
 
-	worker := NewRepositoryWorker(repo)
+	// Recordes são reconstruídos do histórico e atualizados a cada medição salva
+	tracker := records.NewTracker(repo)
+	if err := tracker.Load(); err != nil {
+		logging.Warnf("Failed to load weather records: %v", err)
+	}
 
-	q := queue.NewQueue(ctx, worker, cfg.QueueConfig())
+	worker := NewRepositoryWorker(tracker)
+	queueConfig := cfg.QueueConfig()
+
+	// O dispatcher é criado antes da fila principal para receber as mudanças do seu circuit breaker
+	var dispatcher *webhook.Dispatcher
+	if cfg.Webhooks.Enabled {
+		webhookConfig, err := cfg.WebhookConfig()
+		if err != nil {
+			logging.Fatalf("Invalid webhooks configuration: %v", err)
+		}
+		dispatcher, err = webhook.NewDispatcher(ctx, repo, webhookConfig)
+		if err != nil {
+			logging.Fatalf("Invalid webhooks configuration: %v", err)
+		}
+		queueConfig.CircuitBreakerConfig.OnStateChange = dispatcher.CircuitStateChanged("storage")
+	}
+
+	q := queue.NewQueue(ctx, worker, queueConfig)
 
 	sensor, err := createSensorSetup(cfg, q)
 	if err != nil {
-		log.Fatalf("Failed to setup sensor: %v", err)
+		logging.Fatalf("Failed to setup sensor: %v", err)
 	}
 	defer func() {
 		if err := sensor.cleanup(); err != nil {
-			log.Printf("Error during sensor cleanup: %v", err)
+			logging.Errorf("Error during sensor cleanup: %v", err)
 		}
 	}()
 
-	webServer := web.NewServer(ctx, sensor.dev, cfg.WebConfig(), q, repo)
+	webOptions := []web.Option{web.WithRecords(tracker)}
+
+	// Snapshots sob demanda pela API sempre ficam disponíveis; os diários só com backup.enabled
+	backups, err := backup.NewManager(repo, cfg.BackupConfig())
+	if err != nil {
+		logging.Fatalf("Invalid backup configuration: %v", err)
+	}
+	webOptions = append(webOptions, web.WithBackups(backups), web.WithImport(repo), web.WithRawData(reader))
+
+	var detector *anomaly.Detector
+	var detectorStream <-chan bme280.Measurement
+	if cfg.Anomaly.Enabled {
+		if _, err := anomaly.ParseMethod(cfg.Anomaly.Method); err != nil {
+			logging.Fatalf("Invalid anomaly configuration: %v", err)
+		}
+		detector = anomaly.NewDetector(repo, cfg.AnomalyConfig())
+		detectorStream = sensor.reader.Subscribe(cfg.Queue.BufferSize)
+		webOptions = append(webOptions, web.WithAnomalies(reader))
+	}
+
+	var webhookStream <-chan bme280.Measurement
+	var circuitHook func(source string) func(from, to queue.CircuitBreakerState)
+	if dispatcher != nil {
+		sensor.reader.OnStatusChange(dispatcher.SensorStatusChanged)
+		webhookStream = sensor.reader.Subscribe(cfg.Queue.BufferSize)
+		circuitHook = dispatcher.CircuitStateChanged
+		webOptions = append(webOptions, web.WithWebhooks(reader))
+	}
+
+	var notifier *email.Notifier
+	if cfg.Email.Enabled {
+		notifier, err = email.NewNotifier(ctx, repo, cfg.EmailConfig())
+		if err != nil {
+			logging.Fatalf("Invalid email configuration: %v", err)
+		}
+	}
+
+	var alerts *alert.Engine
+	var alertStream <-chan bme280.Measurement
+	if cfg.Alerts.Enabled {
+		alertConfig, err := cfg.AlertConfig()
+		if err != nil {
+			logging.Fatalf("Invalid alerts configuration: %v", err)
+		}
+		channels := []alert.Channel{alert.LogChannel{}}
+		if dispatcher != nil {
+			channels = append(channels, alert.NewWebhookChannel(dispatcher))
+		}
+		if notifier != nil {
+			channels = append(channels, notifier)
+		}
+		alerts, err = alert.NewEngine(repo, alertConfig, channels...)
+		if err != nil {
+			logging.Fatalf("Invalid alerts configuration: %v", err)
+		}
+		alertStream = sensor.reader.Subscribe(cfg.Queue.BufferSize)
+		webOptions = append(webOptions, web.WithAlerts(alerts))
+	}
+
+	if cfg.Auth.Enabled {
+		auth, err := web.NewAuthenticator(cfg.AuthConfig())
+		if err != nil {
+			logging.Fatalf("Invalid auth configuration: %v", err)
+		}
+		webOptions = append(webOptions, web.WithAuth(auth))
+	}
+
+	if cfg.Ingest.Enabled {
+		if !cfg.Auth.Enabled {
+			logging.Fatalf("Invalid ingest configuration: auth must be enabled to accept remote measurements")
+		}
+		webOptions = append(webOptions, web.WithIngest(q))
+	}
+
+	if cfg.StationUpload.Enabled {
+		stations := cfg.StationConfig()
+		if err := stations.Validate(); err != nil {
+			logging.Fatalf("Invalid station upload configuration: %v", err)
+		}
+		webOptions = append(webOptions, web.WithStations(q, stations))
+	}
+
+	uploaders, err := cfg.UploadWorkers()
+	if err != nil {
+		logging.Fatalf("Invalid uploaders configuration: %v", err)
+	}
+	var uploadWorkers []exportWorker
+	for _, worker := range uploaders {
+		uploadWorkers = append(uploadWorkers, worker)
+	}
+
+	if cfg.MQTT.Enabled {
+		mqttWorker, err := cfg.MQTTWorker(buildInfo.Version)
+		if err != nil {
+			logging.Fatalf("Invalid mqtt configuration: %v", err)
+		}
+		uploadWorkers = append(uploadWorkers, mqttWorker)
+	}
+
+	sinks, err := cfg.SinkWorkers()
+	if err != nil {
+		logging.Fatalf("Invalid sinks configuration: %v", err)
+	}
+	for _, sink := range sinks {
+		uploadWorkers = append(uploadWorkers, sink)
+	}
+
+	// Mudanças seguras da configuração são aplicadas sem reiniciar; as demais são reportadas
+	watcher := config.NewWatcher(loader, func(newCfg *config.AppConfig, changes []config.Change) {
+		if config.LiveChanged(changes, "log.level") {
+			logging.SetLevel(newCfg.LogLevel())
+		}
+		if config.LiveChanged(changes, "sensor.read_interval") {
+			sensor.reader.SetInterval(newCfg.Sensor.ReadInterval)
+		}
+		if config.LiveChanged(changes, "queue") {
+			q.Reconfigure(newCfg.QueueConfig())
+		}
+		if simSensor, ok := sensor.dev.(*bme280.SimulatedSensor); ok && config.LiveChanged(changes, "sensor.simulation") {
+			simSensor.SetConfig(newCfg.SimulatedConfig())
+		}
+	})
+	webOptions = append(webOptions, web.WithReloadStatus(watcher))
+
+	webServer := web.NewServer(ctx, sensor.dev, cfg.WebConfig(), q, reader, webOptions...)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	var wg sync.WaitGroup
 
+	startUploaders(ctx, uploadWorkers, cfg.UploaderQueueConfig(), circuitHook, sensor.reader, &wg)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := watcher.Start(ctx, cfg.Reload.Interval); err != nil && err != context.Canceled {
+			logging.Errorf("Configuration watcher error: %v", err)
+		}
+	}()
+
+	if dispatcher != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dispatcher.Start(webhookStream); err != nil && err != context.Canceled {
+				logging.Errorf("Webhook dispatcher error: %v", err)
+			}
+		}()
+	}
+
+	if cfg.Backup.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Printf("Daily backups at %s in %s", cfg.Backup.Time, cfg.Backup.Dir)
+			if err := backups.Start(ctx); err != nil && err != context.Canceled {
+				logging.Errorf("Backup scheduler error: %v", err)
+			}
+		}()
+	}
+
+	if notifier != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := notifier.Start(); err != nil && err != context.Canceled {
+				logging.Errorf("Email notifier error: %v", err)
+			}
+		}()
+	}
+
+	if alerts != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := alerts.Start(ctx, alertStream); err != nil && err != context.Canceled {
+				logging.Errorf("Alert engine error: %v", err)
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		if err := q.Start(); err != nil && err != context.Canceled {
-			log.Printf("Queue error: %v", err)
+			logging.Errorf("Queue error: %v", err)
 		}
 	}()
 
@@ -140,15 +416,25 @@ func main() {
 	go func() {
 		defer wg.Done()
 		if err := sensor.reader.Start(ctx); err != nil && err != context.Canceled {
-			log.Printf("Sensor worker error: %v", err)
+			logging.Errorf("Sensor worker error: %v", err)
 		}
 	}()
 
+	if detector != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := detector.Start(ctx, detectorStream); err != nil && err != context.Canceled {
+				logging.Errorf("Anomaly detector error: %v", err)
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		if err := webServer.Start(); err != nil && err != context.Canceled {
-			log.Printf("Web server error: %v", err)
+			logging.Errorf("Web server error: %v", err)
 		}
 	}()
 
@@ -0,0 +1,225 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker. It accepts CONNECT,
+// acknowledges QoS 1 publishes, answers PINGREQ, keeps retained messages and
+// publishes the will of connections closed without DISCONNECT.
+type testBroker struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu         sync.Mutex
+	published  []Message
+	retained   map[string]Message
+	connects   []connectInfo
+	conns      []net.Conn
+	returnCode byte
+	changed    chan struct{}
+}
+
+type connectInfo struct {
+	clientID  string
+	username  string
+	password  string
+	keepAlive uint16
+	will      *Message
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testBroker{
+		t:        t,
+		listener: listener,
+		retained: make(map[string]Message),
+		changed:  make(chan struct{}, 100),
+	}
+	t.Cleanup(b.Close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+
+	return b
+}
+
+func (b *testBroker) Addr() string {
+	return b.listener.Addr().String()
+}
+
+func (b *testBroker) Close() {
+	b.listener.Close()
+	b.DropConnections()
+}
+
+// SetReturnCode sets the CONNACK return code sent to new connections
+func (b *testBroker) SetReturnCode(code byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.returnCode = code
+}
+
+// DropConnections closes every client connection, simulating a broker restart
+func (b *testBroker) DropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	p, err := readPacket(reader)
+	if err != nil || p.kind != packetConnect {
+		return
+	}
+	info := parseConnect(b.t, p.body)
+
+	b.mu.Lock()
+	b.connects = append(b.connects, info)
+	code := b.returnCode
+	b.mu.Unlock()
+
+	writePacket(conn, packetConnack, 0, []byte{0, code})
+	if code != 0 {
+		return
+	}
+
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			if info.will != nil {
+				b.store(*info.will)
+			}
+			return
+		}
+
+		switch p.kind {
+		case packetPublish:
+			qos := (p.flags >> 1) & 0x03
+			topic, rest, err := readString(p.body)
+			if err != nil {
+				b.t.Errorf("malformed PUBLISH: %v", err)
+				return
+			}
+			if qos > 0 {
+				writePacket(conn, packetPuback, 0, rest[:2])
+				rest = rest[2:]
+			}
+			b.store(Message{Topic: topic, Payload: rest, QoS: qos, Retain: p.flags&0x01 == 1})
+		case packetPingreq:
+			writePacket(conn, packetPingresp, 0, nil)
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func (b *testBroker) store(msg Message) {
+	b.mu.Lock()
+	b.published = append(b.published, msg)
+	if msg.Retain {
+		b.retained[msg.Topic] = msg
+	}
+	b.mu.Unlock()
+
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Retained returns the retained payload of topic
+func (b *testBroker) Retained(topic string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg, ok := b.retained[topic]
+	return string(msg.Payload), ok
+}
+
+// Published returns the messages published on topic
+func (b *testBroker) Published(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var messages []Message
+	for _, msg := range b.published {
+		if msg.Topic == topic {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// Connects returns the CONNECT packets received
+func (b *testBroker) Connects() []connectInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]connectInfo(nil), b.connects...)
+}
+
+// WaitFor waits until cond holds or fails the test after a timeout
+func (b *testBroker) WaitFor(cond func() bool) {
+	b.t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for !cond() {
+		select {
+		case <-b.changed:
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			b.t.Fatal("timed out waiting for the broker")
+		}
+	}
+}
+
+func parseConnect(t *testing.T, body []byte) connectInfo {
+	protocol, rest, err := readString(body)
+	if err != nil || protocol != "MQTT" || rest[0] != 4 {
+		t.Errorf("unexpected protocol %q level %d", protocol, rest[0])
+	}
+	flags := rest[1]
+	info := connectInfo{keepAlive: binary.BigEndian.Uint16(rest[2:4])}
+	rest = rest[4:]
+
+	info.clientID, rest, _ = readString(rest)
+	if flags&connectWill != 0 {
+		var topic, payload string
+		topic, rest, _ = readString(rest)
+		payload, rest, _ = readString(rest)
+		info.will = &Message{Topic: topic, Payload: []byte(payload), QoS: (flags >> 3) & 0x03, Retain: flags&connectWillRetain != 0}
+	}
+	if flags&connectUsername != 0 {
+		info.username, rest, _ = readString(rest)
+	}
+	if flags&connectPassword != 0 {
+		info.password, _, _ = readString(rest)
+	}
+
+	return info
+}
@@ -0,0 +1,281 @@
+// Package mqtt implements a minimal MQTT 3.1.1 client and a publisher that sends
+// measurements to a broker with Home Assistant discovery.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when the connection to the broker was lost or closed
+var ErrClosed = errors.New("mqtt connection closed")
+
+// Message is an application message
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte // 0 or 1
+	Retain  bool
+}
+
+// Options configures the connection to the broker
+type Options struct {
+	Address   string // host:port
+	TLS       *tls.Config
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // Interval of PINGREQ packets, 0 disables keep alive
+	Will      *Message      // Published by the broker when the connection is lost
+}
+
+// Client is a connection to an MQTT broker. It supports publishing with QoS 0 and 1.
+type Client struct {
+	conn net.Conn
+
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	packetID uint16
+	acks     map[uint16]chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// Connect opens a connection and waits for the broker to accept it
+func Connect(ctx context.Context, opts Options) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", opts.Address, err)
+	}
+	if opts.TLS != nil {
+		tlsConn := tls.Client(conn, opts.TLS)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed TLS handshake with %s: %w", opts.Address, err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader := bufio.NewReader(conn)
+	if err := handshake(conn, reader, opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{
+		conn: conn,
+		acks: make(map[uint16]chan struct{}),
+		done: make(chan struct{}),
+	}
+	go c.readLoop(reader)
+	if opts.KeepAlive > 0 {
+		go c.keepAlive(opts.KeepAlive)
+	}
+
+	return c, nil
+}
+
+// handshake sends CONNECT and reads CONNACK
+func handshake(conn net.Conn, reader *bufio.Reader, opts Options) error {
+	flags := connectCleanSession
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // Protocol level of MQTT 3.1.1
+
+	payload := appendString(nil, opts.ClientID)
+	if opts.Will != nil {
+		flags |= connectWill | opts.Will.QoS<<3
+		if opts.Will.Retain {
+			flags |= connectWillRetain
+		}
+		payload = appendString(payload, opts.Will.Topic)
+		payload = appendBytes(payload, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		flags |= connectUsername
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= connectPassword
+			payload = appendString(payload, opts.Password)
+		}
+	}
+
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = append(body, payload...)
+
+	if err := writePacket(conn, packetConnect, 0, body); err != nil {
+		return fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	p, err := readPacket(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if p.kind != packetConnack || len(p.body) != 2 {
+		return fmt.Errorf("expected CONNACK, got packet type %d", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return fmt.Errorf("connection refused: %s", reason)
+	}
+
+	return nil
+}
+
+// readLoop handles the packets sent by the broker until the connection fails
+func (c *Client) readLoop(reader *bufio.Reader) {
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			c.close(err)
+			return
+		}
+
+		if p.kind == packetPuback && len(p.body) == 2 {
+			id := binary.BigEndian.Uint16(p.body)
+			c.mu.Lock()
+			if ack, ok := c.acks[id]; ok {
+				close(ack)
+				delete(c.acks, id)
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// keepAlive sends PINGREQ packets so the broker does not drop an idle connection
+func (c *Client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(packetPingreq, 0, nil); err != nil {
+				c.close(err)
+				return
+			}
+		}
+	}
+}
+
+// write sends a packet, serializing concurrent writers
+func (c *Client) write(kind, flags byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writePacket(c.conn, kind, flags, body)
+}
+
+// Publish sends msg. With QoS 1 it waits for the broker acknowledgement.
+func (c *Client) Publish(ctx context.Context, msg Message) error {
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+
+	if msg.QoS > 1 {
+		return fmt.Errorf("unsupported QoS %d", msg.QoS)
+	}
+
+	flags := msg.QoS << 1
+	if msg.Retain {
+		flags |= 0x01
+	}
+	body := appendString(nil, msg.Topic)
+
+	var ack chan struct{}
+	var id uint16
+	if msg.QoS == 1 {
+		ack = make(chan struct{})
+		c.mu.Lock()
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID = 1
+		}
+		id = c.packetID
+		c.acks[id] = ack
+		c.mu.Unlock()
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, msg.Payload...)
+
+	if err := c.write(packetPublish, flags, body); err != nil {
+		c.close(err)
+		return c.Err()
+	}
+
+	if ack == nil {
+		return nil
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.acks, id)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Done is closed when the connection is lost or closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection was closed
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Disconnect sends DISCONNECT, so the broker discards the will, and closes the connection
+func (c *Client) Disconnect() error {
+	err := c.write(packetDisconnect, 0, nil)
+	c.close(ErrClosed)
+	return err
+}
+
+// Close closes the connection without DISCONNECT, so the broker publishes the will
+func (c *Client) Close() {
+	c.close(ErrClosed)
+}
+
+// close closes the connection once, recording the cause
+func (c *Client) close(cause error) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.err = fmt.Errorf("%w: %v", ErrClosed, cause)
+		if cause == ErrClosed {
+			c.err = ErrClosed
+		}
+		c.mu.Unlock()
+		c.conn.Close()
+		close(c.done)
+	})
+}
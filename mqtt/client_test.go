@@ -0,0 +1,115 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacket_RemainingLength(t *testing.T) {
+	for _, size := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		var buf bytes.Buffer
+		body := bytes.Repeat([]byte{'x'}, size)
+		if err := writePacket(&buf, packetPublish, 0x03, body); err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := readPacket(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if p.kind != packetPublish || p.flags != 0x03 || len(p.body) != size {
+			t.Errorf("size %d: decoded kind %d flags %d length %d", size, p.kind, p.flags, len(p.body))
+		}
+	}
+}
+
+func TestClient_ConnectAndPublish(t *testing.T) {
+	broker := newTestBroker(t)
+
+	client, err := Connect(t.Context(), Options{
+		Address:   broker.Addr(),
+		ClientID:  "atmosbyte-test",
+		Username:  "user",
+		Password:  "secret",
+		KeepAlive: 30 * time.Second,
+		Will:      &Message{Topic: "atmosbyte/status", Payload: []byte("offline"), QoS: 1, Retain: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	connects := broker.Connects()
+	if len(connects) != 1 {
+		t.Fatalf("expected 1 CONNECT, got %d", len(connects))
+	}
+	info := connects[0]
+	if info.clientID != "atmosbyte-test" || info.username != "user" || info.password != "secret" || info.keepAlive != 30 {
+		t.Errorf("unexpected CONNECT %+v", info)
+	}
+	if info.will == nil || info.will.Topic != "atmosbyte/status" || string(info.will.Payload) != "offline" || !info.will.Retain || info.will.QoS != 1 {
+		t.Errorf("unexpected will %+v", info.will)
+	}
+
+	if err := client.Publish(t.Context(), Message{Topic: "a/b", Payload: []byte("qos0")}); err != nil {
+		t.Fatalf("QoS 0 publish failed: %v", err)
+	}
+	// QoS 1 publishes return after the PUBACK
+	if err := client.Publish(t.Context(), Message{Topic: "a/b", Payload: []byte("qos1"), QoS: 1, Retain: true}); err != nil {
+		t.Fatalf("QoS 1 publish failed: %v", err)
+	}
+	if payload, ok := broker.Retained("a/b"); !ok || payload != "qos1" {
+		t.Errorf("expected retained qos1, got %q", payload)
+	}
+
+	broker.WaitFor(func() bool { return len(broker.Published("a/b")) == 2 })
+
+	// A clean disconnect does not trigger the will
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("disconnect failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := broker.Retained("atmosbyte/status"); ok {
+		t.Error("expected the will to be discarded after DISCONNECT")
+	}
+	if err := client.Publish(t.Context(), Message{Topic: "a/b"}); err == nil {
+		t.Error("expected publish after disconnect to fail")
+	}
+}
+
+func TestClient_WillOnConnectionLoss(t *testing.T) {
+	broker := newTestBroker(t)
+
+	client, err := Connect(t.Context(), Options{
+		Address:  broker.Addr(),
+		ClientID: "atmosbyte-test",
+		Will:     &Message{Topic: "atmosbyte/status", Payload: []byte("offline"), Retain: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	client.Close()
+	broker.WaitFor(func() bool {
+		payload, ok := broker.Retained("atmosbyte/status")
+		return ok && payload == "offline"
+	})
+
+	select {
+	case <-client.Done():
+	default:
+		t.Error("expected Done to be closed")
+	}
+}
+
+func TestClient_ConnectionRefused(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.SetReturnCode(5)
+
+	_, err := Connect(t.Context(), Options{Address: broker.Addr(), ClientID: "atmosbyte-test"})
+	if err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Fatalf("expected not authorized error, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// Payloads of the availability topic
+const (
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+)
+
+// PublisherConfig configures the topics and Home Assistant discovery of a Publisher
+type PublisherConfig struct {
+	Options           Options // Broker connection; the will is set to the availability topic
+	QoS               byte
+	StateTopic        string  // Receives a JSON document per measurement
+	AvailabilityTopic string  // Retained online/offline status
+	Discovery         bool    // Publish Home Assistant discovery payloads
+	DiscoveryPrefix   string  // Home Assistant discovery prefix, usually "homeassistant"
+	DeviceName        string  // Device name shown in Home Assistant
+	Version           string  // Software version shown in Home Assistant
+	Altitude          float64 // Station altitude in meters, used for sea level pressure
+	ConnectTimeout    time.Duration
+	MinBackoff        time.Duration // First delay before reconnecting after a failure
+	MaxBackoff        time.Duration // Maximum delay between reconnection attempts
+}
+
+// State is the JSON document published on the state topic
+type State struct {
+	Timestamp        string  `json:"timestamp"`
+	Temperature      float64 `json:"temperature"`        // °C
+	Humidity         float64 `json:"humidity"`           // %RH
+	Pressure         float64 `json:"pressure"`           // hPa
+	PressureSeaLevel float64 `json:"pressure_sea_level"` // hPa
+	DewPoint         float64 `json:"dew_point"`          // °C
+}
+
+// sensor describes a value of State exposed to Home Assistant
+type sensor struct {
+	key         string
+	name        string
+	deviceClass string
+	unit        string
+	precision   int
+}
+
+var sensors = []sensor{
+	{"temperature", "Temperature", "temperature", "°C", 1},
+	{"humidity", "Humidity", "humidity", "%", 1},
+	{"pressure", "Pressure", "atmospheric_pressure", "hPa", 1},
+	{"pressure_sea_level", "Sea level pressure", "atmospheric_pressure", "hPa", 1},
+	{"dew_point", "Dew point", "temperature", "°C", 1},
+}
+
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Publisher publishes measurements to an MQTT broker. It connects lazily, announces
+// itself on the availability topic, and reconnects with exponential backoff when the
+// connection is lost. It implements the Service interface of the uploader package.
+type Publisher struct {
+	config   PublisherConfig
+	deviceID string
+	now      func() time.Time
+
+	mu          sync.Mutex
+	client      *Client
+	backoff     time.Duration
+	nextAttempt time.Time
+}
+
+// NewPublisher creates a Publisher
+func NewPublisher(config PublisherConfig) *Publisher {
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = 10 * time.Second
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 2 * time.Minute
+	}
+	if config.DeviceName == "" {
+		config.DeviceName = "Atmosbyte"
+	}
+	config.Options.Will = &Message{
+		Topic:   config.AvailabilityTopic,
+		Payload: []byte(PayloadOffline),
+		QoS:     config.QoS,
+		Retain:  true,
+	}
+
+	return &Publisher{
+		config:   config,
+		deviceID: invalidIDChars.ReplaceAllString(config.Options.ClientID, "_"),
+		now:      time.Now,
+	}
+}
+
+// Name implements uploader.Service
+func (p *Publisher) Name() string {
+	return "MQTT"
+}
+
+// Upload implements uploader.Service, publishing the measurement on the state topic
+func (p *Publisher) Upload(ctx context.Context, measurement bme280.Measurement) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	client, err := p.connect(ctx)
+	if err != nil {
+		return queue.NewRetryableError(err, true)
+	}
+
+	payload, err := json.Marshal(p.state(measurement))
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to encode state: %w", err), false)
+	}
+
+	err = client.Publish(ctx, Message{Topic: p.config.StateTopic, Payload: payload, QoS: p.config.QoS})
+	if err != nil {
+		p.drop()
+		return queue.NewRetryableError(fmt.Errorf("failed to publish state: %w", err), true)
+	}
+
+	return nil
+}
+
+// Close marks the device offline and disconnects from the broker
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.ConnectTimeout)
+	defer cancel()
+
+	client := p.client
+	p.client = nil
+	if err := client.Publish(ctx, p.availability(PayloadOffline)); err != nil {
+		client.Disconnect()
+		return fmt.Errorf("failed to publish availability: %w", err)
+	}
+	return client.Disconnect()
+}
+
+// connect returns the current connection or opens a new one, unless the backoff
+// after a failed attempt has not elapsed yet
+func (p *Publisher) connect(ctx context.Context) (*Client, error) {
+	if p.client != nil {
+		select {
+		case <-p.client.Done():
+			logging.Warnf("MQTT connection lost: %v", p.client.Err())
+			p.drop()
+		default:
+			return p.client, nil
+		}
+	}
+
+	if wait := p.nextAttempt.Sub(p.now()); wait > 0 {
+		return nil, fmt.Errorf("reconnecting to MQTT broker in %v", wait.Round(time.Second))
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, p.config.ConnectTimeout)
+	defer cancel()
+
+	client, err := Connect(connectCtx, p.config.Options)
+	if err == nil {
+		err = p.announce(connectCtx, client)
+		if err != nil {
+			client.Close()
+		}
+	}
+	if err != nil {
+		p.backoff = min(max(p.backoff*2, p.config.MinBackoff), p.config.MaxBackoff)
+		p.nextAttempt = p.now().Add(p.backoff)
+		return nil, err
+	}
+
+	log.Printf("Connected to MQTT broker %s", p.config.Options.Address)
+	p.client = client
+	p.backoff = 0
+	p.nextAttempt = time.Time{}
+	return client, nil
+}
+
+// announce publishes the discovery payloads and marks the device online
+func (p *Publisher) announce(ctx context.Context, client *Client) error {
+	if p.config.Discovery {
+		for _, s := range sensors {
+			topic, payload, err := p.discovery(s)
+			if err != nil {
+				return err
+			}
+			msg := Message{Topic: topic, Payload: payload, QoS: p.config.QoS, Retain: true}
+			if err := client.Publish(ctx, msg); err != nil {
+				return fmt.Errorf("failed to publish discovery: %w", err)
+			}
+		}
+	}
+
+	if err := client.Publish(ctx, p.availability(PayloadOnline)); err != nil {
+		return fmt.Errorf("failed to publish availability: %w", err)
+	}
+	return nil
+}
+
+// drop closes and forgets the current connection. The broker publishes the will,
+// so the device shows as offline until it reconnects.
+func (p *Publisher) drop() {
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+}
+
+func (p *Publisher) availability(payload string) Message {
+	return Message{Topic: p.config.AvailabilityTopic, Payload: []byte(payload), QoS: p.config.QoS, Retain: true}
+}
+
+// state converts a measurement to the published document
+func (p *Publisher) state(measurement bme280.Measurement) State {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return State{
+		Timestamp:        measurement.Timestamp.Format(time.RFC3339),
+		Temperature:      round(measurement.Temperature),
+		Humidity:         round(measurement.Humidity),
+		Pressure:         round(float64(measurement.Pressure) / 100),
+		PressureSeaLevel: round(weather.SeaLevelPressure(float64(measurement.Pressure), measurement.Temperature, p.config.Altitude) / 100),
+		DewPoint:         round(weather.DewPoint(measurement.Temperature, measurement.Humidity)),
+	}
+}
+
+// discovery returns the Home Assistant discovery topic and payload of a sensor
+func (p *Publisher) discovery(s sensor) (string, []byte, error) {
+	uniqueID := p.deviceID + "_" + s.key
+	topic := strings.Join([]string{p.config.DiscoveryPrefix, "sensor", p.deviceID, s.key, "config"}, "/")
+
+	payload, err := json.Marshal(map[string]any{
+		"name":                        s.name,
+		"unique_id":                   uniqueID,
+		"object_id":                   uniqueID,
+		"state_topic":                 p.config.StateTopic,
+		"value_template":              fmt.Sprintf("{{ value_json.%s }}", s.key),
+		"device_class":                s.deviceClass,
+		"unit_of_measurement":         s.unit,
+		"state_class":                 "measurement",
+		"suggested_display_precision": s.precision,
+		"availability_topic":          p.config.AvailabilityTopic,
+		"payload_available":           PayloadOnline,
+		"payload_not_available":       PayloadOffline,
+		"device": map[string]any{
+			"identifiers":  []string{p.deviceID},
+			"name":         p.config.DeviceName,
+			"manufacturer": "Atmosbyte",
+			"model":        "BME280",
+			"sw_version":   p.config.Version,
+		},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode discovery payload: %w", err)
+	}
+
+	return topic, payload, nil
+}
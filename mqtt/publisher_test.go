@@ -0,0 +1,142 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+func testPublisher(broker *testBroker) *Publisher {
+	return NewPublisher(PublisherConfig{
+		Options:           Options{Address: broker.Addr(), ClientID: "atmosbyte.test"},
+		QoS:               1,
+		StateTopic:        "atmosbyte/state",
+		AvailabilityTopic: "atmosbyte/status",
+		Discovery:         true,
+		DiscoveryPrefix:   "homeassistant",
+		Version:           "1.0.0",
+		ConnectTimeout:    time.Second,
+	})
+}
+
+func testMeasurement() bme280.Measurement {
+	return bme280.Measurement{
+		Timestamp:   time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Temperature: 25,
+		Humidity:    50,
+		Pressure:    101325,
+	}
+}
+
+func TestPublisher_DiscoveryAndState(t *testing.T) {
+	broker := newTestBroker(t)
+	publisher := testPublisher(broker)
+
+	if err := publisher.Upload(t.Context(), testMeasurement()); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if payload, _ := broker.Retained("atmosbyte/status"); payload != PayloadOnline {
+		t.Errorf("expected retained online availability, got %q", payload)
+	}
+
+	want := map[string][2]string{
+		"temperature":        {"temperature", "°C"},
+		"humidity":           {"humidity", "%"},
+		"pressure":           {"atmospheric_pressure", "hPa"},
+		"pressure_sea_level": {"atmospheric_pressure", "hPa"},
+		"dew_point":          {"temperature", "°C"},
+	}
+	for key, class := range want {
+		payload, ok := broker.Retained("homeassistant/sensor/atmosbyte_test/" + key + "/config")
+		if !ok {
+			t.Errorf("missing discovery payload for %s", key)
+			continue
+		}
+		var config map[string]any
+		if err := json.Unmarshal([]byte(payload), &config); err != nil {
+			t.Fatalf("invalid discovery payload: %v", err)
+		}
+		if config["device_class"] != class[0] || config["unit_of_measurement"] != class[1] {
+			t.Errorf("%s: unexpected device class %v and unit %v", key, config["device_class"], config["unit_of_measurement"])
+		}
+		if config["state_topic"] != "atmosbyte/state" || config["availability_topic"] != "atmosbyte/status" {
+			t.Errorf("%s: unexpected topics %v", key, config)
+		}
+		if config["value_template"] != "{{ value_json."+key+" }}" || config["unique_id"] != "atmosbyte_test_"+key {
+			t.Errorf("%s: unexpected template or id %v", key, config)
+		}
+	}
+
+	states := broker.Published("atmosbyte/state")
+	if len(states) != 1 {
+		t.Fatalf("expected 1 state, got %d", len(states))
+	}
+	var state State
+	if err := json.Unmarshal(states[0].Payload, &state); err != nil {
+		t.Fatalf("invalid state payload: %v", err)
+	}
+	if state.Temperature != 25 || state.Humidity != 50 || state.Pressure != 1013.25 || state.DewPoint != 13.85 {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if states[0].Retain {
+		t.Error("state should not be retained")
+	}
+
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if payload, _ := broker.Retained("atmosbyte/status"); payload != PayloadOffline {
+		t.Errorf("expected offline availability after Close, got %q", payload)
+	}
+}
+
+func TestPublisher_ReconnectsWithBackoff(t *testing.T) {
+	broker := newTestBroker(t)
+	publisher := testPublisher(broker)
+	now := time.Now()
+	publisher.now = func() time.Time { return now }
+
+	if err := publisher.Upload(t.Context(), testMeasurement()); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	// The broker goes away: the will marks the device offline
+	client := publisher.client
+	broker.SetReturnCode(3)
+	broker.DropConnections()
+	broker.WaitFor(func() bool {
+		payload, _ := broker.Retained("atmosbyte/status")
+		return payload == PayloadOffline
+	})
+	<-client.Done()
+
+	err := publisher.Upload(t.Context(), testMeasurement())
+	if retryable, ok := err.(queue.RetryableError); !ok || !retryable.IsRetryable() {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+	attempts := len(broker.Connects())
+
+	// No new attempt is made before the backoff elapses
+	if err := publisher.Upload(t.Context(), testMeasurement()); err == nil {
+		t.Fatal("expected an error during backoff")
+	}
+	if len(broker.Connects()) != attempts {
+		t.Errorf("expected no connection attempt during backoff")
+	}
+
+	broker.SetReturnCode(0)
+	now = now.Add(time.Second)
+	if err := publisher.Upload(t.Context(), testMeasurement()); err != nil {
+		t.Fatalf("expected reconnection, got %v", err)
+	}
+	if payload, _ := broker.Retained("atmosbyte/status"); payload != PayloadOnline {
+		t.Errorf("expected availability to be online again, got %q", payload)
+	}
+	if len(broker.Published("atmosbyte/state")) != 2 {
+		t.Errorf("expected 2 states, got %d", len(broker.Published("atmosbyte/state")))
+	}
+}
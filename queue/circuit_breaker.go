@@ -14,6 +14,20 @@ const (
 	CircuitBreakerHalfOpen
 )
 
+// String retorna o nome do estado
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
 // CircuitBreaker implementa o padrão Circuit Breaker
 type CircuitBreaker struct {
 	mu                sync.RWMutex
@@ -25,6 +39,7 @@ type CircuitBreaker struct {
 	timeout           time.Duration
 	halfOpenSuccesses int
 	maxHalfOpenTries  int
+	onStateChange     func(from, to CircuitBreakerState)
 }
 
 // NewCircuitBreaker cria um novo circuit breaker
@@ -51,8 +66,17 @@ func (cb *CircuitBreaker) Call(fn func() error) error {
 // allowCall verifica se a chamada é permitida
 func (cb *CircuitBreaker) allowCall() bool {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	from := cb.state
+	allowed := cb.allowCallLocked()
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	return allowed
+}
 
+// allowCallLocked implementa allowCall; o chamador mantém o lock
+func (cb *CircuitBreaker) allowCallLocked() bool {
 	switch cb.state {
 	case CircuitBreakerClosed:
 		return true
@@ -73,8 +97,16 @@ func (cb *CircuitBreaker) allowCall() bool {
 // recordResult registra o resultado da chamada
 func (cb *CircuitBreaker) recordResult(err error) {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	from := cb.state
+	cb.recordResultLocked(err)
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
 
+// recordResultLocked implementa recordResult; o chamador mantém o lock
+func (cb *CircuitBreaker) recordResultLocked(err error) {
 	if err != nil {
 		cb.failureCount++
 		cb.lastFailureTime = time.Now()
@@ -95,6 +127,23 @@ func (cb *CircuitBreaker) recordResult(err error) {
 	}
 }
 
+// notify chama onStateChange quando o estado mudou, fora do lock para que o
+// callback possa consultar o circuit breaker
+func (cb *CircuitBreaker) notify(from, to CircuitBreakerState) {
+	if from != to && cb.onStateChange != nil {
+		cb.onStateChange(from, to)
+	}
+}
+
+// SetThresholds altera o número de falhas que abre o circuito e o tempo até uma nova
+// tentativa; o estado atual é mantido
+func (cb *CircuitBreaker) SetThresholds(failureThreshold int, timeout time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failureThreshold = failureThreshold
+	cb.timeout = timeout
+}
+
 // State retorna o estado atual do circuit breaker
 func (cb *CircuitBreaker) State() CircuitBreakerState {
 	cb.mu.RLock()
@@ -16,6 +16,7 @@ type QueueConfig struct {
 type CircuitBreakerConfig struct {
 	FailureThreshold int
 	Timeout          time.Duration
+	OnStateChange    func(from, to CircuitBreakerState) // Chamado a cada mudança de estado, opcional
 }
 
 // QueueStats representa estatísticas da fila
@@ -6,6 +6,8 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 )
 
 // ProcessingContext encapsula o contexto de processamento
@@ -47,7 +49,7 @@ func (pc *ProcessingContext[T]) ShouldRetry(err error) bool {
 // LogError registra erro com prefixo adequado
 func (pc *ProcessingContext[T]) LogError(err error) {
 	prefix := pc.getLogPrefix()
-	log.Printf("%sWorker %d: Error processing message %s (attempt %d/%d): %v",
+	logging.Warnf("%sWorker %d: Error processing message %s (attempt %d/%d): %v",
 		prefix, pc.WorkerID, pc.Message.ID,
 		pc.Message.Attempts, pc.Message.MaxTries, err)
 }
@@ -55,7 +57,7 @@ func (pc *ProcessingContext[T]) LogError(err error) {
 // LogSuccess registra sucesso com prefixo adequado
 func (pc *ProcessingContext[T]) LogSuccess() {
 	prefix := pc.getLogPrefix()
-	log.Printf("%sWorker %d: Successfully processed message %s",
+	logging.Debugf("%sWorker %d: Successfully processed message %s",
 		prefix, pc.WorkerID, pc.Message.ID)
 }
 
@@ -71,7 +73,7 @@ func (pc *ProcessingContext[T]) LogRetryAction() {
 // LogDrop registra quando mensagem é descartada
 func (pc *ProcessingContext[T]) LogDrop(reason string) {
 	prefix := pc.getLogPrefix()
-	log.Printf("%sWorker %d: %s",
+	logging.Warnf("%sWorker %d: %s",
 		prefix, pc.WorkerID, reason)
 }
 
@@ -85,7 +87,11 @@ func (pc *ProcessingContext[T]) getLogPrefix() string {
 
 // Queue representa uma fila de processamento de mensagens
 type Queue[T any] struct {
+	mu             sync.RWMutex // protege config, stops e started
 	config         QueueConfig
+	stops          []chan struct{} // um canal por worker em execução, fechado para pará-lo
+	nextWorkerID   int
+	started        bool
 	messagesQueue  chan Message[T]
 	retryQueue     chan Message[T]
 	worker         Worker[T]
@@ -111,6 +117,7 @@ func NewQueue[T any](ctx context.Context, worker Worker[T], config QueueConfig)
 		ctx:    queueCtx,
 		cancel: cancel,
 	}
+	q.circuitBreaker.onStateChange = config.CircuitBreakerConfig.OnStateChange
 
 	return q
 }
@@ -122,22 +129,71 @@ func (q *Queue[T]) IsShutdown() bool {
 
 // Start inicia todos os workers da queue e bloqueia até o contexto ser cancelado
 func (q *Queue[T]) Start() error {
+	q.mu.Lock()
 	log.Printf("Starting queue with %d workers", q.config.Workers)
-
-	for i := 0; i < q.config.Workers; i++ {
-		q.wg.Add(1)
-		go q.workerLoop(i)
+	q.started = true
+	for len(q.stops) < q.config.Workers {
+		q.startWorkerLocked()
 	}
+	q.mu.Unlock()
 
 	q.wg.Add(1)
 	go q.retryLoop()
 
 	<-q.ctx.Done()
+
+	q.mu.Lock()
+	q.started = false
+	q.mu.Unlock()
 	q.wg.Wait()
 
 	return q.ctx.Err()
 }
 
+// startWorkerLocked inicia um novo worker; o chamador mantém o lock
+func (q *Queue[T]) startWorkerLocked() {
+	stop := make(chan struct{})
+	q.stops = append(q.stops, stop)
+	q.wg.Add(1)
+	go q.workerLoop(q.nextWorkerID, stop)
+	q.nextWorkerID++
+}
+
+// Reconfigure aplica o número de workers, a política de retry e os limites do circuit
+// breaker de config a uma fila em execução. Workers removidos terminam a mensagem em
+// processamento antes de parar. BufferSize e os timeouts só valem para uma nova fila.
+func (q *Queue[T]) Reconfigure(config QueueConfig) {
+	q.circuitBreaker.SetThresholds(config.CircuitBreakerConfig.FailureThreshold, config.CircuitBreakerConfig.Timeout)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.config.Workers = config.Workers
+	q.config.RetryPolicy = config.RetryPolicy
+	q.config.CircuitBreakerConfig.FailureThreshold = config.CircuitBreakerConfig.FailureThreshold
+	q.config.CircuitBreakerConfig.Timeout = config.CircuitBreakerConfig.Timeout
+
+	if !q.started {
+		return
+	}
+	for len(q.stops) < q.config.Workers {
+		q.startWorkerLocked()
+	}
+	for len(q.stops) > q.config.Workers {
+		last := len(q.stops) - 1
+		close(q.stops[last])
+		q.stops = q.stops[:last]
+	}
+	log.Printf("Queue reconfigured with %d workers", q.config.Workers)
+}
+
+// retryPolicy retorna a política de retry atual
+func (q *Queue[T]) retryPolicy() RetryPolicy {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.config.RetryPolicy
+}
+
 // Enqueue adiciona uma nova mensagem à fila
 func (q *Queue[T]) Enqueue(data T) error {
 	if q.IsShutdown() {
@@ -148,7 +204,7 @@ func (q *Queue[T]) Enqueue(data T) error {
 		ID:        generateID(),
 		Data:      data,
 		Attempts:  0,
-		MaxTries:  q.config.RetryPolicy.MaxRetries,
+		MaxTries:  q.retryPolicy().MaxRetries,
 		CreatedAt: time.Now(),
 	}
 
@@ -163,11 +219,14 @@ func (q *Queue[T]) Enqueue(data T) error {
 }
 
 // workerLoop é o loop principal de processamento de cada worker
-func (q *Queue[T]) workerLoop(workerID int) {
+func (q *Queue[T]) workerLoop(workerID int, stop <-chan struct{}) {
 	defer q.wg.Done()
 
 	for {
 		select {
+		case <-stop:
+			log.Printf("Worker %d: Stopped by reconfiguration", workerID)
+			return
 		case msg, ok := <-q.messagesQueue:
 			if !ok {
 				log.Printf("Worker %d: Messages channel closed, shutting down", workerID)
@@ -189,7 +248,7 @@ func (q *Queue[T]) drainMessageQueue(workerID int) {
 				log.Printf("Worker %d: Messages channel closed during shutdown", workerID)
 				return
 			}
-			log.Printf("Worker %d: Dropping message %s due to shutdown", workerID, msg.ID)
+			logging.Warnf("Worker %d: Dropping message %s due to shutdown", workerID, msg.ID)
 		default:
 			log.Printf("Worker %d: No more messages to process, shutting down", workerID)
 			return
@@ -226,7 +285,7 @@ func (q *Queue[T]) drainRetryQueue() {
 				log.Printf("RetryLoop: Retry channel closed during shutdown")
 				return
 			}
-			log.Printf("RetryLoop: Dropping retry message %s due to shutdown", msg.ID)
+			logging.Warnf("RetryLoop: Dropping retry message %s due to shutdown", msg.ID)
 		default:
 			log.Printf("RetryLoop: No more retry messages, shutting down")
 			return
@@ -236,8 +295,8 @@ func (q *Queue[T]) drainRetryQueue() {
 
 // handleRetryMessage processa uma mensagem de retry durante operação normal
 func (q *Queue[T]) handleRetryMessage(msg Message[T]) {
-	delay := q.config.RetryPolicy.CalculateDelay(msg.Attempts)
-	log.Printf("RetryLoop: Waiting %v before retrying message %s", delay, msg.ID)
+	delay := q.retryPolicy().CalculateDelay(msg.Attempts)
+	logging.Debugf("RetryLoop: Waiting %v before retrying message %s", delay, msg.ID)
 
 	timer := time.NewTimer(delay)
 	defer timer.Stop()
@@ -246,7 +305,7 @@ func (q *Queue[T]) handleRetryMessage(msg Message[T]) {
 	case <-timer.C:
 		q.sendToMainQueue(msg)
 	case <-q.ctx.Done():
-		log.Printf("RetryLoop: Dropping message %s due to context cancellation during delay", msg.ID)
+		logging.Warnf("RetryLoop: Dropping message %s due to context cancellation during delay", msg.ID)
 	}
 }
 
@@ -256,9 +315,9 @@ func (q *Queue[T]) sendToMainQueue(msg Message[T]) {
 	case q.messagesQueue <- msg:
 		// Mensagem enviada com sucesso
 	case <-q.ctx.Done():
-		log.Printf("RetryLoop: Dropping message %s due to context cancellation", msg.ID)
+		logging.Warnf("RetryLoop: Dropping message %s due to context cancellation", msg.ID)
 	default:
-		log.Printf("RetryLoop: Messages queue full, dropping message %s", msg.ID)
+		logging.Warnf("RetryLoop: Messages queue full, dropping message %s", msg.ID)
 	}
 }
 
@@ -333,6 +392,9 @@ func (q *Queue[T]) handleRetryAttempt(pc *ProcessingContext[T]) {
 
 // Stats retorna estatísticas da fila
 func (q *Queue[T]) Stats() QueueStats {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
 	return QueueStats{
 		QueueSize:           len(q.messagesQueue),
 		RetryQueueSize:      len(q.retryQueue),
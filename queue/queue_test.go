@@ -309,6 +309,48 @@ func TestCircuitBreaker(t *testing.T) {
 	}
 }
 
+// TestCircuitBreaker_OnStateChange verifica a notificação das mudanças de estado
+func TestCircuitBreaker_OnStateChange(t *testing.T) {
+	var mu sync.Mutex
+	var transitions []string
+
+	config := queue.QueueConfig{
+		Workers:     1,
+		BufferSize:  10,
+		RetryPolicy: queue.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		CircuitBreakerConfig: queue.CircuitBreakerConfig{
+			FailureThreshold: 1,
+			Timeout:          time.Hour,
+			OnStateChange: func(from, to queue.CircuitBreakerState) {
+				mu.Lock()
+				defer mu.Unlock()
+				transitions = append(transitions, from.String()+"->"+to.String())
+			},
+		},
+	}
+
+	worker := queue.WorkerFunc[string](func(ctx context.Context, msg queue.Message[string]) error {
+		return errors.New("test error")
+	})
+	q := queue.NewQueue(t.Context(), worker, config)
+	go q.Start()
+
+	if err := q.Enqueue("message"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Stats().CircuitBreakerState != queue.CircuitBreakerOpen && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(transitions) != 1 || transitions[0] != "closed->open" {
+		t.Errorf("Expected a single closed->open transition, got %v", transitions)
+	}
+}
+
 // ==============================
 // Teste da nova interface RetryableError
 // ==============================
@@ -1202,3 +1244,51 @@ func TestQueue_StartMethod(t *testing.T) {
 		t.Errorf("Expected ErrQueueClosed after cancel, got %v", err)
 	}
 }
+
+func TestQueue_Reconfigure(t *testing.T) {
+	var processed int32
+	worker := &MockWorker{
+		processFunc: func(ctx context.Context, msg OrderData) error {
+			atomic.AddInt32(&processed, 1)
+			return nil
+		},
+	}
+
+	cfg := config.TestQueueConfig()
+	cfg.Workers = 1
+	cfg.BufferSize = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	q := startQueueForTest(t, ctx, worker, cfg)
+	time.Sleep(20 * time.Millisecond)
+
+	// Aumenta os workers e altera a política de retry com a fila em execução
+	cfg.Workers = 3
+	cfg.RetryPolicy.MaxRetries = 1
+	cfg.CircuitBreakerConfig.FailureThreshold = 1
+	q.Reconfigure(cfg)
+
+	if stats := q.Stats(); stats.Workers != 3 {
+		t.Errorf("Expected 3 workers, got %d", stats.Workers)
+	}
+
+	// Reduz para um worker; os demais param e as mensagens continuam sendo processadas
+	cfg.Workers = 1
+	q.Reconfigure(cfg)
+
+	for i := range 5 {
+		if err := q.Enqueue(OrderData{ID: fmt.Sprintf("ORD%d", i), Amount: 10}); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&processed); got != 5 {
+		t.Errorf("Expected 5 processed messages, got %d", got)
+	}
+	if stats := q.Stats(); stats.Workers != 1 {
+		t.Errorf("Expected 1 worker, got %d", stats.Workers)
+	}
+}
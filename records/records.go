@@ -0,0 +1,305 @@
+// Package records maintains almanac-style weather records (all-time, monthly
+// and per calendar day extremes) on top of the measurement repository.
+package records
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// PressureDropWindow is the window used to find the largest pressure drop
+const PressureDropWindow = 3 * time.Hour
+
+// loadChunk is the time span requested from the repository at once while
+// rebuilding records from history
+const loadChunk = 7 * 24 * time.Hour
+
+// Repository is the subset of the measurement repository used by the Tracker
+type Repository interface {
+	SaveMeasurement(measurement bme280.Measurement) error
+	GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error)
+	GetOldestMeasurements(limit int) ([]repository.MeasurementRecord, error)
+}
+
+// Extreme is a single record value and the moment it happened
+type Extreme struct {
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Range holds the record high and low of one quantity
+type Range struct {
+	High *Extreme `json:"high,omitempty"`
+	Low  *Extreme `json:"low,omitempty"`
+}
+
+// Set groups the ranges of every measured quantity
+type Set struct {
+	Temperature Range `json:"temperature"`
+	Humidity    Range `json:"humidity"`
+	Pressure    Range `json:"pressure"`
+}
+
+// MonthRecords is the record set of one calendar month across all years
+type MonthRecords struct {
+	Month int `json:"month"`
+	Set
+}
+
+// TodayRecords compares today's observations with the record for this calendar day
+type TodayRecords struct {
+	Date     string `json:"date"`
+	Observed Set    `json:"observed"`
+	Record   Set    `json:"record"`
+}
+
+// PressureDrop describes the largest pressure drop within PressureDropWindow
+type PressureDrop struct {
+	Drop  int64   `json:"drop"`
+	From  Extreme `json:"from"`
+	To    Extreme `json:"to"`
+	Hours float64 `json:"window_hours"`
+}
+
+// Snapshot is a point-in-time copy of all tracked records
+type Snapshot struct {
+	AllTime      Set            `json:"all_time"`
+	Monthly      []MonthRecords `json:"monthly"`
+	Today        TodayRecords   `json:"today"`
+	PressureDrop *PressureDrop  `json:"pressure_drop_3h,omitempty"`
+	Timestamp    time.Time      `json:"timestamp"`
+}
+
+// calendarDay identifies a day of the year independently of the year
+type calendarDay struct {
+	month time.Month
+	day   int
+}
+
+// Tracker keeps weather records up to date as measurements are saved.
+// It decorates the repository so it can be used wherever measurements are persisted.
+type Tracker struct {
+	repo     Repository
+	location *time.Location
+
+	mu       sync.RWMutex
+	allTime  Set
+	monthly  [12]Set
+	daily    map[calendarDay]Set
+	today    Set
+	todayKey string
+	last     time.Time
+	window   []repository.MeasurementRecord
+	drop     *PressureDrop
+}
+
+// NewTracker creates a Tracker backed by repo
+func NewTracker(repo Repository) *Tracker {
+	return &Tracker{
+		repo:     repo,
+		location: timezone.GetMachineLocation(),
+		daily:    make(map[calendarDay]Set),
+	}
+}
+
+// Load rebuilds all records from the measurements already stored in the repository
+func (t *Tracker) Load() error {
+	oldest, err := t.repo.GetOldestMeasurements(1)
+	if err != nil {
+		return fmt.Errorf("failed to find oldest measurement: %w", err)
+	}
+	if len(oldest) == 0 {
+		return nil
+	}
+
+	end := time.Now()
+	for start := oldest[0].Timestamp; !start.After(end); start = start.Add(loadChunk) {
+		chunkEnd := start.Add(loadChunk - time.Nanosecond)
+		records, err := t.repo.GetMeasurementsByTimeRange(start, chunkEnd)
+		if err != nil {
+			return fmt.Errorf("failed to load measurements for records: %w", err)
+		}
+		for _, record := range records {
+			t.Observe(record)
+		}
+	}
+
+	return nil
+}
+
+// SaveMeasurement persists the measurement and updates the records
+func (t *Tracker) SaveMeasurement(measurement bme280.Measurement) error {
+	if err := t.repo.SaveMeasurement(measurement); err != nil {
+		return err
+	}
+
+	// Records only track the local station
+	if measurement.Node != "" {
+		return nil
+	}
+
+	t.Observe(repository.MeasurementRecord{
+		Timestamp:   measurement.Timestamp,
+		Temperature: measurement.Temperature,
+		Humidity:    measurement.Humidity,
+		Pressure:    measurement.Pressure,
+	})
+
+	return nil
+}
+
+// Observe folds a single measurement into the records
+func (t *Tracker) Observe(record repository.MeasurementRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ts := record.Timestamp.In(t.location)
+
+	key := ts.Format(time.DateOnly)
+	if key != t.todayKey {
+		if key < t.todayKey {
+			// Late reading for a past day: count it towards that calendar day directly
+			day := calendarDay{month: ts.Month(), day: ts.Day()}
+			t.daily[day] = observeSet(t.daily[day], record, ts)
+		} else {
+			t.rollover(key)
+		}
+	}
+
+	t.allTime = observeSet(t.allTime, record, ts)
+	t.monthly[ts.Month()-1] = observeSet(t.monthly[ts.Month()-1], record, ts)
+	if key == t.todayKey {
+		t.today = observeSet(t.today, record, ts)
+	}
+
+	t.observePressure(record, ts)
+}
+
+// rollover moves today's observations into the calendar day records
+func (t *Tracker) rollover(key string) {
+	if t.todayKey != "" {
+		day, _ := time.ParseInLocation(time.DateOnly, t.todayKey, t.location)
+		cd := calendarDay{month: day.Month(), day: day.Day()}
+		t.daily[cd] = mergeSet(t.daily[cd], t.today)
+	}
+	t.todayKey = key
+	t.today = Set{}
+}
+
+// observePressure tracks the largest pressure drop inside PressureDropWindow
+func (t *Tracker) observePressure(record repository.MeasurementRecord, ts time.Time) {
+	if ts.Before(t.last) {
+		return
+	}
+	t.last = ts
+
+	cutoff := ts.Add(-PressureDropWindow)
+	first := 0
+	for first < len(t.window) && t.window[first].Timestamp.Before(cutoff) {
+		first++
+	}
+	t.window = append(t.window[first:], record)
+
+	highest := t.window[0]
+	for _, candidate := range t.window[1:] {
+		if candidate.Pressure > highest.Pressure {
+			highest = candidate
+		}
+	}
+
+	drop := highest.Pressure - record.Pressure
+	if drop <= 0 || (t.drop != nil && drop <= t.drop.Drop) {
+		return
+	}
+
+	t.drop = &PressureDrop{
+		Drop:  drop,
+		From:  Extreme{Value: float64(highest.Pressure), Timestamp: highest.Timestamp.In(t.location)},
+		To:    Extreme{Value: float64(record.Pressure), Timestamp: ts},
+		Hours: PressureDropWindow.Hours(),
+	}
+}
+
+// Snapshot returns a copy of the current records evaluated at now
+func (t *Tracker) Snapshot(now time.Time) Snapshot {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now = now.In(t.location)
+	key := now.Format(time.DateOnly)
+	cd := calendarDay{month: now.Month(), day: now.Day()}
+
+	today := TodayRecords{
+		Date:   key,
+		Record: t.daily[cd],
+	}
+	if key == t.todayKey {
+		today.Observed = t.today
+	} else if t.todayKey != "" {
+		// Today's calendar day record must include the last observed day once it is over
+		lastDay, _ := time.ParseInLocation(time.DateOnly, t.todayKey, t.location)
+		if lastDay.Month() == cd.month && lastDay.Day() == cd.day {
+			today.Record = mergeSet(today.Record, t.today)
+		}
+	}
+
+	monthly := make([]MonthRecords, 0, len(t.monthly))
+	for i, set := range t.monthly {
+		monthly = append(monthly, MonthRecords{Month: i + 1, Set: set})
+	}
+
+	var drop *PressureDrop
+	if t.drop != nil {
+		d := *t.drop
+		drop = &d
+	}
+
+	return Snapshot{
+		AllTime:      t.allTime,
+		Monthly:      monthly,
+		Today:        today,
+		PressureDrop: drop,
+		Timestamp:    now,
+	}
+}
+
+func observeSet(set Set, record repository.MeasurementRecord, ts time.Time) Set {
+	set.Temperature = observeRange(set.Temperature, Extreme{Value: record.Temperature, Timestamp: ts})
+	set.Humidity = observeRange(set.Humidity, Extreme{Value: record.Humidity, Timestamp: ts})
+	set.Pressure = observeRange(set.Pressure, Extreme{Value: float64(record.Pressure), Timestamp: ts})
+	return set
+}
+
+func observeRange(r Range, e Extreme) Range {
+	if r.High == nil || e.Value > r.High.Value {
+		high := e
+		r.High = &high
+	}
+	if r.Low == nil || e.Value < r.Low.Value {
+		low := e
+		r.Low = &low
+	}
+	return r
+}
+
+func mergeSet(dst, src Set) Set {
+	dst.Temperature = mergeRange(dst.Temperature, src.Temperature)
+	dst.Humidity = mergeRange(dst.Humidity, src.Humidity)
+	dst.Pressure = mergeRange(dst.Pressure, src.Pressure)
+	return dst
+}
+
+func mergeRange(dst, src Range) Range {
+	if src.High != nil {
+		dst = observeRange(dst, *src.High)
+	}
+	if src.Low != nil {
+		dst = observeRange(dst, *src.Low)
+	}
+	return dst
+}
@@ -0,0 +1,178 @@
+package records
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+type mockRepository struct {
+	data  []repository.MeasurementRecord
+	saved []bme280.Measurement
+	err   error
+}
+
+func (m *mockRepository) SaveMeasurement(measurement bme280.Measurement) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.saved = append(m.saved, measurement)
+	return nil
+}
+
+func (m *mockRepository) GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error) {
+	var result []repository.MeasurementRecord
+	for _, record := range m.data {
+		if !record.Timestamp.Before(startTime) && !record.Timestamp.After(endTime) {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepository) GetOldestMeasurements(limit int) ([]repository.MeasurementRecord, error) {
+	if len(m.data) == 0 {
+		return nil, nil
+	}
+	return m.data[:min(limit, len(m.data))], nil
+}
+
+func newTestTracker(repo Repository) *Tracker {
+	tracker := NewTracker(repo)
+	tracker.location = time.UTC
+	return tracker
+}
+
+func record(ts time.Time, temp, humidity float64, pressure int64) repository.MeasurementRecord {
+	return repository.MeasurementRecord{Timestamp: ts, Temperature: temp, Humidity: humidity, Pressure: pressure}
+}
+
+func TestTracker_LoadBuildsAllTimeAndMonthlyRecords(t *testing.T) {
+	repo := &mockRepository{data: []repository.MeasurementRecord{
+		record(time.Date(2025, 1, 10, 6, 0, 0, 0, time.UTC), -2.5, 90, 102000),
+		record(time.Date(2025, 1, 10, 15, 0, 0, 0, time.UTC), 8.0, 40, 101500),
+		record(time.Date(2025, 7, 4, 14, 0, 0, 0, time.UTC), 36.2, 25, 100800),
+		record(time.Date(2025, 7, 5, 5, 0, 0, 0, time.UTC), 18.0, 70, 101000),
+	}}
+
+	tracker := newTestTracker(repo)
+	if err := tracker.Load(); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	snapshot := tracker.Snapshot(time.Date(2025, 7, 5, 12, 0, 0, 0, time.UTC))
+
+	if got := snapshot.AllTime.Temperature.High.Value; got != 36.2 {
+		t.Errorf("expected all-time high 36.2, got %v", got)
+	}
+	if got := snapshot.AllTime.Temperature.Low.Value; got != -2.5 {
+		t.Errorf("expected all-time low -2.5, got %v", got)
+	}
+	if got := snapshot.AllTime.Pressure.High.Timestamp; !got.Equal(time.Date(2025, 1, 10, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected pressure high timestamp %v", got)
+	}
+
+	if len(snapshot.Monthly) != 12 {
+		t.Fatalf("expected 12 monthly entries, got %d", len(snapshot.Monthly))
+	}
+	if got := snapshot.Monthly[0].Temperature.High.Value; got != 8.0 {
+		t.Errorf("expected January high 8.0, got %v", got)
+	}
+	if snapshot.Monthly[1].Temperature.High != nil {
+		t.Error("expected no February records")
+	}
+	if got := snapshot.Monthly[6].Humidity.Low.Value; got != 25 {
+		t.Errorf("expected July humidity low 25, got %v", got)
+	}
+}
+
+func TestTracker_TodayComparedWithCalendarDayRecord(t *testing.T) {
+	tracker := newTestTracker(&mockRepository{})
+
+	tracker.Observe(record(time.Date(2024, 3, 15, 14, 0, 0, 0, time.UTC), 30.0, 50, 101000))
+	tracker.Observe(record(time.Date(2024, 3, 15, 4, 0, 0, 0, time.UTC), 12.0, 80, 101200))
+	tracker.Observe(record(time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC), 20.0, 60, 101100))
+	tracker.Observe(record(time.Date(2025, 3, 15, 11, 0, 0, 0, time.UTC), 24.0, 55, 101050))
+
+	today := tracker.Snapshot(time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)).Today
+
+	if today.Date != "2025-03-15" {
+		t.Errorf("expected date 2025-03-15, got %s", today.Date)
+	}
+	if got := today.Observed.Temperature.High.Value; got != 24.0 {
+		t.Errorf("expected today's high 24.0, got %v", got)
+	}
+	if got := today.Observed.Temperature.Low.Value; got != 20.0 {
+		t.Errorf("expected today's low 20.0, got %v", got)
+	}
+	if got := today.Record.Temperature.High.Value; got != 30.0 {
+		t.Errorf("expected calendar day record high 30.0, got %v", got)
+	}
+	if got := today.Record.Temperature.Low.Value; got != 12.0 {
+		t.Errorf("expected calendar day record low 12.0, got %v", got)
+	}
+}
+
+func TestTracker_TodayWithoutReadings(t *testing.T) {
+	tracker := newTestTracker(&mockRepository{})
+	tracker.Observe(record(time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC), 20.0, 60, 101100))
+
+	today := tracker.Snapshot(time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)).Today
+
+	if today.Observed.Temperature.High != nil {
+		t.Error("expected no observations for a day without readings")
+	}
+}
+
+func TestTracker_LargestPressureDrop(t *testing.T) {
+	tracker := newTestTracker(&mockRepository{})
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker.Observe(record(base, 20, 50, 101500))
+	tracker.Observe(record(base.Add(1*time.Hour), 20, 50, 101300))
+	tracker.Observe(record(base.Add(2*time.Hour), 20, 50, 101100))
+	// Outside the 3h window of the 101500 reading
+	tracker.Observe(record(base.Add(4*time.Hour), 20, 50, 100900))
+
+	drop := tracker.Snapshot(base.Add(5 * time.Hour)).PressureDrop
+	if drop == nil {
+		t.Fatal("expected a pressure drop to be recorded")
+	}
+	if drop.Drop != 400 {
+		t.Errorf("expected drop of 400 Pa, got %d", drop.Drop)
+	}
+	if !drop.From.Timestamp.Equal(base) || !drop.To.Timestamp.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("unexpected drop interval %v -> %v", drop.From.Timestamp, drop.To.Timestamp)
+	}
+}
+
+func TestTracker_SaveMeasurementUpdatesRecords(t *testing.T) {
+	repo := &mockRepository{}
+	tracker := newTestTracker(repo)
+	now := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+
+	if err := tracker.SaveMeasurement(bme280.Measurement{Timestamp: now, Temperature: 22, Humidity: 55, Pressure: 101000}); err != nil {
+		t.Fatalf("SaveMeasurement returned error: %v", err)
+	}
+
+	if len(repo.saved) != 1 {
+		t.Fatalf("expected measurement to be saved, got %d", len(repo.saved))
+	}
+	if got := tracker.Snapshot(now).AllTime.Temperature.High.Value; got != 22 {
+		t.Errorf("expected all-time high 22, got %v", got)
+	}
+}
+
+func TestTracker_SaveMeasurementErrorSkipsRecords(t *testing.T) {
+	tracker := newTestTracker(&mockRepository{err: errors.New("disk full")})
+
+	if err := tracker.SaveMeasurement(bme280.Measurement{Timestamp: time.Now(), Temperature: 22}); err == nil {
+		t.Fatal("expected error from repository")
+	}
+	if tracker.Snapshot(time.Now()).AllTime.Temperature.High != nil {
+		t.Error("expected no records after failed save")
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// AlertStateRecord representa o estado persistido de uma regra de alerta, para que
+// alertas ativos não sejam notificados de novo após um reinício
+type AlertStateRecord struct {
+	Rule       string    `json:"rule"`
+	State      string    `json:"state"`
+	Value      float64   `json:"value"`
+	Since      time.Time `json:"since"` // Início do estado atual ou da condição pendente
+	FiredAt    time.Time `json:"fired_at"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	Notified   bool      `json:"notified"` // Se o disparo atual já foi notificado
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SaveAlertState insere ou atualiza o estado de uma regra
+func (r *SQLiteRepository) SaveAlertState(state AlertStateRecord) error {
+	query := `
+	INSERT INTO alert_states (rule, state, value, since, fired_at, resolved_at, notified, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(rule) DO UPDATE SET
+		state = excluded.state,
+		value = excluded.value,
+		since = excluded.since,
+		fired_at = excluded.fired_at,
+		resolved_at = excluded.resolved_at,
+		notified = excluded.notified,
+		updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query, state.Rule, state.State, state.Value, state.Since, state.FiredAt,
+		state.ResolvedAt, state.Notified, state.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save alert state: %w", err)
+	}
+
+	return nil
+}
+
+// GetAlertStates recupera o estado de todas as regras
+func (r *SQLiteRepository) GetAlertStates() ([]AlertStateRecord, error) {
+	query := `
+	SELECT rule, state, value, since, fired_at, resolved_at, notified, updated_at
+	FROM alert_states
+	ORDER BY rule ASC
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert states: %w", err)
+	}
+	defer rows.Close()
+
+	states := []AlertStateRecord{}
+	for rows.Next() {
+		var record AlertStateRecord
+		err := rows.Scan(
+			&record.Rule,
+			&record.State,
+			&record.Value,
+			&record.Since,
+			&record.FiredAt,
+			&record.ResolvedAt,
+			&record.Notified,
+			&record.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert state: %w", err)
+		}
+		states = append(states, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return states, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnomalyRecord representa uma medição considerada anômala pelo detector
+type AnomalyRecord struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Expected  float64   `json:"expected"`
+	Deviation float64   `json:"deviation"`
+	Score     float64   `json:"score"`
+	Method    string    `json:"method"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaveAnomaly salva uma nova anomalia no banco de dados
+func (r *SQLiteRepository) SaveAnomaly(anomaly AnomalyRecord) error {
+	query := `
+	INSERT INTO anomalies (timestamp, metric, value, expected, deviation, score, method)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.Exec(query, anomaly.Timestamp, anomaly.Metric, anomaly.Value, anomaly.Expected,
+		anomaly.Deviation, anomaly.Score, anomaly.Method)
+	if err != nil {
+		return fmt.Errorf("failed to save anomaly: %w", err)
+	}
+
+	return nil
+}
+
+// GetAnomaliesByTimeRange recupera anomalias dentro de um intervalo de tempo
+func (r *SQLiteRepository) GetAnomaliesByTimeRange(startTime, endTime time.Time) ([]AnomalyRecord, error) {
+	query := `
+	SELECT id, timestamp, metric, value, expected, deviation, score, method, created_at
+	FROM anomalies
+	WHERE timestamp >= ? AND timestamp <= ?
+	ORDER BY timestamp ASC
+	`
+
+	rows, err := r.db.Query(query, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []AnomalyRecord{}
+	for rows.Next() {
+		var record AnomalyRecord
+		err := rows.Scan(
+			&record.ID,
+			&record.Timestamp,
+			&record.Metric,
+			&record.Value,
+			&record.Expected,
+			&record.Deviation,
+			&record.Score,
+			&record.Method,
+			&record.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
+		}
+		anomalies = append(anomalies, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return anomalies, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+)
+
+// MeasurementImport grava medições importadas em lotes, por uma conexão dedicada.
+// Medições que já existem com o mesmo nó e timestamp são ignoradas, inclusive as
+// repetidas em lotes anteriores da mesma importação.
+type MeasurementImport struct {
+	conn   *sql.Conn
+	dryRun bool
+}
+
+// BeginImport inicia uma importação. Com dryRun nada é gravado no banco: as chaves
+// das medições que seriam inseridas ficam em uma tabela temporária da conexão, que
+// não bloqueia as escritas do serviço.
+func (r *SQLiteRepository) BeginImport(ctx context.Context, dryRun bool) (*MeasurementImport, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import connection: %w", err)
+	}
+
+	if dryRun {
+		_, err := conn.ExecContext(ctx, `CREATE TEMP TABLE IF NOT EXISTS import_keys (node TEXT NOT NULL, timestamp DATETIME NOT NULL, PRIMARY KEY (node, timestamp))`)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to create import table: %w", err)
+		}
+	}
+
+	return &MeasurementImport{conn: conn, dryRun: dryRun}, nil
+}
+
+// Write grava um lote em uma transação e retorna quantas medições foram inseridas,
+// ou seriam, em uma simulação
+func (i *MeasurementImport) Write(ctx context.Context, measurements []bme280.Measurement) (int, error) {
+	tx, err := i.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT OR IGNORE INTO measurements (timestamp, temperature, humidity, pressure, node)
+	VALUES (?, ?, ?, ?, ?)
+	`
+	if i.dryRun {
+		query = `
+		INSERT OR IGNORE INTO temp.import_keys (timestamp, node)
+		SELECT ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM main.measurements WHERE node = ? AND timestamp = ?)
+		`
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare import statement: %w", err)
+	}
+	defer stmt.Close()
+
+	imported := 0
+	for _, m := range measurements {
+		args := []any{m.Timestamp, m.Temperature, m.Humidity, m.Pressure, m.Node}
+		if i.dryRun {
+			args = []any{m.Timestamp, m.Node, m.Node, m.Timestamp}
+		}
+
+		result, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import measurement: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to import measurement: %w", err)
+		}
+		imported += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return imported, nil
+}
+
+// Close encerra a importação e devolve a conexão ao pool
+func (i *MeasurementImport) Close() error {
+	if i.dryRun {
+		// A conexão volta ao pool; a tabela não deve sobreviver à simulação
+		if _, err := i.conn.ExecContext(context.Background(), `DROP TABLE IF EXISTS temp.import_keys`); err != nil {
+			i.conn.Close()
+			return fmt.Errorf("failed to drop import table: %w", err)
+		}
+	}
+	return i.conn.Close()
+}
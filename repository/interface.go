@@ -12,8 +12,12 @@ type MeasurementRepository interface {
 
 	GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]MeasurementRecord, error)
 
+	GetNodeMeasurementsByTimeRange(node string, startTime, endTime time.Time) ([]MeasurementRecord, error)
+
 	GetLatestMeasurements(limit int) ([]MeasurementRecord, error)
 
+	GetOldestMeasurements(limit int) ([]MeasurementRecord, error)
+
 	GetMeasurementCount() (int64, error)
 
 	Close() error
@@ -3,6 +3,8 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"time"
 
@@ -15,18 +17,53 @@ var _ MeasurementRepository = (*SQLiteRepository)(nil)
 
 // SQLiteRepository implementa um repositório para armazenamento local usando SQLite
 type SQLiteRepository struct {
-	db       *sql.DB
-	filepath string
+	db     *sql.DB
+	config Config
 }
 
-// NewSQLiteRepository cria um novo repositório SQLite
+// Config configura a abertura do banco SQLite. Os pragmas são aplicados a cada
+// conexão do pool.
+type Config struct {
+	Path            string
+	JournalMode     string        // wal ou um modo de rollback journal: delete, truncate ou persist
+	Synchronous     string        // off, normal, full ou extra
+	BusyTimeout     time.Duration // Espera por um lock antes de retornar SQLITE_BUSY
+	CacheSize       int           // Cache de páginas por conexão, em KiB
+	MaxOpenConns    int           // 0 não limita
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration // 0 reutiliza as conexões indefinidamente
+	ReadOnly        bool          // Somente leitura: o banco deve existir e as tabelas não são criadas
+}
+
+// DefaultConfig retorna uma configuração adequada a cartões SD: WAL com synchronous
+// normal faz um fsync por checkpoint em vez de um por transação
+func DefaultConfig() Config {
+	return Config{
+		Path:         "weather.db",
+		JournalMode:  "wal",
+		Synchronous:  "normal",
+		BusyTimeout:  5 * time.Second,
+		CacheSize:    2048,
+		MaxOpenConns: 4,
+		MaxIdleConns: 2,
+	}
+}
+
+// NewSQLiteRepository cria um novo repositório SQLite com a configuração padrão
 func NewSQLiteRepository(filepath string) (*SQLiteRepository, error) {
-	if filepath == "" {
-		filepath = "weather.db"
+	config := DefaultConfig()
+	config.Path = filepath
+	return OpenSQLiteRepository(config)
+}
+
+// OpenSQLiteRepository abre um repositório SQLite com a configuração dada
+func OpenSQLiteRepository(config Config) (*SQLiteRepository, error) {
+	if config.Path == "" {
+		config.Path = "weather.db"
 	}
 
 	repo := &SQLiteRepository{
-		filepath: filepath,
+		config: config,
 	}
 
 	if err := repo.initialize(); err != nil {
@@ -36,32 +73,83 @@ func NewSQLiteRepository(filepath string) (*SQLiteRepository, error) {
 	return repo, nil
 }
 
-// initialize verifica se o arquivo de banco existe, cria se necessário e inicializa as tabelas
+// initialize abre o banco de dados (criando o arquivo se necessário) e inicializa as tabelas
 func (r *SQLiteRepository) initialize() error {
-	// Verifica se o arquivo existe
-	fileExists := true
-	if _, err := os.Stat(r.filepath); os.IsNotExist(err) {
-		fileExists = false
+	dsn, err := r.config.dsn()
+	if err != nil {
+		return err
+	}
+
+	if r.config.ReadOnly {
+		// O driver cria o arquivo quando ele não existe
+		if _, err := os.Stat(r.config.Path); err != nil {
+			return fmt.Errorf("failed to open read-only database: %w", err)
+		}
 	}
 
 	// Abre conexão com o banco (cria o arquivo se não existir)
-	db, err := sql.Open("sqlite", r.filepath)
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
+	db.SetMaxOpenConns(r.config.MaxOpenConns)
+	db.SetMaxIdleConns(r.config.MaxIdleConns)
+	db.SetConnMaxLifetime(r.config.ConnMaxLifetime)
 	r.db = db
 
-	// Se o arquivo não existia, cria as tabelas
-	if !fileExists {
-		if err := r.createTables(); err != nil {
-			return fmt.Errorf("failed to create tables: %w", err)
-		}
+	// A primeira conexão aplica os pragmas e reporta valores rejeitados pelo SQLite
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if r.config.ReadOnly {
+		return nil
+	}
+
+	// As tabelas são criadas com IF NOT EXISTS, o que também adiciona
+	// tabelas novas em bancos criados por versões anteriores
+	if err := r.createTables(); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
 	return nil
 }
 
+// dsn monta o nome da fonte de dados com os pragmas da configuração
+func (c Config) dsn() (string, error) {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", c.BusyTimeout.Milliseconds()))
+	if c.CacheSize != 0 {
+		// Valores negativos de cache_size são em KiB
+		pragmas.Add("_pragma", fmt.Sprintf("cache_size(%d)", -c.CacheSize))
+	}
+
+	if c.ReadOnly {
+		pragmas.Add("_pragma", "query_only(1)")
+		return c.Path + "?" + pragmas.Encode(), nil
+	}
+
+	if c.JournalMode != "" {
+		switch c.JournalMode {
+		case "wal", "delete", "truncate", "persist":
+		default:
+			return "", fmt.Errorf("unknown journal mode %q", c.JournalMode)
+		}
+		pragmas.Add("_pragma", "journal_mode("+c.JournalMode+")")
+	}
+	if c.Synchronous != "" {
+		switch c.Synchronous {
+		case "off", "normal", "full", "extra":
+		default:
+			return "", fmt.Errorf("unknown synchronous level %q", c.Synchronous)
+		}
+		pragmas.Add("_pragma", "synchronous("+c.Synchronous+")")
+	}
+	return c.Path + "?" + pragmas.Encode(), nil
+}
+
 // createTables cria as tabelas necessárias no banco de dados
 func (r *SQLiteRepository) createTables() error {
 	query := `
@@ -75,6 +163,48 @@ func (r *SQLiteRepository) createTables() error {
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_measurements_timestamp ON measurements(timestamp);
+
+	CREATE TABLE IF NOT EXISTS anomalies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		metric TEXT NOT NULL,
+		value REAL NOT NULL,
+		expected REAL NOT NULL,
+		deviation REAL NOT NULL,
+		score REAL NOT NULL,
+		method TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_anomalies_timestamp ON anomalies(timestamp);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		target TEXT NOT NULL,
+		event TEXT NOT NULL,
+		url TEXT NOT NULL,
+		method TEXT NOT NULL,
+		body TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status, updated_at);
+
+	CREATE TABLE IF NOT EXISTS alert_states (
+		rule TEXT PRIMARY KEY,
+		state TEXT NOT NULL,
+		value REAL NOT NULL,
+		since DATETIME NOT NULL,
+		fired_at DATETIME NOT NULL,
+		resolved_at DATETIME NOT NULL,
+		notified INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME NOT NULL
+	);
 	`
 
 	_, err := r.db.Exec(query)
@@ -82,17 +212,78 @@ func (r *SQLiteRepository) createTables() error {
 		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	return r.migrateNodeColumn()
+}
+
+// migrateNodeColumn adiciona a coluna node em bancos criados antes da ingestão remota.
+// Medições do sensor local usam node vazio.
+func (r *SQLiteRepository) migrateNodeColumn() error {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('measurements') WHERE name = 'node'`).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect measurements table: %w", err)
+	}
+
+	if count == 0 {
+		if _, err := r.db.Exec(`ALTER TABLE measurements ADD COLUMN node TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("failed to add node column: %w", err)
+		}
+	}
+
+	return r.migrateUniqueMeasurements()
+}
+
+// migrateUniqueMeasurements torna único o índice de nó e timestamp. Bancos anteriores podem
+// ter medições repetidas, das quais apenas a primeira é mantida; as removidas são registradas no log.
+func (r *SQLiteRepository) migrateUniqueMeasurements() error {
+	var unique int
+	err := r.db.QueryRow(`SELECT COALESCE(MAX("unique"), 0) FROM pragma_index_list('measurements') WHERE name = 'idx_measurements_node_timestamp'`).Scan(&unique)
+	if err != nil {
+		return fmt.Errorf("failed to inspect measurements indexes: %w", err)
+	}
+	if unique == 1 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin index migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM measurements WHERE id NOT IN (SELECT MIN(id) FROM measurements GROUP BY node, timestamp)`)
+	if err != nil {
+		return fmt.Errorf("failed to remove duplicate measurements: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to count duplicate measurements: %w", err)
+	}
+	if _, err := tx.Exec(`DROP INDEX IF EXISTS idx_measurements_node_timestamp`); err != nil {
+		return fmt.Errorf("failed to drop node index: %w", err)
+	}
+	if _, err := tx.Exec(`CREATE UNIQUE INDEX idx_measurements_node_timestamp ON measurements(node, timestamp)`); err != nil {
+		return fmt.Errorf("failed to create node index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit index migration: %w", err)
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d duplicate measurements with the same node and timestamp", removed)
+	}
 	return nil
 }
 
-// SaveMeasurement salva uma nova medição no banco de dados
+// SaveMeasurement salva uma nova medição no banco de dados.
+// Medições são ignoradas se já existir uma do mesmo nó com o mesmo timestamp.
 func (r *SQLiteRepository) SaveMeasurement(measurement bme280.Measurement) error {
 	query := `
-	INSERT INTO measurements (timestamp, temperature, humidity, pressure)
-	VALUES (?, ?, ?, ?)
+	INSERT OR IGNORE INTO measurements (timestamp, temperature, humidity, pressure, node)
+	VALUES (?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.Exec(query, measurement.Timestamp, measurement.Temperature, measurement.Humidity, measurement.Pressure)
+	_, err := r.db.Exec(query, measurement.Timestamp, measurement.Temperature, measurement.Humidity, measurement.Pressure, measurement.Node)
 	if err != nil {
 		return fmt.Errorf("failed to save measurement: %w", err)
 	}
@@ -100,50 +291,35 @@ func (r *SQLiteRepository) SaveMeasurement(measurement bme280.Measurement) error
 	return nil
 }
 
-// GetMeasurementsByTimeRange recupera medições dentro de um intervalo de tempo
+// GetMeasurementsByTimeRange recupera medições do sensor local dentro de um intervalo de tempo
 func (r *SQLiteRepository) GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]MeasurementRecord, error) {
+	return r.GetNodeMeasurementsByTimeRange("", startTime, endTime)
+}
+
+// GetNodeMeasurementsByTimeRange recupera medições de um nó dentro de um intervalo de tempo
+func (r *SQLiteRepository) GetNodeMeasurementsByTimeRange(node string, startTime, endTime time.Time) ([]MeasurementRecord, error) {
 	query := `
-	SELECT id, timestamp, temperature, humidity, pressure, created_at
+	SELECT id, timestamp, temperature, humidity, pressure, node, created_at
 	FROM measurements
-	WHERE timestamp >= ? AND timestamp <= ?
+	WHERE node = ? AND timestamp >= ? AND timestamp <= ?
 	ORDER BY timestamp ASC
 	`
 
-	rows, err := r.db.Query(query, startTime, endTime)
+	rows, err := r.db.Query(query, node, startTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query measurements: %w", err)
 	}
 	defer rows.Close()
 
-	var measurements []MeasurementRecord
-	for rows.Next() {
-		var record MeasurementRecord
-		err := rows.Scan(
-			&record.ID,
-			&record.Timestamp,
-			&record.Temperature,
-			&record.Humidity,
-			&record.Pressure,
-			&record.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan measurement: %w", err)
-		}
-		measurements = append(measurements, record)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	return measurements, nil
+	return scanMeasurements(rows)
 }
 
-// GetLatestMeasurements recupera as N medições mais recentes
+// GetLatestMeasurements recupera as N medições mais recentes do sensor local
 func (r *SQLiteRepository) GetLatestMeasurements(limit int) ([]MeasurementRecord, error) {
 	query := `
-	SELECT id, timestamp, temperature, humidity, pressure, created_at
+	SELECT id, timestamp, temperature, humidity, pressure, node, created_at
 	FROM measurements
+	WHERE node = ''
 	ORDER BY timestamp DESC
 	LIMIT ?
 	`
@@ -154,6 +330,30 @@ func (r *SQLiteRepository) GetLatestMeasurements(limit int) ([]MeasurementRecord
 	}
 	defer rows.Close()
 
+	return scanMeasurements(rows)
+}
+
+// GetOldestMeasurements recupera as N medições mais antigas do sensor local
+func (r *SQLiteRepository) GetOldestMeasurements(limit int) ([]MeasurementRecord, error) {
+	query := `
+	SELECT id, timestamp, temperature, humidity, pressure, node, created_at
+	FROM measurements
+	WHERE node = ''
+	ORDER BY timestamp ASC
+	LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query oldest measurements: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMeasurements(rows)
+}
+
+// scanMeasurements lê todas as linhas de uma consulta de medições
+func scanMeasurements(rows *sql.Rows) ([]MeasurementRecord, error) {
 	var measurements []MeasurementRecord
 	for rows.Next() {
 		var record MeasurementRecord
@@ -163,6 +363,7 @@ func (r *SQLiteRepository) GetLatestMeasurements(limit int) ([]MeasurementRecord
 			&record.Temperature,
 			&record.Humidity,
 			&record.Pressure,
+			&record.Node,
 			&record.CreatedAt,
 		)
 		if err != nil {
@@ -171,7 +372,7 @@ func (r *SQLiteRepository) GetLatestMeasurements(limit int) ([]MeasurementRecord
 		measurements = append(measurements, record)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
@@ -206,6 +407,7 @@ type MeasurementRecord struct {
 	Temperature float64   `json:"temperature"`
 	Humidity    float64   `json:"humidity"`
 	Pressure    int64     `json:"pressure"`
+	Node        string    `json:"node,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -216,5 +418,6 @@ func (m MeasurementRecord) ToMeasurement() bme280.Measurement {
 		Temperature: m.Temperature,
 		Humidity:    m.Humidity,
 		Pressure:    m.Pressure,
+		Node:        m.Node,
 	}
 }
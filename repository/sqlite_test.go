@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -113,13 +115,14 @@ func TestSQLiteRepository(t *testing.T) {
 }
 
 func TestRepositoryWithDefaultPath(t *testing.T) {
-	// Testa criação com caminho padrão
+	// Testa criação com caminho padrão num diretório temporário, que também recebe
+	// os arquivos -wal e -shm do modo WAL
+	t.Chdir(t.TempDir())
 	repo, err := NewSQLiteRepository("")
 	if err != nil {
 		t.Fatalf("Failed to create repository with default path: %v", err)
 	}
 	defer repo.Close()
-	defer os.Remove("weather.db") // Limpa arquivo padrão
 
 	// Verifica se o arquivo foi criado com nome padrão
 	if _, err := os.Stat("weather.db"); os.IsNotExist(err) {
@@ -165,3 +168,502 @@ func TestRepositoryInitializationWithExistingFile(t *testing.T) {
 		t.Errorf("Expected count 1 from existing file, got %d", count)
 	}
 }
+
+func TestGetOldestMeasurements(t *testing.T) {
+	testDB := "test_oldest_weather.db"
+	defer os.Remove(testDB)
+
+	repo, err := NewSQLiteRepository(testDB)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	// Banco vazio não retorna medições
+	oldest, err := repo.GetOldestMeasurements(1)
+	if err != nil {
+		t.Fatalf("Failed to get oldest measurements: %v", err)
+	}
+	if len(oldest) != 0 {
+		t.Fatalf("Expected no measurements, got %d", len(oldest))
+	}
+
+	base := time.Now().Truncate(time.Second)
+	for i := 3; i >= 1; i-- {
+		err := repo.SaveMeasurement(bme280.Measurement{
+			Timestamp:   base.Add(time.Duration(i) * time.Hour),
+			Temperature: float64(i),
+			Humidity:    50.0,
+			Pressure:    101300,
+		})
+		if err != nil {
+			t.Fatalf("Failed to save measurement %d: %v", i, err)
+		}
+	}
+
+	oldest, err = repo.GetOldestMeasurements(2)
+	if err != nil {
+		t.Fatalf("Failed to get oldest measurements: %v", err)
+	}
+	if len(oldest) != 2 {
+		t.Fatalf("Expected 2 measurements, got %d", len(oldest))
+	}
+	if oldest[0].Temperature != 1 || oldest[1].Temperature != 2 {
+		t.Errorf("Expected oldest measurements in ascending order, got %v and %v", oldest[0].Temperature, oldest[1].Temperature)
+	}
+}
+
+func TestNodeMeasurements(t *testing.T) {
+	testDB := "test_node_weather.db"
+	defer os.Remove(testDB)
+
+	repo, err := NewSQLiteRepository(testDB)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	base := time.Now().Truncate(time.Second)
+	measurements := []bme280.Measurement{
+		{Timestamp: base, Temperature: 20, Humidity: 50, Pressure: 101300},
+		{Timestamp: base, Temperature: 18, Humidity: 60, Pressure: 101200, Node: "kitchen"},
+		// Reenvio da mesma leitura de um nó remoto é ignorado
+		{Timestamp: base, Temperature: 18, Humidity: 60, Pressure: 101200, Node: "kitchen"},
+	}
+	for _, m := range measurements {
+		if err := repo.SaveMeasurement(m); err != nil {
+			t.Fatalf("Failed to save measurement: %v", err)
+		}
+	}
+
+	count, err := repo.GetMeasurementCount()
+	if err != nil {
+		t.Fatalf("Failed to get measurement count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected duplicate remote measurement to be ignored, got count %d", count)
+	}
+
+	// Consultas locais não incluem medições de nós remotos
+	local, err := repo.GetMeasurementsByTimeRange(base.Add(-time.Minute), base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to get local measurements: %v", err)
+	}
+	if len(local) != 1 || local[0].Temperature != 20 || local[0].Node != "" {
+		t.Errorf("Expected only the local measurement, got %+v", local)
+	}
+
+	latest, err := repo.GetLatestMeasurements(10)
+	if err != nil {
+		t.Fatalf("Failed to get latest measurements: %v", err)
+	}
+	if len(latest) != 1 {
+		t.Errorf("Expected latest measurements to exclude remote nodes, got %d", len(latest))
+	}
+
+	remote, err := repo.GetNodeMeasurementsByTimeRange("kitchen", base.Add(-time.Minute), base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to get node measurements: %v", err)
+	}
+	if len(remote) != 1 || remote[0].Node != "kitchen" || remote[0].ToMeasurement().Node != "kitchen" {
+		t.Errorf("Expected the kitchen measurement, got %+v", remote)
+	}
+}
+
+// TestMigrateUniqueMeasurements verifica que um banco com medições repetidas de uma
+// versão anterior mantém apenas a primeira de cada nó e timestamp
+func TestMigrateUniqueMeasurements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE measurements (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		temperature REAL NOT NULL,
+		humidity REAL NOT NULL,
+		pressure INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		node TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_measurements_node_timestamp ON measurements(node, timestamp);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+	base := time.Now().Truncate(time.Second)
+	for i, node := range []string{"", "", "kitchen", "kitchen", "kitchen"} {
+		_, err := db.Exec(`INSERT INTO measurements (timestamp, temperature, humidity, pressure, node) VALUES (?, ?, 50, 101300, ?)`, base, float64(i), node)
+		if err != nil {
+			t.Fatalf("Failed to insert measurement: %v", err)
+		}
+	}
+	db.Close()
+
+	repo, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	// O sensor local também ignora uma leitura repetida
+	if err := repo.SaveMeasurement(bme280.Measurement{Timestamp: base, Temperature: 30}); err != nil {
+		t.Fatalf("Failed to save measurement: %v", err)
+	}
+
+	local, err := repo.GetMeasurementsByTimeRange(base, base)
+	if err != nil {
+		t.Fatalf("Failed to get local measurements: %v", err)
+	}
+	remote, err := repo.GetNodeMeasurementsByTimeRange("kitchen", base, base)
+	if err != nil {
+		t.Fatalf("Failed to get node measurements: %v", err)
+	}
+	if len(local) != 1 || local[0].Temperature != 0 || len(remote) != 1 || remote[0].Temperature != 2 {
+		t.Errorf("Expected the first measurement of each node, got %+v and %+v", local, remote)
+	}
+
+	// Reabrir o banco não repete a migração
+	repo.Close()
+	repo, err = NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen repository: %v", err)
+	}
+	defer repo.Close()
+	count, err := repo.GetMeasurementCount()
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 measurements, got %d (%v)", count, err)
+	}
+}
+
+func TestAnomalies(t *testing.T) {
+	testDB := "test_anomalies_weather.db"
+	defer os.Remove(testDB)
+
+	repo, err := NewSQLiteRepository(testDB)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ts := time.Now().Truncate(time.Second)
+	anomaly := AnomalyRecord{
+		Timestamp: ts,
+		Metric:    "temperature",
+		Value:     41.5,
+		Expected:  24.0,
+		Deviation: 1.2,
+		Score:     9.8,
+		Method:    "mad",
+	}
+	if err := repo.SaveAnomaly(anomaly); err != nil {
+		t.Fatalf("Failed to save anomaly: %v", err)
+	}
+
+	anomalies, err := repo.GetAnomaliesByTimeRange(ts.Add(-time.Hour), ts.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get anomalies: %v", err)
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	if anomalies[0].Metric != "temperature" || anomalies[0].Score != 9.8 {
+		t.Errorf("Unexpected anomaly: %+v", anomalies[0])
+	}
+
+	// Fora do intervalo não deve retornar anomalias
+	anomalies, err = repo.GetAnomaliesByTimeRange(ts.Add(time.Hour), ts.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get anomalies: %v", err)
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies, got %d", len(anomalies))
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	testDB := "test_webhooks_weather.db"
+	defer os.Remove(testDB)
+
+	repo, err := NewSQLiteRepository(testDB)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	now := time.Now().Truncate(time.Second)
+	delivery := WebhookDeliveryRecord{
+		Target:    "slack",
+		Event:     "sensor_down",
+		URL:       "https://hooks.example.com/1",
+		Method:    "POST",
+		Body:      `{"text":"sensor down"}`,
+		Status:    WebhookPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	id, err := repo.SaveWebhookDelivery(delivery)
+	if err != nil {
+		t.Fatalf("Failed to save webhook delivery: %v", err)
+	}
+
+	delivery.Target = "ntfy"
+	delivery.Event = "reading"
+	if _, err := repo.SaveWebhookDelivery(delivery); err != nil {
+		t.Fatalf("Failed to save webhook delivery: %v", err)
+	}
+
+	pending, err := repo.GetPendingWebhookDeliveries(now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to get pending deliveries: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != id {
+		t.Fatalf("Expected 2 pending deliveries starting with %d, got %+v", id, pending)
+	}
+
+	// Entregas atualizadas a partir do limite não são retornadas
+	pending, err = repo.GetPendingWebhookDeliveries(now)
+	if err != nil {
+		t.Fatalf("Failed to get pending deliveries: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending deliveries, got %d", len(pending))
+	}
+
+	delivery.ID = id
+	delivery.Status = WebhookDelivered
+	delivery.Attempts = 2
+	delivery.ResponseCode = 200
+	delivery.UpdatedAt = now.Add(time.Minute)
+	if err := repo.UpdateWebhookDelivery(delivery); err != nil {
+		t.Fatalf("Failed to update webhook delivery: %v", err)
+	}
+
+	deliveries, err := repo.GetWebhookDeliveries(WebhookDeliveryFilter{Status: WebhookDelivered})
+	if err != nil {
+		t.Fatalf("Failed to get webhook deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Target != "slack" || deliveries[0].Attempts != 2 || deliveries[0].ResponseCode != 200 {
+		t.Errorf("Unexpected delivered webhooks: %+v", deliveries)
+	}
+
+	// Histórico completo, do mais recente ao mais antigo
+	deliveries, err = repo.GetWebhookDeliveries(WebhookDeliveryFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to get webhook deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Target != "ntfy" {
+		t.Errorf("Expected the most recent delivery, got %+v", deliveries)
+	}
+
+	// Apenas entregas concluídas antes do limite são removidas; as pendentes ficam
+	deleted, err := repo.DeleteWebhookDeliveries(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to delete webhook deliveries: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted delivery, got %d", deleted)
+	}
+	deliveries, err = repo.GetWebhookDeliveries(WebhookDeliveryFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != WebhookPending {
+		t.Errorf("Expected only the pending delivery to be kept, got %+v", deliveries)
+	}
+}
+
+func TestAlertStates(t *testing.T) {
+	testDB := "test_alerts_weather.db"
+	defer os.Remove(testDB)
+
+	repo, err := NewSQLiteRepository(testDB)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	now := time.Now().Truncate(time.Second)
+	state := AlertStateRecord{Rule: "frost", State: "pending", Value: 1.5, Since: now, UpdatedAt: now}
+	if err := repo.SaveAlertState(state); err != nil {
+		t.Fatalf("Failed to save alert state: %v", err)
+	}
+
+	// Salvar a mesma regra de novo atualiza o estado existente
+	state.State = "firing"
+	state.FiredAt = now.Add(10 * time.Minute)
+	state.Notified = true
+	if err := repo.SaveAlertState(state); err != nil {
+		t.Fatalf("Failed to update alert state: %v", err)
+	}
+
+	states, err := repo.GetAlertStates()
+	if err != nil {
+		t.Fatalf("Failed to get alert states: %v", err)
+	}
+	if len(states) != 1 {
+		t.Fatalf("Expected 1 alert state, got %d", len(states))
+	}
+	got := states[0]
+	if got.State != "firing" || !got.Notified || !got.FiredAt.Equal(state.FiredAt) || !got.ResolvedAt.IsZero() {
+		t.Errorf("Unexpected alert state: %+v", got)
+	}
+}
+
+// TestOpenSQLiteRepository_Pragmas verifica que os pragmas e os limites do pool são aplicados
+func TestOpenSQLiteRepository_Pragmas(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "weather.db")
+	config.Synchronous = "full"
+	config.BusyTimeout = 2 * time.Second
+	config.CacheSize = 1024
+	config.MaxOpenConns = 3
+
+	repo, err := OpenSQLiteRepository(config)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	defer repo.Close()
+
+	var journalMode string
+	var synchronous, busyTimeout, cacheSize int
+	if err := repo.db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.db.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.db.QueryRow("PRAGMA cache_size").Scan(&cacheSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if journalMode != "wal" || synchronous != 2 || busyTimeout != 2000 || cacheSize != -1024 {
+		t.Errorf("Unexpected pragmas: journal_mode=%s synchronous=%d busy_timeout=%d cache_size=%d",
+			journalMode, synchronous, busyTimeout, cacheSize)
+	}
+	if max := repo.db.Stats().MaxOpenConnections; max != 3 {
+		t.Errorf("Expected 3 max open connections, got %d", max)
+	}
+
+	// Modos inválidos são rejeitados antes de abrir o banco
+	config.JournalMode = "memory"
+	if _, err := OpenSQLiteRepository(config); err == nil {
+		t.Error("Expected an error for an unknown journal mode")
+	}
+}
+
+// TestOpenSQLiteRepository_ReadOnly verifica que a réplica somente leitura lê o banco e rejeita escritas
+func TestOpenSQLiteRepository_ReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather.db")
+
+	config := DefaultConfig()
+	config.Path = path
+	config.ReadOnly = true
+	if _, err := OpenSQLiteRepository(config); err == nil {
+		t.Fatal("Expected an error opening a missing database read-only")
+	}
+
+	repo, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	if err := repo.SaveMeasurement(bme280.Measurement{Timestamp: time.Now(), Temperature: 21, Humidity: 50, Pressure: 101325}); err != nil {
+		t.Fatalf("Failed to save measurement: %v", err)
+	}
+
+	replica, err := OpenSQLiteRepository(config)
+	if err != nil {
+		t.Fatalf("Failed to open read-only repository: %v", err)
+	}
+	defer replica.Close()
+
+	count, err := replica.GetMeasurementCount()
+	if err != nil || count != 1 {
+		t.Errorf("Expected 1 measurement from the replica, got %d (%v)", count, err)
+	}
+	if err := replica.SaveMeasurement(bme280.Measurement{Timestamp: time.Now()}); err == nil {
+		t.Error("Expected the read-only repository to reject writes")
+	}
+}
+
+// TestStreamMeasurements verifica que o percurso em páginas e a paginação por cursor
+// retornam cada medição uma vez, em ordem
+func TestStreamMeasurements(t *testing.T) {
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "weather.db"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	// Mais que duas páginas de leitura
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	const total = 2*streamPageSize + 100
+	for i := range total {
+		m := bme280.Measurement{Timestamp: start.Add(time.Duration(i) * time.Minute), Temperature: float64(i), Humidity: 50, Pressure: 101325}
+		if err := repo.SaveMeasurement(m); err != nil {
+			t.Fatalf("Failed to save measurement: %v", err)
+		}
+	}
+	if err := repo.SaveMeasurement(bme280.Measurement{Timestamp: start, Node: "attic"}); err != nil {
+		t.Fatalf("Failed to save measurement: %v", err)
+	}
+
+	query := MeasurementQuery{From: start, To: start.Add(24 * time.Hour)}
+	var streamed []float64
+	for record, err := range repo.StreamMeasurements(t.Context(), query) {
+		if err != nil {
+			t.Fatalf("Failed to stream measurements: %v", err)
+		}
+		streamed = append(streamed, record.Temperature)
+	}
+	if len(streamed) != total {
+		t.Fatalf("Expected %d streamed measurements, got %d", total, len(streamed))
+	}
+	for i, temperature := range streamed {
+		if temperature != float64(i) {
+			t.Fatalf("Expected measurement %d in order, got %v", i, temperature)
+		}
+	}
+
+	// Páginas de 333 medições seguindo o cursor até a última
+	query.Limit = 333
+	var paged, pages int
+	for {
+		for record, err := range repo.StreamMeasurements(t.Context(), query) {
+			if err != nil {
+				t.Fatalf("Failed to stream page: %v", err)
+			}
+			if record.Temperature != float64(paged) {
+				t.Fatalf("Expected measurement %d, got %v", paged, record.Temperature)
+			}
+			paged++
+		}
+		pages++
+
+		next, more, err := repo.NextMeasurementCursor(t.Context(), query)
+		if err != nil {
+			t.Fatalf("Failed to get next cursor: %v", err)
+		}
+		if !more {
+			break
+		}
+		cursor, err := ParseMeasurementCursor(next.String())
+		if err != nil || cursor != next {
+			t.Fatalf("Expected the cursor to round trip, got %+v (%v)", cursor, err)
+		}
+		query.After = &cursor
+	}
+	if paged != total || pages != 4 {
+		t.Errorf("Expected %d measurements in 4 pages, got %d in %d", total, paged, pages)
+	}
+
+	if _, err := ParseMeasurementCursor("not a cursor"); err == nil {
+		t.Error("Expected an error for an invalid cursor")
+	}
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamPageSize é o número de linhas lidas por consulta ao percorrer medições
+const streamPageSize = 500
+
+// MeasurementCursor é a posição de uma medição na ordem (timestamp, id). O timestamp
+// é o texto gravado no banco, para que a comparação com as linhas seja exata.
+type MeasurementCursor struct {
+	Timestamp string
+	ID        int64
+}
+
+// String codifica o cursor em um valor opaco, seguro para URLs
+func (c MeasurementCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.ID, 10) + "|" + c.Timestamp))
+}
+
+// ParseMeasurementCursor decodifica um cursor criado por MeasurementCursor.String
+func ParseMeasurementCursor(s string) (MeasurementCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return MeasurementCursor{}, errors.New("invalid cursor")
+	}
+
+	id, timestamp, ok := strings.Cut(string(raw), "|")
+	if !ok || timestamp == "" {
+		return MeasurementCursor{}, errors.New("invalid cursor")
+	}
+	cursor := MeasurementCursor{Timestamp: timestamp}
+	if cursor.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return MeasurementCursor{}, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// MeasurementQuery seleciona as medições de um nó em um intervalo de tempo, a partir
+// de um cursor opcional e limitadas a Limit linhas quando Limit é positivo
+type MeasurementQuery struct {
+	Node  string
+	From  time.Time
+	To    time.Time
+	After *MeasurementCursor
+	Limit int
+}
+
+// where monta o filtro da consulta, continuando depois de after quando informado
+func (q MeasurementQuery) where(after *MeasurementCursor) (string, []any) {
+	if after == nil {
+		return "node = ? AND timestamp >= ? AND timestamp <= ?", []any{q.Node, q.From, q.To}
+	}
+
+	// O cursor entra no limite inferior do índice; com ele em uma condição OR
+	// separada, cada página percorreria o índice desde From
+	where := "node = ? AND timestamp >= MAX(?, ?) AND timestamp <= ? AND (timestamp > ? OR id > ?)"
+	return where, []any{q.Node, q.From, after.Timestamp, q.To, after.Timestamp, after.ID}
+}
+
+// StreamMeasurements percorre as medições da consulta em ordem de timestamp sem
+// carregá-las todas na memória. As linhas são lidas em páginas curtas pela posição
+// da última lida, de modo que uma leitura demorada não prende uma transação aberta
+// e não impede o checkpoint do WAL. O percurso termina no primeiro erro.
+func (r *SQLiteRepository) StreamMeasurements(ctx context.Context, q MeasurementQuery) iter.Seq2[MeasurementRecord, error] {
+	return func(yield func(MeasurementRecord, error) bool) {
+		after := q.After
+		remaining := q.Limit
+		for {
+			pageSize := streamPageSize
+			if q.Limit > 0 {
+				pageSize = min(pageSize, remaining)
+			}
+
+			page, err := r.measurementPage(ctx, q, after, pageSize)
+			if err != nil {
+				yield(MeasurementRecord{}, err)
+				return
+			}
+
+			for _, row := range page {
+				if !yield(row.record, nil) {
+					return
+				}
+			}
+
+			if q.Limit > 0 {
+				remaining -= len(page)
+				if remaining == 0 {
+					return
+				}
+			}
+			if len(page) < pageSize {
+				return
+			}
+			after = &page[len(page)-1].cursor
+		}
+	}
+}
+
+// cursorRecord é uma medição com a sua posição
+type cursorRecord struct {
+	record MeasurementRecord
+	cursor MeasurementCursor
+}
+
+// measurementPage lê até limit medições da consulta depois de after
+func (r *SQLiteRepository) measurementPage(ctx context.Context, q MeasurementQuery, after *MeasurementCursor, limit int) ([]cursorRecord, error) {
+	where, args := q.where(after)
+	query := `
+	SELECT id, timestamp, temperature, humidity, pressure, node, created_at, CAST(timestamp AS TEXT)
+	FROM measurements
+	WHERE ` + where + `
+	ORDER BY timestamp ASC, id ASC
+	LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measurements: %w", err)
+	}
+	defer rows.Close()
+
+	page := make([]cursorRecord, 0, limit)
+	for rows.Next() {
+		var row cursorRecord
+		err := rows.Scan(
+			&row.record.ID,
+			&row.record.Timestamp,
+			&row.record.Temperature,
+			&row.record.Humidity,
+			&row.record.Pressure,
+			&row.record.Node,
+			&row.record.CreatedAt,
+			&row.cursor.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+		row.cursor.ID = row.record.ID
+		page = append(page, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return page, nil
+}
+
+// NextMeasurementCursor retorna o cursor da última medição da página descrita pela
+// consulta quando existem medições depois dela, e false quando a página é a última
+// ou a consulta não tem limite
+func (r *SQLiteRepository) NextMeasurementCursor(ctx context.Context, q MeasurementQuery) (MeasurementCursor, bool, error) {
+	if q.Limit <= 0 {
+		return MeasurementCursor{}, false, nil
+	}
+
+	where, args := q.where(q.After)
+	query := `
+	SELECT id, CAST(timestamp AS TEXT)
+	FROM measurements
+	WHERE ` + where + `
+	ORDER BY timestamp ASC, id ASC
+	LIMIT 2 OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, q.Limit-1)...)
+	if err != nil {
+		return MeasurementCursor{}, false, fmt.Errorf("failed to query next cursor: %w", err)
+	}
+	defer rows.Close()
+
+	var cursors []MeasurementCursor
+	for rows.Next() {
+		var cursor MeasurementCursor
+		if err := rows.Scan(&cursor.ID, &cursor.Timestamp); err != nil {
+			return MeasurementCursor{}, false, fmt.Errorf("failed to scan cursor: %w", err)
+		}
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return MeasurementCursor{}, false, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	// Uma segunda linha mostra que a página não é a última
+	if len(cursors) < 2 {
+		return MeasurementCursor{}, false, nil
+	}
+	return cursors[0], true, nil
+}
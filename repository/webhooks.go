@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Estados de uma entrega de webhook
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookDeliveryRecord representa uma entrega de webhook e o resultado da última tentativa
+type WebhookDeliveryRecord struct {
+	ID           int64     `json:"id"`
+	Target       string    `json:"target"`
+	Event        string    `json:"event"`
+	URL          string    `json:"url"`
+	Method       string    `json:"method"`
+	Body         string    `json:"body"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	ResponseCode int       `json:"response_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryFilter restringe a consulta do histórico de entregas; campos vazios não filtram
+type WebhookDeliveryFilter struct {
+	Target string
+	Event  string
+	Status string
+	Limit  int
+}
+
+// SaveWebhookDelivery salva uma nova entrega e retorna seu ID
+func (r *SQLiteRepository) SaveWebhookDelivery(delivery WebhookDeliveryRecord) (int64, error) {
+	query := `
+	INSERT INTO webhook_deliveries (target, event, url, method, body, status, attempts, response_code, error, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, delivery.Target, delivery.Event, delivery.URL, delivery.Method,
+		delivery.Body, delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error,
+		delivery.CreatedAt, delivery.UpdatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook delivery id: %w", err)
+	}
+
+	return id, nil
+}
+
+// UpdateWebhookDelivery atualiza o estado e o resultado da última tentativa de uma entrega
+func (r *SQLiteRepository) UpdateWebhookDelivery(delivery WebhookDeliveryRecord) error {
+	query := `
+	UPDATE webhook_deliveries
+	SET status = ?, attempts = ?, response_code = ?, error = ?, updated_at = ?
+	WHERE id = ?
+	`
+
+	_, err := r.db.Exec(query, delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error,
+		delivery.UpdatedAt, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingWebhookDeliveries recupera as entregas pendentes atualizadas antes de before,
+// em ordem de criação
+func (r *SQLiteRepository) GetPendingWebhookDeliveries(before time.Time) ([]WebhookDeliveryRecord, error) {
+	query := `
+	SELECT id, target, event, url, method, body, status, attempts, response_code, error, created_at, updated_at
+	FROM webhook_deliveries
+	WHERE status = ? AND updated_at < ?
+	ORDER BY id ASC
+	`
+
+	return r.queryWebhookDeliveries(query, WebhookPending, before)
+}
+
+// DeleteWebhookDeliveries remove as entregas concluídas, entregues ou com falha, atualizadas
+// antes de before e retorna quantas foram removidas. Entregas pendentes são mantidas.
+func (r *SQLiteRepository) DeleteWebhookDeliveries(before time.Time) (int64, error) {
+	query := `
+	DELETE FROM webhook_deliveries
+	WHERE status != ? AND updated_at < ?
+	`
+
+	result, err := r.db.Exec(query, WebhookPending, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted webhook deliveries: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// GetWebhookDeliveries recupera o histórico de entregas, das mais recentes para as mais antigas
+func (r *SQLiteRepository) GetWebhookDeliveries(filter WebhookDeliveryFilter) ([]WebhookDeliveryRecord, error) {
+	var conditions []string
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"target", filter.Target},
+		{"event", filter.Event},
+		{"status", filter.Status},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+
+	query := `
+	SELECT id, target, event, url, method, body, status, attempts, response_code, error, created_at, updated_at
+	FROM webhook_deliveries
+	`
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+	query += "ORDER BY id DESC\n"
+	if filter.Limit > 0 {
+		query += "LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	return r.queryWebhookDeliveries(query, args...)
+}
+
+// queryWebhookDeliveries executa uma consulta de entregas e converte as linhas
+func (r *SQLiteRepository) queryWebhookDeliveries(query string, args ...any) ([]WebhookDeliveryRecord, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDeliveryRecord{}
+	for rows.Next() {
+		var record WebhookDeliveryRecord
+		err := rows.Scan(
+			&record.ID,
+			&record.Target,
+			&record.Event,
+			&record.URL,
+			&record.Method,
+			&record.Body,
+			&record.Status,
+			&record.Attempts,
+			&record.ResponseCode,
+			&record.Error,
+			&record.CreatedAt,
+			&record.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
 )
 
@@ -15,7 +17,13 @@ type SensorReader struct {
 	sensor   bme280.Reader
 	queue    *queue.Queue[bme280.Measurement]
 	interval time.Duration
-	name     string // nome do sensor para logs
+	reset    chan struct{} // sinaliza ao Start que o intervalo mudou
+	name     string        // nome do sensor para logs
+
+	mu             sync.RWMutex
+	subscribers    []chan bme280.Measurement
+	down           bool
+	onStatusChange func(up bool, err error)
 }
 
 // NewSensorReader cria um novo worker genérico de sensor
@@ -24,15 +32,17 @@ func NewSensorReader(sensor bme280.Reader, queue *queue.Queue[bme280.Measurement
 		sensor:   sensor,
 		queue:    queue,
 		interval: interval,
+		reset:    make(chan struct{}, 1),
 		name:     sensor.Name(),
 	}
 }
 
 // Start inicia a leitura contínua do sensor
 func (w *SensorReader) Start(ctx context.Context) error {
-	log.Printf("Starting %s sensor worker (reading every %v)", w.name, w.interval)
+	interval := w.Interval()
+	log.Printf("Starting %s sensor worker (reading every %v)", w.name, interval)
 
-	ticker := time.NewTicker(w.interval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -41,26 +51,106 @@ func (w *SensorReader) Start(ctx context.Context) error {
 			log.Printf("%s sensor worker stopped", w.name)
 			return ctx.Err()
 
+		case <-w.reset:
+			interval = w.Interval()
+			ticker.Reset(interval)
+			log.Printf("%s sensor worker now reading every %v", w.name, interval)
+
 		case <-ticker.C:
 			if err := w.readAndEnqueue(); err != nil {
-				log.Printf("Error reading from %s sensor: %v", w.name, err)
+				logging.Errorf("Error reading from %s sensor: %v", w.name, err)
 			}
 		}
 	}
 }
 
+// Interval retorna o intervalo atual entre leituras
+func (w *SensorReader) Interval() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.interval
+}
+
+// SetInterval altera o intervalo entre leituras; um Start em execução passa a usá-lo
+// a partir da próxima leitura
+func (w *SensorReader) SetInterval(interval time.Duration) {
+	w.mu.Lock()
+	changed := w.interval != interval
+	w.interval = interval
+	w.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	select {
+	case w.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe retorna um canal que recebe cada medição lida do sensor.
+// Envios não bloqueiam: se o canal estiver cheio a medição é descartada para esse assinante.
+func (w *SensorReader) Subscribe(buffer int) <-chan bme280.Measurement {
+	ch := make(chan bme280.Measurement, buffer)
+
+	w.mu.Lock()
+	w.subscribers = append(w.subscribers, ch)
+	w.mu.Unlock()
+
+	return ch
+}
+
+// OnStatusChange registra uma função chamada quando o sensor para de responder
+// (up false, com o erro da leitura) e quando volta a responder (up true)
+func (w *SensorReader) OnStatusChange(fn func(up bool, err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onStatusChange = fn
+}
+
+// setStatus registra o resultado de uma leitura e notifica as mudanças de estado
+func (w *SensorReader) setStatus(up bool, err error) {
+	w.mu.Lock()
+	changed := w.down == up
+	w.down = !up
+	fn := w.onStatusChange
+	w.mu.Unlock()
+
+	if changed && fn != nil {
+		fn(up, err)
+	}
+}
+
+// publish entrega a medição para todos os assinantes
+func (w *SensorReader) publish(measurement bme280.Measurement) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, ch := range w.subscribers {
+		select {
+		case ch <- measurement:
+		default:
+			logging.Warnf("%s subscriber is full, dropping measurement", w.name)
+		}
+	}
+}
+
 // readAndEnqueue lê uma medição do sensor e a envia para a fila
 func (w *SensorReader) readAndEnqueue() error {
 	measurement, err := w.sensor.Read()
 	if err != nil {
+		w.setStatus(false, err)
 		return fmt.Errorf("failed to read from %s sensor: %w", w.name, err)
 	}
+	w.setStatus(true, nil)
+
+	w.publish(measurement)
 
 	if err := w.queue.Enqueue(measurement); err != nil {
 		return fmt.Errorf("failed to enqueue %s measurement: %w", w.name, err)
 	}
 
-	log.Printf("%s reading enqueued: temp=%.1f°C, humidity=%.1f%%, pressure=%d Pa",
+	logging.Debugf("%s reading enqueued: temp=%.1f°C, humidity=%.1f%%, pressure=%d Pa",
 		w.name, measurement.Temperature, measurement.Humidity, measurement.Pressure)
 
 	return nil
@@ -0,0 +1,84 @@
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// GraphiteConfig configures the Graphite sink
+type GraphiteConfig struct {
+	Address string        // Carbon plaintext listener, e.g. localhost:2003
+	Prefix  string        // Metric path prefix, e.g. atmosbyte
+	Timeout time.Duration // Timeout of each write, defaults to 10s
+}
+
+// Graphite writes measurements with the Carbon plaintext protocol over TCP, one
+// connection per measurement
+type Graphite struct {
+	config GraphiteConfig
+}
+
+// NewGraphite creates a Graphite sink
+func NewGraphite(config GraphiteConfig) (*Graphite, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("graphite address is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &Graphite{config: config}, nil
+}
+
+// Name implements Sink
+func (s *Graphite) Name() string {
+	return "Graphite"
+}
+
+// Process implements queue.Worker[bme280.Measurement]
+func (s *Graphite) Process(ctx context.Context, msg queue.Message[bme280.Measurement]) error {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Address)
+	if err != nil {
+		return retryable(fmt.Errorf("failed to connect to %s: %w", s.config.Address, err))
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	timestamp := strconv.FormatInt(msg.Data.Timestamp.Unix(), 10)
+	var b strings.Builder
+	for _, f := range fields(msg.Data) {
+		fmt.Fprintf(&b, "%s %s %s\n", metricPath(s.config.Prefix, f.name), strconv.FormatFloat(f.value, 'f', -1, 64), timestamp)
+	}
+
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return retryable(fmt.Errorf("failed to write metrics: %w", err))
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *Graphite) Close() error {
+	return nil
+}
+
+// metricPath joins prefix and name with a dot
+func metricPath(prefix, name string) string {
+	prefix = strings.Trim(prefix, ".")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,74 @@
+package tsdb
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+func TestGraphite_Process(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var received []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			received = append(received, scanner.Text())
+		}
+		lines <- received
+	}()
+
+	sink, err := NewGraphite(GraphiteConfig{Address: listener.Addr().String(), Prefix: "home.atmosbyte."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Process(t.Context(), measurementAt(time.Unix(1748779200, 0), 21.5)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"home.atmosbyte.temperature 21.5 1748779200",
+		"home.atmosbyte.humidity 55.5 1748779200",
+		"home.atmosbyte.pressure 101325 1748779200",
+	}
+	received := <-lines
+	if len(received) != len(want) {
+		t.Fatalf("expected %q, got %q", want, received)
+	}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Errorf("expected %q, got %q", want[i], received[i])
+		}
+	}
+}
+
+func TestGraphite_ConnectionErrorIsRetryable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	sink, err := NewGraphite(GraphiteConfig{Address: addr, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Process(t.Context(), measurementAt(time.Now(), 20))
+	if re, ok := err.(queue.RetryableError); !ok || !re.IsRetryable() {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// InfluxConfig configures the InfluxDB sink. Version 1 writes to /write with
+// Database, RetentionPolicy and optional basic auth; version 2 writes to
+// /api/v2/write with Org, Bucket and Token.
+type InfluxConfig struct {
+	URL             string // Base URL, e.g. http://localhost:8086
+	Version         int    // 1 or 2
+	Database        string
+	RetentionPolicy string
+	Username        string
+	Password        string
+	Org             string
+	Bucket          string
+	Token           string
+	Measurement     string            // Line protocol measurement name
+	Tags            map[string]string // Tags added to every point
+	BatchSize       int               // Points buffered before a write
+	FlushInterval   time.Duration     // Maximum age of a buffered point before a write
+	MaxBuffer       int               // Points kept while the database is unreachable
+	Client          *http.Client
+}
+
+// Influx writes measurements as InfluxDB line protocol, buffering points locally
+// and writing them in batches
+type Influx struct {
+	config   InfluxConfig
+	client   *http.Client
+	endpoint string
+	tags     string
+	now      func() time.Time
+
+	mu       sync.Mutex
+	buffer   []point
+	buffered map[int64]bool // Timestamps in buffer, so retried messages are not added twice
+	oldest   time.Time      // When the oldest buffered point was added
+}
+
+// point is a buffered line protocol point
+type point struct {
+	timestamp int64
+	line      string
+}
+
+// NewInflux validates the configuration and creates an InfluxDB sink
+func NewInflux(config InfluxConfig) (*Influx, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("influxdb url is required")
+	}
+	if config.Measurement == "" {
+		config.Measurement = "atmosbyte"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Minute
+	}
+	if config.MaxBuffer < config.BatchSize {
+		config.MaxBuffer = max(config.BatchSize, 1000)
+	}
+
+	query := url.Values{"precision": {"s"}}
+	var path string
+	switch config.Version {
+	case 1:
+		if config.Database == "" {
+			return nil, fmt.Errorf("influxdb v1 requires a database")
+		}
+		path = "/write"
+		query.Set("db", config.Database)
+		if config.RetentionPolicy != "" {
+			query.Set("rp", config.RetentionPolicy)
+		}
+	case 2:
+		if config.Org == "" || config.Bucket == "" {
+			return nil, fmt.Errorf("influxdb v2 requires org and bucket")
+		}
+		path = "/api/v2/write"
+		query.Set("org", config.Org)
+		query.Set("bucket", config.Bucket)
+	default:
+		return nil, fmt.Errorf("unsupported influxdb version %d", config.Version)
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Influx{
+		config:   config,
+		client:   client,
+		endpoint: strings.TrimRight(config.URL, "/") + path + "?" + query.Encode(),
+		tags:     formatTags(config.Tags),
+		now:      time.Now,
+		buffered: make(map[int64]bool),
+	}, nil
+}
+
+// Name implements Sink
+func (s *Influx) Name() string {
+	return fmt.Sprintf("InfluxDB v%d", s.config.Version)
+}
+
+// Process implements queue.Worker[bme280.Measurement]. The point is buffered and the
+// buffer is written once it holds BatchSize points or its oldest point is older than
+// FlushInterval. When the write fails the points stay buffered for the retry.
+func (s *Influx) Process(ctx context.Context, msg queue.Message[bme280.Measurement]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timestamp := msg.Data.Timestamp.Unix()
+	if !s.buffered[timestamp] {
+		if len(s.buffer) >= s.config.MaxBuffer {
+			logging.Warnf("%s buffer is full, dropping the oldest point", s.Name())
+			delete(s.buffered, s.buffer[0].timestamp)
+			s.buffer = s.buffer[1:]
+		}
+		if len(s.buffer) == 0 {
+			s.oldest = s.now()
+		}
+		s.buffer = append(s.buffer, point{timestamp: timestamp, line: s.line(msg.Data)})
+		s.buffered[timestamp] = true
+	}
+
+	if len(s.buffer) < s.config.BatchSize && s.now().Sub(s.oldest) < s.config.FlushInterval {
+		return nil
+	}
+
+	return s.flush(ctx)
+}
+
+// Close writes the buffered points
+func (s *Influx) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buffer) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.flush(ctx)
+}
+
+// flush writes the buffer; the caller holds mu
+func (s *Influx) flush(ctx context.Context) error {
+	var body strings.Builder
+	for _, p := range s.buffer {
+		body.WriteString(p.line)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(body.String()))
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to create request: %w", err), false)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	switch {
+	case s.config.Version == 2 && s.config.Token != "":
+		req.Header.Set("Authorization", "Token "+s.config.Token)
+	case s.config.Version == 1 && s.config.Username != "":
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return retryable(fmt.Errorf("failed to write points: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		// Points rejected by the database would fail every later write as well
+		if re, ok := err.(queue.RetryableError); ok && !re.IsRetryable() {
+			logging.Warnf("%s rejected %d points, discarding them", s.Name(), len(s.buffer))
+			s.reset()
+		}
+		return err
+	}
+
+	s.reset()
+	return nil
+}
+
+// reset empties the buffer; the caller holds mu
+func (s *Influx) reset() {
+	s.buffer = s.buffer[:0]
+	clear(s.buffered)
+}
+
+// line formats a measurement as a line protocol point with second precision
+func (s *Influx) line(measurement bme280.Measurement) string {
+	var b strings.Builder
+	b.WriteString(escape(s.config.Measurement, ", "))
+	b.WriteString(s.tags)
+	for i, f := range fields(measurement) {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(f.name)
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(measurement.Timestamp.Unix(), 10))
+	return b.String()
+}
+
+// formatTags formats tags sorted by key, as recommended for write performance
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		if tags[key] == "" {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(escape(key, ",= "))
+		b.WriteByte('=')
+		b.WriteString(escape(tags[key], ",= "))
+	}
+	return b.String()
+}
+
+// escape backslash-escapes the characters of special in s
+func escape(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,193 @@
+package tsdb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+type fakeInflux struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   []string
+}
+
+func newFakeInflux(t *testing.T) *fakeInflux {
+	f := &fakeInflux{status: http.StatusNoContent}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, r)
+		f.bodies = append(f.bodies, string(body))
+		w.WriteHeader(f.status)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func measurementAt(ts time.Time, temperature float64) queue.Message[bme280.Measurement] {
+	return queue.Message[bme280.Measurement]{Data: bme280.Measurement{Timestamp: ts, Temperature: temperature, Humidity: 55.5, Pressure: 101325}}
+}
+
+func TestInflux_V2BatchesPoints(t *testing.T) {
+	server := newFakeInflux(t)
+	sink, err := NewInflux(InfluxConfig{
+		URL:         server.URL,
+		Version:     2,
+		Org:         "home",
+		Bucket:      "weather",
+		Token:       "secret",
+		Measurement: "weather station",
+		Tags:        map[string]string{"location": "living room", "host": "pi"},
+		BatchSize:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1748779200, 0)
+	if err := sink.Process(t.Context(), measurementAt(ts, 21.5)); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.requests) != 0 {
+		t.Fatal("expected the first point to be buffered")
+	}
+	if err := sink.Process(t.Context(), measurementAt(ts.Add(time.Minute), -3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.requests) != 1 {
+		t.Fatalf("expected 1 write, got %d", len(server.requests))
+	}
+	req := server.requests[0]
+	if req.URL.Path != "/api/v2/write" || req.URL.Query().Get("org") != "home" || req.URL.Query().Get("bucket") != "weather" || req.URL.Query().Get("precision") != "s" {
+		t.Errorf("unexpected write URL %s", req.URL)
+	}
+	if req.Header.Get("Authorization") != "Token secret" {
+		t.Errorf("unexpected Authorization %q", req.Header.Get("Authorization"))
+	}
+
+	want := "weather\\ station,host=pi,location=living\\ room temperature=21.5,humidity=55.5,pressure=101325 1748779200\n" +
+		"weather\\ station,host=pi,location=living\\ room temperature=-3,humidity=55.5,pressure=101325 1748779260\n"
+	if server.bodies[0] != want {
+		t.Errorf("expected body\n%s\ngot\n%s", want, server.bodies[0])
+	}
+}
+
+func TestInflux_V1FlushIntervalAndAuth(t *testing.T) {
+	server := newFakeInflux(t)
+	sink, err := NewInflux(InfluxConfig{
+		URL:             server.URL,
+		Version:         1,
+		Database:        "atmosbyte",
+		RetentionPolicy: "autogen",
+		Username:        "user",
+		Password:        "pass",
+		BatchSize:       100,
+		FlushInterval:   time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	sink.now = func() time.Time { return now }
+
+	ts := time.Unix(1748779200, 0)
+	sink.Process(t.Context(), measurementAt(ts, 20))
+	now = now.Add(time.Minute)
+	if err := sink.Process(t.Context(), measurementAt(ts.Add(time.Minute), 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.requests) != 1 {
+		t.Fatalf("expected the buffer to be flushed after the interval, got %d writes", len(server.requests))
+	}
+	req := server.requests[0]
+	if req.URL.Path != "/write" || req.URL.Query().Get("db") != "atmosbyte" || req.URL.Query().Get("rp") != "autogen" {
+		t.Errorf("unexpected write URL %s", req.URL)
+	}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("expected basic auth, got %q %q", user, pass)
+	}
+	if strings.Count(server.bodies[0], "\n") != 2 {
+		t.Errorf("expected 2 points, got %q", server.bodies[0])
+	}
+}
+
+func TestInflux_ErrorClassification(t *testing.T) {
+	server := newFakeInflux(t)
+	sink, err := NewInflux(InfluxConfig{URL: server.URL, Version: 2, Org: "o", Bucket: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1748779200, 0)
+
+	server.status = http.StatusServiceUnavailable
+	err = sink.Process(t.Context(), measurementAt(ts, 20))
+	if re, ok := err.(queue.RetryableError); !ok || !re.IsRetryable() {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+
+	// The retry of the same message does not duplicate the buffered point
+	server.status = http.StatusNoContent
+	if err := sink.Process(t.Context(), measurementAt(ts, 20)); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(server.bodies[1], "\n"); got != 1 {
+		t.Errorf("expected 1 point after the retry, got %d", got)
+	}
+
+	server.status = http.StatusBadRequest
+	err = sink.Process(t.Context(), measurementAt(ts.Add(time.Minute), 20))
+	if re, ok := err.(queue.RetryableError); !ok || re.IsRetryable() {
+		t.Fatalf("expected a non-retryable error, got %v", err)
+	}
+	if len(sink.buffer) != 0 {
+		t.Errorf("expected rejected points to be discarded, got %d", len(sink.buffer))
+	}
+
+	server.Close()
+	err = sink.Process(t.Context(), measurementAt(ts.Add(2*time.Minute), 20))
+	if re, ok := err.(queue.RetryableError); !ok || !re.IsRetryable() {
+		t.Fatalf("expected transport errors to be retryable, got %v", err)
+	}
+}
+
+func TestInflux_CloseFlushesBuffer(t *testing.T) {
+	server := newFakeInflux(t)
+	sink, err := NewInflux(InfluxConfig{URL: server.URL, Version: 2, Org: "o", Bucket: "b", BatchSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Process(t.Context(), measurementAt(time.Unix(1748779200, 0), 20))
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.requests) != 1 {
+		t.Errorf("expected Close to write the buffer, got %d writes", len(server.requests))
+	}
+}
+
+func TestNewInflux_Validation(t *testing.T) {
+	for _, config := range []InfluxConfig{
+		{Version: 2, Org: "o", Bucket: "b"},
+		{URL: "http://localhost", Version: 3},
+		{URL: "http://localhost", Version: 1},
+		{URL: "http://localhost", Version: 2, Org: "o"},
+	} {
+		if _, err := NewInflux(config); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// StatsDConfig configures the StatsD sink
+type StatsDConfig struct {
+	Address string // StatsD UDP listener, e.g. localhost:8125
+	Prefix  string // Metric name prefix, e.g. atmosbyte
+}
+
+// StatsD sends measurements as StatsD gauges over UDP. StatsD has no timestamps,
+// so the server records the values at arrival time.
+type StatsD struct {
+	config StatsDConfig
+	conn   net.Conn
+}
+
+// NewStatsD creates a StatsD sink
+func NewStatsD(config StatsDConfig) (*StatsD, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("statsd address is required")
+	}
+
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve statsd address: %w", err)
+	}
+
+	return &StatsD{config: config, conn: conn}, nil
+}
+
+// Name implements Sink
+func (s *StatsD) Name() string {
+	return "StatsD"
+}
+
+// Process implements queue.Worker[bme280.Measurement]. All gauges of a measurement
+// are sent in a single datagram.
+func (s *StatsD) Process(ctx context.Context, msg queue.Message[bme280.Measurement]) error {
+	var b strings.Builder
+	for _, f := range fields(msg.Data) {
+		name := metricPath(s.config.Prefix, f.name)
+		// A signed gauge value is a delta, so negative values are set from zero
+		if f.value < 0 {
+			fmt.Fprintf(&b, "%s:0|g\n", name)
+		}
+		fmt.Fprintf(&b, "%s:%s|g\n", name, strconv.FormatFloat(f.value, 'f', -1, 64))
+	}
+
+	if _, err := s.conn.Write([]byte(strings.TrimSuffix(b.String(), "\n"))); err != nil {
+		return retryable(fmt.Errorf("failed to send gauges: %w", err))
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
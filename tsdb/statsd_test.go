@@ -0,0 +1,37 @@
+package tsdb
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsD_Process(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewStatsD(StatsDConfig{Address: conn.LocalAddr().String(), Prefix: "atmosbyte"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	if err := sink.Process(t.Context(), measurementAt(time.Now(), -2.5)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "atmosbyte.temperature:0|g\natmosbyte.temperature:-2.5|g\natmosbyte.humidity:55.5|g\natmosbyte.pressure:101325|g"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("expected datagram\n%s\ngot\n%s", want, got)
+	}
+}
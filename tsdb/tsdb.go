@@ -0,0 +1,53 @@
+// Package tsdb exports local measurements to time-series databases. Each sink is a
+// queue.Worker running on its own queue; transport errors are classified as
+// queue.RetryableError so the queue retry and circuit breaker apply.
+package tsdb
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// Sink is a queue worker exporting measurements
+type Sink interface {
+	queue.Worker[bme280.Measurement]
+	Name() string
+	Close() error
+}
+
+// field is a named value of a measurement
+type field struct {
+	name  string
+	value float64
+}
+
+// fields returns the exported values of a measurement
+func fields(measurement bme280.Measurement) []field {
+	return []field{
+		{"temperature", measurement.Temperature},
+		{"humidity", measurement.Humidity},
+		{"pressure", float64(measurement.Pressure)},
+	}
+}
+
+// retryable wraps a transport error as retryable
+func retryable(err error) error {
+	return queue.NewRetryableError(err, true)
+}
+
+// checkResponse classifies an HTTP response. 429 and 5xx are retryable, other
+// non-2xx statuses are not since retrying would fail the same way.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err := fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	return queue.NewRetryableError(err, resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+}
@@ -0,0 +1,137 @@
+package uploader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// DefaultCWOPServer is the APRS-IS server rotation for CWOP stations
+const DefaultCWOPServer = "cwop.aprs.net:14580"
+
+// CWOPConfig holds the identity and position of a CWOP station
+type CWOPConfig struct {
+	Server    string // APRS-IS host:port, defaults to DefaultCWOPServer
+	Callsign  string // CWOP ID (e.g. EW1234) or amateur radio callsign
+	Passcode  string // APRS-IS passcode, "-1" for CWOP IDs
+	Latitude  float64
+	Longitude float64
+	Station   Station
+	Timeout   time.Duration // Timeout of the whole exchange, defaults to 30s
+}
+
+// CWOP sends APRS weather packets to the Citizen Weather Observer Program over APRS-IS
+type CWOP struct {
+	config CWOPConfig
+}
+
+// NewCWOP creates an uploader for CWOP
+func NewCWOP(config CWOPConfig) *CWOP {
+	if config.Server == "" {
+		config.Server = DefaultCWOPServer
+	}
+	if config.Passcode == "" {
+		config.Passcode = "-1"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &CWOP{config: config}
+}
+
+// Name implements Service
+func (u *CWOP) Name() string {
+	return "CWOP"
+}
+
+// Upload implements Service
+func (u *CWOP) Upload(ctx context.Context, measurement bme280.Measurement) error {
+	ctx, cancel := context.WithTimeout(ctx, u.config.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", u.config.Server)
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to connect to %s: %w", u.config.Server, err), true)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader := bufio.NewReader(conn)
+	// The server greets with a comment line before accepting the login
+	if _, err := reader.ReadString('\n'); err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to read server banner: %w", err), true)
+	}
+
+	login := fmt.Sprintf("user %s pass %s vers %s 1.0\r\n", u.config.Callsign, u.config.Passcode, userAgent)
+	if _, err := conn.Write([]byte(login)); err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to send login: %w", err), true)
+	}
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to read login response: %w", err), true)
+	}
+	if !strings.HasPrefix(response, "# logresp") {
+		return queue.NewRetryableError(errors.New("unexpected login response: "+strings.TrimSpace(response)), true)
+	}
+
+	if _, err := conn.Write([]byte(u.packet(measurement) + "\r\n")); err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to send packet: %w", err), true)
+	}
+
+	return nil
+}
+
+// packet formats a positioned APRS weather report. Wind is not measured and is
+// reported as unknown; temperature is in °F, humidity in % (00 for 100) and
+// pressure in tenths of hPa at sea level.
+func (u *CWOP) packet(measurement bme280.Measurement) string {
+	fahrenheit := weather.Units{Temperature: weather.Fahrenheit}
+	temperature := int(math.Round(fahrenheit.ConvertTemperature(measurement.Temperature)))
+	humidity := int(math.Round(measurement.Humidity)) % 100
+	pressure := int(math.Round(u.config.Station.seaLevelPressure(measurement) / 10))
+
+	return fmt.Sprintf("%s>APRS,TCPIP*:@%s%s/%s_.../...g...t%sh%02db%05d%s",
+		u.config.Callsign,
+		measurement.Timestamp.UTC().Format("021504z"),
+		aprsCoordinate(u.config.Latitude, 2, "N", "S"),
+		aprsCoordinate(u.config.Longitude, 3, "E", "W"),
+		aprsTemperature(temperature),
+		humidity,
+		pressure,
+		userAgent,
+	)
+}
+
+// aprsCoordinate formats degrees as DDMM.mmH (latitude) or DDDMM.mmH (longitude)
+func aprsCoordinate(degrees float64, width int, positive, negative string) string {
+	hemisphere := positive
+	if degrees < 0 {
+		hemisphere = negative
+		degrees = -degrees
+	}
+	hundredths := int(math.Round(degrees * 60 * 100)) // Hundredths of a minute
+	whole, minutes := hundredths/6000, float64(hundredths%6000)/100
+	return fmt.Sprintf("%0*d%05.2f%s", width, whole, minutes, hemisphere)
+}
+
+// aprsTemperature formats °F as three characters, e.g. 077, 005 or -05
+func aprsTemperature(fahrenheit int) string {
+	if fahrenheit < 0 {
+		return fmt.Sprintf("-%02d", -fahrenheit)
+	}
+	return fmt.Sprintf("%03d", fahrenheit)
+}
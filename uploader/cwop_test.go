@@ -0,0 +1,104 @@
+package uploader
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// fakeAPRSServer accepts one connection and returns the lines sent by the client
+func fakeAPRSServer(t *testing.T, logresp string) (string, <-chan []string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("# aprsc 2.1.19\r\n"))
+		reader := bufio.NewReader(conn)
+		var received []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				lines <- received
+				return
+			}
+			received = append(received, strings.TrimRight(line, "\r\n"))
+			if len(received) == 1 {
+				conn.Write([]byte(logresp + "\r\n"))
+			}
+		}
+	}()
+
+	return listener.Addr().String(), lines
+}
+
+func TestCWOP_Upload(t *testing.T) {
+	addr, lines := fakeAPRSServer(t, "# logresp EW1234 unverified, server CWOP-1")
+
+	service := NewCWOP(CWOPConfig{
+		Server:    addr,
+		Callsign:  "EW1234",
+		Latitude:  -23.5505,
+		Longitude: -46.6333,
+	})
+	measurement := bme280.Measurement{
+		Timestamp:   time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC),
+		Temperature: 25,
+		Humidity:    100,
+		Pressure:    101325,
+	}
+	if err := service.Upload(t.Context(), measurement); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received := <-lines
+	if len(received) != 2 {
+		t.Fatalf("expected login and packet, got %q", received)
+	}
+	if received[0] != "user EW1234 pass -1 vers atmosbyte 1.0" {
+		t.Errorf("unexpected login %q", received[0])
+	}
+	want := "EW1234>APRS,TCPIP*:@011230z2333.03S/04638.00W_.../...g...t077h00b10133atmosbyte"
+	if received[1] != want {
+		t.Errorf("expected packet\n%s\ngot\n%s", want, received[1])
+	}
+}
+
+func TestCWOP_UnexpectedLoginResponse(t *testing.T) {
+	addr, _ := fakeAPRSServer(t, "# invalid")
+
+	err := NewCWOP(CWOPConfig{Server: addr, Callsign: "EW1234"}).Upload(t.Context(), bme280.Measurement{Timestamp: time.Now()})
+	if _, ok := err.(queue.RetryableError); !ok {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+}
+
+func TestAPRSFormatting(t *testing.T) {
+	if got := aprsCoordinate(49.0583, 2, "N", "S"); got != "4903.50N" {
+		t.Errorf("unexpected latitude %s", got)
+	}
+	if got := aprsCoordinate(-72.0292, 3, "E", "W"); got != "07201.75W" {
+		t.Errorf("unexpected longitude %s", got)
+	}
+	for fahrenheit, want := range map[int]string{77: "077", 5: "005", -5: "-05", 105: "105"} {
+		if got := aprsTemperature(fahrenheit); got != want {
+			t.Errorf("aprsTemperature(%d) = %s, want %s", fahrenheit, got, want)
+		}
+	}
+}
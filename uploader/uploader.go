@@ -0,0 +1,145 @@
+// Package uploader shares local measurements with public weather networks. Each
+// service is wrapped in a Worker and runs on its own queue, so it gets its own
+// retries, circuit breaker and rate limit.
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// userAgent identifies the uploads to the services
+const userAgent = "atmosbyte"
+
+// Service uploads a measurement to a weather network
+type Service interface {
+	Name() string
+	Upload(ctx context.Context, measurement bme280.Measurement) error
+}
+
+// Worker adapts a Service to queue.Worker. Measurements taken less than the
+// minimum interval after the last uploaded one are skipped, which also drops
+// retries that were overtaken by a newer upload.
+type Worker struct {
+	service  Service
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time // Timestamp of the last uploaded measurement
+}
+
+// NewWorker creates a Worker uploading at most once per interval
+func NewWorker(service Service, interval time.Duration) *Worker {
+	return &Worker{service: service, interval: interval}
+}
+
+// Name returns the name of the uploaded service
+func (w *Worker) Name() string {
+	return w.service.Name()
+}
+
+// Process implements queue.Worker[bme280.Measurement]
+func (w *Worker) Process(ctx context.Context, msg queue.Message[bme280.Measurement]) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	measurement := msg.Data
+	if !w.last.IsZero() && measurement.Timestamp.Sub(w.last) < w.interval {
+		return nil
+	}
+
+	if err := w.service.Upload(ctx, measurement); err != nil {
+		wrapped := fmt.Errorf("%s upload failed: %w", w.service.Name(), err)
+		// The queue only recognizes a RetryableError that is not wrapped
+		if retryable, ok := err.(queue.RetryableError); ok {
+			return queue.NewRetryableError(wrapped, retryable.IsRetryable())
+		}
+		return wrapped
+	}
+
+	w.last = measurement.Timestamp
+	logging.Debugf("Uploaded measurement of %s to %s", measurement.Timestamp.Format(time.RFC3339), w.service.Name())
+	return nil
+}
+
+// Close releases the service resources, such as open connections
+func (w *Worker) Close() error {
+	if closer, ok := w.service.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Enqueuer accepts measurements to be uploaded
+type Enqueuer interface {
+	Enqueue(measurement bme280.Measurement) error
+}
+
+// Forward enqueues every measurement of stream on q until ctx is done or stream is closed
+func Forward(ctx context.Context, name string, stream <-chan bme280.Measurement, q Enqueuer) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case measurement, ok := <-stream:
+			if !ok {
+				return nil
+			}
+			if err := q.Enqueue(measurement); err != nil {
+				logging.Errorf("Failed to enqueue measurement for %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// Station holds the station properties shared by every service
+type Station struct {
+	Altitude float64 // Meters above sea level, used to report sea level pressure
+}
+
+// seaLevelPressure returns the measurement pressure reduced to sea level in Pa
+func (s Station) seaLevelPressure(measurement bme280.Measurement) float64 {
+	return weather.SeaLevelPressure(float64(measurement.Pressure), measurement.Temperature, s.Altitude)
+}
+
+// get performs a GET request and classifies the response. Transport errors, 429 and
+// 5xx responses are retryable; other non-2xx responses are not.
+func get(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to create request: %w", err), false)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return queue.NewRetryableError(fmt.Errorf("failed to send request: %w", err), true)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return queue.NewRetryableError(fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body))), retryable)
+}
+
+// httpClient returns client or a client with a default timeout
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
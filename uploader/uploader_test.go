@@ -0,0 +1,133 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+type fakeService struct {
+	uploads []bme280.Measurement
+	err     error
+}
+
+func (f *fakeService) Name() string { return "fake" }
+
+func (f *fakeService) Upload(ctx context.Context, measurement bme280.Measurement) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.uploads = append(f.uploads, measurement)
+	return nil
+}
+
+func message(ts time.Time) queue.Message[bme280.Measurement] {
+	return queue.Message[bme280.Measurement]{Data: bme280.Measurement{Timestamp: ts, Temperature: 20, Humidity: 50, Pressure: 101325}}
+}
+
+func TestWorker_RateLimit(t *testing.T) {
+	service := &fakeService{}
+	worker := NewWorker(service, 5*time.Minute)
+	base := time.Now()
+
+	for _, offset := range []time.Duration{0, time.Minute, 4 * time.Minute, 5 * time.Minute, -time.Minute, 11 * time.Minute} {
+		if err := worker.Process(t.Context(), message(base.Add(offset))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(service.uploads) != 3 {
+		t.Fatalf("expected 3 uploads, got %d", len(service.uploads))
+	}
+	if !service.uploads[1].Timestamp.Equal(base.Add(5*time.Minute)) || !service.uploads[2].Timestamp.Equal(base.Add(11*time.Minute)) {
+		t.Errorf("unexpected uploads %+v", service.uploads)
+	}
+}
+
+func TestWorker_FailedUploadIsRetried(t *testing.T) {
+	service := &fakeService{err: queue.NewRetryableError(errors.New("unauthorized"), false)}
+	worker := NewWorker(service, time.Minute)
+	base := time.Now()
+
+	err := worker.Process(t.Context(), message(base))
+	retryable, ok := err.(queue.RetryableError)
+	if !ok || retryable.IsRetryable() {
+		t.Fatalf("expected a non-retryable error, got %v", err)
+	}
+
+	// A failed upload does not consume the rate limit
+	service.err = nil
+	if err := worker.Process(t.Context(), message(base)); err != nil || len(service.uploads) != 1 {
+		t.Fatalf("expected the retry to be uploaded, got %v and %d uploads", err, len(service.uploads))
+	}
+}
+
+func TestGet_ClassifiesErrors(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("unexpected User-Agent %q", r.Header.Get("User-Agent"))
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	if err := get(t.Context(), client, server.URL); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusUnauthorized, false},
+		{http.StatusBadRequest, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		status = tt.status
+		err := get(t.Context(), client, server.URL)
+		retryable, ok := err.(queue.RetryableError)
+		if !ok || retryable.IsRetryable() != tt.retryable {
+			t.Errorf("status %d: expected retryable=%v, got %v", tt.status, tt.retryable, err)
+		}
+	}
+
+	server.Close()
+	err := get(t.Context(), client, server.URL)
+	if retryable, ok := err.(queue.RetryableError); !ok || !retryable.IsRetryable() {
+		t.Errorf("expected transport errors to be retryable, got %v", err)
+	}
+}
+
+func TestForward(t *testing.T) {
+	stream := make(chan bme280.Measurement, 2)
+	stream <- bme280.Measurement{Temperature: 1}
+	stream <- bme280.Measurement{Temperature: 2}
+	close(stream)
+
+	q := &recordingQueue{}
+	if err := Forward(t.Context(), "fake", stream, q); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(q.measurements) != 2 {
+		t.Errorf("expected 2 forwarded measurements, got %d", len(q.measurements))
+	}
+}
+
+type recordingQueue struct {
+	measurements []bme280.Measurement
+}
+
+func (q *recordingQueue) Enqueue(measurement bme280.Measurement) error {
+	q.measurements = append(q.measurements, measurement)
+	return nil
+}
@@ -0,0 +1,56 @@
+package uploader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// DefaultWindyURL is the Windy station API endpoint; the API key is appended to it
+const DefaultWindyURL = "https://stations.windy.com/pws/update/"
+
+// WindyConfig holds the credentials of a Windy station
+type WindyConfig struct {
+	URL     string // Upload endpoint, defaults to DefaultWindyURL
+	APIKey  string
+	Index   int // Station index of the API key, 0 for the first station
+	Station Station
+	Client  *http.Client
+}
+
+// Windy uploads to the Windy station API in metric units (°C, Pa)
+type Windy struct {
+	config WindyConfig
+	client *http.Client
+}
+
+// NewWindy creates an uploader for Windy
+func NewWindy(config WindyConfig) *Windy {
+	if config.URL == "" {
+		config.URL = DefaultWindyURL
+	}
+	return &Windy{config: config, client: httpClient(config.Client)}
+}
+
+// Name implements Service
+func (u *Windy) Name() string {
+	return "Windy"
+}
+
+// Upload implements Service
+func (u *Windy) Upload(ctx context.Context, measurement bme280.Measurement) error {
+	query := url.Values{
+		"station":  {strconv.Itoa(u.config.Index)},
+		"ts":       {strconv.FormatInt(measurement.Timestamp.Unix(), 10)},
+		"temp":     {formatFloat(measurement.Temperature, 1)},
+		"humidity": {formatFloat(measurement.Humidity, 0)},
+		"dewpoint": {formatFloat(weather.DewPoint(measurement.Temperature, measurement.Humidity), 1)},
+		"pressure": {formatFloat(u.config.Station.seaLevelPressure(measurement), 0)},
+	}
+
+	return get(ctx, u.client, u.config.URL+url.PathEscape(u.config.APIKey)+"?"+query.Encode())
+}
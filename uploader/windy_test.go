@@ -0,0 +1,43 @@
+package uploader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+)
+
+func TestWindy_Upload(t *testing.T) {
+	var path string
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+
+	service := NewWindy(WindyConfig{URL: server.URL + "/pws/update/", APIKey: "secret-key", Index: 1})
+	ts := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+	if err := service.Upload(t.Context(), bme280.Measurement{Timestamp: ts, Temperature: 21.44, Humidity: 60, Pressure: 101300}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != "/pws/update/secret-key" {
+		t.Errorf("expected API key in the path, got %s", path)
+	}
+	want := map[string]string{
+		"station":  "1",
+		"ts":       "1748781000",
+		"temp":     "21.4",
+		"humidity": "60",
+		"pressure": "101300",
+	}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("expected %s=%s, got %s", key, value, query.Get(key))
+		}
+	}
+}
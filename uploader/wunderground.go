@@ -0,0 +1,82 @@
+package uploader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+const (
+	// DefaultWundergroundURL is the Weather Underground PWS upload endpoint
+	DefaultWundergroundURL = "https://weatherstation.wunderground.com/weatherstation/updateweatherstation.php"
+	// DefaultPWSWeatherURL is the PWSWeather upload endpoint, which speaks the same protocol
+	DefaultPWSWeatherURL = "https://pwsupdate.pwsweather.com/api/v1/submitwx"
+)
+
+// WundergroundConfig holds the credentials of a station using the Weather Underground protocol
+type WundergroundConfig struct {
+	URL       string // Upload endpoint, defaults to the service endpoint
+	StationID string
+	Password  string // Station key (Weather Underground) or API key (PWSWeather)
+	Station   Station
+	Client    *http.Client
+}
+
+// Wunderground uploads with the Weather Underground PWS protocol: imperial units
+// (°F, inHg) and sea level pressure
+type Wunderground struct {
+	name   string
+	config WundergroundConfig
+	client *http.Client
+}
+
+// NewWunderground creates an uploader for Weather Underground
+func NewWunderground(config WundergroundConfig) *Wunderground {
+	if config.URL == "" {
+		config.URL = DefaultWundergroundURL
+	}
+	return &Wunderground{name: "Weather Underground", config: config, client: httpClient(config.Client)}
+}
+
+// NewPWSWeather creates an uploader for PWSWeather
+func NewPWSWeather(config WundergroundConfig) *Wunderground {
+	if config.URL == "" {
+		config.URL = DefaultPWSWeatherURL
+	}
+	return &Wunderground{name: "PWSWeather", config: config, client: httpClient(config.Client)}
+}
+
+// Name implements Service
+func (u *Wunderground) Name() string {
+	return u.name
+}
+
+// Upload implements Service
+func (u *Wunderground) Upload(ctx context.Context, measurement bme280.Measurement) error {
+	fahrenheit := weather.Units{Temperature: weather.Fahrenheit}
+	inHg := weather.Units{Pressure: weather.InchMercury}
+	dewPoint := weather.DewPoint(measurement.Temperature, measurement.Humidity)
+
+	query := url.Values{
+		"ID":           {u.config.StationID},
+		"PASSWORD":     {u.config.Password},
+		"dateutc":      {measurement.Timestamp.UTC().Format("2006-01-02 15:04:05")},
+		"tempf":        {formatFloat(fahrenheit.ConvertTemperature(measurement.Temperature), 1)},
+		"humidity":     {formatFloat(measurement.Humidity, 0)},
+		"dewptf":       {formatFloat(fahrenheit.ConvertTemperature(dewPoint), 1)},
+		"baromin":      {formatFloat(inHg.ConvertPressure(u.config.Station.seaLevelPressure(measurement)), 3)},
+		"softwaretype": {userAgent},
+		"action":       {"updateraw"},
+	}
+
+	return get(ctx, u.client, u.config.URL+"?"+query.Encode())
+}
+
+// formatFloat formats v with the given decimal places
+func formatFloat(v float64, decimals int) string {
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
@@ -0,0 +1,68 @@
+package uploader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+)
+
+func TestWunderground_Upload(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte("success\n"))
+	}))
+	defer server.Close()
+
+	for _, service := range []*Wunderground{
+		NewWunderground(WundergroundConfig{URL: server.URL, StationID: "KTEST1", Password: "key"}),
+		NewPWSWeather(WundergroundConfig{URL: server.URL, StationID: "KTEST1", Password: "key"}),
+	} {
+		measurement := bme280.Measurement{
+			Timestamp:   time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC),
+			Temperature: 25,
+			Humidity:    50,
+			Pressure:    101325,
+		}
+		if err := service.Upload(t.Context(), measurement); err != nil {
+			t.Fatalf("%s: unexpected error: %v", service.Name(), err)
+		}
+
+		want := map[string]string{
+			"ID":       "KTEST1",
+			"PASSWORD": "key",
+			"dateutc":  "2025-06-01 12:30:00",
+			"tempf":    "77.0",
+			"humidity": "50",
+			"dewptf":   "56.9",
+			"baromin":  "29.921",
+			"action":   "updateraw",
+		}
+		for key, value := range want {
+			if query.Get(key) != value {
+				t.Errorf("%s: expected %s=%s, got %s", service.Name(), key, value, query.Get(key))
+			}
+		}
+	}
+}
+
+func TestWunderground_SeaLevelPressure(t *testing.T) {
+	var baromin string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		baromin = r.URL.Query().Get("baromin")
+	}))
+	defer server.Close()
+
+	service := NewWunderground(WundergroundConfig{URL: server.URL, Station: Station{Altitude: 100}})
+	measurement := bme280.Measurement{Timestamp: time.Now(), Temperature: 15, Humidity: 50, Pressure: 100000}
+	if err := service.Upload(t.Context(), measurement); err != nil {
+		t.Fatal(err)
+	}
+	if baromin != "29.882" {
+		t.Errorf("expected station pressure reduced to sea level, got %s", baromin)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/uploader"
+)
+
+// exportWorker é um destino externo das leituras locais, como um uploader ou um banco de séries temporais
+type exportWorker interface {
+	queue.Worker[bme280.Measurement]
+	Name() string
+	Close() error
+}
+
+// startUploaders inicia uma fila por destino externo, alimentada pelas leituras do sensor.
+// Cada fila tem seu próprio circuit breaker, então a falha de um destino não afeta os demais.
+// circuitHook, quando definido, cria o callback de mudança de estado do circuit breaker de cada destino.
+func startUploaders(ctx context.Context, workers []exportWorker, config queue.QueueConfig, circuitHook func(source string) func(from, to queue.CircuitBreakerState), reader *SensorReader, wg *sync.WaitGroup) {
+	for _, worker := range workers {
+		name := worker.Name()
+		workerConfig := config
+		if circuitHook != nil {
+			workerConfig.CircuitBreakerConfig.OnStateChange = circuitHook(name)
+		}
+		q := queue.NewQueue[bme280.Measurement](ctx, worker, workerConfig)
+		stream := reader.Subscribe(config.BufferSize)
+
+		log.Printf("Uploading measurements to %s", name)
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := q.Start(); err != nil && err != context.Canceled {
+				logging.Errorf("%s upload queue error: %v", name, err)
+			}
+			if err := worker.Close(); err != nil {
+				logging.Errorf("Error closing %s: %v", name, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := uploader.Forward(ctx, name, stream, q); err != nil && err != context.Canceled {
+				logging.Errorf("%s upload error: %v", name, err)
+			}
+		}()
+	}
+}
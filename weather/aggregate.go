@@ -2,6 +2,7 @@ package weather
 
 import (
 	"fmt"
+	"iter"
 	"math"
 	"slices"
 	"time"
@@ -70,6 +71,12 @@ func ConvertKind(kind string) (AggregationKind, error) {
 }
 
 func AggregateMeasurements(measurements []repository.MeasurementRecord, kind AggregationKind) []AggregateMeasurement {
+	return AggregateMeasurementsIn(measurements, kind, time.Local)
+}
+
+// AggregateMeasurementsIn is AggregateMeasurements with the periods starting in loc
+// instead of the local timezone
+func AggregateMeasurementsIn(measurements []repository.MeasurementRecord, kind AggregationKind, loc *time.Location) []AggregateMeasurement {
 	if len(measurements) == 0 {
 		return []AggregateMeasurement{}
 	}
@@ -77,18 +84,7 @@ func AggregateMeasurements(measurements []repository.MeasurementRecord, kind Agg
 	grouped := make(map[int64][]repository.MeasurementRecord)
 
 	for _, measurement := range measurements {
-		t := time.Unix(measurement.Timestamp.Unix(), 0)
-
-		var key int64
-		switch kind {
-		case Minute:
-			key = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Unix()
-		case Hour:
-			key = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Unix()
-		case Day:
-			key = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Unix()
-		}
-
+		key := periodStart(measurement.Timestamp, kind, loc)
 		grouped[key] = append(grouped[key], measurement)
 	}
 
@@ -100,6 +96,52 @@ func AggregateMeasurements(measurements []repository.MeasurementRecord, kind Agg
 	return sortAggregateMeasurementByDateAsc(results)
 }
 
+// AggregateStream aggregates measurements ordered by timestamp as they are read,
+// holding the measurements of a single period in memory. It yields the aggregates
+// of AggregateMeasurements in the same order and stops at the first error.
+func AggregateStream(measurements iter.Seq2[repository.MeasurementRecord, error], kind AggregationKind) iter.Seq2[AggregateMeasurement, error] {
+	return func(yield func(AggregateMeasurement, error) bool) {
+		var (
+			group []repository.MeasurementRecord
+			key   int64
+		)
+		for measurement, err := range measurements {
+			if err != nil {
+				yield(AggregateMeasurement{}, err)
+				return
+			}
+
+			next := periodStart(measurement.Timestamp, kind, time.Local)
+			if len(group) > 0 && next != key {
+				if !yield(calculateAggregates(group, key, kind), nil) {
+					return
+				}
+				group = group[:0]
+			}
+			key = next
+			group = append(group, measurement)
+		}
+
+		if len(group) > 0 {
+			yield(calculateAggregates(group, key, kind), nil)
+		}
+	}
+}
+
+// periodStart returns the Unix time of the start of the period of kind containing t,
+// in loc
+func periodStart(timestamp time.Time, kind AggregationKind, loc *time.Location) int64 {
+	t := time.Unix(timestamp.Unix(), 0).In(loc)
+	switch kind {
+	case Minute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Unix()
+	case Hour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Unix()
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Unix()
+	}
+}
+
 func sortAggregateMeasurementByDateAsc(measurements []AggregateMeasurement) []AggregateMeasurement {
 	slices.SortFunc(measurements, func(a, b AggregateMeasurement) int {
 		if a.Date < b.Date {
@@ -0,0 +1,79 @@
+package weather
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+func TestAggregateStream_MatchesAggregateMeasurements(t *testing.T) {
+	start := time.Date(2026, 3, 15, 22, 0, 0, 0, time.Local)
+	var records []repository.MeasurementRecord
+	for i := range 300 {
+		records = append(records, repository.MeasurementRecord{
+			Timestamp:   start.Add(time.Duration(i) * 7 * time.Minute),
+			Temperature: 20 + float64(i%13),
+			Humidity:    50 + float64(i%7),
+			Pressure:    101000 + int64(i%11),
+		})
+	}
+
+	stream := func(yield func(repository.MeasurementRecord, error) bool) {
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+
+	for _, kind := range []AggregationKind{Minute, Hour, Day} {
+		var streamed []AggregateMeasurement
+		for aggregate, err := range AggregateStream(stream, kind) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			streamed = append(streamed, aggregate)
+		}
+
+		if want := AggregateMeasurements(records, kind); !reflect.DeepEqual(streamed, want) {
+			t.Errorf("%s: streamed %d aggregates differ from %d", kind, len(streamed), len(want))
+		}
+	}
+}
+
+func TestAggregateStream_StopsAtError(t *testing.T) {
+	failure := errors.New("read failed")
+	stream := func(yield func(repository.MeasurementRecord, error) bool) {
+		if yield(repository.MeasurementRecord{Timestamp: time.Now(), Temperature: 20}, nil) {
+			yield(repository.MeasurementRecord{}, failure)
+		}
+	}
+
+	var got []error
+	for _, err := range AggregateStream(stream, Hour) {
+		got = append(got, err)
+	}
+	if len(got) != 1 || !errors.Is(got[0], failure) {
+		t.Errorf("expected only the read error, got %v", got)
+	}
+}
+
+func TestAggregateMeasurementsIn_UsesLocationDays(t *testing.T) {
+	loc := time.FixedZone("UTC+13", 13*60*60)
+	day := time.Date(2026, 3, 14, 0, 0, 0, 0, loc)
+	records := []repository.MeasurementRecord{
+		{Timestamp: day.Add(time.Hour), Temperature: 10, Humidity: 40, Pressure: 100000},
+		{Timestamp: day.Add(23 * time.Hour), Temperature: 20, Humidity: 60, Pressure: 102000},
+	}
+
+	aggregates := AggregateMeasurementsIn(records, Day, loc)
+	if len(aggregates) != 1 || aggregates[0].Date != day.Unix() {
+		t.Fatalf("expected a single day starting at %d, got %+v", day.Unix(), aggregates)
+	}
+	if *aggregates[0].Temp.Average != 15 {
+		t.Errorf("expected average 15, got %v", *aggregates[0].Temp.Average)
+	}
+}
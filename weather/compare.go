@@ -0,0 +1,198 @@
+package weather
+
+import (
+	"slices"
+	"time"
+)
+
+// AlignedSeries maps a relative offset, in units of the aggregation kind since the
+// start of its range, to the aggregate of that period
+type AlignedSeries map[int64]AggregateMeasurement
+
+// ComparisonSeries describes one of the compared ranges
+type ComparisonSeries struct {
+	Label string    `json:"label"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Years int       `json:"years,omitempty"` // Number of years averaged in a climatology series
+}
+
+// Delta is the difference between the averages of a series and the reference series
+type Delta struct {
+	Temperature *float64 `json:"temp,omitempty"`
+	Humidity    *float64 `json:"humidity,omitempty"`
+	Pressure    *float64 `json:"pressure,omitempty"`
+}
+
+// ComparisonPoint holds the aggregates of every series at the same relative offset.
+// Values and Deltas are indexed like Comparison.Series; Deltas[0] is always nil
+// since the first series is the reference.
+type ComparisonPoint struct {
+	Offset int64                   `json:"offset"`
+	Values []*AggregateMeasurement `json:"values"`
+	Deltas []*Delta                `json:"deltas"`
+}
+
+// Comparison is a set of series aligned on a relative time axis
+type Comparison struct {
+	Type   string             `json:"type"`
+	Series []ComparisonSeries `json:"series"`
+	Points []ComparisonPoint  `json:"points"`
+}
+
+// Align converts aggregates into an AlignedSeries relative to start
+func Align(aggregates []AggregateMeasurement, start time.Time, kind AggregationKind) AlignedSeries {
+	aligned := make(AlignedSeries, len(aggregates))
+	for _, aggregate := range aggregates {
+		aligned[Offset(start, time.Unix(aggregate.Date, 0).In(start.Location()), kind)] = aggregate
+	}
+	return aligned
+}
+
+// Offset returns how many periods of kind separate the period containing start
+// from the period containing t. Days are counted on the calendar so DST changes
+// do not shift the offsets.
+func Offset(start, t time.Time, kind AggregationKind) int64 {
+	switch kind {
+	case Day:
+		startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return int64(day.Sub(startDay) / (24 * time.Hour))
+	case Minute:
+		return int64(t.Truncate(time.Minute).Sub(start.Truncate(time.Minute)) / time.Minute)
+	default:
+		return int64(t.Truncate(time.Hour).Sub(start.Truncate(time.Hour)) / time.Hour)
+	}
+}
+
+// AverageSeries averages every statistic of several aligned series offset by offset,
+// e.g. to build a climatology out of the same window in past years
+func AverageSeries(series []AlignedSeries) AlignedSeries {
+	grouped := make(map[int64][]AggregateMeasurement)
+	for _, s := range series {
+		for offset, aggregate := range s {
+			grouped[offset] = append(grouped[offset], aggregate)
+		}
+	}
+
+	averaged := make(AlignedSeries, len(grouped))
+	for offset, group := range grouped {
+		averaged[offset] = averageAggregates(group)
+	}
+	return averaged
+}
+
+func averageAggregates(group []AggregateMeasurement) AggregateMeasurement {
+	collect := func(get func(AggregateMeasurement) *float64) *float64 {
+		var sum float64
+		var count int
+		for _, aggregate := range group {
+			if v := get(aggregate); v != nil {
+				sum += *v
+				count++
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		avg := roundToDecimal(sum/float64(count), 1)
+		return &avg
+	}
+	collectInt := func(get func(AggregateMeasurement) *int64) *int64 {
+		avg := collect(func(a AggregateMeasurement) *float64 {
+			if v := get(a); v != nil {
+				f := float64(*v)
+				return &f
+			}
+			return nil
+		})
+		if avg == nil {
+			return nil
+		}
+		v := int64(*avg + 0.5)
+		return &v
+	}
+
+	return AggregateMeasurement{
+		Type: group[0].Type,
+		Date: group[0].Date,
+		Temp: Temperature{
+			Min:     collect(func(a AggregateMeasurement) *float64 { return a.Temp.Min }),
+			Max:     collect(func(a AggregateMeasurement) *float64 { return a.Temp.Max }),
+			Average: collect(func(a AggregateMeasurement) *float64 { return a.Temp.Average }),
+		},
+		Humidity: Humidity{
+			Min:     collect(func(a AggregateMeasurement) *float64 { return a.Humidity.Min }),
+			Max:     collect(func(a AggregateMeasurement) *float64 { return a.Humidity.Max }),
+			Average: collect(func(a AggregateMeasurement) *float64 { return a.Humidity.Average }),
+		},
+		Pressure: Pressure{
+			Min:     collectInt(func(a AggregateMeasurement) *int64 { return a.Pressure.Min }),
+			Max:     collectInt(func(a AggregateMeasurement) *int64 { return a.Pressure.Max }),
+			Average: collect(func(a AggregateMeasurement) *float64 { return a.Pressure.Average }),
+		},
+	}
+}
+
+// Compare lines up aligned series side by side and computes the deltas of each
+// series against the first one
+func Compare(kind AggregationKind, info []ComparisonSeries, series []AlignedSeries) Comparison {
+	offsetSet := make(map[int64]struct{})
+	for _, s := range series {
+		for offset := range s {
+			offsetSet[offset] = struct{}{}
+		}
+	}
+
+	offsets := make([]int64, 0, len(offsetSet))
+	for offset := range offsetSet {
+		offsets = append(offsets, offset)
+	}
+	slices.Sort(offsets)
+
+	points := make([]ComparisonPoint, 0, len(offsets))
+	for _, offset := range offsets {
+		point := ComparisonPoint{
+			Offset: offset,
+			Values: make([]*AggregateMeasurement, len(series)),
+			Deltas: make([]*Delta, len(series)),
+		}
+
+		for i, s := range series {
+			if aggregate, ok := s[offset]; ok {
+				point.Values[i] = &aggregate
+			}
+		}
+
+		reference := point.Values[0]
+		for i := 1; i < len(series); i++ {
+			if reference != nil && point.Values[i] != nil {
+				point.Deltas[i] = delta(*reference, *point.Values[i])
+			}
+		}
+
+		points = append(points, point)
+	}
+
+	return Comparison{
+		Type:   kind.String(),
+		Series: info,
+		Points: points,
+	}
+}
+
+func delta(reference, other AggregateMeasurement) *Delta {
+	diff := func(a, b *float64) *float64 {
+		if a == nil || b == nil {
+			return nil
+		}
+		d := roundToDecimal(*b-*a, 1)
+		return &d
+	}
+
+	return &Delta{
+		Temperature: diff(reference.Temp.Average, other.Temp.Average),
+		Humidity:    diff(reference.Humidity.Average, other.Humidity.Average),
+		Pressure:    diff(reference.Pressure.Average, other.Pressure.Average),
+	}
+}
@@ -0,0 +1,97 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+func TestOffset(t *testing.T) {
+	loc := time.UTC
+	start := time.Date(2026, 3, 15, 10, 30, 0, 0, loc)
+
+	tests := []struct {
+		kind AggregationKind
+		t    time.Time
+		want int64
+	}{
+		{Hour, time.Date(2026, 3, 15, 10, 0, 0, 0, loc), 0},
+		{Hour, time.Date(2026, 3, 15, 13, 0, 0, 0, loc), 3},
+		{Minute, time.Date(2026, 3, 15, 10, 45, 0, 0, loc), 15},
+		{Day, time.Date(2026, 3, 17, 0, 0, 0, 0, loc), 2},
+	}
+
+	for _, tt := range tests {
+		if got := Offset(start, tt.t, tt.kind); got != tt.want {
+			t.Errorf("Offset(%s, %v) = %d, want %d", tt.kind, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCompare_AlignsSeriesAndComputesDeltas(t *testing.T) {
+	today := time.Date(2026, 3, 15, 0, 0, 0, 0, time.Local)
+	yesterday := today.AddDate(0, 0, -1)
+
+	todayData := AggregateMeasurements([]repository.MeasurementRecord{
+		{Timestamp: today.Add(1 * time.Hour), Temperature: 20, Humidity: 50, Pressure: 101000},
+		{Timestamp: today.Add(2 * time.Hour), Temperature: 22, Humidity: 55, Pressure: 101100},
+	}, Hour)
+	yesterdayData := AggregateMeasurements([]repository.MeasurementRecord{
+		{Timestamp: yesterday.Add(1 * time.Hour), Temperature: 18, Humidity: 60, Pressure: 100900},
+	}, Hour)
+
+	comparison := Compare(Hour,
+		[]ComparisonSeries{{Label: "today"}, {Label: "yesterday"}},
+		[]AlignedSeries{Align(todayData, today, Hour), Align(yesterdayData, yesterday, Hour)},
+	)
+
+	if comparison.Type != "hour" {
+		t.Errorf("expected type hour, got %s", comparison.Type)
+	}
+	if len(comparison.Points) != 2 {
+		t.Fatalf("expected 2 aligned points, got %d", len(comparison.Points))
+	}
+
+	first := comparison.Points[0]
+	if first.Offset != 1 {
+		t.Errorf("expected offset 1, got %d", first.Offset)
+	}
+	if first.Values[0] == nil || first.Values[1] == nil {
+		t.Fatal("expected both series at offset 1")
+	}
+	if first.Deltas[0] != nil {
+		t.Error("expected no delta for the reference series")
+	}
+	if d := first.Deltas[1]; d == nil || *d.Temperature != -2 || *d.Humidity != 10 || *d.Pressure != -100 {
+		t.Errorf("unexpected delta at offset 1: %+v", d)
+	}
+
+	second := comparison.Points[1]
+	if second.Values[1] != nil || second.Deltas[1] != nil {
+		t.Error("expected missing second series at offset 2")
+	}
+}
+
+func TestAverageSeries(t *testing.T) {
+	a := AlignedSeries{0: dailyAggregate(1, 10, 20)}
+	b := AlignedSeries{0: dailyAggregate(2, 14, 30), 1: dailyAggregate(3, 5, 6)}
+
+	averaged := AverageSeries([]AlignedSeries{a, b})
+
+	if len(averaged) != 2 {
+		t.Fatalf("expected 2 offsets, got %d", len(averaged))
+	}
+	if got := *averaged[0].Temp.Min; got != 12 {
+		t.Errorf("expected averaged min 12, got %v", got)
+	}
+	if got := *averaged[0].Temp.Max; got != 25 {
+		t.Errorf("expected averaged max 25, got %v", got)
+	}
+	if averaged[0].Temp.Average != nil {
+		t.Error("expected no average when no series has one")
+	}
+	if got := *averaged[1].Temp.Max; got != 6 {
+		t.Errorf("expected single-series max 6, got %v", got)
+	}
+}
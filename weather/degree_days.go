@@ -0,0 +1,78 @@
+package weather
+
+// DegreeDayConfig holds the base temperatures (°C) used for degree-day accumulation
+type DegreeDayConfig struct {
+	HeatingBase float64 // Below this daily mean temperature a day accrues heating degree days
+	CoolingBase float64 // Above this daily mean temperature a day accrues cooling degree days
+	GrowingBase float64 // Minimum temperature considered for growing degree days
+	GrowingCap  float64 // Maximum temperature considered for growing degree days
+}
+
+// DefaultDegreeDayConfig returns commonly used base temperatures
+func DefaultDegreeDayConfig() DegreeDayConfig {
+	return DegreeDayConfig{
+		HeatingBase: 18.0,
+		CoolingBase: 18.0,
+		GrowingBase: 10.0,
+		GrowingCap:  30.0,
+	}
+}
+
+// DegreeDay holds the degree-day values of a single day and the running
+// totals since the start of the requested season
+type DegreeDay struct {
+	Date         int64   `json:"date"`
+	TempMin      float64 `json:"temp_min"`
+	TempMax      float64 `json:"temp_max"`
+	TempMean     float64 `json:"temp_mean"`
+	Heating      float64 `json:"hdd"`
+	Cooling      float64 `json:"cdd"`
+	Growing      float64 `json:"gdd"`
+	HeatingTotal float64 `json:"hdd_total"`
+	CoolingTotal float64 `json:"cdd_total"`
+	GrowingTotal float64 `json:"gdd_total"`
+}
+
+// CalculateDegreeDays computes heating, cooling and growing degree days from
+// daily aggregates. The daily mean is (min + max) / 2; for growing degree days
+// min and max are first clamped to [GrowingBase, GrowingCap].
+// Aggregates must be sorted by date; days without temperature data are skipped.
+func CalculateDegreeDays(daily []AggregateMeasurement, cfg DegreeDayConfig) []DegreeDay {
+	result := make([]DegreeDay, 0, len(daily))
+
+	var heatingTotal, coolingTotal, growingTotal float64
+	for _, day := range daily {
+		if day.Temp.Min == nil || day.Temp.Max == nil {
+			continue
+		}
+
+		tMin, tMax := *day.Temp.Min, *day.Temp.Max
+		mean := (tMin + tMax) / 2
+
+		heating := max(0, cfg.HeatingBase-mean)
+		cooling := max(0, mean-cfg.CoolingBase)
+
+		gMin := min(max(tMin, cfg.GrowingBase), cfg.GrowingCap)
+		gMax := min(max(tMax, cfg.GrowingBase), cfg.GrowingCap)
+		growing := max(0, (gMin+gMax)/2-cfg.GrowingBase)
+
+		heatingTotal += heating
+		coolingTotal += cooling
+		growingTotal += growing
+
+		result = append(result, DegreeDay{
+			Date:         day.Date,
+			TempMin:      tMin,
+			TempMax:      tMax,
+			TempMean:     roundToDecimal(mean, 2),
+			Heating:      roundToDecimal(heating, 2),
+			Cooling:      roundToDecimal(cooling, 2),
+			Growing:      roundToDecimal(growing, 2),
+			HeatingTotal: roundToDecimal(heatingTotal, 2),
+			CoolingTotal: roundToDecimal(coolingTotal, 2),
+			GrowingTotal: roundToDecimal(growingTotal, 2),
+		})
+	}
+
+	return result
+}
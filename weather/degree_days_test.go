@@ -0,0 +1,61 @@
+package weather
+
+import (
+	"testing"
+)
+
+func dailyAggregate(date int64, tMin, tMax float64) AggregateMeasurement {
+	return AggregateMeasurement{
+		Type: Day.String(),
+		Date: date,
+		Temp: Temperature{Min: &tMin, Max: &tMax},
+	}
+}
+
+func TestCalculateDegreeDays(t *testing.T) {
+	cfg := DefaultDegreeDayConfig()
+	daily := []AggregateMeasurement{
+		dailyAggregate(1, 4, 12),  // mean 8: HDD 10, CDD 0, GDD (10+12)/2-10 = 1
+		dailyAggregate(2, 20, 36), // mean 28: HDD 0, CDD 10, GDD (20+30)/2-10 = 15
+		{Date: 3},                 // no temperature data, skipped
+		dailyAggregate(4, 16, 20), // mean 18: HDD 0, CDD 0, GDD 8
+	}
+
+	result := CalculateDegreeDays(daily, cfg)
+
+	if len(result) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(result))
+	}
+
+	expected := []DegreeDay{
+		{Date: 1, Heating: 10, Cooling: 0, Growing: 1, HeatingTotal: 10, CoolingTotal: 0, GrowingTotal: 1},
+		{Date: 2, Heating: 0, Cooling: 10, Growing: 15, HeatingTotal: 10, CoolingTotal: 10, GrowingTotal: 16},
+		{Date: 4, Heating: 0, Cooling: 0, Growing: 8, HeatingTotal: 10, CoolingTotal: 10, GrowingTotal: 24},
+	}
+
+	for i, want := range expected {
+		got := result[i]
+		if got.Date != want.Date || got.Heating != want.Heating || got.Cooling != want.Cooling || got.Growing != want.Growing {
+			t.Errorf("day %d: expected %+v, got %+v", i, want, got)
+		}
+		if got.HeatingTotal != want.HeatingTotal || got.CoolingTotal != want.CoolingTotal || got.GrowingTotal != want.GrowingTotal {
+			t.Errorf("day %d: expected totals %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestCalculateDegreeDays_CustomBases(t *testing.T) {
+	cfg := DegreeDayConfig{HeatingBase: 15.5, CoolingBase: 22, GrowingBase: 5, GrowingCap: 25}
+
+	result := CalculateDegreeDays([]AggregateMeasurement{dailyAggregate(1, 0, 10)}, cfg)
+
+	if len(result) != 1 {
+		t.Fatalf("expected 1 day, got %d", len(result))
+	}
+	if result[0].Heating != 10.5 {
+		t.Errorf("expected HDD 10.5, got %v", result[0].Heating)
+	}
+	if result[0].Growing != 2.5 {
+		t.Errorf("expected GDD 2.5, got %v", result[0].Growing)
+	}
+}
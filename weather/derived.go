@@ -0,0 +1,24 @@
+package weather
+
+import "math"
+
+// DewPoint returns the dew point (°C) for a temperature (°C) and relative humidity (%)
+// using the Magnus formula
+func DewPoint(celsius, humidity float64) float64 {
+	const b, c = 17.62, 243.12
+	if humidity <= 0 {
+		humidity = 0.01
+	}
+	gamma := math.Log(humidity/100) + b*celsius/(c+celsius)
+	return c * gamma / (b - gamma)
+}
+
+// SeaLevelPressure reduces a station pressure (Pa) measured at altitude (m) and
+// temperature (°C) to mean sea level with the hypsometric formula
+func SeaLevelPressure(pascal, celsius, altitude float64) float64 {
+	if altitude == 0 {
+		return pascal
+	}
+	const lapseRate = 0.0065 // K/m
+	return pascal * math.Pow(1-lapseRate*altitude/(celsius+lapseRate*altitude+273.15), -5.257)
+}
@@ -0,0 +1,34 @@
+package weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDewPoint(t *testing.T) {
+	tests := []struct {
+		celsius, humidity, want float64
+	}{
+		{20, 100, 20},
+		{25, 50, 13.85},
+		{0, 80, -3.0},
+	}
+
+	for _, tt := range tests {
+		if got := DewPoint(tt.celsius, tt.humidity); math.Abs(got-tt.want) > 0.05 {
+			t.Errorf("DewPoint(%v, %v) = %v, want %v", tt.celsius, tt.humidity, got, tt.want)
+		}
+	}
+}
+
+func TestSeaLevelPressure(t *testing.T) {
+	if got := SeaLevelPressure(101325, 15, 0); got != 101325 {
+		t.Errorf("expected pressure at sea level to be unchanged, got %v", got)
+	}
+
+	// About 12 hPa per 100 m near sea level
+	got := SeaLevelPressure(100000, 15, 100)
+	if math.Abs(got-101195) > 20 {
+		t.Errorf("expected about 101195 Pa, got %v", got)
+	}
+}
@@ -0,0 +1,214 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TemperatureUnit is a unit for temperature values
+type TemperatureUnit string
+
+// PressureUnit is a unit for pressure values
+type PressureUnit string
+
+const (
+	Celsius    TemperatureUnit = "C"
+	Fahrenheit TemperatureUnit = "F"
+)
+
+const (
+	Pascal            PressureUnit = "Pa"
+	Hectopascal       PressureUnit = "hPa"
+	Kilopascal        PressureUnit = "kPa"
+	InchMercury       PressureUnit = "inHg"
+	MillimeterMercury PressureUnit = "mmHg"
+)
+
+// PercentRH is the only supported humidity unit
+const PercentRH = "%"
+
+// pressureUnits holds the number of Pascal per unit and the decimal places used when rounding
+var pressureUnits = map[PressureUnit]struct {
+	pascals   float64
+	precision int
+}{
+	Pascal:            {1, 1},
+	Hectopascal:       {100, 2},
+	Kilopascal:        {1000, 3},
+	InchMercury:       {3386.389, 3},
+	MillimeterMercury: {133.322387415, 2},
+}
+
+// unitPresets are named unit systems accepted by ParseUnits
+var unitPresets = map[string]Units{
+	"metric":   {Temperature: Celsius, Humidity: PercentRH, Pressure: Hectopascal},
+	"imperial": {Temperature: Fahrenheit, Humidity: PercentRH, Pressure: InchMercury},
+	"si":       {Temperature: Celsius, Humidity: PercentRH, Pressure: Pascal},
+}
+
+// Units selects the unit of each measured quantity in API and export outputs
+type Units struct {
+	Temperature TemperatureUnit `json:"temperature"`
+	Humidity    string          `json:"humidity"`
+	Pressure    PressureUnit    `json:"pressure"`
+}
+
+// DefaultUnits returns the units the sensor measures in (°C, %RH, Pa)
+func DefaultUnits() Units {
+	return unitPresets["si"]
+}
+
+// ParseUnits applies a units specification on top of base. The specification is a
+// preset name (metric, imperial, si) or a comma separated list of unit symbols
+// (C, F, Pa, hPa, kPa, inHg, mmHg); quantities not mentioned keep the base unit.
+// Symbols are case-insensitive.
+func ParseUnits(spec string, base Units) (Units, error) {
+	units := base
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return units, nil
+	}
+
+	if preset, ok := unitPresets[strings.ToLower(spec)]; ok {
+		return preset, nil
+	}
+
+	for token := range strings.SplitSeq(spec, ",") {
+		token = strings.TrimSpace(token)
+		switch {
+		case token == "":
+			continue
+		case strings.EqualFold(token, string(Celsius)):
+			units.Temperature = Celsius
+		case strings.EqualFold(token, string(Fahrenheit)):
+			units.Temperature = Fahrenheit
+		default:
+			pressure, ok := lookupPressureUnit(token)
+			if !ok {
+				return base, fmt.Errorf("unknown unit: %s", token)
+			}
+			units.Pressure = pressure
+		}
+	}
+
+	return units, nil
+}
+
+func lookupPressureUnit(symbol string) (PressureUnit, bool) {
+	for unit := range pressureUnits {
+		if strings.EqualFold(symbol, string(unit)) {
+			return unit, true
+		}
+	}
+	return "", false
+}
+
+// String formats the units as a header value, e.g. "temperature=C; humidity=%; pressure=Pa"
+func (u Units) String() string {
+	return fmt.Sprintf("temperature=%s; humidity=%s; pressure=%s", u.Temperature, u.Humidity, u.Pressure)
+}
+
+// ConvertTemperature converts a temperature in Celsius to the selected unit
+func (u Units) ConvertTemperature(celsius float64) float64 {
+	if u.Temperature == Fahrenheit {
+		return roundToDecimal(celsius*9/5+32, 2)
+	}
+	return celsius
+}
+
+// ConvertPressure converts a pressure in Pascal to the selected unit
+func (u Units) ConvertPressure(pascal float64) float64 {
+	unit, ok := pressureUnits[u.Pressure]
+	if !ok || u.Pressure == Pascal {
+		return pascal
+	}
+	return roundToDecimal(pascal/unit.pascals, unit.precision)
+}
+
+// ToCelsius converts a temperature in unit to Celsius
+func ToCelsius(value float64, unit TemperatureUnit) float64 {
+	if unit == Fahrenheit {
+		return (value - 32) * 5 / 9
+	}
+	return value
+}
+
+// ToPascal converts a pressure in unit to Pascal
+func ToPascal(value float64, unit PressureUnit) float64 {
+	if p, ok := pressureUnits[unit]; ok {
+		return value * p.pascals
+	}
+	return value
+}
+
+// PressurePrecision returns the decimal places meaningful for the selected pressure unit
+func (u Units) PressurePrecision() int {
+	if unit, ok := pressureUnits[u.Pressure]; ok {
+		return unit.precision
+	}
+	return 1
+}
+
+// ConvertedAggregate is an AggregateMeasurement expressed in the selected units.
+// Its JSON layout matches AggregateMeasurement.
+type ConvertedAggregate struct {
+	Type     string            `json:"type"`
+	Date     int64             `json:"date"`
+	Temp     Temperature       `json:"temp"`
+	Humidity Humidity          `json:"humidity"`
+	Pressure ConvertedPressure `json:"pressure"`
+}
+
+// ConvertedPressure holds pressure statistics in the selected unit
+type ConvertedPressure struct {
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Average *float64 `json:"average,omitempty"`
+}
+
+// ConvertAggregates converts aggregates to the selected units
+func (u Units) ConvertAggregates(aggregates []AggregateMeasurement) []ConvertedAggregate {
+	converted := make([]ConvertedAggregate, 0, len(aggregates))
+
+	temperature := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		c := u.ConvertTemperature(*v)
+		return &c
+	}
+	pressure := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		c := u.ConvertPressure(*v)
+		return &c
+	}
+	pressureInt := func(v *int64) *float64 {
+		if v == nil {
+			return nil
+		}
+		f := float64(*v)
+		return pressure(&f)
+	}
+
+	for _, aggregate := range aggregates {
+		converted = append(converted, ConvertedAggregate{
+			Type: aggregate.Type,
+			Date: aggregate.Date,
+			Temp: Temperature{
+				Max:     temperature(aggregate.Temp.Max),
+				Min:     temperature(aggregate.Temp.Min),
+				Average: temperature(aggregate.Temp.Average),
+			},
+			Humidity: aggregate.Humidity,
+			Pressure: ConvertedPressure{
+				Min:     pressureInt(aggregate.Pressure.Min),
+				Max:     pressureInt(aggregate.Pressure.Max),
+				Average: pressure(aggregate.Pressure.Average),
+			},
+		})
+	}
+
+	return converted
+}
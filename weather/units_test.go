@@ -0,0 +1,94 @@
+package weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	base := DefaultUnits()
+
+	tests := []struct {
+		spec    string
+		want    Units
+		wantErr bool
+	}{
+		{"", base, false},
+		{"imperial", Units{Temperature: Fahrenheit, Humidity: PercentRH, Pressure: InchMercury}, false},
+		{"METRIC", Units{Temperature: Celsius, Humidity: PercentRH, Pressure: Hectopascal}, false},
+		{"F", Units{Temperature: Fahrenheit, Humidity: PercentRH, Pressure: Pascal}, false},
+		{"c, mmhg", Units{Temperature: Celsius, Humidity: PercentRH, Pressure: MillimeterMercury}, false},
+		{"kPa", Units{Temperature: Celsius, Humidity: PercentRH, Pressure: Kilopascal}, false},
+		{"K", base, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUnits(tt.spec, base)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUnits(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUnits(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestUnitsConversions(t *testing.T) {
+	imperial := Units{Temperature: Fahrenheit, Pressure: InchMercury}
+	if got := imperial.ConvertTemperature(25); got != 77 {
+		t.Errorf("expected 77°F, got %v", got)
+	}
+	if got := imperial.ConvertPressure(101325); got != 29.921 {
+		t.Errorf("expected 29.921 inHg, got %v", got)
+	}
+
+	mmHg := Units{Temperature: Celsius, Pressure: MillimeterMercury}
+	if got := mmHg.ConvertTemperature(25); got != 25 {
+		t.Errorf("expected Celsius to be unchanged, got %v", got)
+	}
+	if got := mmHg.ConvertPressure(101325); got != 760 {
+		t.Errorf("expected 760 mmHg, got %v", got)
+	}
+
+	if got := (Units{Pressure: Kilopascal}).ConvertPressure(101325); got != 101.325 {
+		t.Errorf("expected 101.325 kPa, got %v", got)
+	}
+
+	if got := ToCelsius(77, Fahrenheit); got != 25 {
+		t.Errorf("expected 25°C, got %v", got)
+	}
+	if got := ToCelsius(25, Celsius); got != 25 {
+		t.Errorf("expected Celsius to be unchanged, got %v", got)
+	}
+	if got := ToPascal(29.92, InchMercury); math.Abs(got-101320.8) > 0.1 {
+		t.Errorf("expected 101320.8 Pa, got %v", got)
+	}
+	if got := ToPascal(1013.25, Hectopascal); got != 101325 {
+		t.Errorf("expected 101325 Pa, got %v", got)
+	}
+}
+
+func TestConvertAggregates(t *testing.T) {
+	tMin, tMax := 10.0, 20.0
+	pMin, pMax := int64(100000), int64(102000)
+	pAvg := 101000.0
+
+	converted := Units{Temperature: Fahrenheit, Humidity: PercentRH, Pressure: Hectopascal}.ConvertAggregates([]AggregateMeasurement{{
+		Type:     "hour",
+		Date:     1,
+		Temp:     Temperature{Min: &tMin, Max: &tMax},
+		Pressure: Pressure{Min: &pMin, Max: &pMax, Average: &pAvg},
+	}})
+
+	if len(converted) != 1 {
+		t.Fatalf("expected 1 aggregate, got %d", len(converted))
+	}
+	row := converted[0]
+	if *row.Temp.Min != 50 || *row.Temp.Max != 68 || row.Temp.Average != nil {
+		t.Errorf("unexpected converted temperature: %+v", row.Temp)
+	}
+	if *row.Pressure.Min != 1000 || *row.Pressure.Max != 1020 || *row.Pressure.Average != 1010 {
+		t.Errorf("unexpected converted pressure: %+v", row.Pressure)
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/alert"
+)
+
+// AlertProvider define a interface para consultar o estado das regras de alerta
+type AlertProvider interface {
+	Alerts() []alert.Status
+}
+
+// WithAlerts enables the /alerts endpoint backed by the given provider
+func WithAlerts(alerts AlertProvider) Option {
+	return func(s *Server) {
+		s.alerts = alerts
+	}
+}
+
+// handleAlerts handles GET /alerts - returns the rules in the requested state,
+// firing by default, or every rule with state=all
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := alert.State(r.URL.Query().Get("state"))
+	switch state {
+	case "":
+		state = alert.StateFiring
+	case "all", alert.StateFiring, alert.StatePending, alert.StateResolved, alert.StateInactive:
+	default:
+		s.sendErrorResponse(w, "Invalid state, use firing, pending, resolved, inactive or all", http.StatusBadRequest)
+		return
+	}
+
+	if s.alerts == nil {
+		s.sendErrorResponse(w, "Alerts not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	statuses := []alert.Status{}
+	for _, status := range s.alerts.Alerts() {
+		if state == "all" || status.State == state {
+			statuses = append(statuses, status)
+		}
+	}
+
+	s.sendJSONResponse(w, statuses, http.StatusOK)
+}
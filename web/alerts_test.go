@@ -0,0 +1,75 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/alert"
+)
+
+type MockAlertProvider struct {
+	statuses []alert.Status
+}
+
+func (m *MockAlertProvider) Alerts() []alert.Status {
+	return m.statuses
+}
+
+func TestHandleAlerts(t *testing.T) {
+	value := 1.2
+	alerts := &MockAlertProvider{statuses: []alert.Status{
+		{Rule: "frost", Description: "temperature < 2 for 10m", State: alert.StateFiring, Value: &value},
+		{Rule: "storm", Description: "pressure change over 3h < -3", State: alert.StateInactive},
+	}}
+
+	tests := []struct {
+		name   string
+		alerts AlertProvider
+		url    string
+		want   int
+		rules  []string
+	}{
+		{"firing by default", alerts, "/api/v1/alerts", http.StatusOK, []string{"frost"}},
+		{"inactive", alerts, "/api/v1/alerts?state=inactive", http.StatusOK, []string{"storm"}},
+		{"pending", alerts, "/api/v1/alerts?state=pending", http.StatusOK, []string{}},
+		{"all", alerts, "/api/v1/alerts?state=all", http.StatusOK, []string{"frost", "storm"}},
+		{"invalid state", alerts, "/api/v1/alerts?state=silenced", http.StatusBadRequest, nil},
+		{"not configured", nil, "/api/v1/alerts", http.StatusServiceUnavailable, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.alerts != nil {
+				opts = append(opts, WithAlerts(tt.alerts))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+			if tt.rules == nil {
+				return
+			}
+
+			var response []alert.Status
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(response) != len(tt.rules) {
+				t.Fatalf("expected rules %v, got %+v", tt.rules, response)
+			}
+			for i, rule := range tt.rules {
+				if response[i].Rule != rule {
+					t.Errorf("expected rule %s at %d, got %s", rule, i, response[i].Rule)
+				}
+			}
+		})
+	}
+}
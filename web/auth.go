@@ -0,0 +1,289 @@
+package web
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+)
+
+// Scope is a permission level required by a route
+type Scope string
+
+const (
+	// ScopeRead allows reading measurements, statistics and the dashboard
+	ScopeRead Scope = "read"
+	// ScopeIngest allows remote nodes to post measurements
+	ScopeIngest Scope = "ingest"
+	// ScopeAdmin allows administrative and write operations; it implies every other scope
+	ScopeAdmin Scope = "admin"
+)
+
+const (
+	apiKeyHeader     = "X-API-Key"
+	apiKeyQueryParam = "api_key"
+	authRealm        = "atmosbyte"
+
+	// dummyPasswordHash is compared for unknown users so they take as long as a wrong password
+	dummyPasswordHash = "$2a$10$QhQJSHrlPwGjuV/0OmPDueuAnVokcN.RaYrgL0kdzgljL6tLpVRhy"
+)
+
+// ParseScope converts a configuration string into a Scope
+func ParseScope(scope string) (Scope, error) {
+	switch Scope(scope) {
+	case ScopeRead, ScopeIngest, ScopeAdmin:
+		return Scope(scope), nil
+	default:
+		return "", fmt.Errorf("unknown scope: %s", scope)
+	}
+}
+
+// allows reports whether a credential with scope s may access a route requiring required
+func (s Scope) allows(required Scope) bool {
+	return s == ScopeAdmin || s == required
+}
+
+// APIKey is a static key sent in the X-API-Key header, as a Bearer token or in the api_key query parameter
+type APIKey struct {
+	Name  string
+	Key   string
+	Scope Scope
+}
+
+// User is an HTTP Basic user with a bcrypt password hash
+type User struct {
+	Username     string
+	PasswordHash string
+	Scope        Scope
+}
+
+// AuthConfig holds the authentication configuration
+type AuthConfig struct {
+	APIKeys       []APIKey
+	Users         []User
+	PublicRead    bool          // Routes requiring only ScopeRead are served without credentials
+	MaxFailures   int           // Failed attempts per client before it is blocked
+	FailureWindow time.Duration // Window in which failures are counted and for which a client stays blocked
+}
+
+// principal identifies an authenticated credential
+type principal struct {
+	name  string
+	scope Scope
+}
+
+// failureRecord counts the failed attempts of one client
+type failureRecord struct {
+	count int
+	first time.Time
+}
+
+// Authenticator checks request credentials and rate-limits failed attempts
+type Authenticator struct {
+	config AuthConfig
+	users  map[string]User
+	now    func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]*failureRecord
+}
+
+// NewAuthenticator validates the configuration and creates an Authenticator
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	if len(config.APIKeys) == 0 && len(config.Users) == 0 {
+		return nil, fmt.Errorf("authentication requires at least one API key or user")
+	}
+
+	for _, key := range config.APIKeys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("API key %q has an empty key", key.Name)
+		}
+		if _, err := ParseScope(string(key.Scope)); err != nil {
+			return nil, fmt.Errorf("API key %q: %w", key.Name, err)
+		}
+	}
+
+	users := make(map[string]User, len(config.Users))
+	for _, user := range config.Users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("user with empty username")
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("user %q has an invalid bcrypt password hash: %w", user.Username, err)
+		}
+		if _, err := ParseScope(string(user.Scope)); err != nil {
+			return nil, fmt.Errorf("user %q: %w", user.Username, err)
+		}
+		if _, ok := users[user.Username]; ok {
+			return nil, fmt.Errorf("duplicate user %q", user.Username)
+		}
+		users[user.Username] = user
+	}
+
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = 5
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = 15 * time.Minute
+	}
+
+	return &Authenticator{
+		config:   config,
+		users:    users,
+		now:      time.Now,
+		failures: make(map[string]*failureRecord),
+	}, nil
+}
+
+// HashPassword returns the bcrypt hash of password for use in the configuration
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// authenticate returns the principal of the request credentials.
+// ok is false when no credentials were sent; err is set when they are invalid.
+func (a *Authenticator) authenticate(r *http.Request) (p principal, ok bool, err error) {
+	if key := requestAPIKey(r); key != "" {
+		for _, candidate := range a.config.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(candidate.Key)) == 1 {
+				return principal{name: candidate.Name, scope: candidate.Scope}, true, nil
+			}
+		}
+		return principal{}, true, fmt.Errorf("invalid API key")
+	}
+
+	if username, password, hasBasic := r.BasicAuth(); hasBasic {
+		user, exists := a.users[username]
+		if !exists {
+			_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+			return principal{}, true, fmt.Errorf("unknown user %q", username)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+			return principal{}, true, fmt.Errorf("invalid password for user %q", username)
+		}
+		return principal{name: user.Username, scope: user.Scope}, true, nil
+	}
+
+	return principal{}, false, nil
+}
+
+// requestAPIKey extracts an API key from the header, a Bearer token or the query string
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get(apiKeyQueryParam)
+}
+
+// blocked reports whether client exceeded the allowed failures and for how long it stays blocked
+func (a *Authenticator) blocked(client string) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record, ok := a.failures[client]
+	if !ok {
+		return 0, false
+	}
+
+	remaining := record.first.Add(a.config.FailureWindow).Sub(a.now())
+	if remaining <= 0 {
+		delete(a.failures, client)
+		return 0, false
+	}
+
+	return remaining, record.count >= a.config.MaxFailures
+}
+
+// recordFailure counts a failed attempt of client and drops expired records
+func (a *Authenticator) recordFailure(client string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for key, record := range a.failures {
+		if now.Sub(record.first) >= a.config.FailureWindow {
+			delete(a.failures, key)
+		}
+	}
+
+	record, ok := a.failures[client]
+	if !ok {
+		record = &failureRecord{first: now}
+		a.failures[client] = record
+	}
+	record.count++
+}
+
+// resetFailures forgets the failed attempts of client after a successful login
+func (a *Authenticator) resetFailures(client string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.failures, client)
+}
+
+// clientAddress returns the host part of the request remote address
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requireScope wraps next so it is only served to requests whose credentials grant scope.
+// Without an Authenticator every route is public.
+func (s *Server) requireScope(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := s.auth
+		if auth == nil || (scope == ScopeRead && auth.config.PublicRead) {
+			next(w, r)
+			return
+		}
+
+		client := clientAddress(r)
+		if remaining, blocked := auth.blocked(client); blocked {
+			w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Seconds()+0.5)))
+			s.sendErrorResponse(w, "Too many failed authentication attempts", http.StatusTooManyRequests)
+			return
+		}
+
+		p, ok, err := auth.authenticate(r)
+		if err != nil {
+			auth.recordFailure(client)
+			logging.Warnf("Authentication failed for %s %s from %s: %v", r.Method, r.URL.Path, client, err)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", authRealm))
+			s.sendErrorResponse(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", authRealm))
+			s.sendErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		auth.resetFailures(client)
+
+		if !p.scope.allows(scope) {
+			logging.Warnf("Authorization denied for %s on %s %s: %s scope required", p.name, r.Method, r.URL.Path, scope)
+			s.sendErrorResponse(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
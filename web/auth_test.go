@@ -0,0 +1,168 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthenticator(t *testing.T, publicRead bool) *Authenticator {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	auth, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{
+			{Name: "dashboard", Key: "read-key", Scope: ScopeRead},
+			{Name: "ops", Key: "admin-key", Scope: ScopeAdmin},
+		},
+		Users: []User{
+			{Username: "viewer", PasswordHash: string(hash), Scope: ScopeRead},
+		},
+		PublicRead:    publicRead,
+		MaxFailures:   3,
+		FailureWindow: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator returned error: %v", err)
+	}
+	return auth
+}
+
+func TestRequireScope(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{},
+		WithAuth(newTestAuthenticator(t, false)))
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name   string
+		scope  Scope
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no credentials", ScopeRead, func(r *http.Request) {}, http.StatusUnauthorized},
+		{"api key header", ScopeRead, func(r *http.Request) { r.Header.Set("X-API-Key", "read-key") }, http.StatusOK},
+		{"bearer token", ScopeRead, func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-key") }, http.StatusOK},
+		{"query parameter", ScopeRead, func(r *http.Request) { r.URL.RawQuery = "api_key=read-key" }, http.StatusOK},
+		{"basic auth", ScopeRead, func(r *http.Request) { r.SetBasicAuth("viewer", "secret") }, http.StatusOK},
+		{"wrong password", ScopeRead, func(r *http.Request) { r.SetBasicAuth("viewer", "nope") }, http.StatusUnauthorized},
+		{"read key on admin route", ScopeAdmin, func(r *http.Request) { r.Header.Set("X-API-Key", "read-key") }, http.StatusForbidden},
+		{"admin key on admin route", ScopeAdmin, func(r *http.Request) { r.Header.Set("X-API-Key", "admin-key") }, http.StatusOK},
+		{"admin key on read route", ScopeRead, func(r *http.Request) { r.Header.Set("X-API-Key", "admin-key") }, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.10:1234"
+			tt.setup(req)
+			w := httptest.NewRecorder()
+
+			server.requireScope(tt.scope, ok)(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestRequireScope_PublicRead(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{},
+		WithAuth(newTestAuthenticator(t, true)))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected public read route to return 200, got %d", w.Code)
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	w = httptest.NewRecorder()
+	server.requireScope(ScopeAdmin, ok)(w, httptest.NewRequest(http.MethodPost, "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected admin route to require credentials, got %d", w.Code)
+	}
+}
+
+func TestRequireScope_RateLimitsFailures(t *testing.T) {
+	auth := newTestAuthenticator(t, false)
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithAuth(auth))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	request := func(key, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/measurements", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		server.requireScope(ScopeRead, ok)(w, req)
+		return w
+	}
+
+	for i := range 3 {
+		if w := request("wrong", "192.0.2.10:1000"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, w.Code)
+		}
+	}
+
+	w := request("read-key", "192.0.2.10:1001")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected blocked client to get 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", w.Header().Get("Retry-After"))
+	}
+
+	if w := request("read-key", "192.0.2.20:1000"); w.Code != http.StatusOK {
+		t.Fatalf("expected other clients to be unaffected, got %d", w.Code)
+	}
+
+	now = now.Add(time.Minute)
+	if w := request("read-key", "192.0.2.10:1002"); w.Code != http.StatusOK {
+		t.Fatalf("expected block to expire after the window, got %d", w.Code)
+	}
+}
+
+func TestNewAuthenticator_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AuthConfig
+	}{
+		{"no credentials", AuthConfig{}},
+		{"unknown scope", AuthConfig{APIKeys: []APIKey{{Name: "k", Key: "x", Scope: "write"}}}},
+		{"empty key", AuthConfig{APIKeys: []APIKey{{Name: "k", Scope: ScopeRead}}}},
+		{"plain text password", AuthConfig{Users: []User{{Username: "u", PasswordHash: "secret", Scope: ScopeRead}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAuthenticator(tt.config); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatalf("expected a valid bcrypt hash: %v", err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("expected cost %d like HashPassword, got %d", bcrypt.DefaultCost, cost)
+	}
+}
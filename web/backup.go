@@ -0,0 +1,51 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/backup"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+)
+
+// BackupProvider define a interface para criar um snapshot do banco de dados
+type BackupProvider interface {
+	Backup(ctx context.Context) (backup.Snapshot, error)
+}
+
+// WithBackups enables the /admin/backup endpoint backed by the given provider
+func WithBackups(backups BackupProvider) Option {
+	return func(s *Server) {
+		s.backups = backups
+	}
+}
+
+// handleBackup handles POST /admin/backup - takes a snapshot of the database into
+// the backup directory and rotates the older ones
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.backups == nil {
+		s.sendErrorResponse(w, "Backups not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	// A large database takes longer than the write timeout to copy
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Errorf("Failed to clear the backup response deadline: %v", err)
+	}
+
+	snapshot, err := s.backups.Backup(r.Context())
+	if err != nil {
+		logging.Errorf("Backup failed: %v", err)
+		s.sendErrorResponse(w, "Backup failed", http.StatusInternalServerError)
+		return
+	}
+
+	s.sendJSONResponse(w, snapshot, http.StatusCreated)
+}
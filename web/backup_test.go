@@ -0,0 +1,73 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/backup"
+)
+
+type MockBackupProvider struct {
+	snapshot backup.Snapshot
+	err      error
+}
+
+func (m *MockBackupProvider) Backup(ctx context.Context) (backup.Snapshot, error) {
+	return m.snapshot, m.err
+}
+
+func TestHandleBackup(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	backups := &MockBackupProvider{snapshot: backup.Snapshot{
+		File:      "backups/atmosbyte-20260102-030000.db.gz",
+		Size:      4096,
+		CreatedAt: created,
+		Removed:   []string{"atmosbyte-20251201-030000.db.gz"},
+	}}
+
+	tests := []struct {
+		name    string
+		backups BackupProvider
+		method  string
+		want    int
+	}{
+		{"backup", backups, http.MethodPost, http.StatusCreated},
+		{"method not allowed", backups, http.MethodGet, http.StatusMethodNotAllowed},
+		{"not configured", nil, http.MethodPost, http.StatusServiceUnavailable},
+		{"failure", &MockBackupProvider{err: errors.New("disk full")}, http.MethodPost, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.backups != nil {
+				opts = append(opts, WithBackups(tt.backups))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			req := httptest.NewRequest(tt.method, "/api/v1/admin/backup", nil)
+			w := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+			if tt.want != http.StatusCreated {
+				return
+			}
+
+			var response backup.Snapshot
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.File != backups.snapshot.File || response.Size != 4096 || len(response.Removed) != 1 {
+				t.Errorf("unexpected response %+v", response)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package web
 
-import "time"
+import (
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
 
 // Config holds configuration options for the web server
 type Config struct {
@@ -8,5 +12,10 @@ type Config struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
-	ShutdownTimeout time.Duration // Timeout for graceful shutdown
+	ShutdownTimeout time.Duration           // Timeout for graceful shutdown
+	DegreeDays      weather.DegreeDayConfig // Default base temperatures for /data/degree-days
+	Units           string                  // Default units specification, see weather.ParseUnits
+	TLS             TLSConfig               // HTTPS settings, plain HTTP when disabled
+	Ingest          IngestConfig            // Limits for measurements posted by remote nodes
+	DashboardNode   string                  // Node shown when a request names none, empty for the local sensor
 }
@@ -4,34 +4,45 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"iter"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 )
 
-func writeHistoricalCSV(w io.Writer, rows []weather.AggregateMeasurement) error {
+func writeHistoricalCSV(w io.Writer, rows []weather.AggregateMeasurement, units weather.Units) error {
 	csvWriter := csv.NewWriter(w)
 	defer csvWriter.Flush()
 
+	// Celsius columns keep their historical names; other units are suffixed
+	tempSuffix := ""
+	if units.Temperature != weather.Celsius {
+		tempSuffix = "_" + strings.ToLower(string(units.Temperature))
+	}
+	pressureSuffix := "_" + strings.ToLower(string(units.Pressure))
+
 	header := []string{
 		"timestamp",
-		"temp_min",
-		"temp_avg",
-		"temp_max",
+		"temp_min" + tempSuffix,
+		"temp_avg" + tempSuffix,
+		"temp_max" + tempSuffix,
 		"humidity_min",
 		"humidity_avg",
 		"humidity_max",
-		"pressure_min_hpa",
-		"pressure_avg_hpa",
-		"pressure_max_hpa",
+		"pressure_min" + pressureSuffix,
+		"pressure_avg" + pressureSuffix,
+		"pressure_max" + pressureSuffix,
 	}
 
 	if err := csvWriter.Write(header); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	for _, row := range rows {
+	pressurePrecision := units.PressurePrecision()
+	for _, row := range units.ConvertAggregates(rows) {
 		record := []string{
 			time.Unix(row.Date, 0).UTC().Format(time.RFC3339),
 			formatFloatPtr(row.Temp.Min, 2),
@@ -40,9 +51,9 @@ func writeHistoricalCSV(w io.Writer, rows []weather.AggregateMeasurement) error
 			formatFloatPtr(row.Humidity.Min, 2),
 			formatFloatPtr(row.Humidity.Average, 2),
 			formatFloatPtr(row.Humidity.Max, 2),
-			formatInt64PtrAsHPA(row.Pressure.Min, 2),
-			formatFloatPtrAsHPA(row.Pressure.Average, 2),
-			formatInt64PtrAsHPA(row.Pressure.Max, 2),
+			formatFloatPtr(row.Pressure.Min, pressurePrecision),
+			formatFloatPtr(row.Pressure.Average, pressurePrecision),
+			formatFloatPtr(row.Pressure.Max, pressurePrecision),
 		}
 
 		if err := csvWriter.Write(record); err != nil {
@@ -57,21 +68,172 @@ func writeHistoricalCSV(w io.Writer, rows []weather.AggregateMeasurement) error
 	return nil
 }
 
-func formatFloatPtr(value *float64, precision int) string {
-	if value == nil {
-		return ""
+// writeRawCSV writes the measurements as they are read; an error leaves the output truncated
+func writeRawCSV(w io.Writer, records iter.Seq2[repository.MeasurementRecord, error], units weather.Units) error {
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	tempSuffix := ""
+	if units.Temperature != weather.Celsius {
+		tempSuffix = "_" + strings.ToLower(string(units.Temperature))
 	}
 
-	return strconv.FormatFloat(*value, 'f', precision, 64)
+	header := []string{
+		"id",
+		"timestamp",
+		"node",
+		"temperature" + tempSuffix,
+		"humidity",
+		"pressure_" + strings.ToLower(string(units.Pressure)),
+	}
+
+	if err := csvWriter.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	pressurePrecision := units.PressurePrecision()
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+
+		m := rawMeasurement(record, units)
+		row := []string{
+			strconv.FormatInt(m.ID, 10),
+			m.Timestamp.UTC().Format(time.RFC3339Nano),
+			m.Node,
+			strconv.FormatFloat(m.Temperature, 'f', 2, 64),
+			strconv.FormatFloat(m.Humidity, 'f', 2, 64),
+			strconv.FormatFloat(m.Pressure, 'f', pressurePrecision, 64),
+		}
+
+		if err := csvWriter.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+
+	return nil
+}
+
+func writeDegreeDaysCSV(w io.Writer, rows []weather.DegreeDay) error {
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	header := []string{
+		"date",
+		"temp_min",
+		"temp_max",
+		"temp_mean",
+		"hdd",
+		"cdd",
+		"gdd",
+		"hdd_total",
+		"cdd_total",
+		"gdd_total",
+	}
+
+	if err := csvWriter.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, row := range rows {
+		record := []string{
+			time.Unix(row.Date, 0).UTC().Format(time.RFC3339),
+			strconv.FormatFloat(row.TempMin, 'f', 2, 64),
+			strconv.FormatFloat(row.TempMax, 'f', 2, 64),
+			strconv.FormatFloat(row.TempMean, 'f', 2, 64),
+			strconv.FormatFloat(row.Heating, 'f', 2, 64),
+			strconv.FormatFloat(row.Cooling, 'f', 2, 64),
+			strconv.FormatFloat(row.Growing, 'f', 2, 64),
+			strconv.FormatFloat(row.HeatingTotal, 'f', 2, 64),
+			strconv.FormatFloat(row.CoolingTotal, 'f', 2, 64),
+			strconv.FormatFloat(row.GrowingTotal, 'f', 2, 64),
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+
+	return nil
 }
 
-func formatInt64PtrAsHPA(value *int64, precision int) string {
+func writeComparisonCSV(w io.Writer, comparison weather.Comparison) error {
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	header := []string{"offset"}
+	for _, series := range comparison.Series {
+		header = append(header,
+			series.Label+"_temp_avg",
+			series.Label+"_humidity_avg",
+			series.Label+"_pressure_avg_hpa",
+		)
+	}
+	for _, series := range comparison.Series[1:] {
+		header = append(header,
+			series.Label+"_temp_delta",
+			series.Label+"_humidity_delta",
+			series.Label+"_pressure_delta_hpa",
+		)
+	}
+
+	if err := csvWriter.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, point := range comparison.Points {
+		record := []string{strconv.FormatInt(point.Offset, 10)}
+		for _, value := range point.Values {
+			if value == nil {
+				record = append(record, "", "", "")
+				continue
+			}
+			record = append(record,
+				formatFloatPtr(value.Temp.Average, 2),
+				formatFloatPtr(value.Humidity.Average, 2),
+				formatFloatPtrAsHPA(value.Pressure.Average, 2),
+			)
+		}
+		for _, delta := range point.Deltas[1:] {
+			if delta == nil {
+				record = append(record, "", "", "")
+				continue
+			}
+			record = append(record,
+				formatFloatPtr(delta.Temperature, 2),
+				formatFloatPtr(delta.Humidity, 2),
+				formatFloatPtrAsHPA(delta.Pressure, 2),
+			)
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+
+	return nil
+}
+
+func formatFloatPtr(value *float64, precision int) string {
 	if value == nil {
 		return ""
 	}
 
-	hpa := float64(*value) / 100.0
-	return strconv.FormatFloat(hpa, 'f', precision, 64)
+	return strconv.FormatFloat(*value, 'f', precision, 64)
 }
 
 func formatFloatPtrAsHPA(value *float64, precision int) string {
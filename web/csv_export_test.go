@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 )
 
 func TestHandleHistoricalExportCSV_Success(t *testing.T) {
@@ -146,3 +147,103 @@ func TestHandleHistoricalExportCSV_FromAfterTo400(t *testing.T) {
 		t.Fatalf("expected 400, got %d", w.Code)
 	}
 }
+
+func TestHandleDegreeDaysCSV_Success(t *testing.T) {
+	repo := &MockMeasurementRepository{
+		data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 6, 0, 0, 0, time.UTC), Temperature: 4.0, Humidity: 80.0, Pressure: 101000},
+			{Timestamp: time.Date(2026, 3, 15, 15, 0, 0, 0, time.UTC), Temperature: 12.0, Humidity: 60.0, Pressure: 101100},
+		},
+	}
+	cfg := testConfig()
+	cfg.DegreeDays = weather.DefaultDegreeDayConfig()
+	server := NewServer(t.Context(), &MockSensorProvider{}, cfg, queueProvider, repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/data/degree-days/export?from=2026-03-15T00:00:00Z&to=2026-03-16T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	server.handleDegreeDaysCSV(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected text/csv content type, got %s", w.Header().Get("Content-Type"))
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV body: %v", err)
+	}
+	if len(rows) < 2 {
+		t.Fatalf("expected header plus at least one row, got %d rows", len(rows))
+	}
+	if rows[0][4] != "hdd" || rows[0][9] != "gdd_total" {
+		t.Fatalf("unexpected CSV header: %+v", rows[0])
+	}
+}
+
+func TestHandleCompareCSV_Success(t *testing.T) {
+	repo := &MockMeasurementRepository{
+		data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Temperature: 24.1, Humidity: 62.0, Pressure: 100900},
+		},
+	}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/data/compare/export?range=2026-03-15T00:00:00Z/2026-03-16T00:00:00Z&range=2026-03-14T00:00:00Z/2026-03-15T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	server.handleCompareCSV(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV body: %v", err)
+	}
+	if len(rows) < 2 {
+		t.Fatalf("expected header plus at least one row, got %d rows", len(rows))
+	}
+
+	expectedHeader := []string{
+		"offset",
+		"range1_temp_avg", "range1_humidity_avg", "range1_pressure_avg_hpa",
+		"range2_temp_avg", "range2_humidity_avg", "range2_pressure_avg_hpa",
+		"range2_temp_delta", "range2_humidity_delta", "range2_pressure_delta_hpa",
+	}
+	if strings.Join(rows[0], ",") != strings.Join(expectedHeader, ",") {
+		t.Fatalf("unexpected CSV header: %+v", rows[0])
+	}
+}
+
+func TestHandleHistoricalExportCSV_Units(t *testing.T) {
+	repo := &MockMeasurementRepository{
+		data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Temperature: 25, Humidity: 62.0, Pressure: 101325},
+		},
+	}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/data/export?type=h&from=2026-03-15T00:00:00Z&to=2026-03-16T00:00:00Z&units=imperial", nil)
+	w := httptest.NewRecorder()
+	server.handleHistoricalWeatherCSV(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV body: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected header plus one row, got %d rows", len(rows))
+	}
+	if rows[0][1] != "temp_min_f" || rows[0][9] != "pressure_max_inhg" {
+		t.Fatalf("unexpected CSV header: %+v", rows[0])
+	}
+	if rows[1][1] != "77.00" || rows[1][9] != "29.921" {
+		t.Fatalf("unexpected CSV values: %+v", rows[1])
+	}
+}
@@ -1,17 +1,22 @@
 package web
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
+	"iter"
 	"net/http"
+	"net/url"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 )
 
@@ -22,24 +27,82 @@ func (s *Server) handleMeasurements(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	units, err := s.requestUnits(r, weather.DefaultUnits())
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if node := s.requestNode(r); node != "" {
+		s.sendNodeMeasurement(w, node, units)
+		return
+	}
+
 	measurement, err := s.sensor.Read()
 	if err != nil {
-		log.Printf("Failed to read sensor: %v", err)
+		logging.Errorf("Failed to read sensor: %v", err)
 		s.sendErrorResponse(w, "Failed to read sensor data", http.StatusInternalServerError)
 		return
 	}
 
 	response := MeasurementResponse{
 		Timestamp:   time.Now(),
-		Temperature: measurement.Temperature,
+		Temperature: units.ConvertTemperature(measurement.Temperature),
 		Humidity:    measurement.Humidity,
-		Pressure:    float64(measurement.Pressure),
+		Pressure:    units.ConvertPressure(float64(measurement.Pressure)),
 		Source:      s.sensor.Name(),
+		Units:       units,
 	}
 
+	w.Header().Set(contentUnitsHeader, units.String())
 	s.sendJSONResponse(w, response, http.StatusOK)
 }
 
+// nodeReadingMaxAge is how old the latest reading of a node may be to be shown as current
+const nodeReadingMaxAge = time.Hour
+
+// sendNodeMeasurement responds with the latest stored reading of a remote node or station
+func (s *Server) sendNodeMeasurement(w http.ResponseWriter, node string, units weather.Units) {
+	if s.repository == nil {
+		s.sendErrorResponse(w, "Repository not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	now := time.Now()
+	records, err := s.repository.GetNodeMeasurementsByTimeRange(node, now.Add(-nodeReadingMaxAge), now)
+	if err != nil {
+		logging.Errorf("Failed to get latest measurement of node %s: %v", node, err)
+		s.sendErrorResponse(w, "Failed to fetch node data", http.StatusInternalServerError)
+		return
+	}
+	if len(records) == 0 {
+		s.sendErrorResponse(w, fmt.Sprintf("No readings from node %s in the last %v", node, nodeReadingMaxAge), http.StatusServiceUnavailable)
+		return
+	}
+
+	latest := records[len(records)-1]
+	response := MeasurementResponse{
+		Timestamp:   latest.Timestamp,
+		Temperature: units.ConvertTemperature(latest.Temperature),
+		Humidity:    latest.Humidity,
+		Pressure:    units.ConvertPressure(float64(latest.Pressure)),
+		Source:      node,
+		Units:       units,
+	}
+
+	w.Header().Set(contentUnitsHeader, units.String())
+	s.sendJSONResponse(w, response, http.StatusOK)
+}
+
+// requestNode returns the node named in the request, or the dashboard node when the
+// request names none. The empty node is the local sensor.
+func (s *Server) requestNode(r *http.Request) string {
+	if query := r.URL.Query(); query.Has(nodeQueryParam) {
+		return query.Get(nodeQueryParam)
+	}
+	return s.config.DashboardNode
+}
+
 // handleHealth handles GET /health - returns server health status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -93,23 +156,32 @@ func (s *Server) handleHistoricalWeatherAPI(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	units, err := s.requestUnits(r, weather.DefaultUnits())
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if s.repository == nil {
 		s.sendErrorResponse(w, "Repository not configured", http.StatusServiceUnavailable)
 		return
 	}
 
 	// Call repository to get historical weather data
-	records, err := s.repository.GetMeasurementsByTimeRange(fromTime, toTime)
+	records, err := s.repository.GetNodeMeasurementsByTimeRange(s.requestNode(r), fromTime, toTime)
 	if err != nil {
-		log.Printf("Failed to get historical weather data: %v", err)
+		logging.Errorf("Failed to get historical weather data: %v", err)
 		s.sendErrorResponse(w, "Failed to fetch historical weather data", http.StatusInternalServerError)
 		return
 	}
 
 	// Return the response as JSON
-	s.sendJSONResponse(w, weather.AggregateMeasurements(records, aggregationKind), http.StatusOK)
+	w.Header().Set(contentUnitsHeader, units.String())
+	s.sendJSONResponse(w, units.ConvertAggregates(weather.AggregateMeasurements(records, aggregationKind)), http.StatusOK)
 }
 
+// handleHistoricalWeatherCSV handles GET /data/export - returns historical weather data as a
+// CSV, Parquet or XLSX download
 func (s *Server) handleHistoricalWeatherCSV(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -122,41 +194,294 @@ func (s *Server) handleHistoricalWeatherCSV(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	units, err := s.requestUnits(r, csvBaseUnits)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := cmp.Or(r.URL.Query().Get("format"), formatCSV)
+	export, ok := exportFormats[format]
+	if !ok {
+		s.sendErrorResponse(w, "invalid format, use csv, parquet or xlsx", http.StatusBadRequest)
+		return
+	}
+
 	if s.repository == nil {
 		s.sendErrorResponse(w, "Repository not configured", http.StatusServiceUnavailable)
 		return
 	}
 
-	records, err := s.repository.GetMeasurementsByTimeRange(fromTime, toTime)
+	// Years of history take longer than the write timeout to aggregate and download
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Errorf("Failed to clear the export response deadline: %v", err)
+	}
+
+	var aggregated []weather.AggregateMeasurement
+	var aggregates iter.Seq2[weather.AggregateMeasurement, error]
+	if format != formatCSV && s.raw != nil {
+		// Large ranges are aggregated one period at a time while the rows are read
+		query := repository.MeasurementQuery{Node: s.requestNode(r), From: fromTime, To: toTime}
+		aggregates = weather.AggregateStream(s.raw.StreamMeasurements(r.Context(), query), aggregationKind)
+	} else {
+		records, err := s.repository.GetNodeMeasurementsByTimeRange(s.requestNode(r), fromTime, toTime)
+		if err != nil {
+			logging.Errorf("Failed to get historical weather data for %s: %v", format, err)
+			s.sendErrorResponse(w, "Failed to fetch historical weather data", http.StatusInternalServerError)
+			return
+		}
+
+		aggregated = weather.AggregateMeasurements(records, aggregationKind)
+		sortAggregatesByDateAsc(aggregated)
+		aggregates = allAggregates(aggregated)
+	}
+
+	w.Header().Set("Content-Type", export.contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"atmosbyte-historico-%s.%s\"", time.Now().UTC().Format("20060102-150405"), export.extension))
+	w.Header().Set(contentUnitsHeader, units.String())
+	w.WriteHeader(http.StatusOK)
+
+	if format == formatCSV {
+		err = writeHistoricalCSV(w, aggregated, units)
+	} else {
+		err = writeHistoricalTable(w, format, aggregates, units)
+	}
+	if err != nil {
+		logging.Errorf("Failed to write historical %s: %v", format, err)
+	}
+}
+
+// handleDegreeDays handles GET /data/degree-days - returns daily degree days and season totals as JSON
+func (s *Server) handleDegreeDays(w http.ResponseWriter, r *http.Request) {
+	degreeDays, ok := s.degreeDays(w, r)
+	if !ok {
+		return
+	}
+
+	s.sendJSONResponse(w, degreeDays, http.StatusOK)
+}
+
+// handleDegreeDaysCSV handles GET /data/degree-days/export - returns degree days as CSV
+func (s *Server) handleDegreeDaysCSV(w http.ResponseWriter, r *http.Request) {
+	degreeDays, ok := s.degreeDays(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"atmosbyte-graus-dia-%s.csv\"", time.Now().UTC().Format("20060102-150405")))
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeDegreeDaysCSV(w, degreeDays); err != nil {
+		logging.Errorf("Failed to write degree days CSV: %v", err)
+	}
+}
+
+// degreeDays validates the request and computes degree days, writing an error response on failure
+func (s *Server) degreeDays(w http.ResponseWriter, r *http.Request) ([]weather.DegreeDay, bool) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	fromTime, toTime, cfg, err := parseDegreeDaysQuery(r, s.config.DegreeDays)
 	if err != nil {
-		log.Printf("Failed to get historical weather data for CSV: %v", err)
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if s.repository == nil {
+		s.sendErrorResponse(w, "Repository not configured", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	records, err := s.repository.GetNodeMeasurementsByTimeRange(s.requestNode(r), fromTime, toTime)
+	if err != nil {
+		logging.Errorf("Failed to get weather data for degree days: %v", err)
 		s.sendErrorResponse(w, "Failed to fetch historical weather data", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	daily := weather.AggregateMeasurements(records, weather.Day)
+	return weather.CalculateDegreeDays(daily, cfg), true
+}
+
+// handleCompare handles GET /data/compare - returns several ranges aligned on a relative time axis as JSON
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	comparison, ok := s.compare(w, r)
+	if !ok {
 		return
 	}
 
-	aggregated := weather.AggregateMeasurements(records, aggregationKind)
-	sortAggregatesByDateAsc(aggregated)
+	s.sendJSONResponse(w, comparison, http.StatusOK)
+}
+
+// handleCompareCSV handles GET /data/compare/export - returns the comparison as CSV
+func (s *Server) handleCompareCSV(w http.ResponseWriter, r *http.Request) {
+	comparison, ok := s.compare(w, r)
+	if !ok {
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"atmosbyte-historico-%s.csv\"", time.Now().UTC().Format("20060102-150405")))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"atmosbyte-comparacao-%s.csv\"", time.Now().UTC().Format("20060102-150405")))
 	w.WriteHeader(http.StatusOK)
 
-	if err := writeHistoricalCSV(w, aggregated); err != nil {
-		log.Printf("Failed to write historical CSV: %v", err)
+	if err := writeComparisonCSV(w, comparison); err != nil {
+		logging.Errorf("Failed to write comparison CSV: %v", err)
 	}
 }
 
+// compare validates the request and builds the comparison, writing an error response on failure
+func (s *Server) compare(w http.ResponseWriter, r *http.Request) (weather.Comparison, bool) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return weather.Comparison{}, false
+	}
+
+	query, err := parseCompareQuery(r)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return weather.Comparison{}, false
+	}
+
+	if s.repository == nil {
+		s.sendErrorResponse(w, "Repository not configured", http.StatusServiceUnavailable)
+		return weather.Comparison{}, false
+	}
+
+	aligned := func(from, to time.Time) (weather.AlignedSeries, error) {
+		records, err := s.repository.GetNodeMeasurementsByTimeRange(s.requestNode(r), from, to)
+		if err != nil {
+			return nil, err
+		}
+		return weather.Align(weather.AggregateMeasurements(records, query.kind), from, query.kind), nil
+	}
+
+	var (
+		info   []weather.ComparisonSeries
+		series []weather.AlignedSeries
+	)
+
+	for _, rng := range query.ranges {
+		data, err := aligned(rng.From, rng.To)
+		if err != nil {
+			logging.Errorf("Failed to get weather data for comparison: %v", err)
+			s.sendErrorResponse(w, "Failed to fetch historical weather data", http.StatusInternalServerError)
+			return weather.Comparison{}, false
+		}
+		info = append(info, rng)
+		series = append(series, data)
+	}
+
+	if query.climatologyYears > 0 {
+		reference := query.ranges[0]
+		var years []weather.AlignedSeries
+		for year := 1; year <= query.climatologyYears; year++ {
+			data, err := aligned(reference.From.AddDate(-year, 0, 0), reference.To.AddDate(-year, 0, 0))
+			if err != nil {
+				logging.Errorf("Failed to get weather data for climatology: %v", err)
+				s.sendErrorResponse(w, "Failed to fetch historical weather data", http.StatusInternalServerError)
+				return weather.Comparison{}, false
+			}
+			if len(data) > 0 {
+				years = append(years, data)
+			}
+		}
+
+		info = append(info, weather.ComparisonSeries{
+			Label: "climatology",
+			From:  reference.From,
+			To:    reference.To,
+			Years: len(years),
+		})
+		series = append(series, weather.AverageSeries(years))
+	}
+
+	return weather.Compare(query.kind, info, series), true
+}
+
+// handleRecords handles GET /records - returns all-time, monthly and daily weather records
+func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.records == nil {
+		s.sendErrorResponse(w, "Records not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.sendJSONResponse(w, s.records.Snapshot(time.Now()), http.StatusOK)
+}
+
+// handleAnomalies handles GET /anomalies - returns detected anomalies in the requested range
+func (s *Server) handleAnomalies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	fromTime, toTime, err := parseTimeRange(r.URL.Query(), now.Add(-7*24*time.Hour), now)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.anomalies == nil {
+		s.sendErrorResponse(w, "Anomaly detection not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	anomalies, err := s.anomalies.GetAnomaliesByTimeRange(fromTime, toTime)
+	if err != nil {
+		logging.Errorf("Failed to get anomalies: %v", err)
+		s.sendErrorResponse(w, "Failed to fetch anomalies", http.StatusInternalServerError)
+		return
+	}
+
+	s.sendJSONResponse(w, anomalies, http.StatusOK)
+}
+
+const (
+	// unitsQueryParam selects output units per request, e.g. ?units=imperial or ?units=F,mmHg
+	unitsQueryParam = "units"
+	// acceptUnitsHeader selects output units per request when the query parameter is absent
+	acceptUnitsHeader = "Accept-Units"
+	// contentUnitsHeader echoes the units used in a response
+	contentUnitsHeader = "Content-Units"
+)
+
+// csvBaseUnits are the CSV export units when nothing else is configured; pressure has always been exported in hPa
+var csvBaseUnits = weather.Units{Temperature: weather.Celsius, Humidity: weather.PercentRH, Pressure: weather.Hectopascal}
+
+// requestUnits resolves the output units: base, then the configured default, then the request selection
+func (s *Server) requestUnits(r *http.Request, base weather.Units) (weather.Units, error) {
+	units, err := weather.ParseUnits(s.config.Units, base)
+	if err != nil {
+		return base, fmt.Errorf("invalid configured units: %w", err)
+	}
+
+	spec := r.URL.Query().Get(unitsQueryParam)
+	if spec == "" {
+		spec = r.Header.Get(acceptUnitsHeader)
+	}
+
+	return weather.ParseUnits(spec, units)
+}
+
 func parseHistoricalQuery(r *http.Request) (time.Time, time.Time, weather.AggregationKind, error) {
 	query := r.URL.Query()
 	aggregationType := query.Get("type")
-	fromStr := query.Get("from")
-	toStr := query.Get("to")
 
 	var (
-		fromTime, toTime time.Time
-		aggregationKind  weather.AggregationKind
-		err              error
+		aggregationKind weather.AggregationKind
+		err             error
 	)
 
 	if aggregationType != "" {
@@ -168,29 +493,49 @@ func parseHistoricalQuery(r *http.Request) (time.Time, time.Time, weather.Aggreg
 		aggregationKind = weather.Hour
 	}
 
+	now := time.Now()
+	fromTime, toTime, err := parseTimeRange(query, now.Add(-24*time.Hour), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, weather.Hour, err
+	}
+
+	return fromTime, toTime, aggregationKind, nil
+}
+
+// parseTimeRange parses the RFC3339 from/to query parameters, falling back to the given
+// defaults, and returns them in the machine's local timezone
+func parseTimeRange(query url.Values, defaultFrom, defaultTo time.Time) (time.Time, time.Time, error) {
+	fromStr := query.Get("from")
+	toStr := query.Get("to")
+
+	var (
+		fromTime, toTime time.Time
+		err              error
+	)
+
 	// Get machine's local timezone for database queries
 	machineLocation := timezone.GetMachineLocation()
 
 	if fromStr != "" {
 		fromTime, err = time.Parse(time.RFC3339, fromStr)
 		if err != nil {
-			return time.Time{}, time.Time{}, weather.Hour, errors.New("invalid from time format, use RFC3339")
+			return time.Time{}, time.Time{}, errors.New("invalid from time format, use RFC3339")
 		}
 	} else {
-		fromTime = time.Now().Add(-24 * time.Hour)
+		fromTime = defaultFrom
 	}
 
 	if toStr != "" {
 		toTime, err = time.Parse(time.RFC3339, toStr)
 		if err != nil {
-			return time.Time{}, time.Time{}, weather.Hour, errors.New("invalid to time format, use RFC3339")
+			return time.Time{}, time.Time{}, errors.New("invalid to time format, use RFC3339")
 		}
 	} else {
-		toTime = time.Now()
+		toTime = defaultTo
 	}
 
 	if fromTime.After(toTime) {
-		return time.Time{}, time.Time{}, weather.Hour, errors.New("from must be before or equal to to")
+		return time.Time{}, time.Time{}, errors.New("from must be before or equal to to")
 	}
 
 	// Convert UTC timestamps from frontend to machine's local timezone
@@ -208,7 +553,120 @@ func parseHistoricalQuery(r *http.Request) (time.Time, time.Time, weather.Aggreg
 	fromTime = fromTime.In(machineLocation)
 	toTime = toTime.In(machineLocation)
 
-	return fromTime, toTime, aggregationKind, nil
+	return fromTime, toTime, nil
+}
+
+// compareQuery holds the parsed parameters of a comparison request
+type compareQuery struct {
+	kind             weather.AggregationKind
+	ranges           []weather.ComparisonSeries
+	climatologyYears int
+}
+
+const (
+	maxCompareRanges      = 8
+	maxClimatologyYears   = 50
+	compareRangeSeparator = "/"
+)
+
+// parseCompareQuery parses repeated range=<from>/<to> parameters (RFC3339), optional
+// labels and an optional climatology=<years> series averaging the first range in past years
+func parseCompareQuery(r *http.Request) (compareQuery, error) {
+	query := r.URL.Query()
+	result := compareQuery{kind: weather.Hour}
+
+	if aggregationType := query.Get("type"); aggregationType != "" {
+		kind, err := weather.ConvertKind(aggregationType)
+		if err != nil {
+			return result, err
+		}
+		result.kind = kind
+	}
+
+	rawRanges := query["range"]
+	if len(rawRanges) == 0 {
+		return result, errors.New("at least one range is required, use range=<from>/<to>")
+	}
+	if len(rawRanges) > maxCompareRanges {
+		return result, fmt.Errorf("at most %d ranges can be compared", maxCompareRanges)
+	}
+
+	labels := query["label"]
+	for i, raw := range rawRanges {
+		fromStr, toStr, found := strings.Cut(raw, compareRangeSeparator)
+		if !found || fromStr == "" || toStr == "" {
+			return result, fmt.Errorf("invalid range %q, use range=<from>/<to>", raw)
+		}
+
+		values := url.Values{"from": {fromStr}, "to": {toStr}}
+		fromTime, toTime, err := parseTimeRange(values, time.Time{}, time.Time{})
+		if err != nil {
+			return result, fmt.Errorf("invalid range %q: %w", raw, err)
+		}
+
+		label := fmt.Sprintf("range%d", i+1)
+		if i < len(labels) && labels[i] != "" {
+			label = labels[i]
+		}
+
+		result.ranges = append(result.ranges, weather.ComparisonSeries{Label: label, From: fromTime, To: toTime})
+	}
+
+	if raw := query.Get("climatology"); raw != "" {
+		years, err := strconv.Atoi(raw)
+		if err != nil || years < 1 || years > maxClimatologyYears {
+			return result, fmt.Errorf("invalid climatology, must be a number of years between 1 and %d", maxClimatologyYears)
+		}
+		result.climatologyYears = years
+	}
+
+	if len(result.ranges)+min(result.climatologyYears, 1) < 2 {
+		return result, errors.New("at least two ranges are required, add another range or climatology=<years>")
+	}
+
+	return result, nil
+}
+
+// parseDegreeDaysQuery parses the season range and optional base temperature overrides.
+// The season defaults to January 1st of the current year until now.
+func parseDegreeDaysQuery(r *http.Request, defaults weather.DegreeDayConfig) (time.Time, time.Time, weather.DegreeDayConfig, error) {
+	query := r.URL.Query()
+	cfg := defaults
+
+	now := time.Now().In(timezone.GetMachineLocation())
+	seasonStart := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+
+	fromTime, toTime, err := parseTimeRange(query, seasonStart, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, cfg, err
+	}
+
+	overrides := []struct {
+		name  string
+		value *float64
+	}{
+		{"heating_base", &cfg.HeatingBase},
+		{"cooling_base", &cfg.CoolingBase},
+		{"growing_base", &cfg.GrowingBase},
+		{"growing_cap", &cfg.GrowingCap},
+	}
+	for _, override := range overrides {
+		raw := query.Get(override.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return time.Time{}, time.Time{}, cfg, fmt.Errorf("invalid %s, must be a number", override.name)
+		}
+		*override.value = value
+	}
+
+	if cfg.GrowingCap <= cfg.GrowingBase {
+		return time.Time{}, time.Time{}, cfg, errors.New("growing_cap must be greater than growing_base")
+	}
+
+	return fromTime, toTime, cfg, nil
 }
 
 func sortAggregatesByDateAsc(data []weather.AggregateMeasurement) {
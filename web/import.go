@@ -0,0 +1,82 @@
+package web
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/importer"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// ImportProvider define a interface para gravar medições importadas em lotes
+type ImportProvider interface {
+	BeginImport(ctx context.Context, dryRun bool) (*repository.MeasurementImport, error)
+}
+
+// WithImport enables the /admin/import endpoint backed by the given provider
+func WithImport(imports ImportProvider) Option {
+	return func(s *Server) {
+		s.imports = imports
+	}
+}
+
+// ImportResponse is the report of an import, with the error that stopped it
+type ImportResponse struct {
+	importer.Report
+	Error string `json:"error,omitempty"`
+}
+
+// handleImport handles POST /admin/import - imports the historical measurements in
+// the request body, configured by the query parameters listed in importer.Parameters
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, err := importer.ParseOptions(r.URL.Query().Get)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.imports == nil {
+		s.sendErrorResponse(w, "Import not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Years of history take longer than the read and write timeouts
+	rc := http.NewResponseController(w)
+	for _, setDeadline := range []func(time.Time) error{rc.SetReadDeadline, rc.SetWriteDeadline} {
+		if err := setDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logging.Errorf("Failed to clear the import deadlines: %v", err)
+		}
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			s.sendErrorResponse(w, "Invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	report, err := importer.Import(r.Context(), body, s.imports, opts)
+	switch {
+	case errors.Is(err, importer.ErrInvalidData):
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		logging.Errorf("Import failed after %d measurements: %v", report.Imported, err)
+		s.sendJSONResponse(w, ImportResponse{Report: report, Error: "Import failed"}, http.StatusInternalServerError)
+	default:
+		s.sendJSONResponse(w, ImportResponse{Report: report}, http.StatusOK)
+	}
+}
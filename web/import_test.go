@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+func TestHandleImport(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "weather.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	csv := "time,temp,rh,hpa\n2024-01-01T00:00:00Z,21.5,50,1013.25\n2024-01-01T00:01:00Z,21.6,50,1013.20\n"
+	mapping := "?timestamp_column=time&temperature_column=temp&humidity_column=rh&pressure_column=hpa&units=hPa"
+
+	tests := []struct {
+		name     string
+		imports  ImportProvider
+		method   string
+		query    string
+		body     string
+		want     int
+		imported int
+	}{
+		{"dry run", repo, http.MethodPost, mapping + "&dry_run=true", csv, http.StatusOK, 2},
+		{"import", repo, http.MethodPost, mapping, csv, http.StatusOK, 2},
+		{"duplicates", repo, http.MethodPost, mapping, csv, http.StatusOK, 0},
+		{"missing column", repo, http.MethodPost, "", csv, http.StatusBadRequest, 0},
+		{"invalid option", repo, http.MethodPost, "?format=xml", csv, http.StatusBadRequest, 0},
+		{"method not allowed", repo, http.MethodGet, "", "", http.StatusMethodNotAllowed, 0},
+		{"not configured", nil, http.MethodPost, mapping, csv, http.StatusServiceUnavailable, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.imports != nil {
+				opts = append(opts, WithImport(tt.imports))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			req := httptest.NewRequest(tt.method, "/api/v1/admin/import"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var response ImportResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Read != 2 || response.Imported != tt.imported || response.Duplicates != 2-tt.imported {
+				t.Errorf("unexpected response %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/timezone"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+// MeasurementQueue accepts measurements to be persisted
+type MeasurementQueue interface {
+	Enqueue(measurement bme280.Measurement) error
+}
+
+// IngestConfig holds the limits applied to measurements posted by remote nodes
+type IngestConfig struct {
+	MaxBatch     int           // Maximum readings per request
+	MaxBodyBytes int64         // Maximum request body size
+	MaxAge       time.Duration // Readings older than this are rejected
+	MaxClockSkew time.Duration // Readings further in the future than this are rejected
+	DedupWindow  time.Duration // How long node and timestamp pairs are remembered to drop duplicates
+}
+
+// withDefaults fills unset limits with sensible defaults
+func (c IngestConfig) withDefaults() IngestConfig {
+	if c.MaxBatch <= 0 {
+		c.MaxBatch = 500
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = 7 * 24 * time.Hour
+	}
+	if c.MaxClockSkew <= 0 {
+		c.MaxClockSkew = 5 * time.Minute
+	}
+	if c.DedupWindow <= 0 {
+		c.DedupWindow = 24 * time.Hour
+	}
+	return c
+}
+
+const (
+	nodeHeader     = "X-Node-ID"
+	nodeQueryParam = "node"
+	// ingestRetryAfter is suggested to nodes when the queue is full
+	ingestRetryAfter = "5"
+)
+
+var nodeIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ValidNodeID reports whether id can name a remote node or station
+func ValidNodeID(id string) bool {
+	return nodeIDPattern.MatchString(id)
+}
+
+// readingLimits are the accepted ranges of temperature (°C), humidity (%) and pressure (Pa)
+type readingLimits struct {
+	temperature, humidity, pressure [2]float64
+}
+
+var (
+	// nodeLimits is the measuring range of the BME280 used by remote nodes
+	nodeLimits = readingLimits{temperature: [2]float64{-40, 85}, humidity: [2]float64{0, 100}, pressure: [2]float64{30000, 110000}}
+	// stationLimits covers commercial outdoor stations, whose sensors report winter
+	// temperatures below the BME280 range
+	stationLimits = readingLimits{temperature: [2]float64{-90, 70}, humidity: [2]float64{0, 100}, pressure: [2]float64{30000, 110000}}
+)
+
+// IngestReading is a measurement posted by a remote node. Pointers distinguish
+// missing fields from zero values.
+type IngestReading struct {
+	Node        string     `json:"node"`
+	Timestamp   *time.Time `json:"timestamp"`
+	Temperature *float64   `json:"temperature"` // °C
+	Humidity    *float64   `json:"humidity"`    // %RH
+	Pressure    *float64   `json:"pressure"`    // Pa
+}
+
+// IngestRejection describes why a reading of a batch was rejected
+type IngestRejection struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// IngestResponse summarizes the outcome of an ingestion request
+type IngestResponse struct {
+	Accepted   int               `json:"accepted"`
+	Duplicates int               `json:"duplicates"`
+	Rejected   []IngestRejection `json:"rejected"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// WithIngest enables POST /api/v1/ingest, enqueueing remote measurements on q
+func WithIngest(q MeasurementQueue) Option {
+	return func(s *Server) {
+		s.ingest = q
+		s.ingestSeen = newDedupCache()
+	}
+}
+
+// dedupCache remembers node and timestamp pairs already enqueued
+type dedupCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newDedupCache() *dedupCache {
+	return &dedupCache{seen: make(map[string]time.Time)}
+}
+
+func dedupKey(m bme280.Measurement) string {
+	return fmt.Sprintf("%s|%d", m.Node, m.Timestamp.UnixNano())
+}
+
+// contains reports whether the measurement was enqueued within window
+func (c *dedupCache) contains(m bme280.Measurement, now time.Time, window time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	at, ok := c.seen[dedupKey(m)]
+	return ok && now.Sub(at) < window
+}
+
+// add records the measurement as enqueued at now
+func (c *dedupCache) add(m bme280.Measurement, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[dedupKey(m)] = now
+}
+
+// prune drops entries older than window
+func (c *dedupCache) prune(now time.Time, window time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, at := range c.seen {
+		if now.Sub(at) >= window {
+			delete(c.seen, key)
+		}
+	}
+}
+
+// handleIngest handles POST /api/v1/ingest - enqueues measurements posted by remote nodes.
+// The body is a JSON object, a JSON array or NDJSON (application/x-ndjson). Readings without
+// a node use the X-Node-ID header or the node query parameter.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.ingest == nil {
+		s.sendErrorResponse(w, "Ingestion not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg := s.config.Ingest.withDefaults()
+	body := http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+
+	readings, err := decodeIngestBody(body, r.Header.Get("Content-Type"), cfg.MaxBatch)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		s.sendErrorResponse(w, err.Error(), status)
+		return
+	}
+
+	defaultNode := r.Header.Get(nodeHeader)
+	if defaultNode == "" {
+		defaultNode = r.URL.Query().Get(nodeQueryParam)
+	}
+
+	now := time.Now()
+	s.ingestSeen.prune(now, cfg.DedupWindow)
+
+	response := IngestResponse{Rejected: []IngestRejection{}}
+	batch := make(map[string]bool, len(readings))
+
+	for i, reading := range readings {
+		measurement, err := reading.toMeasurement(defaultNode, now, cfg, nodeLimits)
+		if err != nil {
+			response.Rejected = append(response.Rejected, IngestRejection{Index: i, Error: err.Error()})
+			continue
+		}
+
+		key := dedupKey(measurement)
+		if batch[key] || s.ingestSeen.contains(measurement, now, cfg.DedupWindow) {
+			response.Duplicates++
+			continue
+		}
+
+		if err := s.ingest.Enqueue(measurement); err != nil {
+			if errors.Is(err, queue.ErrQueueFull) {
+				response.Error = fmt.Sprintf("queue is full, %d of %d readings accepted", response.Accepted, len(readings))
+				w.Header().Set("Retry-After", ingestRetryAfter)
+				s.sendJSONResponse(w, response, http.StatusTooManyRequests)
+				return
+			}
+			logging.Errorf("Failed to enqueue ingested measurement: %v", err)
+			response.Error = "Failed to enqueue measurements"
+			s.sendJSONResponse(w, response, http.StatusServiceUnavailable)
+			return
+		}
+
+		batch[key] = true
+		s.ingestSeen.add(measurement, now)
+		response.Accepted++
+	}
+
+	status := http.StatusAccepted
+	if response.Accepted == 0 && response.Duplicates == 0 {
+		status = http.StatusBadRequest
+		response.Error = "No valid readings"
+	}
+
+	s.sendJSONResponse(w, response, status)
+}
+
+// decodeIngestBody parses one reading, an array of readings or NDJSON
+func decodeIngestBody(body io.Reader, contentType string, maxBatch int) ([]IngestReading, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var readings []IngestReading
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl":
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 4096), 64*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var reading IngestReading
+			if err := json.Unmarshal(text, &reading); err != nil {
+				return nil, fmt.Errorf("invalid JSON on line %d: %w", line, err)
+			}
+			readings = append(readings, reading)
+			if len(readings) > maxBatch {
+				return nil, fmt.Errorf("batch exceeds %d readings", maxBatch)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read body: %w", err)
+		}
+
+	default:
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body: %w", err)
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) > 0 && data[0] == '[' {
+			if err := json.Unmarshal(data, &readings); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+		} else {
+			var reading IngestReading
+			if err := json.Unmarshal(data, &reading); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			readings = []IngestReading{reading}
+		}
+		if len(readings) > maxBatch {
+			return nil, fmt.Errorf("batch exceeds %d readings", maxBatch)
+		}
+	}
+
+	if len(readings) == 0 {
+		return nil, errors.New("no readings in request body")
+	}
+
+	return readings, nil
+}
+
+// toMeasurement validates the reading against limits and converts it to a measurement in
+// the machine timezone
+func (r IngestReading) toMeasurement(defaultNode string, now time.Time, cfg IngestConfig, limits readingLimits) (bme280.Measurement, error) {
+	node := r.Node
+	if node == "" {
+		node = defaultNode
+	}
+	if !nodeIDPattern.MatchString(node) {
+		return bme280.Measurement{}, errors.New("node must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+
+	if r.Timestamp == nil {
+		return bme280.Measurement{}, errors.New("timestamp is required")
+	}
+	if r.Timestamp.After(now.Add(cfg.MaxClockSkew)) {
+		return bme280.Measurement{}, errors.New("timestamp is in the future")
+	}
+	if r.Timestamp.Before(now.Add(-cfg.MaxAge)) {
+		return bme280.Measurement{}, fmt.Errorf("timestamp is older than %v", cfg.MaxAge)
+	}
+
+	fields := []struct {
+		name   string
+		value  *float64
+		limits [2]float64
+	}{
+		{"temperature", r.Temperature, limits.temperature},
+		{"humidity", r.Humidity, limits.humidity},
+		{"pressure", r.Pressure, limits.pressure},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			return bme280.Measurement{}, fmt.Errorf("%s is required", field.name)
+		}
+		if math.IsNaN(*field.value) || *field.value < field.limits[0] || *field.value > field.limits[1] {
+			return bme280.Measurement{}, fmt.Errorf("%s must be between %g and %g", field.name, field.limits[0], field.limits[1])
+		}
+	}
+
+	return bme280.Measurement{
+		// Timestamps are stored in the machine timezone so range queries compare them correctly
+		Timestamp:   r.Timestamp.In(timezone.GetMachineLocation()),
+		Temperature: *r.Temperature,
+		Humidity:    *r.Humidity,
+		Pressure:    int64(math.Round(*r.Pressure)),
+		Node:        node,
+	}, nil
+}
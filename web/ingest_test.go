@@ -0,0 +1,197 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+)
+
+type MockMeasurementQueue struct {
+	mu       sync.Mutex
+	enqueued []bme280.Measurement
+	capacity int // Enqueue returns queue.ErrQueueFull once capacity readings were enqueued, 0 is unlimited
+}
+
+func (m *MockMeasurementQueue) Enqueue(measurement bme280.Measurement) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.capacity > 0 && len(m.enqueued) >= m.capacity {
+		return queue.ErrQueueFull
+	}
+	m.enqueued = append(m.enqueued, measurement)
+	return nil
+}
+
+func postIngest(t *testing.T, server *Server, contentType, body string) (*httptest.ResponseRecorder, IngestResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest?node=hall", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	server.handleIngest(w, req)
+
+	var response IngestResponse
+	if w.Header().Get("Content-Type") == "application/json" {
+		json.NewDecoder(w.Body).Decode(&response)
+	}
+	return w, response
+}
+
+func reading(node string, ts time.Time, temperature float64) string {
+	return fmt.Sprintf(`{"node":%q,"timestamp":%q,"temperature":%v,"humidity":55,"pressure":101325}`,
+		node, ts.UTC().Format(time.RFC3339), temperature)
+}
+
+func TestHandleIngest_JSONAndNDJSON(t *testing.T) {
+	q := &MockMeasurementQueue{}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithIngest(q))
+	ts := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	w, response := postIngest(t, server, "application/json", reading("kitchen", ts, 21.5))
+	if w.Code != http.StatusAccepted || response.Accepted != 1 {
+		t.Fatalf("expected single reading to be accepted, got %d %+v", w.Code, response)
+	}
+
+	batch := "[" + reading("kitchen", ts.Add(time.Minute), 21.6) + "," + reading("", ts, 19) + "]"
+	w, response = postIngest(t, server, "application/json", batch)
+	if w.Code != http.StatusAccepted || response.Accepted != 2 {
+		t.Fatalf("expected batch to be accepted, got %d %+v", w.Code, response)
+	}
+
+	ndjson := reading("bedroom", ts, 18) + "\n\n" + reading("bedroom", ts.Add(time.Minute), 18.1) + "\n"
+	w, response = postIngest(t, server, "application/x-ndjson", ndjson)
+	if w.Code != http.StatusAccepted || response.Accepted != 2 {
+		t.Fatalf("expected NDJSON to be accepted, got %d %+v", w.Code, response)
+	}
+
+	if len(q.enqueued) != 5 {
+		t.Fatalf("expected 5 enqueued measurements, got %d", len(q.enqueued))
+	}
+	if q.enqueued[2].Node != "hall" {
+		t.Errorf("expected reading without node to use the query parameter, got %q", q.enqueued[2].Node)
+	}
+	if q.enqueued[0].Pressure != 101325 || !q.enqueued[0].Timestamp.Equal(ts) {
+		t.Errorf("unexpected enqueued measurement: %+v", q.enqueued[0])
+	}
+}
+
+func TestHandleIngest_DeduplicatesByNodeAndTimestamp(t *testing.T) {
+	q := &MockMeasurementQueue{}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithIngest(q))
+	ts := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	batch := "[" + reading("kitchen", ts, 21.5) + "," + reading("kitchen", ts, 21.5) + "," + reading("bedroom", ts, 18) + "]"
+	_, response := postIngest(t, server, "application/json", batch)
+	if response.Accepted != 2 || response.Duplicates != 1 {
+		t.Fatalf("expected 2 accepted and 1 duplicate, got %+v", response)
+	}
+
+	// Retries of an already accepted reading are reported as duplicates
+	w, response := postIngest(t, server, "application/json", reading("kitchen", ts, 21.5))
+	if w.Code != http.StatusAccepted || response.Accepted != 0 || response.Duplicates != 1 {
+		t.Fatalf("expected retry to be a duplicate, got %d %+v", w.Code, response)
+	}
+	if len(q.enqueued) != 2 {
+		t.Fatalf("expected 2 enqueued measurements, got %d", len(q.enqueued))
+	}
+}
+
+func TestHandleIngest_Validation(t *testing.T) {
+	q := &MockMeasurementQueue{}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithIngest(q))
+	now := time.Now()
+
+	invalid := []string{
+		reading("bad node!", now, 20),
+		reading("kitchen", now.Add(time.Hour), 20),
+		reading("kitchen", now.Add(-30*24*time.Hour), 20),
+		reading("kitchen", now, 120),
+		`{"node":"kitchen","timestamp":"` + now.UTC().Format(time.RFC3339) + `","temperature":20,"humidity":50}`,
+	}
+	batch := "[" + strings.Join(append(invalid, reading("kitchen", now.Add(-time.Minute), 20)), ",") + "]"
+
+	w, response := postIngest(t, server, "application/json", batch)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected partial batch to be accepted, got %d", w.Code)
+	}
+	if response.Accepted != 1 || len(response.Rejected) != len(invalid) {
+		t.Fatalf("expected 1 accepted and %d rejected, got %+v", len(invalid), response)
+	}
+	for i, rejection := range response.Rejected {
+		if rejection.Index != i || rejection.Error == "" {
+			t.Errorf("unexpected rejection %+v", rejection)
+		}
+	}
+
+	w, response = postIngest(t, server, "application/json", "["+invalid[3]+"]")
+	if w.Code != http.StatusBadRequest || response.Error == "" {
+		t.Fatalf("expected 400 when no reading is valid, got %d %+v", w.Code, response)
+	}
+
+	w, _ = postIngest(t, server, "application/json", "{not json")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed JSON, got %d", w.Code)
+	}
+}
+
+func TestHandleIngest_QueueFull429(t *testing.T) {
+	q := &MockMeasurementQueue{capacity: 1}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithIngest(q))
+	ts := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	batch := "[" + reading("kitchen", ts, 20) + "," + reading("kitchen", ts.Add(time.Minute), 20) + "]"
+	w, response := postIngest(t, server, "application/json", batch)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+	if response.Accepted != 1 {
+		t.Errorf("expected the first reading to be accepted, got %+v", response)
+	}
+
+	// The reading rejected by the full queue is not remembered as a duplicate
+	q.capacity = 0
+	_, response = postIngest(t, server, "application/json", batch)
+	if response.Accepted != 1 || response.Duplicates != 1 {
+		t.Fatalf("expected the retried reading to be accepted, got %+v", response)
+	}
+}
+
+func TestHandleIngest_RequiresIngestScope(t *testing.T) {
+	auth, err := NewAuthenticator(AuthConfig{APIKeys: []APIKey{
+		{Name: "esp32", Key: "node-key", Scope: ScopeIngest},
+		{Name: "dashboard", Key: "read-key", Scope: ScopeRead},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{},
+		WithAuth(auth), WithIngest(&MockMeasurementQueue{}))
+
+	send := func(path, key string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(reading("kitchen", time.Now(), 20)))
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, path := range []string{"/api/v1/ingest", "/api/ingest"} {
+		if code := send(path, "read-key"); code != http.StatusForbidden {
+			t.Errorf("%s: expected read key to be forbidden, got %d", path, code)
+		}
+		if code := send(path, "node-key"); code != http.StatusAccepted {
+			t.Errorf("%s: expected ingest key to be accepted, got %d", path, code)
+		}
+	}
+}
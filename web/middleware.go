@@ -1,9 +1,10 @@
 package web
 
 import (
-	"log"
 	"net/http"
 	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 )
 
 // loggingMiddleware logs all HTTP requests
@@ -16,7 +17,7 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(lrw, r)
 
 		duration := time.Since(start)
-		log.Printf("%s %s %d %v %s",
+		logging.Debugf("%s %s %d %v %s",
 			r.Method,
 			r.URL.Path,
 			lrw.statusCode,
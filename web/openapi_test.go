@@ -0,0 +1,157 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func loadOpenAPISpec(t *testing.T) openAPIDocument {
+	t.Helper()
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("embedded openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("expected an OpenAPI 3 document, got version %q", doc.OpenAPI)
+	}
+	return doc
+}
+
+func TestOpenAPISpec_MatchesRoutes(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	registered := make(map[string]bool)
+	for _, rt := range server.apiRoutes() {
+		registered[rt.path] = true
+
+		operations, ok := doc.Paths[rt.path]
+		if !ok {
+			t.Errorf("route %s %s is not documented in openapi.json", rt.method, rt.path)
+			continue
+		}
+		if _, ok := operations[strings.ToLower(rt.method)]; !ok {
+			t.Errorf("openapi.json documents %s but not its %s operation", rt.path, rt.method)
+		}
+	}
+
+	for path := range doc.Paths {
+		if !registered[path] {
+			t.Errorf("openapi.json documents %s which is not registered", path)
+		}
+	}
+}
+
+func TestOpenAPISpec_ReferencesResolve(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("embedded openapi.json is not valid JSON: %v", err)
+	}
+
+	var walk func(node any)
+	walk = func(node any) {
+		switch v := node.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				var target any = doc
+				for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					m, ok := target.(map[string]any)
+					if !ok {
+						target = nil
+						break
+					}
+					target = m[part]
+				}
+				if target == nil {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func TestAPIRoutes_ServedUnderPrefix(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	for _, rt := range server.apiRoutes() {
+		req := httptest.NewRequest(rt.method, apiPrefix+rt.path, nil)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+
+		if w.Code == http.StatusNotFound || strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("%s %s was not served by its handler (status %d)", rt.method, apiPrefix+rt.path, w.Code)
+		}
+		if w.Header().Get("Deprecation") != "" {
+			t.Errorf("%s should not be marked deprecated", apiPrefix+rt.path)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, apiPrefix+"/unknown", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("expected JSON 404 for unknown API path, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestLegacyRoutes_AreDeprecatedAliases(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	for _, path := range legacyRoutes {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+
+		if w.Header().Get("Deprecation") != "true" {
+			t.Errorf("%s: expected Deprecation header", path)
+		}
+		if want := "<" + apiPrefix + path + `>; rel="successor-version"`; w.Header().Get("Link") != want {
+			t.Errorf("%s: expected Link %s, got %s", path, want, w.Header().Get("Link"))
+		}
+	}
+	// Newer endpoints have no unversioned alias
+	for _, path := range []string{"/data/raw", "/alerts", "/webhooks/deliveries"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+
+		if w.Header().Get("Deprecation") != "" || strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("%s: expected no API alias, got %d %s", path, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	req := httptest.NewRequest(http.MethodGet, apiPrefix+"/openapi.json", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected application/json, got %s", w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != string(openAPISpec) {
+		t.Error("expected the embedded document to be served verbatim")
+	}
+}
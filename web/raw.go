@@ -0,0 +1,272 @@
+package web
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// RawDataProvider define a interface para percorrer as medições gravadas sem agregá-las
+type RawDataProvider interface {
+	StreamMeasurements(ctx context.Context, q repository.MeasurementQuery) iter.Seq2[repository.MeasurementRecord, error]
+	NextMeasurementCursor(ctx context.Context, q repository.MeasurementQuery) (repository.MeasurementCursor, bool, error)
+}
+
+// WithRawData enables the /data/raw endpoint backed by the given provider
+func WithRawData(raw RawDataProvider) Option {
+	return func(s *Server) {
+		s.raw = raw
+	}
+}
+
+// RawMeasurement is a stored measurement in the requested units
+type RawMeasurement struct {
+	ID          int64     `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Temperature float64   `json:"temperature"`
+	Humidity    float64   `json:"humidity"`
+	Pressure    float64   `json:"pressure"`
+	Node        string    `json:"node,omitempty"`
+}
+
+// rawRangeUnit is the unit of the Range, Next-Range and Accept-Ranges headers of /data/raw
+const rawRangeUnit = "measurements"
+
+// rawPage is the part of the measurements selected by the limit and cursor of a request
+type rawPage struct {
+	limit  int
+	cursor string
+	ranged bool // Requested with a Range header, answered with 206 Partial Content
+}
+
+// parseRawPage reads the page from the limit and cursor query parameters or, when
+// they are absent, from a "Range: measurements [<cursor>][; max=<limit>]" header.
+// Ranges in other units, such as bytes, are ignored.
+func parseRawPage(r *http.Request) (rawPage, error) {
+	var page rawPage
+	if spec, ok := strings.CutPrefix(r.Header.Get("Range"), rawRangeUnit); ok && (spec == "" || spec[0] == ' ' || spec[0] == ';') {
+		cursor, params, _ := strings.Cut(spec, ";")
+		page.ranged = true
+		page.cursor = strings.TrimSpace(cursor)
+		if maxStr, ok := strings.CutPrefix(strings.TrimSpace(params), "max="); ok {
+			limit, err := strconv.Atoi(maxStr)
+			if err != nil || limit <= 0 {
+				return page, errors.New("invalid Range max, must be a positive integer")
+			}
+			page.limit = limit
+		} else if params != "" {
+			return page, errors.New("invalid Range header, use measurements [<cursor>][; max=<limit>]")
+		}
+	}
+
+	query := r.URL.Query()
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, errors.New("invalid limit, must be a positive integer")
+		}
+		page.limit = limit
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		page.cursor = cursor
+	}
+	return page, nil
+}
+
+// acceptsGzip reports whether the client accepts a gzip encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		q, _ := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// handleRawData handles GET /data/raw - streams the stored measurements of a node as
+// JSON, NDJSON, CSV, Parquet or XLSX, optionally paginated by a cursor
+func (s *Server) handleRawData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	base := weather.DefaultUnits()
+	switch format {
+	case "", "json", "ndjson":
+	case formatCSV, formatParquet, formatXLSX:
+		base = csvBaseUnits
+	default:
+		s.sendErrorResponse(w, "invalid format, use json, ndjson, csv, parquet or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	fromTime, toTime, err := parseTimeRange(query, now.Add(-24*time.Hour), now)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	units, err := s.requestUnits(r, base)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := parseRawPage(r)
+	if err != nil {
+		s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	measurementQuery := repository.MeasurementQuery{Node: s.requestNode(r), From: fromTime, To: toTime, Limit: page.limit}
+	if page.cursor != "" {
+		cursor, err := repository.ParseMeasurementCursor(page.cursor)
+		if err != nil {
+			s.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		measurementQuery.After = &cursor
+	}
+
+	if s.raw == nil {
+		s.sendErrorResponse(w, "Raw data not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	next, more, err := s.raw.NextMeasurementCursor(r.Context(), measurementQuery)
+	if err != nil {
+		logging.Errorf("Failed to paginate raw measurements: %v", err)
+		s.sendErrorResponse(w, "Failed to fetch measurements", http.StatusInternalServerError)
+		return
+	}
+
+	// A year of measurements takes longer than the write timeout to download
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Errorf("Failed to clear the raw data response deadline: %v", err)
+	}
+
+	header := w.Header()
+	export, download := exportFormats[format]
+	switch {
+	case download:
+		header.Set("Content-Type", export.contentType)
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"atmosbyte-medicoes-%s.%s\"", time.Now().UTC().Format("20060102-150405"), export.extension))
+	case format == "ndjson":
+		header.Set("Content-Type", "application/x-ndjson")
+	default:
+		header.Set("Content-Type", "application/json")
+	}
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set(contentUnitsHeader, units.String())
+	header.Set("Accept-Ranges", rawRangeUnit)
+
+	status := http.StatusOK
+	if more {
+		nextQuery := r.URL.Query()
+		nextQuery.Set("cursor", next.String())
+		nextQuery.Set("limit", strconv.Itoa(page.limit))
+		header.Set("Next-Range", fmt.Sprintf("%s %s; max=%d", rawRangeUnit, next, page.limit))
+		header.Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, nextQuery.Encode()))
+		if page.ranged {
+			status = http.StatusPartialContent
+		}
+	}
+
+	var out io.Writer = w
+	// Parquet and XLSX are compressed already
+	if acceptsGzip(r) && format != formatParquet && format != formatXLSX {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		// The fastest level keeps the Pi's CPU free for the sensor while still shrinking CSV several times
+		zw, _ := gzip.NewWriterLevel(w, gzip.BestSpeed)
+		defer zw.Close()
+		out = zw
+	}
+	w.WriteHeader(status)
+
+	records := s.raw.StreamMeasurements(r.Context(), measurementQuery)
+	switch format {
+	case formatCSV:
+		err = writeRawCSV(out, records, units)
+	case formatParquet, formatXLSX:
+		err = writeRawTable(out, format, records, units)
+	default:
+		err = writeRawJSON(out, records, units, format != "ndjson")
+	}
+	if err != nil {
+		logging.Errorf("Failed to stream raw measurements: %v", err)
+	}
+}
+
+// rawMeasurement converts a stored measurement to the given units
+func rawMeasurement(record repository.MeasurementRecord, units weather.Units) RawMeasurement {
+	return RawMeasurement{
+		ID:          record.ID,
+		Timestamp:   record.Timestamp,
+		Temperature: units.ConvertTemperature(record.Temperature),
+		Humidity:    record.Humidity,
+		Pressure:    units.ConvertPressure(float64(record.Pressure)),
+		Node:        record.Node,
+	}
+}
+
+// writeRawJSON writes the measurements as they are read, one JSON object per line,
+// enclosed in an array when array is set. An error leaves the output truncated.
+func writeRawJSON(w io.Writer, records iter.Seq2[repository.MeasurementRecord, error], units weather.Units, array bool) error {
+	buf := bufio.NewWriter(w)
+	separator := ""
+	if array {
+		buf.WriteString("[")
+		separator = "\n"
+	}
+
+	for record, err := range records {
+		if err != nil {
+			buf.Flush()
+			return err
+		}
+
+		data, err := json.Marshal(rawMeasurement(record, units))
+		if err != nil {
+			return fmt.Errorf("failed to encode measurement: %w", err)
+		}
+		buf.WriteString(separator)
+		buf.Write(data)
+		if array {
+			separator = ",\n"
+		} else {
+			buf.WriteString("\n")
+		}
+	}
+
+	if array {
+		buf.WriteString("\n]\n")
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write measurements: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+func newRawRepository(t *testing.T, measurements int) *repository.SQLiteRepository {
+	t.Helper()
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "weather.db"))
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	start := time.Now().Add(-time.Hour)
+	for i := range measurements {
+		m := bme280.Measurement{Timestamp: start.Add(time.Duration(i) * time.Minute), Temperature: 20 + float64(i), Humidity: 50, Pressure: 101325}
+		if err := repo.SaveMeasurement(m); err != nil {
+			t.Fatalf("failed to save measurement: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestHandleRawData(t *testing.T) {
+	repo := newRawRepository(t, 5)
+
+	tests := []struct {
+		name  string
+		raw   RawDataProvider
+		query string
+		want  int
+	}{
+		{"invalid format", repo, "?format=xml", http.StatusBadRequest},
+		{"invalid limit", repo, "?limit=0", http.StatusBadRequest},
+		{"invalid cursor", repo, "?cursor=abc", http.StatusBadRequest},
+		{"invalid time range", repo, "?from=yesterday", http.StatusBadRequest},
+		{"not configured", nil, "", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.raw != nil {
+				opts = append(opts, WithRawData(tt.raw))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			w := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/data/raw"+tt.query, nil))
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithRawData(repo))
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := get("/api/v1/data/raw", nil)
+		var measurements []RawMeasurement
+		if err := json.NewDecoder(w.Body).Decode(&measurements); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("expected a JSON array, got %d (%v)", w.Code, err)
+		}
+		if len(measurements) != 5 || measurements[0].Temperature != 20 || measurements[4].Pressure != 101325 {
+			t.Errorf("unexpected measurements %+v", measurements)
+		}
+	})
+
+	t.Run("ndjson gzip", func(t *testing.T) {
+		w := get("/api/v1/data/raw?format=ndjson&units=hPa", http.Header{"Accept-Encoding": {"br, gzip"}})
+		if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Fatalf("unexpected headers %v", w.Header())
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(zr)
+		lines := 0
+		for scanner.Scan() {
+			var m RawMeasurement
+			if err := json.Unmarshal(scanner.Bytes(), &m); err != nil || m.Pressure != 1013.25 {
+				t.Errorf("unexpected line %q (%v)", scanner.Text(), err)
+			}
+			lines++
+		}
+		if lines != 5 {
+			t.Errorf("expected 5 lines, got %d", lines)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		w := get("/api/v1/data/raw?format=csv&units=F", nil)
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil || len(rows) != 6 {
+			t.Fatalf("expected a header and 5 rows, got %d (%v)", len(rows), err)
+		}
+		if strings.Join(rows[0], ",") != "id,timestamp,node,temperature_f,humidity,pressure_hpa" || rows[1][3] != "68.00" || rows[1][5] != "1013.25" {
+			t.Errorf("unexpected CSV %v", rows[:2])
+		}
+	})
+
+	t.Run("cursor pages", func(t *testing.T) {
+		target := "/api/v1/data/raw?format=ndjson&limit=2"
+		var temperatures []float64
+		for pages := 1; ; pages++ {
+			w := get(target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			decoder := json.NewDecoder(w.Body)
+			for {
+				var m RawMeasurement
+				if err := decoder.Decode(&m); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				temperatures = append(temperatures, m.Temperature)
+			}
+
+			link := w.Header().Get("Link")
+			if link == "" {
+				if pages != 3 {
+					t.Errorf("expected 3 pages, got %d", pages)
+				}
+				break
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+		if len(temperatures) != 5 || temperatures[4] != 24 {
+			t.Errorf("unexpected measurements %v", temperatures)
+		}
+	})
+
+	t.Run("range pages", func(t *testing.T) {
+		w := get("/api/v1/data/raw", http.Header{"Range": {"measurements; max=3"}})
+		next := w.Header().Get("Next-Range")
+		if w.Code != http.StatusPartialContent || !strings.HasSuffix(next, "; max=3") {
+			t.Fatalf("expected 206 with Next-Range, got %d %q", w.Code, next)
+		}
+
+		w = get("/api/v1/data/raw", http.Header{"Range": {next}})
+		var measurements []RawMeasurement
+		if err := json.NewDecoder(w.Body).Decode(&measurements); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("expected the last page, got %d (%v)", w.Code, err)
+		}
+		if len(measurements) != 2 || measurements[0].Temperature != 23 || w.Header().Get("Next-Range") != "" {
+			t.Errorf("unexpected last page %+v", measurements)
+		}
+
+		// Byte ranges are not pages
+		if w := get("/api/v1/data/raw", http.Header{"Range": {"bytes=0-10"}}); w.Code != http.StatusOK {
+			t.Errorf("expected a byte range to be ignored, got %d", w.Code)
+		}
+	})
+}
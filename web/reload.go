@@ -0,0 +1,53 @@
+package web
+
+import (
+	"net/http"
+	"time"
+)
+
+// ReloadStatus describes the last configuration reload
+type ReloadStatus struct {
+	File            string         `json:"file,omitempty"` // Empty when running on defaults
+	LoadedAt        time.Time      `json:"loaded_at"`      // Last successful load, at startup or by a reload
+	LastAttempt     *time.Time     `json:"last_attempt,omitempty"`
+	Trigger         string         `json:"trigger,omitempty"` // SIGHUP or file change
+	Success         bool           `json:"success"`
+	Error           string         `json:"error,omitempty"`
+	Applied         []ConfigChange `json:"applied"`          // Changes applied live
+	RestartRequired []ConfigChange `json:"restart_required"` // Changes that take effect on restart
+}
+
+// ConfigChange is a configuration field changed by a reload
+type ConfigChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// ReloadStatusProvider define a interface para consultar o estado do último recarregamento da configuração
+type ReloadStatusProvider interface {
+	ReloadStatus() ReloadStatus
+}
+
+// WithReloadStatus enables the /admin/config/reload endpoint backed by the given provider
+func WithReloadStatus(reload ReloadStatusProvider) Option {
+	return func(s *Server) {
+		s.reload = reload
+	}
+}
+
+// handleReloadStatus handles GET /admin/config/reload - returns the outcome of the
+// last configuration reload
+func (s *Server) handleReloadStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.reload == nil {
+		s.sendErrorResponse(w, "Configuration reload not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	s.sendJSONResponse(w, s.reload.ReloadStatus(), http.StatusOK)
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockReloadStatusProvider struct {
+	status ReloadStatus
+}
+
+func (m *MockReloadStatusProvider) ReloadStatus() ReloadStatus {
+	return m.status
+}
+
+func TestHandleReloadStatus(t *testing.T) {
+	attempt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	reload := &MockReloadStatusProvider{status: ReloadStatus{
+		File:            "atmosbyte.yaml",
+		LoadedAt:        attempt,
+		LastAttempt:     &attempt,
+		Trigger:         "SIGHUP",
+		Success:         true,
+		Applied:         []ConfigChange{{Path: "sensor.read_interval", Old: "1m0s", New: "30s"}},
+		RestartRequired: []ConfigChange{{Path: "web.port", Old: "8080", New: "9090"}},
+	}}
+
+	tests := []struct {
+		name   string
+		reload ReloadStatusProvider
+		method string
+		want   int
+	}{
+		{"status", reload, http.MethodGet, http.StatusOK},
+		{"method not allowed", reload, http.MethodPost, http.StatusMethodNotAllowed},
+		{"not configured", nil, http.MethodGet, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.reload != nil {
+				opts = append(opts, WithReloadStatus(tt.reload))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			req := httptest.NewRequest(tt.method, "/api/v1/admin/config/reload", nil)
+			w := httptest.NewRecorder()
+			server.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var response ReloadStatus
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Trigger != "SIGHUP" || !response.Success || len(response.Applied) != 1 || response.RestartRequired[0].Path != "web.port" {
+				t.Errorf("unexpected response %+v", response)
+			}
+		})
+	}
+}
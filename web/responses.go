@@ -2,9 +2,10 @@ package web
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 )
 
 // sendJSONResponse sends a JSON response with proper headers
@@ -14,7 +15,7 @@ func (s *Server) sendJSONResponse(w http.ResponseWriter, data any, statusCode in
 	w.WriteHeader(statusCode)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Failed to encode JSON response: %v", err)
+		logging.Errorf("Failed to encode JSON response: %v", err)
 	}
 }
 
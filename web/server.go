@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
 )
 
@@ -16,18 +17,43 @@ type Server struct {
 	config     *Config
 	sensor     bme280.Reader
 	server     *http.Server
+	redirect   *http.Server // HTTP to HTTPS redirect, only set when TLS is enabled
 	queue      QueueStatsProvider
 	ctx        context.Context
 	repository MeasurementRepository
+	records    RecordsProvider
+	anomalies  AnomalyProvider
+	auth       *Authenticator
+	ingest     MeasurementQueue
+	ingestSeen *dedupCache
+	stations   *stationReceiver
+	webhooks   WebhookHistoryProvider
+	alerts     AlertProvider
+	reload     ReloadStatusProvider
+	backups    BackupProvider
+	imports    ImportProvider
+	raw        RawDataProvider
+}
+
+// Option configures optional Server dependencies
+type Option func(*Server)
+
+// WithRecords enables the /records endpoint backed by the given provider
+func WithRecords(records RecordsProvider) Option {
+	return func(s *Server) {
+		s.records = records
+	}
 }
 
 type MeasurementRepository interface {
 	GetMeasurementsByTimeRange(startTime, endTime time.Time) ([]repository.MeasurementRecord, error)
+	GetNodeMeasurementsByTimeRange(node string, startTime, endTime time.Time) ([]repository.MeasurementRecord, error)
 }
 
 // NewServer creates a new HTTP server instance with the given sensor provider
 // Optionally accepts a queue parameter for queue monitoring functionality
-func NewServer(ctx context.Context, sensor bme280.Reader, config *Config, queue QueueStatsProvider, repo MeasurementRepository) *Server {
+// and options for features that depend on additional components
+func NewServer(ctx context.Context, sensor bme280.Reader, config *Config, queue QueueStatsProvider, repo MeasurementRepository, opts ...Option) *Server {
 	if config == nil {
 		panic("web.Config cannot be nil - use config.Load().WebConfig() instead")
 	}
@@ -40,6 +66,10 @@ func NewServer(ctx context.Context, sensor bme280.Reader, config *Config, queue
 		repository: repo,
 	}
 
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	mux := http.NewServeMux()
 	s.setupRoutes(mux)
 
@@ -54,39 +84,155 @@ func NewServer(ctx context.Context, sensor bme280.Reader, config *Config, queue
 	return s
 }
 
+// WithAnomalies enables the /anomalies endpoint backed by the given provider
+func WithAnomalies(anomalies AnomalyProvider) Option {
+	return func(s *Server) {
+		s.anomalies = anomalies
+	}
+}
+
+// WithAuth requires credentials on every route according to its scope
+func WithAuth(auth *Authenticator) Option {
+	return func(s *Server) {
+		s.auth = auth
+	}
+}
+
+// apiPrefix is the namespace of the versioned JSON API
+const apiPrefix = "/api/v1"
+
+// route is an endpoint of the versioned API, documented in openapi.json
+type route struct {
+	method  string
+	path    string // Path relative to apiPrefix
+	scope   Scope
+	handler http.HandlerFunc
+}
+
+// apiRoutes lists every endpoint served under apiPrefix
+func (s *Server) apiRoutes() []route {
+	return []route{
+		{http.MethodGet, "/measurements", ScopeRead, s.handleMeasurements},
+		{http.MethodGet, "/health", ScopeRead, s.handleHealth},
+		{http.MethodGet, "/queue", ScopeRead, s.handleQueue},
+		{http.MethodGet, "/data", ScopeRead, s.handleHistoricalWeatherAPI},
+		{http.MethodGet, "/data/export", ScopeRead, s.handleHistoricalWeatherCSV},
+		{http.MethodGet, "/data/raw", ScopeRead, s.handleRawData},
+		{http.MethodGet, "/data/degree-days", ScopeRead, s.handleDegreeDays},
+		{http.MethodGet, "/data/degree-days/export", ScopeRead, s.handleDegreeDaysCSV},
+		{http.MethodGet, "/data/compare", ScopeRead, s.handleCompare},
+		{http.MethodGet, "/data/compare/export", ScopeRead, s.handleCompareCSV},
+		{http.MethodGet, "/records", ScopeRead, s.handleRecords},
+		{http.MethodGet, "/anomalies", ScopeRead, s.handleAnomalies},
+		{http.MethodPost, "/ingest", ScopeIngest, s.handleIngest},
+		{http.MethodGet, "/webhooks/deliveries", ScopeAdmin, s.handleWebhookDeliveries},
+		{http.MethodGet, "/alerts", ScopeRead, s.handleAlerts},
+		{http.MethodGet, "/admin/config/reload", ScopeAdmin, s.handleReloadStatus},
+		{http.MethodPost, "/admin/backup", ScopeAdmin, s.handleBackup},
+		{http.MethodPost, "/admin/import", ScopeAdmin, s.handleImport},
+		{http.MethodGet, "/openapi.json", ScopeRead, s.handleOpenAPI},
+	}
+}
+
+// ingestAlias is the unversioned ingestion path remote nodes may post to
+const ingestAlias = "/api/ingest"
+
+// legacyRoutes are the API paths served before apiPrefix existed, kept as deprecated aliases.
+// Endpoints added since then, such as /alerts, /admin/backup and /data/raw, are only served under apiPrefix.
+var legacyRoutes = []string{
+	"/measurements",
+	"/health",
+	"/queue",
+	"/data",
+	"/data/export",
+	"/data/degree-days",
+	"/data/degree-days/export",
+	"/data/compare",
+	"/data/compare/export",
+	"/records",
+	"/anomalies",
+}
+
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes(mux *http.ServeMux) {
-	mux.Handle("/assets/", http.FileServer(http.FS(frontendAssetFS())))
-	mux.HandleFunc("/measurements", s.handleMeasurements)
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/queue", s.handleQueue)
-	mux.HandleFunc("/data", s.handleHistoricalWeatherAPI)
-	mux.HandleFunc("/data/export", s.handleHistoricalWeatherCSV)
-	mux.HandleFunc("/", s.handleSPA)
+	handlers := make(map[string]route)
+	for _, rt := range s.apiRoutes() {
+		handlers[rt.path] = rt
+		mux.HandleFunc(apiPrefix+rt.path, s.requireScope(rt.scope, rt.handler))
+	}
+
+	for _, path := range legacyRoutes {
+		rt := handlers[path]
+		mux.HandleFunc(path, s.requireScope(rt.scope, deprecatedAlias(apiPrefix+path, rt.handler)))
+	}
+
+	mux.HandleFunc(ingestAlias, s.requireScope(ScopeIngest, s.handleIngest))
+	s.setupStationRoutes(mux)
+
+	// Unknown API paths must not fall through to the SPA
+	mux.HandleFunc(apiPrefix+"/", s.requireScope(ScopeRead, func(w http.ResponseWriter, r *http.Request) {
+		s.sendErrorResponse(w, "Not found", http.StatusNotFound)
+	}))
+
+	mux.HandleFunc("/assets/", s.requireScope(ScopeRead, http.FileServer(http.FS(frontendAssetFS())).ServeHTTP))
+	mux.HandleFunc("/", s.requireScope(ScopeRead, s.handleSPA))
+}
+
+// deprecatedAlias serves next on a legacy path, pointing clients to its successor
+func deprecatedAlias(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		next(w, r)
+	}
 }
 
 // Start starts the HTTP server and handles graceful shutdown via context
 func (s *Server) Start() error {
-	log.Printf("Starting HTTP server on %s", s.server.Addr)
-
 	// Canal para capturar erros do servidor
-	serverErr := make(chan error, 1)
+	serverErr := make(chan error, 2)
 
-	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			serverErr <- fmt.Errorf("failed to start server: %w", err)
-		} else {
-			serverErr <- nil
+	if s.config.TLS.Enabled {
+		certs, err := s.setupTLS()
+		if err != nil {
+			return err
 		}
-	}()
+
+		watchCtx, stopWatch := context.WithCancel(s.ctx)
+		defer stopWatch()
+		go certs.Watch(watchCtx, s.config.TLS.ReloadInterval)
+
+		log.Printf("Starting HTTPS server on %s", s.server.Addr)
+		go func() {
+			serverErr <- serveResult(s.server.ListenAndServeTLS("", ""))
+		}()
+
+		if s.redirect != nil {
+			log.Printf("Starting HTTP to HTTPS redirect on %s", s.redirect.Addr)
+			go func() {
+				serverErr <- serveResult(s.redirect.ListenAndServe())
+			}()
+		}
+	} else {
+		log.Printf("Starting HTTP server on %s", s.server.Addr)
+		go func() {
+			serverErr <- serveResult(s.server.ListenAndServe())
+		}()
+	}
 
 	select {
 	case <-s.ctx.Done():
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
 		defer cancel()
 
+		if s.redirect != nil {
+			if err := s.redirect.Shutdown(shutdownCtx); err != nil {
+				logging.Errorf("Error during redirect server shutdown: %v", err)
+			}
+		}
+
 		if err := s.server.Shutdown(shutdownCtx); err != nil {
-			log.Printf("Error during server shutdown: %v", err)
+			logging.Errorf("Error during server shutdown: %v", err)
 			return fmt.Errorf("failed to shutdown server: %w", err)
 		}
 
@@ -94,6 +240,18 @@ func (s *Server) Start() error {
 		return s.ctx.Err()
 
 	case err := <-serverErr:
+		if s.redirect != nil {
+			s.redirect.Close()
+		}
+		s.server.Close()
 		return err
 	}
 }
+
+// serveResult converts the error returned by a ListenAndServe call
+func serveResult(err error) error {
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package web
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+const (
+	// wundergroundPath is the upload path hardcoded in stations speaking the Weather Underground protocol
+	wundergroundPath = "/weatherstation/updateweatherstation.php"
+	// defaultEcowittPath is the path suggested by the Ecowitt app for custom servers
+	defaultEcowittPath = "/data/report/"
+	// stationDateLayout is the dateutc format of both protocols
+	stationDateLayout = "2006-01-02 15:04:05"
+)
+
+// Station is a weather station uploading with the Weather Underground or Ecowitt protocol
+type Station struct {
+	ID       string // Weather Underground station ID or Ecowitt PASSKEY
+	Password string // Weather Underground station key, unused by Ecowitt
+	Node     string // Node the readings are stored under
+}
+
+// StationConfig configures the Weather Underground and Ecowitt upload receivers
+type StationConfig struct {
+	Stations    []Station
+	EcowittPath string // Path of the Ecowitt custom server upload, defaults to /data/report/
+}
+
+// Validate checks station IDs, nodes and the Ecowitt path
+func (c StationConfig) Validate() error {
+	if len(c.Stations) == 0 {
+		return errors.New("at least one station is required")
+	}
+
+	seen := make(map[string]bool, len(c.Stations))
+	for _, station := range c.Stations {
+		if station.ID == "" {
+			return errors.New("station with empty id")
+		}
+		if seen[station.ID] {
+			return fmt.Errorf("duplicate station %q", station.ID)
+		}
+		seen[station.ID] = true
+		if !nodeIDPattern.MatchString(station.Node) {
+			return fmt.Errorf("station %q: node must be 1-64 letters, digits, '.', '_' or '-'", station.ID)
+		}
+	}
+
+	path := c.ecowittPath()
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, apiPrefix+"/") || path == wundergroundPath {
+		return fmt.Errorf("invalid ecowitt path %q", path)
+	}
+	for _, legacy := range legacyRoutes {
+		if path == legacy {
+			return fmt.Errorf("ecowitt path %q conflicts with an API route", path)
+		}
+	}
+
+	return nil
+}
+
+func (c StationConfig) ecowittPath() string {
+	if c.EcowittPath == "" {
+		return defaultEcowittPath
+	}
+	return c.EcowittPath
+}
+
+// stationReceiver holds the state of the upload receivers
+type stationReceiver struct {
+	config   StationConfig
+	stations map[string]Station
+	queue    MeasurementQueue
+	seen     *dedupCache
+}
+
+// WithStations enables the Weather Underground and Ecowitt upload receivers, enqueueing
+// readings on q. The configuration must have been checked with StationConfig.Validate.
+func WithStations(q MeasurementQueue, config StationConfig) Option {
+	return func(s *Server) {
+		stations := make(map[string]Station, len(config.Stations))
+		for _, station := range config.Stations {
+			stations[station.ID] = station
+		}
+		s.stations = &stationReceiver{
+			config:   config,
+			stations: stations,
+			queue:    q,
+			seen:     newDedupCache(),
+		}
+	}
+}
+
+// setupStationRoutes registers the upload receivers. Stations authenticate with their own
+// credentials, so these routes are not behind requireScope.
+func (s *Server) setupStationRoutes(mux *http.ServeMux) {
+	if s.stations == nil {
+		return
+	}
+	mux.HandleFunc(wundergroundPath, s.handleWundergroundUpload)
+	mux.HandleFunc(s.stations.config.ecowittPath(), s.handleEcowittUpload)
+}
+
+// handleWundergroundUpload handles GET /weatherstation/updateweatherstation.php - the
+// Weather Underground upload protocol (ID, PASSWORD, dateutc, tempf, humidity, baromin)
+func (s *Server) handleWundergroundUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	station, ok := s.stations.stations[r.Form.Get("ID")]
+	if !ok || subtle.ConstantTimeCompare([]byte(r.Form.Get("PASSWORD")), []byte(station.Password)) != 1 {
+		logging.Warnf("Rejected Weather Underground upload from %s for station %q", clientAddress(r), r.Form.Get("ID"))
+		http.Error(w, "INVALID PASSWORDID|Password or key and/or id are incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	pressure := firstField(r.Form, "absbaromin", "baromin")
+	s.receiveStationUpload(w, station, r.Form, "tempf", "humidity", pressure, "success")
+}
+
+// handleEcowittUpload handles POST on the Ecowitt custom server path (PASSKEY, dateutc,
+// tempf, humidity, baromabsin)
+func (s *Server) handleEcowittUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	station, ok := s.stations.stations[r.PostForm.Get("PASSKEY")]
+	if !ok {
+		logging.Warnf("Rejected Ecowitt upload from %s: unknown PASSKEY", clientAddress(r))
+		http.Error(w, "Unknown station", http.StatusUnauthorized)
+		return
+	}
+
+	pressure := firstField(r.PostForm, "baromabsin", "baromrelin")
+	s.receiveStationUpload(w, station, r.PostForm, "tempf", "humidity", pressure, "OK")
+}
+
+// firstField returns the first of names present in form, or the last name when none is
+func firstField(form url.Values, names ...string) string {
+	for _, name := range names {
+		if form.Has(name) {
+			return name
+		}
+	}
+	return names[len(names)-1]
+}
+
+// receiveStationUpload converts an upload in imperial units to a measurement and enqueues it
+func (s *Server) receiveStationUpload(w http.ResponseWriter, station Station, form url.Values, tempField, humidityField, pressureField, success string) {
+	now := time.Now()
+	cfg := s.config.Ingest.withDefaults()
+
+	reading, err := parseStationForm(form, now, tempField, humidityField, pressureField)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	measurement, err := reading.toMeasurement(station.Node, now, cfg, stationLimits)
+	if err != nil {
+		logging.Warnf("Rejected upload from station %q: %v", station.ID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	receiver := s.stations
+	receiver.seen.prune(now, cfg.DedupWindow)
+	if receiver.seen.contains(measurement, now, cfg.DedupWindow) {
+		fmt.Fprintln(w, success)
+		return
+	}
+
+	if err := receiver.queue.Enqueue(measurement); err != nil {
+		if errors.Is(err, queue.ErrQueueFull) {
+			w.Header().Set("Retry-After", ingestRetryAfter)
+			http.Error(w, "Queue is full", http.StatusTooManyRequests)
+			return
+		}
+		logging.Errorf("Failed to enqueue upload from station %q: %v", station.ID, err)
+		http.Error(w, "Failed to enqueue measurement", http.StatusServiceUnavailable)
+		return
+	}
+
+	receiver.seen.add(measurement, now)
+	fmt.Fprintln(w, success)
+}
+
+// parseStationForm reads the timestamp, temperature (°F), humidity (%) and pressure (inHg) fields
+func parseStationForm(form url.Values, now time.Time, tempField, humidityField, pressureField string) (IngestReading, error) {
+	var reading IngestReading
+
+	timestamp := now
+	if date := form.Get("dateutc"); date != "" && date != "now" {
+		parsed, err := time.Parse(stationDateLayout, date)
+		if err != nil {
+			return reading, fmt.Errorf("invalid dateutc: %s", date)
+		}
+		timestamp = parsed
+	}
+	reading.Timestamp = &timestamp
+
+	number := func(field string) (*float64, error) {
+		value := form.Get(field)
+		if value == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", field, value)
+		}
+		return &f, nil
+	}
+
+	temperature, err := number(tempField)
+	if err != nil {
+		return reading, err
+	}
+	if temperature != nil {
+		celsius := weather.ToCelsius(*temperature, weather.Fahrenheit)
+		reading.Temperature = &celsius
+	}
+
+	if reading.Humidity, err = number(humidityField); err != nil {
+		return reading, err
+	}
+
+	pressure, err := number(pressureField)
+	if err != nil {
+		return reading, err
+	}
+	if pressure != nil {
+		pascal := weather.ToPascal(*pressure, weather.InchMercury)
+		reading.Pressure = &pascal
+	}
+
+	return reading, nil
+}
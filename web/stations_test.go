@@ -0,0 +1,261 @@
+package web
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+func stationServer(t *testing.T, q *MockMeasurementQueue, opts ...Option) *Server {
+	t.Helper()
+
+	config := StationConfig{Stations: []Station{
+		{ID: "KTEST1", Password: "secret", Node: "outdoor"},
+		{ID: "ABCDEF0123456789", Node: "garden"},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	opts = append(opts, WithStations(q, config))
+	return NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+}
+
+func TestWundergroundUpload(t *testing.T) {
+	q := &MockMeasurementQueue{}
+	auth, err := NewAuthenticator(AuthConfig{APIKeys: []APIKey{{Name: "admin", Key: "k", Scope: ScopeAdmin}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := stationServer(t, q, WithAuth(auth))
+	date := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+
+	upload := func(password string) *httptest.ResponseRecorder {
+		query := url.Values{
+			"ID":       {"KTEST1"},
+			"PASSWORD": {password},
+			"dateutc":  {date.Format(stationDateLayout)},
+			"tempf":    {"77"},
+			"humidity": {"40"},
+			"baromin":  {"29.92"},
+			"action":   {"updateraw"},
+		}
+		req := httptest.NewRequest(http.MethodGet, wundergroundPath+"?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := upload("wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", w.Code)
+	}
+
+	// Stations authenticate with their own credentials even when auth is enabled
+	w := upload("secret")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "success" {
+		t.Fatalf("expected success, got %d %q", w.Code, w.Body.String())
+	}
+
+	if len(q.enqueued) != 1 {
+		t.Fatalf("expected 1 enqueued measurement, got %d", len(q.enqueued))
+	}
+	m := q.enqueued[0]
+	if m.Node != "outdoor" || m.Temperature != 25 || m.Humidity != 40 || m.Pressure != 101321 || !m.Timestamp.Equal(date) {
+		t.Errorf("unexpected measurement %+v", m)
+	}
+
+	// Repeated uploads of the same reading are acknowledged but not enqueued again
+	if w := upload("secret"); w.Code != http.StatusOK || len(q.enqueued) != 1 {
+		t.Errorf("expected duplicate to be acknowledged without enqueueing, got %d and %d measurements", w.Code, len(q.enqueued))
+	}
+}
+
+func TestStationUploadOutdoorRange(t *testing.T) {
+	q := &MockMeasurementQueue{}
+	server := stationServer(t, q)
+	date := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+
+	upload := func(tempf string) int {
+		query := url.Values{
+			"ID":       {"KTEST1"},
+			"PASSWORD": {"secret"},
+			"dateutc":  {date.Format(stationDateLayout)},
+			"tempf":    {tempf},
+			"humidity": {"70"},
+			"baromin":  {"30.10"},
+		}
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, wundergroundPath+"?"+query.Encode(), nil))
+		return w.Code
+	}
+
+	// -49 °F is -45 °C, below the BME280 range accepted from remote nodes
+	if code := upload("-49"); code != http.StatusOK || len(q.enqueued) != 1 || q.enqueued[0].Temperature != -45 {
+		t.Errorf("expected a winter reading to be accepted, got %d and %+v", code, q.enqueued)
+	}
+	if code := upload("-200"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an impossible temperature, got %d", code)
+	}
+}
+
+func TestEcowittUpload(t *testing.T) {
+	q := &MockMeasurementQueue{}
+	server := stationServer(t, q)
+
+	upload := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, defaultEcowittPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	form := url.Values{
+		"PASSKEY":     {"ABCDEF0123456789"},
+		"stationtype": {"GW1000B_V1.7.3"},
+		"dateutc":     {time.Now().UTC().Format(stationDateLayout)},
+		"tempinf":     {"72.1"},
+		"tempf":       {"50"},
+		"humidity":    {"80"},
+		"baromrelin":  {"30.10"},
+		"baromabsin":  {"29.50"},
+	}
+	if w := upload(form); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if len(q.enqueued) != 1 {
+		t.Fatalf("expected 1 enqueued measurement, got %d", len(q.enqueued))
+	}
+	m := q.enqueued[0]
+	if m.Node != "garden" || math.Abs(m.Temperature-10) > 1e-9 || m.Pressure != 99898 {
+		t.Errorf("expected outdoor temperature and absolute pressure, got %+v", m)
+	}
+
+	form.Set("PASSKEY", "unknown")
+	if w := upload(form); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown PASSKEY, got %d", w.Code)
+	}
+
+	form.Set("PASSKEY", "ABCDEF0123456789")
+	form.Del("humidity")
+	if w := upload(form); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a missing field, got %d", w.Code)
+	}
+}
+
+func TestStationUploadsBypassHTTPSRedirect(t *testing.T) {
+	server := stationServer(t, &MockMeasurementQueue{})
+	mux := server.redirectMux()
+
+	req := httptest.NewRequest(http.MethodGet, wundergroundPath+"?ID=KTEST1&PASSWORD=secret&dateutc=now&tempf=60&humidity=50&baromin=30", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected upload to be served over HTTP, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/measurements", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expected other paths to redirect, got %d", w.Code)
+	}
+}
+
+func TestStationConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config StationConfig
+	}{
+		{"no stations", StationConfig{}},
+		{"empty id", StationConfig{Stations: []Station{{Node: "a"}}}},
+		{"invalid node", StationConfig{Stations: []Station{{ID: "K1", Node: "bad node"}}}},
+		{"duplicate", StationConfig{Stations: []Station{{ID: "K1", Node: "a"}, {ID: "K1", Node: "b"}}}},
+		{"api path", StationConfig{Stations: []Station{{ID: "K1", Node: "a"}}, EcowittPath: "/api/v1/ecowitt"}},
+		{"legacy route", StationConfig{Stations: []Station{{ID: "K1", Node: "a"}}, EcowittPath: "/data"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDashboardNode(t *testing.T) {
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "weather.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	now := time.Now()
+	for _, m := range []bme280.Measurement{
+		{Timestamp: now.Add(-2 * time.Minute), Temperature: -41.5, Humidity: 80, Pressure: 102000, Node: "outdoor"},
+		{Timestamp: now.Add(-time.Minute), Temperature: -42, Humidity: 81, Pressure: 102100, Node: "outdoor"},
+		{Timestamp: now.Add(-2 * time.Hour), Temperature: 5, Humidity: 60, Pressure: 101000, Node: "garden"},
+	} {
+		if err := repo.SaveMeasurement(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := testConfig()
+	config.DashboardNode = "outdoor"
+	sensor := &MockSensorProvider{measurement: bme280.Measurement{Temperature: 21, Humidity: 50, Pressure: 101325}}
+	server := NewServer(t.Context(), sensor, config, queueProvider, repo)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		code        int
+		source      string
+		temperature float64
+	}{
+		{"dashboard node", "/api/v1/measurements", http.StatusOK, "outdoor", -42},
+		{"local sensor", "/api/v1/measurements?node=", http.StatusOK, "BME280", 21},
+		{"stale node", "/api/v1/measurements?node=garden", http.StatusServiceUnavailable, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.target)
+			if w.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var response MeasurementResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Source != tt.source || response.Temperature != tt.temperature {
+				t.Errorf("unexpected measurement %+v", response)
+			}
+		})
+	}
+
+	t.Run("history", func(t *testing.T) {
+		w := get("/api/v1/data?type=m")
+		var aggregates []weather.AggregateMeasurement
+		if err := json.NewDecoder(w.Body).Decode(&aggregates); err != nil || len(aggregates) != 2 {
+			t.Fatalf("expected the 2 outdoor minutes, got %d (%v)", len(aggregates), err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/parquet"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/xlsx"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
+)
+
+// Download formats of the export endpoints
+const (
+	formatCSV     = "csv"
+	formatParquet = "parquet"
+	formatXLSX    = "xlsx"
+)
+
+// exportFormats maps the download formats to their content type and file extension
+var exportFormats = map[string]struct{ contentType, extension string }{
+	formatCSV:     {"text/csv; charset=utf-8", "csv"},
+	formatParquet: {"application/vnd.apache.parquet", "parquet"},
+	formatXLSX:    {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+}
+
+// tableWriter writes typed rows to a Parquet file or an Excel workbook
+type tableWriter interface {
+	Write(row []any) error
+	Close() error
+}
+
+// tableColumn describes a column of a typed export; the unit goes to the Parquet
+// column metadata and to the spreadsheet header
+type tableColumn struct {
+	name     string
+	unit     string
+	kind     parquet.Type
+	optional bool
+}
+
+// newTableWriter starts a Parquet file or a workbook with the given columns
+func newTableWriter(w io.Writer, format, sheet string, columns []tableColumn, units weather.Units) (tableWriter, error) {
+	if format == formatParquet {
+		parquetColumns := make([]parquet.Column, len(columns))
+		for i, column := range columns {
+			parquetColumns[i] = parquet.Column{Name: column.name, Type: column.kind, Optional: column.optional}
+			if column.unit != "" {
+				parquetColumns[i].Metadata = map[string]string{"unit": column.unit}
+			}
+		}
+		return parquet.NewWriter(w, parquetColumns, map[string]string{"atmosbyte.units": units.String()}), nil
+	}
+
+	xlsxColumns := make([]xlsx.Column, len(columns))
+	for i, column := range columns {
+		xlsxColumns[i] = xlsx.Column{Name: column.name}
+		switch {
+		case column.kind == parquet.Timestamp:
+			xlsxColumns[i] = xlsx.Column{Name: column.name + " (UTC)", Width: 20}
+		case column.unit == string(weather.Celsius) || column.unit == string(weather.Fahrenheit):
+			xlsxColumns[i].Name += " (°" + column.unit + ")"
+		case column.unit != "":
+			xlsxColumns[i].Name += " (" + column.unit + ")"
+		}
+	}
+	return xlsx.NewWriter(w, sheet, xlsxColumns)
+}
+
+// writeHistoricalTable writes aggregates as Parquet or XLSX as they are computed, with
+// the columns of writeHistoricalCSV and the units kept apart from the column names.
+// An error leaves the file unfinished.
+func writeHistoricalTable(w io.Writer, format string, rows iter.Seq2[weather.AggregateMeasurement, error], units weather.Units) error {
+	temperature, humidity, pressure := string(units.Temperature), units.Humidity, string(units.Pressure)
+	columns := []tableColumn{{name: "timestamp", kind: parquet.Timestamp}}
+	for _, quantity := range []struct{ name, unit string }{{"temp", temperature}, {"humidity", humidity}, {"pressure", pressure}} {
+		for _, stat := range []string{"min", "avg", "max"} {
+			columns = append(columns, tableColumn{name: quantity.name + "_" + stat, unit: quantity.unit, kind: parquet.Double, optional: true})
+		}
+	}
+
+	table, err := newTableWriter(w, format, "historico", columns, units)
+	if err != nil {
+		return fmt.Errorf("failed to start %s export: %w", format, err)
+	}
+
+	pressurePrecision := units.PressurePrecision()
+	for aggregate, err := range rows {
+		if err != nil {
+			return err
+		}
+
+		row := units.ConvertAggregates([]weather.AggregateMeasurement{aggregate})[0]
+		err := table.Write([]any{
+			time.Unix(row.Date, 0).UTC(),
+			roundPtr(row.Temp.Min, 2),
+			roundPtr(row.Temp.Average, 2),
+			roundPtr(row.Temp.Max, 2),
+			roundPtr(row.Humidity.Min, 2),
+			roundPtr(row.Humidity.Average, 2),
+			roundPtr(row.Humidity.Max, 2),
+			roundPtr(row.Pressure.Min, pressurePrecision),
+			roundPtr(row.Pressure.Average, pressurePrecision),
+			roundPtr(row.Pressure.Max, pressurePrecision),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s row: %w", format, err)
+		}
+	}
+
+	if err := table.Close(); err != nil {
+		return fmt.Errorf("failed to finish %s export: %w", format, err)
+	}
+	return nil
+}
+
+// writeRawTable writes the measurements as Parquet or XLSX as they are read. An error
+// leaves the file unfinished, so it cannot be mistaken for a complete one.
+func writeRawTable(w io.Writer, format string, records iter.Seq2[repository.MeasurementRecord, error], units weather.Units) error {
+	columns := []tableColumn{
+		{name: "id", kind: parquet.Int64},
+		{name: "timestamp", kind: parquet.Timestamp},
+		{name: "node", kind: parquet.String},
+		{name: "temperature", unit: string(units.Temperature), kind: parquet.Double},
+		{name: "humidity", unit: units.Humidity, kind: parquet.Double},
+		{name: "pressure", unit: string(units.Pressure), kind: parquet.Double},
+	}
+
+	table, err := newTableWriter(w, format, "medicoes", columns, units)
+	if err != nil {
+		return fmt.Errorf("failed to start %s export: %w", format, err)
+	}
+
+	pressurePrecision := units.PressurePrecision()
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+
+		m := rawMeasurement(record, units)
+		err := table.Write([]any{
+			m.ID,
+			m.Timestamp.UTC(),
+			m.Node,
+			round(m.Temperature, 2),
+			round(m.Humidity, 2),
+			round(m.Pressure, pressurePrecision),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s row: %w", format, err)
+		}
+	}
+
+	if err := table.Close(); err != nil {
+		return fmt.Errorf("failed to finish %s export: %w", format, err)
+	}
+	return nil
+}
+
+// allAggregates yields aggregates already in memory to writeHistoricalTable
+func allAggregates(rows []weather.AggregateMeasurement) iter.Seq2[weather.AggregateMeasurement, error] {
+	return func(yield func(weather.AggregateMeasurement, error) bool) {
+		for _, row := range rows {
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+// round rounds value to the given decimal places, as formatted in the CSV exports
+func round(value float64, precision int) float64 {
+	scale := math.Pow(10, float64(precision))
+	return math.Round(value*scale) / scale
+}
+
+// roundPtr rounds an optional value, keeping nil
+func roundPtr(value *float64, precision int) *float64 {
+	if value == nil {
+		return nil
+	}
+	rounded := round(*value, precision)
+	return &rounded
+}
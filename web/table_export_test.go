@@ -0,0 +1,165 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// readSheet returns the XML of the first sheet of a workbook
+func readSheet(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("invalid workbook: %v", err)
+	}
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(sheet)
+}
+
+func TestHandleHistoricalExport_Formats(t *testing.T) {
+	repo := &MockMeasurementRepository{
+		data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Temperature: 24.1, Humidity: 62.0, Pressure: 100900},
+			{Timestamp: time.Date(2026, 3, 15, 11, 0, 0, 0, time.UTC), Temperature: 26.8, Humidity: 65.0, Pressure: 101200},
+		},
+	}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, repo)
+	get := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/data/export?type=h&from=2026-03-15T00:00:00Z&to=2026-03-16T00:00:00Z&format="+format, nil)
+		w := httptest.NewRecorder()
+		server.handleHistoricalWeatherCSV(w, req)
+		return w
+	}
+
+	t.Run("parquet", func(t *testing.T) {
+		w := get("parquet")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.apache.parquet" {
+			t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+		}
+		if !strings.HasSuffix(w.Header().Get("Content-Disposition"), ".parquet\"") {
+			t.Errorf("unexpected content disposition %s", w.Header().Get("Content-Disposition"))
+		}
+		body := w.Body.Bytes()
+		if !bytes.HasPrefix(body, []byte("PAR1")) || !bytes.HasSuffix(body, []byte("PAR1")) || !bytes.Contains(body, []byte("pressure_max")) {
+			t.Errorf("expected a Parquet file, got %q", body)
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		w := get("xlsx")
+		if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), "spreadsheetml") {
+			t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+		}
+		sheet := readSheet(t, w.Body.Bytes())
+		for _, want := range []string{"timestamp (UTC)", "temp_min (°C)", "pressure_max (hPa)", "<v>24.1</v>"} {
+			if !strings.Contains(sheet, want) {
+				t.Errorf("sheet lacks %s: %s", want, sheet)
+			}
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		if w := get("ods"); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleHistoricalExport_StreamsFromRawData(t *testing.T) {
+	raw := newRawRepository(t, 5)
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithRawData(raw))
+
+	from := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/data/export?type=m&format=xlsx&units=hPa&from="+from, nil)
+	w := httptest.NewRecorder()
+	server.handleHistoricalWeatherCSV(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	sheet := readSheet(t, w.Body.Bytes())
+	if rows := strings.Count(sheet, "<row "); rows != 6 {
+		t.Errorf("expected a header and 5 minutes, got %d rows", rows)
+	}
+	if !strings.Contains(sheet, "<v>1013.25</v>") {
+		t.Errorf("expected pressures in hPa: %s", sheet)
+	}
+}
+
+// slowRepository takes longer than the write timeout to read the measurements
+type slowRepository struct {
+	MockMeasurementRepository
+	delay time.Duration
+}
+
+func (r *slowRepository) GetNodeMeasurementsByTimeRange(node string, startTime, endTime time.Time) ([]repository.MeasurementRecord, error) {
+	time.Sleep(r.delay)
+	return r.MockMeasurementRepository.GetNodeMeasurementsByTimeRange(node, startTime, endTime)
+}
+
+func TestHandleHistoricalExport_ClearsWriteDeadline(t *testing.T) {
+	repo := &slowRepository{
+		MockMeasurementRepository: MockMeasurementRepository{data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Temperature: 24.1, Humidity: 62.0, Pressure: 100900},
+		}},
+		delay: 300 * time.Millisecond,
+	}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, repo)
+
+	ts := httptest.NewUnstartedServer(server.server.Handler)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/data/export?type=h&from=2026-03-15T00:00:00Z&to=2026-03-16T00:00:00Z")
+	if err != nil {
+		t.Fatalf("expected the export to outlive the write timeout: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "24.1") {
+		t.Errorf("unexpected response %d %q (%v)", resp.StatusCode, body, err)
+	}
+}
+
+func TestHandleRawData_TableFormats(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithRawData(newRawRepository(t, 5)))
+	get := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/data/raw?format="+format, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("parquet")
+	body := w.Body.Bytes()
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if !bytes.HasPrefix(body, []byte("PAR1")) || !bytes.HasSuffix(body, []byte("PAR1")) || !bytes.Contains(body, []byte("atmosbyte.units")) {
+		t.Errorf("expected a Parquet file, got %q", body)
+	}
+
+	w = get("xlsx")
+	sheet := readSheet(t, w.Body.Bytes())
+	if rows := strings.Count(sheet, "<row "); rows != 6 || !strings.Contains(sheet, "temperature (°C)") {
+		t.Errorf("expected a header and 5 measurements, got %d rows: %s", rows, sheet)
+	}
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnsureCertificate_GeneratesAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls", "server.crt")
+	keyFile := filepath.Join(dir, "tls", "server.key")
+
+	generated, err := ensureCertificate(certFile, keyFile, "weather-pi")
+	if err != nil {
+		t.Fatalf("ensureCertificate returned error: %v", err)
+	}
+	if !generated {
+		t.Fatal("expected a certificate to be generated")
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("expected key file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected key permissions 0600, got %v", info.Mode().Perm())
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("generated pair does not load: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if err := cert.VerifyHostname("weather-pi.local"); err != nil {
+		t.Errorf("expected certificate to cover the mDNS name: %v", err)
+	}
+
+	generated, err = ensureCertificate(certFile, keyFile, "weather-pi")
+	if err != nil || generated {
+		t.Fatalf("expected existing certificate to be kept, got generated=%v err=%v", generated, err)
+	}
+}
+
+func TestEnsureCertificate_RejectsHalfPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	if err := os.WriteFile(certFile, []byte("cert"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ensureCertificate(certFile, filepath.Join(dir, "server.key"), "localhost"); err == nil {
+		t.Fatal("expected error when only the certificate exists")
+	}
+}
+
+func TestCertReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	if _, err := ensureCertificate(certFile, keyFile, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader returned error: %v", err)
+	}
+	first, _ := reloader.GetCertificate(nil)
+
+	if reloader.changed() {
+		t.Fatal("expected no change right after loading")
+	}
+
+	os.Remove(certFile)
+	os.Remove(keyFile)
+	if _, err := ensureCertificate(certFile, keyFile, "second"); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+
+	if !reloader.changed() {
+		t.Fatal("expected rewritten files to be detected")
+	}
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+
+	second, _ := reloader.GetCertificate(nil)
+	if second == first {
+		t.Fatal("expected a new certificate after reload")
+	}
+	leaf, _ := x509.ParseCertificate(second.Certificate[0])
+	if leaf.Subject.CommonName != "second" {
+		t.Errorf("expected reloaded certificate for second, got %s", leaf.Subject.CommonName)
+	}
+
+	// A broken pair keeps the previous certificate
+	os.WriteFile(keyFile, []byte("garbage"), 0600)
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("expected reload of a broken key to fail")
+	}
+	if current, _ := reloader.GetCertificate(nil); current != second {
+		t.Error("expected previous certificate to be kept after a failed reload")
+	}
+}
+
+func TestCertReloader_ServesTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	if _, err := ensureCertificate(certFile, keyFile, "localhost"); err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+		TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate},
+	}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pem, _ := os.ReadFile(certFile)
+	pool.AppendCertsFromPEM(pem)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	resp, err := client.Get("https://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("TLS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port int
+		want string
+	}{
+		{8443, "https://weather.local:8443/data?type=h"},
+		{443, "https://weather.local/data?type=h"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://weather.local:8080/data?type=h", nil)
+		w := httptest.NewRecorder()
+		redirectHandler(tt.port).ServeHTTP(w, req)
+
+		if w.Code != http.StatusMovedPermanently {
+			t.Fatalf("expected 301, got %d", w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tt.want {
+			t.Errorf("expected redirect to %s, got %s", tt.want, got)
+		}
+	}
+}
+
+func TestRedirectHandler_KeepsMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://weather.local:8080/api/v1/ingest", strings.NewReader("{}"))
+	w := httptest.NewRecorder()
+	redirectHandler(8443).ServeHTTP(w, req)
+
+	if w.Code != http.StatusPermanentRedirect {
+		t.Fatalf("expected 308 for POST, got %d", w.Code)
+	}
+	if got := w.Header().Get("Location"); got != "https://weather.local:8443/api/v1/ingest" {
+		t.Errorf("expected redirect to the HTTPS ingest URL, got %s", got)
+	}
+}
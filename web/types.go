@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/records"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 )
 
 // QueueStatsProvider define a interface para obter estatísticas da fila
@@ -12,13 +15,24 @@ type QueueStatsProvider interface {
 	Stats() queue.QueueStats
 }
 
+// RecordsProvider define a interface para obter os recordes meteorológicos
+type RecordsProvider interface {
+	Snapshot(now time.Time) records.Snapshot
+}
+
+// AnomalyProvider define a interface para consultar anomalias detectadas
+type AnomalyProvider interface {
+	GetAnomaliesByTimeRange(startTime, endTime time.Time) ([]repository.AnomalyRecord, error)
+}
+
 // MeasurementResponse represents the JSON response for measurement endpoints
 type MeasurementResponse struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Temperature float64   `json:"temperature"`
-	Humidity    float64   `json:"humidity"`
-	Pressure    float64   `json:"pressure"`
-	Source      string    `json:"source"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Temperature float64       `json:"temperature"`
+	Humidity    float64       `json:"humidity"`
+	Pressure    float64       `json:"pressure"`
+	Source      string        `json:"source"`
+	Units       weather.Units `json:"units"`
 }
 
 // ErrorResponse represents the JSON response for errors
@@ -38,3 +52,8 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
 	lrw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the connection to flush and change deadlines
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
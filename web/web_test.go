@@ -13,7 +13,9 @@ import (
 
 	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/records"
 	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/weather"
 )
 
 func testConfig() *Config {
@@ -72,6 +74,10 @@ func (m *MockMeasurementRepository) GetMeasurementsByTimeRange(startTime, endTim
 	return measurement, nil
 }
 
+func (m *MockMeasurementRepository) GetNodeMeasurementsByTimeRange(node string, startTime, endTime time.Time) ([]repository.MeasurementRecord, error) {
+	return m.GetMeasurementsByTimeRange(startTime, endTime)
+}
+
 var queueProvider = &MockQueueStatsProvider{
 	stats: queue.QueueStats{
 		QueueSize:      5,
@@ -258,3 +264,294 @@ func BenchmarkHandleMeasurements(b *testing.B) {
 		server.handleMeasurements(w, req)
 	}
 }
+
+type MockRecordsProvider struct {
+	snapshot records.Snapshot
+}
+
+func (m *MockRecordsProvider) Snapshot(now time.Time) records.Snapshot {
+	m.snapshot.Timestamp = now
+	return m.snapshot
+}
+
+func TestHandleRecords_Success(t *testing.T) {
+	high := records.Extreme{Value: 31.5, Timestamp: time.Date(2026, 1, 20, 15, 0, 0, 0, time.UTC)}
+	provider := &MockRecordsProvider{snapshot: records.Snapshot{
+		AllTime: records.Set{Temperature: records.Range{High: &high}},
+	}}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithRecords(provider))
+
+	req := httptest.NewRequest(http.MethodGet, "/records", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response records.Snapshot
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.AllTime.Temperature.High == nil || response.AllTime.Temperature.High.Value != 31.5 {
+		t.Fatalf("unexpected all-time temperature high: %+v", response.AllTime.Temperature.High)
+	}
+}
+
+func TestHandleRecords_NotConfigured503(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	req := httptest.NewRequest(http.MethodGet, "/records", nil)
+	w := httptest.NewRecorder()
+	server.handleRecords(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestHandleDegreeDays_Success(t *testing.T) {
+	repo := &MockMeasurementRepository{
+		data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), Temperature: 8.0, Humidity: 80.0, Pressure: 101000},
+		},
+	}
+	cfg := testConfig()
+	cfg.DegreeDays = weather.DefaultDegreeDayConfig()
+	server := NewServer(t.Context(), &MockSensorProvider{}, cfg, queueProvider, repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/data/degree-days?from=2026-03-01T00:00:00Z&to=2026-03-31T00:00:00Z&heating_base=15", nil)
+	w := httptest.NewRecorder()
+	server.handleDegreeDays(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response []weather.DegreeDay
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response) != 1 {
+		t.Fatalf("expected 1 day, got %d", len(response))
+	}
+	if response[0].Heating != 7 || response[0].HeatingTotal != 7 {
+		t.Fatalf("expected HDD 7 with base 15, got %+v", response[0])
+	}
+}
+
+func TestHandleDegreeDays_InvalidBase400(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	for _, query := range []string{"growing_base=abc", "growing_base=20&growing_cap=20"} {
+		req := httptest.NewRequest(http.MethodGet, "/data/degree-days?"+query, nil)
+		w := httptest.NewRecorder()
+		server.handleDegreeDays(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+type MockAnomalyProvider struct {
+	data []repository.AnomalyRecord
+	err  error
+}
+
+func (m *MockAnomalyProvider) GetAnomaliesByTimeRange(startTime, endTime time.Time) ([]repository.AnomalyRecord, error) {
+	return m.data, m.err
+}
+
+func TestHandleAnomalies_Success(t *testing.T) {
+	provider := &MockAnomalyProvider{data: []repository.AnomalyRecord{
+		{ID: 1, Timestamp: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Metric: "temperature", Value: 41.2, Expected: 24.0, Score: 5.1, Method: "mad"},
+	}}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithAnomalies(provider))
+
+	req := httptest.NewRequest(http.MethodGet, "/anomalies?from=2026-03-15T00:00:00Z&to=2026-03-16T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response []repository.AnomalyRecord
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response) != 1 || response[0].Metric != "temperature" {
+		t.Fatalf("unexpected anomalies response: %+v", response)
+	}
+}
+
+func TestHandleAnomalies_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider AnomalyProvider
+		url      string
+		want     int
+	}{
+		{"not configured", nil, "/anomalies", http.StatusServiceUnavailable},
+		{"invalid range", &MockAnomalyProvider{}, "/anomalies?from=yesterday", http.StatusBadRequest},
+		{"provider error", &MockAnomalyProvider{err: errors.New("db unavailable")}, "/anomalies", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.provider != nil {
+				opts = append(opts, WithAnomalies(tt.provider))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			server.handleAnomalies(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleCompare_Success(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	req := httptest.NewRequest(http.MethodGet, "/data/compare?range=2026-03-15T00:00:00Z/2026-03-16T00:00:00Z&range=2026-03-14T00:00:00Z/2026-03-15T00:00:00Z&label=today&label=yesterday&climatology=2", nil)
+	w := httptest.NewRecorder()
+	server.handleCompare(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response weather.Comparison
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Series) != 3 {
+		t.Fatalf("expected 3 series, got %d", len(response.Series))
+	}
+	if response.Series[0].Label != "today" || response.Series[1].Label != "yesterday" || response.Series[2].Label != "climatology" {
+		t.Fatalf("unexpected series labels: %+v", response.Series)
+	}
+	if response.Series[2].Years != 2 {
+		t.Fatalf("expected climatology over 2 years, got %d", response.Series[2].Years)
+	}
+}
+
+func TestHandleCompare_InvalidQuery400(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	for _, query := range []string{
+		"",
+		"?range=2026-03-15T00:00:00Z/2026-03-16T00:00:00Z",
+		"?range=2026-03-15T00:00:00Z&range=2026-03-14T00:00:00Z/2026-03-15T00:00:00Z",
+		"?range=2026-03-16T00:00:00Z/2026-03-15T00:00:00Z&climatology=1",
+		"?range=2026-03-15T00:00:00Z/2026-03-16T00:00:00Z&climatology=0",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/data/compare"+query, nil)
+		w := httptest.NewRecorder()
+		server.handleCompare(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestHandleMeasurements_Units(t *testing.T) {
+	measurement := bme280.Measurement{Temperature: 25, Humidity: 60, Pressure: 101325}
+	cfg := testConfig()
+	cfg.Units = "hPa"
+	server := NewServer(t.Context(), &MockSensorProvider{measurement: measurement}, cfg, queueProvider, &MockMeasurementRepository{})
+
+	tests := []struct {
+		name     string
+		url      string
+		header   string
+		wantTemp float64
+		wantPres float64
+		wantUnit weather.PressureUnit
+	}{
+		{"configured default", "/measurements", "", 25, 1013.25, weather.Hectopascal},
+		{"query parameter", "/measurements?units=imperial", "", 77, 29.921, weather.InchMercury},
+		{"accept header", "/measurements", "F, mmHg", 77, 760, weather.MillimeterMercury},
+		{"query wins over header", "/measurements?units=kPa", "mmHg", 25, 101.325, weather.Kilopascal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Units", tt.header)
+			}
+			w := httptest.NewRecorder()
+			server.handleMeasurements(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", w.Code)
+			}
+
+			var response MeasurementResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Temperature != tt.wantTemp || response.Pressure != tt.wantPres {
+				t.Fatalf("expected %v/%v, got %v/%v", tt.wantTemp, tt.wantPres, response.Temperature, response.Pressure)
+			}
+			if response.Units.Pressure != tt.wantUnit {
+				t.Fatalf("expected pressure unit %s, got %s", tt.wantUnit, response.Units.Pressure)
+			}
+			if !strings.Contains(w.Header().Get("Content-Units"), "pressure="+string(tt.wantUnit)) {
+				t.Fatalf("unexpected Content-Units header %q", w.Header().Get("Content-Units"))
+			}
+		})
+	}
+}
+
+func TestHandleMeasurements_InvalidUnits400(t *testing.T) {
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{})
+
+	req := httptest.NewRequest(http.MethodGet, "/measurements?units=kelvin", nil)
+	w := httptest.NewRecorder()
+	server.handleMeasurements(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleHistoricalWeatherAPI_Units(t *testing.T) {
+	repo := &MockMeasurementRepository{
+		data: []repository.MeasurementRecord{
+			{Timestamp: time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), Temperature: 20, Humidity: 50, Pressure: 100000},
+		},
+	}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, repo)
+
+	req := httptest.NewRequest(http.MethodGet, "/data?type=h&from=2026-03-15T00:00:00Z&to=2026-03-16T00:00:00Z&units=F,kPa", nil)
+	w := httptest.NewRecorder()
+	server.handleHistoricalWeatherAPI(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response []weather.ConvertedAggregate
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response) != 1 {
+		t.Fatalf("expected 1 aggregate, got %d", len(response))
+	}
+	if *response[0].Temp.Average != 68 || *response[0].Pressure.Average != 100 {
+		t.Fatalf("unexpected converted aggregate: temp %v, pressure %v", *response[0].Temp.Average, *response[0].Pressure.Average)
+	}
+	if w.Header().Get("Content-Units") != "temperature=F; humidity=%; pressure=kPa" {
+		t.Fatalf("unexpected Content-Units header %q", w.Header().Get("Content-Units"))
+	}
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+const (
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+// WebhookHistoryProvider define a interface para consultar o histórico de entregas de webhooks
+type WebhookHistoryProvider interface {
+	GetWebhookDeliveries(filter repository.WebhookDeliveryFilter) ([]repository.WebhookDeliveryRecord, error)
+}
+
+// WithWebhooks enables the /webhooks/deliveries endpoint backed by the given provider
+func WithWebhooks(webhooks WebhookHistoryProvider) Option {
+	return func(s *Server) {
+		s.webhooks = webhooks
+	}
+}
+
+// handleWebhookDeliveries handles GET /webhooks/deliveries - returns the most recent
+// webhook deliveries, optionally filtered by target, event and status
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.WebhookDeliveryFilter{
+		Target: query.Get("target"),
+		Event:  query.Get("event"),
+		Status: query.Get("status"),
+		Limit:  defaultDeliveryLimit,
+	}
+
+	switch filter.Status {
+	case "", repository.WebhookPending, repository.WebhookDelivered, repository.WebhookFailed:
+	default:
+		s.sendErrorResponse(w, "Invalid status, use pending, delivered or failed", http.StatusBadRequest)
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxDeliveryLimit {
+			s.sendErrorResponse(w, "Invalid limit, use 1 to 1000", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	if s.webhooks == nil {
+		s.sendErrorResponse(w, "Webhooks not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	deliveries, err := s.webhooks.GetWebhookDeliveries(filter)
+	if err != nil {
+		logging.Errorf("Failed to get webhook deliveries: %v", err)
+		s.sendErrorResponse(w, "Failed to fetch webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	s.sendJSONResponse(w, deliveries, http.StatusOK)
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+type MockWebhookHistory struct {
+	data   []repository.WebhookDeliveryRecord
+	err    error
+	filter repository.WebhookDeliveryFilter
+}
+
+func (m *MockWebhookHistory) GetWebhookDeliveries(filter repository.WebhookDeliveryFilter) ([]repository.WebhookDeliveryRecord, error) {
+	m.filter = filter
+	return m.data, m.err
+}
+
+func TestHandleWebhookDeliveries_Success(t *testing.T) {
+	history := &MockWebhookHistory{data: []repository.WebhookDeliveryRecord{
+		{ID: 7, Target: "slack", Event: "sensor_down", Status: repository.WebhookFailed, Attempts: 10, ResponseCode: 500},
+	}}
+	server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, WithWebhooks(history))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/deliveries?target=slack&status=failed&limit=5", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	want := repository.WebhookDeliveryFilter{Target: "slack", Status: repository.WebhookFailed, Limit: 5}
+	if history.filter != want {
+		t.Errorf("expected filter %+v, got %+v", want, history.filter)
+	}
+
+	var response []repository.WebhookDeliveryRecord
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response) != 1 || response[0].ID != 7 || response[0].Attempts != 10 {
+		t.Fatalf("unexpected deliveries response: %+v", response)
+	}
+}
+
+func TestHandleWebhookDeliveries_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		history WebhookHistoryProvider
+		url     string
+		want    int
+	}{
+		{"not configured", nil, "/webhooks/deliveries", http.StatusServiceUnavailable},
+		{"invalid status", &MockWebhookHistory{}, "/webhooks/deliveries?status=lost", http.StatusBadRequest},
+		{"invalid limit", &MockWebhookHistory{}, "/webhooks/deliveries?limit=5000", http.StatusBadRequest},
+		{"provider error", &MockWebhookHistory{err: errors.New("db unavailable")}, "/webhooks/deliveries", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.history != nil {
+				opts = append(opts, WithWebhooks(tt.history))
+			}
+			server := NewServer(t.Context(), &MockSensorProvider{}, testConfig(), queueProvider, &MockMeasurementRepository{}, opts...)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			server.handleWebhookDeliveries(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/internal/logging"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// Repository is the subset of the repository storing deliveries
+type Repository interface {
+	SaveWebhookDelivery(delivery repository.WebhookDeliveryRecord) (int64, error)
+	UpdateWebhookDelivery(delivery repository.WebhookDeliveryRecord) error
+	GetPendingWebhookDeliveries(before time.Time) ([]repository.WebhookDeliveryRecord, error)
+	DeleteWebhookDeliveries(before time.Time) (int64, error)
+}
+
+// Config configures the Dispatcher
+type Config struct {
+	Targets         []Target
+	Thresholds      []Threshold
+	Queue           queue.QueueConfig
+	RequeueInterval time.Duration // Pending deliveries not attempted for this long are enqueued again
+	Retention       time.Duration // Delivered and failed deliveries older than this are deleted
+	Client          *http.Client
+}
+
+// Dispatcher renders events into deliveries and sends them through its own queue.
+// Deliveries are stored as pending before they are enqueued; the ones dropped by the
+// queue, because it was full, its circuit breaker was open or the process stopped,
+// are enqueued again by Start.
+type Dispatcher struct {
+	config    Config
+	targets   []*target
+	repo      Repository
+	client    *http.Client
+	queue     *queue.Queue[repository.WebhookDeliveryRecord]
+	startedAt time.Time
+	now       func() time.Time
+
+	mu      sync.Mutex
+	crossed map[string]bool // Thresholds currently crossed, by metric and direction
+}
+
+// NewDispatcher validates the targets and thresholds and creates a dispatcher
+func NewDispatcher(ctx context.Context, repo Repository, config Config) (*Dispatcher, error) {
+	d := &Dispatcher{
+		config:    config,
+		repo:      repo,
+		client:    config.Client,
+		startedAt: time.Now(),
+		now:       time.Now,
+		crossed:   make(map[string]bool),
+	}
+
+	names := make(map[string]bool)
+	for _, t := range config.Targets {
+		parsed, err := newTarget(t)
+		if err != nil {
+			return nil, err
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("duplicate webhook name %s", t.Name)
+		}
+		names[t.Name] = true
+		d.targets = append(d.targets, parsed)
+	}
+	for _, threshold := range config.Thresholds {
+		if err := threshold.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.client == nil {
+		d.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if d.config.RequeueInterval <= 0 {
+		d.config.RequeueInterval = 10 * time.Minute
+	}
+	if d.config.Retention <= 0 {
+		d.config.Retention = 30 * 24 * time.Hour
+	}
+
+	d.queue = queue.NewQueue[repository.WebhookDeliveryRecord](ctx, d, config.Queue)
+	return d, nil
+}
+
+// Publish creates and enqueues a delivery of the event for each subscribed target
+func (d *Dispatcher) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = d.now()
+	}
+
+	for _, t := range d.targets {
+		if !t.subscribed(event.Type) {
+			continue
+		}
+
+		body, err := t.render(event)
+		if err != nil {
+			logging.Errorf("Webhook %s: %v", t.Name, err)
+			continue
+		}
+
+		now := d.now()
+		delivery := repository.WebhookDeliveryRecord{
+			Target:    t.Name,
+			Event:     string(event.Type),
+			URL:       t.URL,
+			Method:    t.Method,
+			Body:      body,
+			Status:    repository.WebhookPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		delivery.ID, err = d.repo.SaveWebhookDelivery(delivery)
+		if err != nil {
+			logging.Errorf("Webhook %s: %v", t.Name, err)
+			continue
+		}
+
+		d.enqueue(delivery)
+	}
+}
+
+// enqueue sends a stored delivery to the queue. Deliveries the queue rejects stay
+// pending and are enqueued again by the next requeue.
+func (d *Dispatcher) enqueue(delivery repository.WebhookDeliveryRecord) {
+	if err := d.queue.Enqueue(delivery); err != nil {
+		logging.Warnf("Webhook %s: delivery %d kept pending: %v", delivery.Target, delivery.ID, err)
+	}
+}
+
+// Start runs the delivery queue, enqueues deliveries left pending by a previous run,
+// and publishes reading and threshold events from readings until the context passed
+// to NewDispatcher is cancelled. Finished deliveries older than the retention are
+// deleted at start and every requeue interval.
+func (d *Dispatcher) Start(readings <-chan bme280.Measurement) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- d.queue.Start()
+	}()
+
+	d.requeue(d.startedAt)
+	d.prune()
+
+	ticker := time.NewTicker(d.config.RequeueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			d.requeue(d.now().Add(-d.config.RequeueInterval))
+			d.prune()
+		case measurement, ok := <-readings:
+			if !ok {
+				readings = nil
+				continue
+			}
+			d.Publish(Event{
+				Type:        EventReading,
+				Time:        measurement.Timestamp,
+				Message:     fmt.Sprintf("%.1f °C, %.1f%% humidity, %.1f hPa", measurement.Temperature, measurement.Humidity, float64(measurement.Pressure)/100),
+				Measurement: &measurement,
+			})
+			d.checkThresholds(measurement)
+		}
+	}
+}
+
+// requeue enqueues the pending deliveries last updated before the given time
+func (d *Dispatcher) requeue(before time.Time) {
+	pending, err := d.repo.GetPendingWebhookDeliveries(before)
+	if err != nil {
+		logging.Errorf("Failed to load pending webhook deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range pending {
+		delivery.UpdatedAt = d.now()
+		if err := d.repo.UpdateWebhookDelivery(delivery); err != nil {
+			logging.Errorf("Webhook %s: %v", delivery.Target, err)
+			continue
+		}
+		d.enqueue(delivery)
+	}
+}
+
+// prune deletes the delivered and failed deliveries last updated before the retention
+func (d *Dispatcher) prune() {
+	deleted, err := d.repo.DeleteWebhookDeliveries(d.now().Add(-d.config.Retention))
+	if err != nil {
+		logging.Errorf("Failed to delete old webhook deliveries: %v", err)
+		return
+	}
+	if deleted > 0 {
+		logging.Debugf("Deleted %d webhook deliveries older than %v", deleted, d.config.Retention)
+	}
+}
+
+// checkThresholds publishes a threshold event for each limit the measurement crosses
+func (d *Dispatcher) checkThresholds(measurement bme280.Measurement) {
+	for _, threshold := range d.config.Thresholds {
+		value, _ := metricValue(threshold.Metric, measurement)
+		if threshold.Above != nil {
+			d.checkLimit(threshold.Metric, "above", value, *threshold.Above, value > *threshold.Above, measurement)
+		}
+		if threshold.Below != nil {
+			d.checkLimit(threshold.Metric, "below", value, *threshold.Below, value < *threshold.Below, measurement)
+		}
+	}
+}
+
+// checkLimit publishes a threshold event when a limit becomes crossed
+func (d *Dispatcher) checkLimit(metric, direction string, value, limit float64, crossed bool, measurement bme280.Measurement) {
+	key := metric + " " + direction
+	d.mu.Lock()
+	fire := crossed && !d.crossed[key]
+	d.crossed[key] = crossed
+	d.mu.Unlock()
+
+	if !fire {
+		return
+	}
+
+	d.Publish(Event{
+		Type:        EventThreshold,
+		Time:        measurement.Timestamp,
+		Message:     fmt.Sprintf("%s is %s %s (%s)", metric, direction, formatValue(limit), formatValue(value)),
+		Measurement: &measurement,
+		Metric:      metric,
+		Value:       value,
+		Limit:       limit,
+		Direction:   direction,
+	})
+}
+
+// CircuitStateChanged returns a queue.CircuitBreakerConfig.OnStateChange callback
+// publishing circuit events of the named queue. Half-open probes are not reported.
+func (d *Dispatcher) CircuitStateChanged(source string) func(from, to queue.CircuitBreakerState) {
+	return func(from, to queue.CircuitBreakerState) {
+		switch to {
+		case queue.CircuitBreakerOpen:
+			if from == queue.CircuitBreakerHalfOpen {
+				return
+			}
+			d.Publish(Event{Type: EventCircuitOpen, Source: source, Message: fmt.Sprintf("%s circuit breaker opened", source)})
+		case queue.CircuitBreakerClosed:
+			d.Publish(Event{Type: EventCircuitClosed, Source: source, Message: fmt.Sprintf("%s circuit breaker closed", source)})
+		}
+	}
+}
+
+// SensorStatusChanged publishes sensor_down and sensor_up events
+func (d *Dispatcher) SensorStatusChanged(up bool, err error) {
+	if up {
+		d.Publish(Event{Type: EventSensorUp, Message: "Sensor is answering again"})
+		return
+	}
+
+	event := Event{Type: EventSensorDown, Message: "Sensor stopped answering"}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	d.Publish(event)
+}
+
+// Process implements queue.Worker[repository.WebhookDeliveryRecord]. The outcome of
+// each attempt is stored; a delivery fails once its error is not retryable or the
+// queue has no tries left.
+func (d *Dispatcher) Process(ctx context.Context, msg queue.Message[repository.WebhookDeliveryRecord]) error {
+	delivery := msg.Data
+	delivery.Attempts += msg.Attempts
+
+	code, err := d.send(ctx, delivery)
+	delivery.ResponseCode = code
+	delivery.UpdatedAt = d.now()
+	delivery.Error = ""
+	delivery.Status = repository.WebhookDelivered
+	if err != nil {
+		delivery.Error = err.Error()
+		delivery.Status = repository.WebhookPending
+		if re, ok := err.(queue.RetryableError); (ok && !re.IsRetryable()) || msg.Attempts >= msg.MaxTries {
+			delivery.Status = repository.WebhookFailed
+		}
+	}
+
+	if updateErr := d.repo.UpdateWebhookDelivery(delivery); updateErr != nil {
+		logging.Errorf("Webhook %s: %v", delivery.Target, updateErr)
+	}
+
+	return err
+}
+
+// send performs a delivery with the current headers and secret of its target
+func (d *Dispatcher) send(ctx context.Context, delivery repository.WebhookDeliveryRecord) (int, error) {
+	t := d.target(delivery.Target)
+	if t == nil {
+		return 0, queue.NewRetryableError(fmt.Errorf("webhook %s is no longer configured", delivery.Target), false)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, delivery.Method, delivery.URL, strings.NewReader(delivery.Body))
+	if err != nil {
+		return 0, queue.NewRetryableError(fmt.Errorf("failed to create request: %w", err), false)
+	}
+	for name, value := range t.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", t.ContentType)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	if t.Secret != "" {
+		timestamp := d.now()
+		req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+		req.Header.Set(HeaderSignature, Sign(t.Secret, timestamp, []byte(delivery.Body)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, queue.NewRetryableError(fmt.Errorf("failed to call webhook: %w", err), true)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500
+	return resp.StatusCode, queue.NewRetryableError(err, retryable)
+}
+
+// target returns the configured target with the given name
+func (d *Dispatcher) target(name string) *target {
+	for _, t := range d.targets {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// formatValue formats a metric value without trailing zeros
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,360 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/queue"
+	"github.com/anibaldeboni/zero-paper/atmosbyte/repository"
+)
+
+// memoryRepository stores deliveries in memory
+type memoryRepository struct {
+	mu         sync.Mutex
+	deliveries []repository.WebhookDeliveryRecord
+}
+
+func (r *memoryRepository) SaveWebhookDelivery(delivery repository.WebhookDeliveryRecord) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = int64(len(r.deliveries) + 1)
+	r.deliveries = append(r.deliveries, delivery)
+	return delivery.ID, nil
+}
+
+func (r *memoryRepository) UpdateWebhookDelivery(delivery repository.WebhookDeliveryRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if delivery.ID < 1 || int(delivery.ID) > len(r.deliveries) {
+		return errors.New("unknown delivery")
+	}
+	r.deliveries[delivery.ID-1] = delivery
+	return nil
+}
+
+func (r *memoryRepository) GetPendingWebhookDeliveries(before time.Time) ([]repository.WebhookDeliveryRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []repository.WebhookDeliveryRecord
+	for _, delivery := range r.deliveries {
+		if delivery.Status == repository.WebhookPending && delivery.UpdatedAt.Before(before) {
+			pending = append(pending, delivery)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memoryRepository) DeleteWebhookDeliveries(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for i, delivery := range r.deliveries {
+		if delivery.ID != 0 && delivery.Status != repository.WebhookPending && delivery.UpdatedAt.Before(before) {
+			r.deliveries[i] = repository.WebhookDeliveryRecord{} // Keeps the index of the others
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *memoryRepository) get(id int64) repository.WebhookDeliveryRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[id-1]
+}
+
+func (r *memoryRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.deliveries)
+}
+
+// receiver is a webhook endpoint answering with the queued statuses, then 200
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, string(body))
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func testQueueConfig() queue.QueueConfig {
+	return queue.QueueConfig{
+		Workers:     1,
+		BufferSize:  10,
+		RetryPolicy: queue.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+		CircuitBreakerConfig: queue.CircuitBreakerConfig{
+			FailureThreshold: 10,
+			Timeout:          time.Second,
+		},
+	}
+}
+
+// startDispatcher starts a dispatcher and returns its readings channel
+func startDispatcher(t *testing.T, repo Repository, config Config) (*Dispatcher, chan bme280.Measurement) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	if config.Queue.Workers == 0 {
+		config.Queue = testQueueConfig()
+	}
+	d, err := NewDispatcher(ctx, repo, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readings := make(chan bme280.Measurement)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Start(readings)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d, readings
+}
+
+// waitFor waits until cond holds or fails the test after a timeout
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcher_TemplatedSignedDelivery(t *testing.T) {
+	server := newReceiver(t)
+	repo := &memoryRepository{}
+	_, readings := startDispatcher(t, repo, Config{
+		Targets: []Target{{
+			Name:     "slack",
+			URL:      server.URL + "/hook",
+			Events:   []EventType{EventReading},
+			Headers:  map[string]string{"Authorization": "Bearer token"},
+			Template: `{"text": {{json .Message}}, "temperature": {{round 1 .Measurement.Temperature}}}`,
+			Secret:   "s3cret",
+		}},
+	})
+
+	readings <- bme280.Measurement{Timestamp: time.Unix(1748779200, 0), Temperature: 21.46, Humidity: 55, Pressure: 101325}
+	waitFor(t, func() bool { return repo.count() == 1 && repo.get(1).Status == repository.WebhookDelivered })
+
+	want := `{"text": "21.5 °C, 55.0% humidity, 1013.2 hPa", "temperature": 21.5}`
+	if server.bodies[0] != want {
+		t.Errorf("expected body %s, got %s", want, server.bodies[0])
+	}
+
+	req := server.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/hook" {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+	}
+	if req.Header.Get("Authorization") != "Bearer token" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+	if req.Header.Get(HeaderEvent) != "reading" || req.Header.Get(HeaderDelivery) != "1" {
+		t.Errorf("unexpected event headers %v", req.Header)
+	}
+
+	unix, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid timestamp header: %v", err)
+	}
+	if got := req.Header.Get(HeaderSignature); got != Sign("s3cret", time.Unix(unix, 0), []byte(want)) {
+		t.Errorf("signature %s does not match the body", got)
+	}
+}
+
+func TestDispatcher_RetriesRetryableFailures(t *testing.T) {
+	server := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	repo := &memoryRepository{}
+	d, _ := startDispatcher(t, repo, Config{
+		Targets: []Target{{Name: "ntfy", URL: server.URL, Events: []EventType{EventSensorDown}}},
+	})
+
+	d.SensorStatusChanged(false, errors.New("i2c timeout"))
+	waitFor(t, func() bool { return repo.count() == 1 && repo.get(1).Status == repository.WebhookDelivered })
+
+	delivery := repo.get(1)
+	if delivery.Attempts != 3 || delivery.ResponseCode != http.StatusOK || delivery.Error != "" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+}
+
+func TestDispatcher_FailsOnClientError(t *testing.T) {
+	server := newReceiver(t, http.StatusBadRequest)
+	repo := &memoryRepository{}
+	d, _ := startDispatcher(t, repo, Config{
+		Targets: []Target{{Name: "discord", URL: server.URL, Events: []EventType{EventSensorUp}}},
+	})
+
+	d.SensorStatusChanged(true, nil)
+	waitFor(t, func() bool { return repo.count() == 1 && repo.get(1).Status == repository.WebhookFailed })
+
+	time.Sleep(20 * time.Millisecond)
+	if server.received() != 1 {
+		t.Errorf("expected a single attempt, got %d", server.received())
+	}
+	if delivery := repo.get(1); delivery.ResponseCode != http.StatusBadRequest || delivery.Error == "" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+}
+
+func TestDispatcher_DeliversPendingFromPreviousRun(t *testing.T) {
+	server := newReceiver(t)
+	repo := &memoryRepository{}
+	created := time.Now().Add(-time.Hour)
+	repo.SaveWebhookDelivery(repository.WebhookDeliveryRecord{
+		Target:    "ntfy",
+		Event:     string(EventSensorDown),
+		URL:       server.URL,
+		Method:    http.MethodPost,
+		Body:      "sensor down",
+		Status:    repository.WebhookPending,
+		Attempts:  2,
+		CreatedAt: created,
+		UpdatedAt: created,
+	})
+
+	startDispatcher(t, repo, Config{
+		Targets: []Target{{Name: "ntfy", URL: server.URL, Events: []EventType{EventSensorDown}}},
+	})
+
+	waitFor(t, func() bool { return repo.get(1).Status == repository.WebhookDelivered })
+	if delivery := repo.get(1); delivery.Attempts != 3 {
+		t.Errorf("expected attempts to continue from the stored count, got %d", delivery.Attempts)
+	}
+	if server.bodies[0] != "sensor down" {
+		t.Errorf("unexpected body %q", server.bodies[0])
+	}
+}
+
+func TestDispatcher_DeletesOldDeliveries(t *testing.T) {
+	repo := &memoryRepository{}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, status := range []string{repository.WebhookDelivered, repository.WebhookFailed, repository.WebhookDelivered} {
+		repo.SaveWebhookDelivery(repository.WebhookDeliveryRecord{Target: "ntfy", Status: status, CreatedAt: old, UpdatedAt: old})
+	}
+	recent := time.Now()
+	repo.SaveWebhookDelivery(repository.WebhookDeliveryRecord{Target: "ntfy", Status: repository.WebhookDelivered, CreatedAt: recent, UpdatedAt: recent})
+
+	startDispatcher(t, repo, Config{Retention: 24 * time.Hour})
+
+	waitFor(t, func() bool { return repo.get(1).ID == 0 })
+	for id := range int64(3) {
+		if delivery := repo.get(id + 1); delivery.ID != 0 {
+			t.Errorf("expected delivery %d to be deleted, got %+v", id+1, delivery)
+		}
+	}
+	if repo.get(4).ID != 4 {
+		t.Error("expected the recent delivery to be kept")
+	}
+}
+
+func TestDispatcher_ThresholdFiresOncePerCrossing(t *testing.T) {
+	server := newReceiver(t)
+	repo := &memoryRepository{}
+	above := 30.0
+	_, readings := startDispatcher(t, repo, Config{
+		Targets:    []Target{{Name: "alerts", URL: server.URL, Events: []EventType{EventThreshold}, Template: "{{.Message}}"}},
+		Thresholds: []Threshold{{Metric: "temperature", Above: &above}},
+	})
+
+	for _, temperature := range []float64{25, 31, 32, 29, 30.5} {
+		readings <- bme280.Measurement{Timestamp: time.Now(), Temperature: temperature}
+	}
+
+	waitFor(t, func() bool { return server.received() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if server.received() != 2 {
+		t.Fatalf("expected 2 threshold deliveries, got %d", server.received())
+	}
+	if server.bodies[0] != "temperature is above 30 (31)" || server.bodies[1] != "temperature is above 30 (30.5)" {
+		t.Errorf("unexpected bodies %q", server.bodies)
+	}
+}
+
+func TestDispatcher_CircuitEvents(t *testing.T) {
+	server := newReceiver(t)
+	repo := &memoryRepository{}
+	d, _ := startDispatcher(t, repo, Config{
+		Targets: []Target{{Name: "ops", URL: server.URL, Events: []EventType{EventCircuitOpen, EventCircuitClosed}, Template: "{{.Type}} {{.Source}}"}},
+	})
+
+	changed := d.CircuitStateChanged("storage")
+	changed(queue.CircuitBreakerClosed, queue.CircuitBreakerOpen)
+	changed(queue.CircuitBreakerOpen, queue.CircuitBreakerHalfOpen)
+	changed(queue.CircuitBreakerHalfOpen, queue.CircuitBreakerOpen)
+	changed(queue.CircuitBreakerOpen, queue.CircuitBreakerHalfOpen)
+	changed(queue.CircuitBreakerHalfOpen, queue.CircuitBreakerClosed)
+
+	waitFor(t, func() bool { return server.received() == 2 })
+	if repo.count() != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", repo.count())
+	}
+	if repo.get(1).Body != "circuit_open storage" || repo.get(2).Body != "circuit_closed storage" {
+		t.Errorf("unexpected bodies %q and %q", repo.get(1).Body, repo.get(2).Body)
+	}
+}
+
+func TestNewDispatcher_Validation(t *testing.T) {
+	above := 1.0
+	for name, config := range map[string]Config{
+		"no name":        {Targets: []Target{{URL: "http://localhost", Events: []EventType{EventReading}}}},
+		"bad url":        {Targets: []Target{{Name: "a", URL: "localhost", Events: []EventType{EventReading}}}},
+		"no events":      {Targets: []Target{{Name: "a", URL: "http://localhost"}}},
+		"bad template":   {Targets: []Target{{Name: "a", URL: "http://localhost", Events: []EventType{EventReading}, Template: "{{.Message"}}},
+		"duplicate name": {Targets: []Target{{Name: "a", URL: "http://localhost", Events: []EventType{EventReading}}, {Name: "a", URL: "http://localhost", Events: []EventType{EventReading}}}},
+		"bad metric":     {Thresholds: []Threshold{{Metric: "wind", Above: &above}}},
+		"no limit":       {Thresholds: []Threshold{{Metric: "humidity"}}},
+	} {
+		if _, err := NewDispatcher(t.Context(), &memoryRepository{}, config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=" + "49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"
+	if got := Sign("secret", time.Unix(1700000000, 0), []byte(`{"a":1}`)); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,88 @@
+// Package webhook calls HTTP endpoints when station events occur. Each target
+// renders its payload with a text/template, optionally signs it with HMAC-SHA256,
+// and every delivery is stored before it is sent so it survives restarts.
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anibaldeboni/zero-paper/atmosbyte/bme280"
+)
+
+// EventType identifies what happened
+type EventType string
+
+// Event types a target can subscribe to
+const (
+	EventReading       EventType = "reading"        // New local sensor reading
+	EventCircuitOpen   EventType = "circuit_open"   // A queue's circuit breaker opened
+	EventCircuitClosed EventType = "circuit_closed" // A queue's circuit breaker closed again
+	EventSensorDown    EventType = "sensor_down"    // The sensor stopped answering
+	EventSensorUp      EventType = "sensor_up"      // The sensor answers again
+	EventThreshold     EventType = "threshold"      // A reading crossed a configured threshold
+	EventAlertFiring   EventType = "alert_firing"   // An alert rule fired
+	EventAlertResolved EventType = "alert_resolved" // A firing alert rule resolved
+)
+
+// ParseEventType validates an event type name
+func ParseEventType(name string) (EventType, error) {
+	switch t := EventType(name); t {
+	case EventReading, EventCircuitOpen, EventCircuitClosed, EventSensorDown, EventSensorUp, EventThreshold,
+		EventAlertFiring, EventAlertResolved:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown webhook event %q", name)
+	}
+}
+
+// Event is the data passed to payload templates. Fields not related to the event
+// type are left empty.
+type Event struct {
+	Type        EventType           `json:"type"`
+	Time        time.Time           `json:"time"`
+	Message     string              `json:"message"`               // Human-readable summary
+	Measurement *bme280.Measurement `json:"measurement,omitempty"` // reading and threshold events
+	Source      string              `json:"source,omitempty"`      // Queue of circuit events
+	Metric      string              `json:"metric,omitempty"`      // threshold and alert events
+	Value       float64             `json:"value,omitempty"`
+	Limit       float64             `json:"limit,omitempty"`
+	Direction   string              `json:"direction,omitempty"` // "above" or "below"
+	Error       string              `json:"error,omitempty"`     // sensor_down events
+	Rule        string              `json:"rule,omitempty"`      // alert events
+	Severity    string              `json:"severity,omitempty"`
+}
+
+// Threshold emits a threshold event when a metric of a reading goes above Above or
+// below Below. It fires once per crossing and is armed again when the value returns
+// inside the limit.
+type Threshold struct {
+	Metric string // temperature, humidity or pressure
+	Above  *float64
+	Below  *float64
+}
+
+// Validate checks the metric and that at least one limit is set
+func (t Threshold) Validate() error {
+	if _, ok := metricValue(t.Metric, bme280.Measurement{}); !ok {
+		return fmt.Errorf("unknown threshold metric %q", t.Metric)
+	}
+	if t.Above == nil && t.Below == nil {
+		return fmt.Errorf("threshold on %s requires above or below", t.Metric)
+	}
+	return nil
+}
+
+// metricValue returns the value of a metric of a measurement
+func metricValue(metric string, m bme280.Measurement) (float64, bool) {
+	switch metric {
+	case "temperature":
+		return m.Temperature, true
+	case "humidity":
+		return m.Humidity, true
+	case "pressure":
+		return float64(m.Pressure), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-Atmosbyte-Event"
+	HeaderDelivery  = "X-Atmosbyte-Delivery"
+	HeaderTimestamp = "X-Atmosbyte-Timestamp"
+	HeaderSignature = "X-Atmosbyte-Signature"
+)
+
+// Target is an HTTP endpoint called for the events it subscribes to
+type Target struct {
+	Name        string
+	URL         string
+	Method      string // Defaults to POST
+	Events      []EventType
+	Headers     map[string]string
+	Template    string // text/template executed with the Event; the Event as JSON when empty
+	ContentType string // Defaults to application/json
+	Secret      string // HMAC-SHA256 key; deliveries are not signed when empty
+}
+
+// target is a validated Target with its parsed template
+type target struct {
+	Target
+	template *template.Template
+}
+
+// templateFuncs are available to payload templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value, e.g. to embed the message in a JSON string
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// round formats a number with the given decimals
+	"round": func(decimals int, v float64) string {
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	},
+}
+
+// newTarget validates a target and parses its template
+func newTarget(t Target) (*target, error) {
+	if t.Name == "" {
+		return nil, errors.New("webhook target requires a name")
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook %s requires an http or https url", t.Name)
+	}
+	if len(t.Events) == 0 {
+		return nil, fmt.Errorf("webhook %s requires at least one event", t.Name)
+	}
+	if t.Method == "" {
+		t.Method = http.MethodPost
+	}
+	t.Method = strings.ToUpper(t.Method)
+	if t.ContentType == "" {
+		t.ContentType = "application/json"
+	}
+
+	parsed := &target{Target: t}
+	if t.Template != "" {
+		parsed.template, err = template.New(t.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of webhook %s: %w", t.Name, err)
+		}
+	}
+	return parsed, nil
+}
+
+// subscribed reports whether the target receives events of type t
+func (t *target) subscribed(eventType EventType) bool {
+	return slices.Contains(t.Events, eventType)
+}
+
+// render builds the payload of an event
+func (t *target) render(event Event) (string, error) {
+	if t.template == nil {
+		b, err := json.Marshal(event)
+		return string(b), err
+	}
+
+	var b bytes.Buffer
+	if err := t.template.Execute(&b, event); err != nil {
+		return "", fmt.Errorf("failed to render webhook %s: %w", t.Name, err)
+	}
+	return b.String(), nil
+}
+
+// Sign returns the signature header value of a delivery: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the target secret. Including the timestamp lets
+// receivers reject replayed deliveries.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}